@@ -0,0 +1,462 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"saas-account/config"
+	"saas-account/model"
+	"saas-account/notify"
+	"saas-account/repository"
+	"saas-account/service"
+	"saas-account/utils"
+	"strconv"
+	"time"
+)
+
+// userUsage user 子命令用法
+const userUsage = `用法: saas-account user <命令>
+
+命令:
+  create -name <姓名> -email <邮箱> -password <密码> [-phone <手机号>] [-admin]
+                   创建用户，-admin 表示同时设置为平台管理员
+  promote <id>     设置为平台管理员
+  demote <id>      取消平台管理员
+  token <id>       签发访问令牌，令牌中包含用户当前的平台角色
+`
+
+// orgUsage org 子命令用法
+const orgUsage = `用法: saas-account org <命令>
+
+命令:
+  create -name <名称> -owner <用户ID> [-description <描述>] [-time-zone <时区>]
+                   创建组织，拥有者自动成为组织成员
+`
+
+// appUsage app 子命令用法
+const appUsage = `用法: saas-account app <命令>
+
+命令:
+  rotate-secret <id>          重新生成应用密钥，旧密钥立即失效
+  set-limit <id> [参数]        设置应用限制，未指定的参数保持不变
+      -max-users <n> -max-storage <字节> -max-requests <n/天>
+      -enforcement-mode <hard|soft|notify> -overage-percent <n>
+      -rate-limit-burst <n> -rate-limit-per-min <n> -features <JSON>
+  set-plan <id> <套餐ID>       变更应用套餐，将套餐权益复制到应用限制
+`
+
+// usageUsage usage 子命令用法
+const usageUsage = `用法: saas-account usage summary (-app <应用ID> | -org <组织ID>) [参数]
+
+参数:
+  -from <YYYY-MM-DD>   开始日期，默认为30天前
+  -to <YYYY-MM-DD>     结束日期（含），默认为今天
+  -granularity <hour|day|month>
+                       指定时返回时间序列，否则返回汇总
+`
+
+// snowflakeUsage snowflake 子命令用法
+const snowflakeUsage = `用法: saas-account snowflake decode <id>...
+`
+
+// newUserService 创建管理命令使用的用户服务
+var newUserService = func() service.UserService {
+	txManager := repository.NewTransactionManager()
+	return service.NewUserService(
+		repository.NewUserRepository(),
+		service.NewOutboxPublisher(repository.NewOutboxRepository()),
+		txManager,
+		service.NewAuditService(repository.NewAuditLogRepository(), txManager),
+	)
+}
+
+// newOrganizationService 创建管理命令使用的组织服务
+var newOrganizationService = func() service.OrganizationService {
+	txManager := repository.NewTransactionManager()
+	return service.NewOrganizationService(
+		repository.NewOrganizationRepository(),
+		repository.NewOrganizationMemberRepository(),
+		repository.NewUserRepository(),
+		repository.NewOrganizationApplicationRepository(),
+		service.NewOutboxPublisher(repository.NewOutboxRepository()),
+		txManager,
+		service.NewAuditService(repository.NewAuditLogRepository(), txManager),
+		repository.NewUsageRollupRepository(),
+	)
+}
+
+// newOrganizationApplicationService 创建管理命令使用的组织应用服务
+var newOrganizationApplicationService = func() service.OrganizationApplicationService {
+	txManager := repository.NewTransactionManager()
+	return service.NewOrganizationApplicationService(
+		repository.NewOrganizationApplicationRepository(),
+		repository.NewOrganizationApplicationMemberRepository(),
+		repository.NewOrganizationApplicationLimitRepository(),
+		repository.NewOrganizationRepository(),
+		repository.NewUserRepository(),
+		repository.NewPlanRepository(),
+		repository.NewPlanChangeRepository(),
+		service.NewOutboxPublisher(repository.NewOutboxRepository()),
+		txManager,
+		service.NewAuditService(repository.NewAuditLogRepository(), txManager),
+		repository.NewStorageGaugeRepository(),
+	)
+}
+
+// newApplicationUsageService 创建管理命令使用的使用记录服务，只用于查询，不启用异步写入
+var newApplicationUsageService = func() service.ApplicationUsageService {
+	appRepo := repository.NewOrganizationApplicationRepository()
+	orgRepo := repository.NewOrganizationRepository()
+	limitRepo := repository.NewOrganizationApplicationLimitRepository()
+	counterRepo := repository.NewUsageCounterRepository()
+	gaugeRepo := repository.NewStorageGaugeRepository()
+	alertService := service.NewAlertService(
+		repository.NewAlertRepository(), appRepo, orgRepo, limitRepo, counterRepo, gaugeRepo, notify.GetNotifier(),
+		service.NewOutboxPublisher(repository.NewOutboxRepository()), repository.NewTransactionManager(),
+	)
+	return service.NewApplicationUsageService(
+		repository.NewApplicationUsageRepository(), repository.NewUsageRollupRepository(),
+		appRepo, orgRepo, limitRepo, counterRepo,
+		notify.GetNotifier(), nil,
+		repository.NewUsageIdempotencyRepository(), time.Duration(config.GetConfig().UsageIdempotencyWindow)*time.Hour,
+		service.NewStorageService(gaugeRepo, appRepo, orgRepo, limitRepo, notify.GetNotifier(), alertService, repository.NewTransactionManager()),
+		alertService,
+		repository.NewTransactionManager(),
+	)
+}
+
+// parseIDArg 解析位置参数中的ID
+func parseIDArg(args []string, index int, name string) (int64, error) {
+	if len(args) <= index {
+		return 0, fmt.Errorf("缺少%s", name)
+	}
+
+	id, err := strconv.ParseInt(args[index], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("无效的%s: %s", name, args[index])
+	}
+	return id, nil
+}
+
+// runUserCommand 执行 user 子命令
+func runUserCommand(args []string) int {
+	if len(args) == 0 {
+		return printSubcommandUsage(userUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		return createUser(args[1:])
+	case "promote", "demote", "token":
+		id, err := parseIDArg(args, 1, "用户ID")
+		if err != nil {
+			return fail(err)
+		}
+
+		connectDB()
+		userService := newUserService()
+		switch args[0] {
+		case "promote":
+			user, err := userService.SetRole(commandContext(), id, model.UserRoleAdmin)
+			if err != nil {
+				return fail(err)
+			}
+			return printJSON(user)
+		case "demote":
+			user, err := userService.SetRole(commandContext(), id, model.UserRoleUser)
+			if err != nil {
+				return fail(err)
+			}
+			return printJSON(user)
+		default:
+			token, err := userService.IssueToken(commandContext(), id)
+			if err != nil {
+				return fail(err)
+			}
+			fmt.Fprintln(stdout, token)
+			return 0
+		}
+	default:
+		return printSubcommandUsage(userUsage)
+	}
+}
+
+// createUser 创建用户
+func createUser(args []string) int {
+	fs := newFlagSet("user create")
+	name := fs.String("name", "", "用户姓名")
+	email := fs.String("email", "", "电子邮件")
+	password := fs.String("password", "", "密码")
+	phone := fs.String("phone", "", "手机号")
+	admin := fs.Bool("admin", false, "设置为平台管理员")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *name == "" || *email == "" || *password == "" {
+		return printSubcommandUsage(userUsage)
+	}
+	if !utils.IsValidEmail(*email) {
+		return fail(errors.New("邮箱格式无效"))
+	}
+	if *phone != "" && !utils.IsValidPhone(*phone) {
+		return fail(errors.New("手机号格式无效"))
+	}
+
+	connectDB()
+	userService := newUserService()
+	ctx := commandContext()
+
+	user := &model.User{
+		Name:     *name,
+		Email:    *email,
+		Phone:    *phone,
+		Password: *password,
+	}
+	if err := userService.Create(ctx, user); err != nil {
+		return fail(err)
+	}
+
+	if *admin {
+		var err error
+		if user, err = userService.SetRole(ctx, user.ID, model.UserRoleAdmin); err != nil {
+			return fail(err)
+		}
+	}
+	return printJSON(user)
+}
+
+// runOrgCommand 执行 org 子命令
+func runOrgCommand(args []string) int {
+	if len(args) == 0 || args[0] != "create" {
+		return printSubcommandUsage(orgUsage)
+	}
+
+	fs := newFlagSet("org create")
+	name := fs.String("name", "", "组织名称")
+	ownerID := fs.Int64("owner", 0, "拥有者用户ID")
+	description := fs.String("description", "", "组织描述")
+	timeZone := fs.String("time-zone", "", "组织时区（IANA名称），默认为UTC")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if *name == "" || *ownerID <= 0 {
+		return printSubcommandUsage(orgUsage)
+	}
+
+	connectDB()
+	org := &model.Organization{
+		Name:        *name,
+		Description: *description,
+		TimeZone:    *timeZone,
+	}
+	if err := newOrganizationService().Create(commandContext(), org, *ownerID); err != nil {
+		return fail(err)
+	}
+	return printJSON(org)
+}
+
+// runAppCommand 执行 app 子命令
+func runAppCommand(args []string) int {
+	if len(args) == 0 {
+		return printSubcommandUsage(appUsage)
+	}
+
+	switch args[0] {
+	case "rotate-secret":
+		id, err := parseIDArg(args, 1, "应用ID")
+		if err != nil {
+			return fail(err)
+		}
+
+		connectDB()
+		secret, err := newOrganizationApplicationService().RegenerateAppSecret(commandContext(), id)
+		if err != nil {
+			return fail(err)
+		}
+		return printJSON(map[string]interface{}{
+			"app_id":     id,
+			"app_secret": secret,
+		})
+	case "set-limit":
+		return setApplicationLimit(args[1:])
+	case "set-plan":
+		appID, err := parseIDArg(args, 1, "应用ID")
+		if err != nil {
+			return fail(err)
+		}
+		planID, err := parseIDArg(args, 2, "套餐ID")
+		if err != nil {
+			return fail(err)
+		}
+
+		connectDB()
+		limit, err := newOrganizationApplicationService().ChangePlan(commandContext(), appID, planID)
+		if err != nil {
+			return fail(err)
+		}
+		return printJSON(limit)
+	default:
+		return printSubcommandUsage(appUsage)
+	}
+}
+
+// setApplicationLimit 设置应用限制，在现有限制的基础上修改指定的参数
+func setApplicationLimit(args []string) int {
+	appID, err := parseIDArg(args, 0, "应用ID")
+	if err != nil {
+		return fail(err)
+	}
+
+	fs := newFlagSet("app set-limit")
+	maxUsers := fs.Int("max-users", 0, "最大用户数")
+	maxStorage := fs.Int64("max-storage", 0, "最大存储空间（字节）")
+	maxRequests := fs.Int("max-requests", 0, "最大请求数/天")
+	enforcementMode := fs.String("enforcement-mode", "", "配额执行模式：hard, soft, notify")
+	overagePercent := fs.Int("overage-percent", 0, "soft模式下允许超出配额的百分比，0表示不限")
+	rateLimitBurst := fs.Int("rate-limit-burst", 0, "限流令牌桶容量，0表示不限流")
+	rateLimitPerMin := fs.Int("rate-limit-per-min", 0, "限流令牌桶每分钟补充的令牌数")
+	features := fs.String("features", "", "功能权益，JSON格式")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	connectDB()
+	appService := newOrganizationApplicationService()
+	ctx := commandContext()
+
+	limit, err := appService.GetLimit(ctx, appID)
+	if err != nil {
+		if _, appErr := appService.GetByID(ctx, appID); appErr != nil {
+			return fail(appErr)
+		}
+		// 应用还没有限制，从模型默认值开始
+		limit = &model.OrganizationApplicationLimit{
+			OrganizationApplicationId: appID,
+			MaxUsers:                  5,
+			MaxStorage:                1073741824,
+			MaxRequests:               10000,
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-users":
+			limit.MaxUsers = *maxUsers
+		case "max-storage":
+			limit.MaxStorage = *maxStorage
+		case "max-requests":
+			limit.MaxRequests = *maxRequests
+		case "enforcement-mode":
+			limit.EnforcementMode = *enforcementMode
+		case "overage-percent":
+			limit.OveragePercent = *overagePercent
+		case "rate-limit-burst":
+			limit.RateLimitBurst = *rateLimitBurst
+		case "rate-limit-per-min":
+			limit.RateLimitPerMin = *rateLimitPerMin
+		case "features":
+			limit.Features = *features
+		}
+	})
+
+	if err := appService.SetLimit(ctx, limit); err != nil {
+		return fail(err)
+	}
+	return printJSON(limit)
+}
+
+// runUsageCommand 执行 usage 子命令
+func runUsageCommand(args []string) int {
+	if len(args) == 0 || args[0] != "summary" {
+		return printSubcommandUsage(usageUsage)
+	}
+
+	fs := newFlagSet("usage summary")
+	appID := fs.Int64("app", 0, "应用ID")
+	orgID := fs.Int64("org", 0, "组织ID")
+	from := fs.String("from", "", "开始日期")
+	to := fs.String("to", "", "结束日期（含）")
+	granularity := fs.String("granularity", "", "统计粒度：hour, day, month")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if (*appID > 0) == (*orgID > 0) {
+		return printSubcommandUsage(usageUsage)
+	}
+	switch *granularity {
+	case "", service.UsageGranularityHour, service.UsageGranularityDay, service.UsageGranularityMonth:
+	default:
+		return fail(errors.New("无效的统计粒度，可选值为hour, day, month"))
+	}
+
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -30)
+	if *from != "" || *to != "" {
+		var err1, err2 error
+		startDate, err1 = time.Parse("2006-01-02", *from)
+		endDate, err2 = time.Parse("2006-01-02", *to)
+		if err1 != nil || err2 != nil {
+			return fail(errors.New("无效的日期格式，请同时指定 -from 和 -to，使用YYYY-MM-DD格式"))
+		}
+		if startDate.After(endDate) {
+			return fail(errors.New("开始日期不能晚于结束日期"))
+		}
+	}
+
+	connectDB()
+	usageService := newApplicationUsageService()
+	ctx := commandContext()
+
+	var result interface{}
+	var err error
+	switch {
+	case *appID > 0 && *granularity != "":
+		result, err = usageService.GetSeriesByApplication(ctx, *appID, *granularity, startDate, endDate)
+	case *appID > 0:
+		result, err = usageService.GetSummaryByApplication(ctx, *appID, startDate, endDate)
+	case *granularity != "":
+		result, err = usageService.GetSeriesByOrganization(ctx, *orgID, *granularity, startDate, endDate)
+	default:
+		result, err = usageService.GetSummaryByOrganization(ctx, *orgID, startDate, endDate)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	return printJSON(map[string]interface{}{
+		"start_date": startDate.Format("2006-01-02"),
+		"end_date":   endDate.Format("2006-01-02"),
+		"usage":      result,
+	})
+}
+
+// runSnowflakeCommand 执行 snowflake 子命令，不需要连接数据库
+func runSnowflakeCommand(args []string) int {
+	if len(args) < 2 || args[0] != "decode" {
+		return printSubcommandUsage(snowflakeUsage)
+	}
+
+	results := make([]map[string]interface{}, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fail(fmt.Errorf("无效的ID: %s", arg))
+		}
+
+		parts, err := utils.DecodeSnowflake(id)
+		if err != nil {
+			return fail(fmt.Errorf("%s: %w", arg, err))
+		}
+		results = append(results, map[string]interface{}{
+			"id":            strconv.FormatInt(id, 10),
+			"time":          parts.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			"datacenter_id": parts.DatacenterID,
+			"worker_id":     parts.WorkerID,
+			"sequence":      parts.Sequence,
+		})
+	}
+	return printJSON(results)
+}
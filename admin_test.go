@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"saas-account/service"
+	"saas-account/utils"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeUserService 内存中的用户服务，只实现管理命令使用的方法
+type fakeUserService struct {
+	service.UserService
+	users map[int64]*model.User
+	err   error
+}
+
+func newFakeUserService() *fakeUserService {
+	return &fakeUserService{users: map[int64]*model.User{
+		1: {Base: model.Base{ID: 1}, Name: "张三", Email: "zhangsan@example.com", Role: model.UserRoleUser},
+	}}
+}
+
+func (s *fakeUserService) Create(ctx context.Context, user *model.User) error {
+	if s.err != nil {
+		return s.err
+	}
+	user.ID = int64(len(s.users) + 1)
+	user.Role = model.UserRoleUser
+	s.users[user.ID] = user
+	return nil
+}
+
+func (s *fakeUserService) SetRole(ctx context.Context, id int64, role string) (*model.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, errors.New("用户不存在")
+	}
+	user.Role = role
+	return user, nil
+}
+
+func (s *fakeUserService) IssueToken(ctx context.Context, id int64) (string, error) {
+	if _, ok := s.users[id]; !ok {
+		return "", errors.New("用户不存在")
+	}
+	return "token-" + strconv.FormatInt(id, 10), nil
+}
+
+// fakeOrganizationService 内存中的组织服务
+type fakeOrganizationService struct {
+	service.OrganizationService
+	created *model.Organization
+	ownerID int64
+	err     error
+}
+
+func (s *fakeOrganizationService) Create(ctx context.Context, org *model.Organization, creatorID int64) error {
+	if s.err != nil {
+		return s.err
+	}
+	org.ID = 100
+	s.created, s.ownerID = org, creatorID
+	return nil
+}
+
+// fakeOrganizationApplicationService 内存中的组织应用服务
+type fakeOrganizationApplicationService struct {
+	service.OrganizationApplicationService
+	apps   map[int64]bool
+	limits map[int64]*model.OrganizationApplicationLimit
+	saved  *model.OrganizationApplicationLimit
+	planID int64
+}
+
+func newFakeOrganizationApplicationService() *fakeOrganizationApplicationService {
+	return &fakeOrganizationApplicationService{
+		apps: map[int64]bool{1: true, 2: true},
+		limits: map[int64]*model.OrganizationApplicationLimit{
+			1: {OrganizationApplicationId: 1, MaxUsers: 10, MaxStorage: 2048, MaxRequests: 500, EnforcementMode: "hard"},
+		},
+	}
+}
+
+func (s *fakeOrganizationApplicationService) GetByID(ctx context.Context, id int64) (*model.OrganizationApplication, error) {
+	if !s.apps[id] {
+		return nil, errors.New("应用不存在")
+	}
+	return &model.OrganizationApplication{Base: model.Base{ID: id}}, nil
+}
+
+func (s *fakeOrganizationApplicationService) RegenerateAppSecret(ctx context.Context, id int64) (string, error) {
+	if !s.apps[id] {
+		return "", errors.New("应用不存在")
+	}
+	return "new-secret", nil
+}
+
+func (s *fakeOrganizationApplicationService) GetLimit(ctx context.Context, appID int64) (*model.OrganizationApplicationLimit, error) {
+	limit, ok := s.limits[appID]
+	if !ok {
+		return nil, errors.New("应用限制不存在")
+	}
+	return limit, nil
+}
+
+func (s *fakeOrganizationApplicationService) SetLimit(ctx context.Context, limit *model.OrganizationApplicationLimit) error {
+	s.saved = limit
+	return nil
+}
+
+func (s *fakeOrganizationApplicationService) ChangePlan(ctx context.Context, appID, planID int64) (*model.OrganizationApplicationLimit, error) {
+	if !s.apps[appID] {
+		return nil, errors.New("应用不存在")
+	}
+	s.planID = planID
+	return &model.OrganizationApplicationLimit{OrganizationApplicationId: appID, PlanId: planID}, nil
+}
+
+// fakeApplicationUsageService 记录查询方式的使用记录服务
+type fakeApplicationUsageService struct {
+	service.ApplicationUsageService
+	call        string
+	id          int64
+	granularity string
+	start, end  time.Time
+}
+
+func (s *fakeApplicationUsageService) record(call string, id int64, granularity string, start, end time.Time) {
+	s.call, s.id, s.granularity, s.start, s.end = call, id, granularity, start, end
+}
+
+func (s *fakeApplicationUsageService) GetSummaryByApplication(ctx context.Context, appID int64, start, end time.Time) (map[string]int64, error) {
+	s.record("app-summary", appID, "", start, end)
+	return map[string]int64{"api": 42}, nil
+}
+
+func (s *fakeApplicationUsageService) GetSummaryByOrganization(ctx context.Context, orgID int64, start, end time.Time) (map[string]int64, error) {
+	s.record("org-summary", orgID, "", start, end)
+	return map[string]int64{"api": 42}, nil
+}
+
+func (s *fakeApplicationUsageService) GetSeriesByApplication(ctx context.Context, appID int64, granularity string, start, end time.Time) (*service.UsageSeries, error) {
+	s.record("app-series", appID, granularity, start, end)
+	return &service.UsageSeries{Granularity: granularity}, nil
+}
+
+func (s *fakeApplicationUsageService) GetSeriesByOrganization(ctx context.Context, orgID int64, granularity string, start, end time.Time) (*service.UsageSeries, error) {
+	s.record("org-series", orgID, granularity, start, end)
+	return &service.UsageSeries{Granularity: granularity}, nil
+}
+
+// useFakeServices 替换管理命令使用的服务
+func useFakeServices(t *testing.T, users *fakeUserService, orgs *fakeOrganizationService,
+	apps *fakeOrganizationApplicationService, usage *fakeApplicationUsageService) {
+	origUsers, origOrgs, origApps, origUsage := newUserService, newOrganizationService, newOrganizationApplicationService, newApplicationUsageService
+	newUserService = func() service.UserService { return users }
+	newOrganizationService = func() service.OrganizationService { return orgs }
+	newOrganizationApplicationService = func() service.OrganizationApplicationService { return apps }
+	newApplicationUsageService = func() service.ApplicationUsageService { return usage }
+	t.Cleanup(func() {
+		newUserService, newOrganizationService, newOrganizationApplicationService, newApplicationUsageService = origUsers, origOrgs, origApps, origUsage
+	})
+}
+
+func TestUserCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantRole string
+		wantOut  string
+	}{
+		{name: "无参数", args: nil, wantCode: 2},
+		{name: "创建用户", args: []string{"create", "-name", "李四", "-email", "lisi@example.com", "-password", "secret123"}, wantCode: 0, wantRole: model.UserRoleUser},
+		{name: "创建管理员", args: []string{"create", "-name", "李四", "-email", "lisi@example.com", "-password", "secret123", "-admin"}, wantCode: 0, wantRole: model.UserRoleAdmin},
+		{name: "创建缺少参数", args: []string{"create", "-name", "李四"}, wantCode: 2},
+		{name: "创建邮箱无效", args: []string{"create", "-name", "李四", "-email", "lisi", "-password", "secret123"}, wantCode: 1},
+		{name: "创建手机号无效", args: []string{"create", "-name", "李四", "-email", "lisi@example.com", "-password", "secret123", "-phone", "123"}, wantCode: 1},
+		{name: "设置管理员", args: []string{"promote", "1"}, wantCode: 0, wantRole: model.UserRoleAdmin},
+		{name: "取消管理员", args: []string{"demote", "1"}, wantCode: 0, wantRole: model.UserRoleUser},
+		{name: "用户不存在", args: []string{"promote", "9"}, wantCode: 1},
+		{name: "无效ID", args: []string{"promote", "abc"}, wantCode: 1},
+		{name: "缺少ID", args: []string{"token"}, wantCode: 1},
+		{name: "签发令牌", args: []string{"token", "1"}, wantCode: 0, wantOut: "token-1\n"},
+		{name: "未知命令", args: []string{"remove", "1"}, wantCode: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeServices(t, newFakeUserService(), nil, nil, nil)
+
+			code, out, _ := runTestCommand(t, append([]string{"user"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Fatalf("退出码 = %d, 期望 %d", code, tt.wantCode)
+			}
+			if tt.wantRole != "" {
+				var user model.User
+				decodeOutput(t, out, &user)
+				if user.Role != tt.wantRole {
+					t.Errorf("角色 = %q, 期望 %q", user.Role, tt.wantRole)
+				}
+			}
+			if tt.wantOut != "" && out != tt.wantOut {
+				t.Errorf("输出 = %q, 期望 %q", out, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestOrgCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		err      error
+		wantCode int
+	}{
+		{name: "无参数", args: nil, wantCode: 2},
+		{name: "创建组织", args: []string{"create", "-name", "示例", "-owner", "1", "-time-zone", "Asia/Shanghai"}, wantCode: 0},
+		{name: "缺少拥有者", args: []string{"create", "-name", "示例"}, wantCode: 2},
+		{name: "参数无效", args: []string{"create", "-owner", "x"}, wantCode: 2},
+		{name: "创建失败", args: []string{"create", "-name", "示例", "-owner", "1"}, err: errors.New("拥有者不存在"), wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs := &fakeOrganizationService{err: tt.err}
+			useFakeServices(t, nil, orgs, nil, nil)
+
+			code, out, _ := runTestCommand(t, append([]string{"org"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Fatalf("退出码 = %d, 期望 %d", code, tt.wantCode)
+			}
+			if code != 0 {
+				return
+			}
+
+			var org model.Organization
+			decodeOutput(t, out, &org)
+			if org.ID != 100 || org.Name != "示例" || org.TimeZone != "Asia/Shanghai" || orgs.ownerID != 1 {
+				t.Errorf("组织 = %+v, 拥有者 = %d", org, orgs.ownerID)
+			}
+		})
+	}
+}
+
+func TestAppCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		check    func(t *testing.T, apps *fakeOrganizationApplicationService, out string)
+	}{
+		{name: "无参数", args: nil, wantCode: 2},
+		{name: "未知命令", args: []string{"delete", "1"}, wantCode: 2},
+		{
+			name: "轮换密钥", args: []string{"rotate-secret", "1"}, wantCode: 0,
+			check: func(t *testing.T, apps *fakeOrganizationApplicationService, out string) {
+				if !strings.Contains(out, `"app_secret": "new-secret"`) {
+					t.Errorf("输出缺少新密钥: %s", out)
+				}
+			},
+		},
+		{name: "轮换密钥应用不存在", args: []string{"rotate-secret", "9"}, wantCode: 1},
+		{
+			name: "修改部分限制", args: []string{"set-limit", "1", "-max-users", "20", "-enforcement-mode", "soft"}, wantCode: 0,
+			check: func(t *testing.T, apps *fakeOrganizationApplicationService, out string) {
+				limit := apps.saved
+				if limit == nil || limit.MaxUsers != 20 || limit.EnforcementMode != "soft" {
+					t.Fatalf("保存的限制 = %+v", limit)
+				}
+				if limit.MaxStorage != 2048 || limit.MaxRequests != 500 {
+					t.Errorf("未指定的参数被修改: %+v", limit)
+				}
+			},
+		},
+		{
+			name: "没有限制时使用默认值", args: []string{"set-limit", "2", "-rate-limit-burst", "50"}, wantCode: 0,
+			check: func(t *testing.T, apps *fakeOrganizationApplicationService, out string) {
+				limit := apps.saved
+				if limit == nil || limit.OrganizationApplicationId != 2 || limit.RateLimitBurst != 50 || limit.MaxUsers != 5 {
+					t.Errorf("保存的限制 = %+v", limit)
+				}
+			},
+		},
+		{name: "设置限制应用不存在", args: []string{"set-limit", "9", "-max-users", "1"}, wantCode: 1},
+		{name: "设置限制缺少ID", args: []string{"set-limit"}, wantCode: 1},
+		{
+			name: "变更套餐", args: []string{"set-plan", "1", "7"}, wantCode: 0,
+			check: func(t *testing.T, apps *fakeOrganizationApplicationService, out string) {
+				if apps.planID != 7 {
+					t.Errorf("套餐ID = %d, 期望 7", apps.planID)
+				}
+			},
+		},
+		{name: "变更套餐缺少套餐ID", args: []string{"set-plan", "1"}, wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apps := newFakeOrganizationApplicationService()
+			useFakeServices(t, nil, nil, apps, nil)
+
+			code, out, _ := runTestCommand(t, append([]string{"app"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Fatalf("退出码 = %d, 期望 %d", code, tt.wantCode)
+			}
+			if tt.check != nil {
+				tt.check(t, apps, out)
+			}
+		})
+	}
+}
+
+func TestUsageCommand(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		wantCode        int
+		wantCall        string
+		wantGranularity string
+		wantStart       string
+		wantEnd         string
+	}{
+		{name: "无参数", args: nil, wantCode: 2},
+		{name: "应用汇总", args: []string{"summary", "-app", "1", "-from", "2024-01-01", "-to", "2024-01-31"}, wantCode: 0, wantCall: "app-summary", wantStart: "2024-01-01", wantEnd: "2024-01-31"},
+		{name: "组织汇总", args: []string{"summary", "-org", "2"}, wantCode: 0, wantCall: "org-summary"},
+		{name: "应用时间序列", args: []string{"summary", "-app", "1", "-granularity", "day"}, wantCode: 0, wantCall: "app-series", wantGranularity: "day"},
+		{name: "组织时间序列", args: []string{"summary", "-org", "2", "-granularity", "month"}, wantCode: 0, wantCall: "org-series", wantGranularity: "month"},
+		{name: "同时指定应用和组织", args: []string{"summary", "-app", "1", "-org", "2"}, wantCode: 2},
+		{name: "未指定应用和组织", args: []string{"summary"}, wantCode: 2},
+		{name: "无效粒度", args: []string{"summary", "-app", "1", "-granularity", "week"}, wantCode: 1},
+		{name: "只指定开始日期", args: []string{"summary", "-app", "1", "-from", "2024-01-01"}, wantCode: 1},
+		{name: "开始日期晚于结束日期", args: []string{"summary", "-app", "1", "-from", "2024-02-01", "-to", "2024-01-01"}, wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := &fakeApplicationUsageService{}
+			useFakeServices(t, nil, nil, nil, usage)
+
+			code, out, _ := runTestCommand(t, append([]string{"usage"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Fatalf("退出码 = %d, 期望 %d", code, tt.wantCode)
+			}
+			if usage.call != tt.wantCall || usage.granularity != tt.wantGranularity {
+				t.Errorf("查询 = %s/%s, 期望 %s/%s", usage.call, usage.granularity, tt.wantCall, tt.wantGranularity)
+			}
+			if code != 0 {
+				return
+			}
+
+			var result struct {
+				StartDate string `json:"start_date"`
+				EndDate   string `json:"end_date"`
+			}
+			decodeOutput(t, out, &result)
+			if tt.wantStart != "" && (result.StartDate != tt.wantStart || result.EndDate != tt.wantEnd) {
+				t.Errorf("日期 = %s ~ %s, 期望 %s ~ %s", result.StartDate, result.EndDate, tt.wantStart, tt.wantEnd)
+			}
+			if tt.wantStart == "" && !usage.start.Equal(usage.end.AddDate(0, 0, -30)) {
+				t.Errorf("默认统计区间 = %s ~ %s, 期望30天", usage.start, usage.end)
+			}
+		})
+	}
+}
+
+func TestSnowflakeCommand(t *testing.T) {
+	id := utils.GenerateID()
+	parts, err := utils.DecodeSnowflake(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{name: "无参数", args: nil, wantCode: 2},
+		{name: "缺少ID", args: []string{"decode"}, wantCode: 2},
+		{name: "解析ID", args: []string{"decode", strconv.FormatInt(id, 10)}, wantCode: 0},
+		{name: "非数字ID", args: []string{"decode", "abc"}, wantCode: 1},
+		{name: "非正数ID", args: []string{"decode", "0"}, wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, out, _ := runTestCommand(t, append([]string{"snowflake"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Fatalf("退出码 = %d, 期望 %d", code, tt.wantCode)
+			}
+			if code != 0 {
+				return
+			}
+
+			var results []struct {
+				ID        string `json:"id"`
+				Sequence  int64  `json:"sequence"`
+				WorkerID  int64  `json:"worker_id"`
+				Timestamp string `json:"time"`
+			}
+			decodeOutput(t, out, &results)
+			if len(results) != 1 || results[0].ID != strconv.FormatInt(id, 10) ||
+				results[0].Sequence != parts.Sequence || results[0].WorkerID != parts.WorkerID {
+				t.Errorf("解析结果 = %+v, 期望 %+v", results, parts)
+			}
+		})
+	}
+}
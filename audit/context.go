@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"context"
+)
+
+// 操作者类型
+const (
+	ActorUser      = "user"      // 通过JWT认证的用户
+	ActorApp       = "app"       // 通过AppKey调用的应用
+	ActorSystem    = "system"    // 定时任务等系统操作
+	ActorAnonymous = "anonymous" // 未认证的请求
+)
+
+// Metadata 审计元数据，记录操作者和请求信息
+type Metadata struct {
+	ActorType string // 操作者类型
+	ActorId   string // 操作者标识：用户ID、AppKey 或任务名称
+	RequestId string // 请求ID
+	TraceId   string // 跟踪ID
+	IP        string // 客户端IP
+	UserAgent string // 客户端UA
+}
+
+// metadataKey 上下文中保存审计元数据的键
+type metadataKey struct{}
+
+// WithMetadata 将审计元数据保存到上下文中
+func WithMetadata(ctx context.Context, m Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, m)
+}
+
+// WithSystemActor 将操作者设置为系统，用于定时任务等没有请求的操作
+func WithSystemActor(ctx context.Context, name string) context.Context {
+	return WithMetadata(ctx, Metadata{ActorType: ActorSystem, ActorId: name})
+}
+
+// FromContext 从上下文中获取审计元数据，没有时视为系统操作
+func FromContext(ctx context.Context) Metadata {
+	if m, ok := ctx.Value(metadataKey{}).(Metadata); ok {
+		return m
+	}
+	return Metadata{ActorType: ActorSystem}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"saas-account/audit"
+	"saas-account/buildinfo"
+	"saas-account/config"
+	"sort"
+)
+
+// 子命令的标准输出和错误输出，测试时替换以检查输出内容
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// connectDB 连接数据库，测试时替换为空操作，配合替换的服务构造函数使用
+var connectDB = config.InitDB
+
+// command 管理子命令，返回进程退出码
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+// commands 主程序支持的管理子命令，不带子命令时启动服务
+var commands = map[string]command{
+	"migrate":   {usage: "执行数据库迁移", run: runMigrate},
+	"user":      {usage: "创建用户、设置平台管理员、签发访问令牌", run: runUserCommand},
+	"org":       {usage: "创建组织", run: runOrgCommand},
+	"app":       {usage: "轮换应用密钥、设置应用限制和套餐", run: runAppCommand},
+	"usage":     {usage: "查看应用或组织的使用统计", run: runUsageCommand},
+	"snowflake": {usage: "解析Snowflake ID", run: runSnowflakeCommand},
+	"version":   {usage: "输出构建信息", run: runVersionCommand},
+}
+
+// runCommand 执行管理子命令，返回进程退出码
+func runCommand(name string, args []string) int {
+	cmd, ok := commands[name]
+	if !ok {
+		printCommandUsage()
+		return 2
+	}
+	return cmd.run(args)
+}
+
+// runVersionCommand 输出构建信息，与 /version 接口相同
+func runVersionCommand(args []string) int {
+	return printJSON(buildinfo.Get())
+}
+
+// printCommandUsage 输出全部子命令
+func printCommandUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(stderr, "用法: saas-account [命令] [参数]")
+	fmt.Fprintln(stderr, "\n不带命令时启动服务，可用命令:")
+	for _, name := range names {
+		fmt.Fprintf(stderr, "  %-10s  %s\n", name, commands[name].usage)
+	}
+}
+
+// printSubcommandUsage 输出子命令用法
+func printSubcommandUsage(usage string) int {
+	fmt.Fprint(stderr, usage)
+	return 2
+}
+
+// commandContext 管理命令的上下文，审计日志中的操作者记录为 cli
+func commandContext() context.Context {
+	return audit.WithSystemActor(context.Background(), "cli")
+}
+
+// newFlagSet 创建子命令参数解析器，解析失败时输出用法
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// printJSON 以缩进的JSON格式输出结果
+func printJSON(v interface{}) int {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Fprintln(stdout, string(data))
+	return 0
+}
+
+// fail 输出错误，返回退出码1
+func fail(err error) int {
+	fmt.Fprintf(stderr, "错误: %v\n", err)
+	return 1
+}
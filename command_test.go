@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// runTestCommand 执行子命令，返回退出码、标准输出和错误输出，不连接数据库
+func runTestCommand(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	var out, errOut bytes.Buffer
+	origStdout, origStderr, origConnectDB := stdout, stderr, connectDB
+	stdout, stderr, connectDB = &out, &errOut, func() {}
+	t.Cleanup(func() {
+		stdout, stderr, connectDB = origStdout, origStderr, origConnectDB
+	})
+
+	code := runCommand(args[0], args[1:])
+	return code, out.String(), errOut.String()
+}
+
+// decodeOutput 解析子命令输出的JSON
+func decodeOutput(t *testing.T, out string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		t.Fatalf("输出不是有效的JSON: %v\n%s", err, out)
+	}
+}
+
+func TestRunCommandUnknown(t *testing.T) {
+	code, _, errOut := runTestCommand(t, "unknown")
+	if code != 2 {
+		t.Fatalf("退出码 = %d, 期望 2", code)
+	}
+	for name := range commands {
+		if !strings.Contains(errOut, name) {
+			t.Errorf("用法中缺少命令 %s", name)
+		}
+	}
+}
+
+func TestVersionCommand(t *testing.T) {
+	code, out, _ := runTestCommand(t, "version")
+	if code != 0 {
+		t.Fatalf("退出码 = %d, 期望 0", code)
+	}
+
+	var info struct {
+		Version   string `json:"version"`
+		GoVersion string `json:"go_version"`
+	}
+	decodeOutput(t, out, &info)
+	if info.Version == "" || info.GoVersion == "" {
+		t.Errorf("构建信息不完整: %s", out)
+	}
+}
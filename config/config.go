@@ -1,13 +1,26 @@
 package config
 
 import (
-	"encoding/json"
-	"os"
+	"fmt"
+	"log"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
+// 运行环境
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentTest        = "test"
+	EnvironmentStaging     = "staging"
+	EnvironmentProduction  = "production"
+)
+
+// defaultJWTSecret 默认JWT密钥，只能用于本地开发
+const defaultJWTSecret = "your-secret-key"
+
 // Config 配置结构体
 type Config struct {
 	// 数据库配置
@@ -18,143 +31,332 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 
+	DBAutoMigrate bool // 启动时是否自动执行数据库迁移
+
+	DBMaxOpenConns     int      // 每个连接池的最大连接数，0表示不限制
+	DBMaxIdleConns     int      // 每个连接池的最大空闲连接数
+	DBConnMaxLifetime  int      // 连接最长使用时间（分钟），0表示不限制
+	DBConnMaxIdleTime  int      // 连接最长空闲时间（分钟），0表示不限制
+	DBStatementTimeout int      // SQL语句超时时间（毫秒），0表示不限制
+	DBLogLevel         string   // SQL日志级别：silent, error, warn, info
+	DBSlowThreshold    int      // 慢SQL阈值（毫秒）
+	DBReplicas         []string // 只读副本地址，格式为 host 或 host:port，用户名、密码和数据库名与主库相同
+
 	// 服务器配置
-	ServerHost string
-	ServerPort int
+	ServerHost       string
+	ServerPort       int
+	CORSAllowOrigins []string // 允许跨域请求的来源，*表示全部，支持热更新
+
+	ServerDrainTimeout int    // 关闭时等待正在处理的请求完成的最长时间（秒）
+	ShutdownTimeout    int    // 关闭的最长时间（秒），包括等待请求、停止定时任务和写入缓冲数据
+	HealthCheckTimeout int    // 就绪检查中每项检查的超时时间（毫秒）
+	MetricsToken       string // 抓取 /metrics 需要的令牌，为空时不校验，支持热更新
 
 	// JWT配置
 	JWTSecret     string
 	JWTExpiration int // 过期时间（分钟）
 
 	// 日志配置
-	LogLevel  string
-	LogOutput string
+	LogLevel  string // 日志级别：debug, info, warn, error, fatal，支持热更新
+	LogOutput string // 日志输出：console, file
 
 	// Snowflake配置
 	SnowflakeWorkerID     int64
 	SnowflakeDatacenterID int64
 
+	// 回收站配置
+	TrashRetentionDays int // 软删除数据保留天数
+	TrashPurgeInterval int // 清理任务执行间隔（分钟）
+
+	// 订阅配置
+	PaymentProvider           string // 支付渠道：fake
+	SubscriptionGraceDays     int    // 到期未续费的宽限天数
+	SubscriptionNotifyDays    int    // 到期前提醒天数
+	SubscriptionCheckInterval int    // 订阅检查任务执行间隔（分钟）
+
+	// 使用量配置
+	UsageRawRetentionDays    int  // 原始使用记录保留天数，0表示永久保留
+	UsageHourlyRetentionDays int  // 小时汇总保留天数，0表示永久保留
+	UsageCompactInterval     int  // 使用记录清理任务执行间隔（分钟）
+	UsageIngestAsync         bool // 是否异步批量写入使用记录
+	UsageIngestQueueSize     int  // 异步写入队列容量
+	UsageIngestBatchSize     int  // 异步写入每批最大记录数
+	UsageIngestFlushInterval int  // 异步写入刷新间隔（毫秒）
+	UsageIdempotencyWindow   int  // 使用事件幂等键有效期（小时）
+
+	// 导出配置
+	ExportDir            string // 导出文件存储目录
+	ExportRetentionHours int    // 导出文件保留小时数
+	ExportJobInterval    int    // 导出任务执行间隔（秒）
+
+	// 账单配置
+	BillingInterval int // 出账任务执行间隔（分钟）
+
+	// 限流配置
+	RateLimitStore          string   // 令牌桶存储：memory（单节点）, redis（多节点共享）
+	RateLimitKeyBy          string   // 限流维度：app（按应用）, user（按应用和用户）, ip（按应用和客户端IP）
+	RateLimitTrustedProxies []string // 可信代理的IP或CIDR，只有来自可信代理的请求才使用 X-Forwarded-For、X-Real-IP 中的客户端IP，为空时使用连接的远端地址
+	RateLimitFailOpen       bool     // 访问令牌桶存储失败时是否放行请求，为false时返回503
+	RedisAddr               string   // Redis地址，格式为 host:port，Redis限流存储和Redis消息队列共用
+	RedisPassword           string
+	RedisDB                 int
+
+	// 通知配置
+	SMTPHost           string // SMTP服务器地址，为空时邮件通知只写日志
+	SMTPPort           int
+	SMTPUsername       string
+	SMTPPassword       string
+	SMTPFrom           string // 发件人地址
+	AlertCheckInterval int    // 用量告警检查任务执行间隔（分钟）
+
+	// Webhook配置
+	WebhookMaxAttempts int // 单次投递最多尝试次数
+	WebhookTimeout     int // 投递请求超时时间（秒）
+	WebhookRetryBase   int // 首次重试间隔（秒），之后每次翻倍
+	WebhookJobInterval int // 投递任务执行间隔（秒）
+
+	// 事件发件箱配置
+	OutboxSinks         string // 事件投递目标，逗号分隔：webhook, memory（进程内总线）, broker（消息队列）
+	OutboxRelayInterval int    // 发件箱中继执行间隔（秒）
+	OutboxRetryBase     int    // 发布失败后首次重试间隔（秒），之后每次翻倍
+	OutboxMaxAttempts   int    // 单个事件最多尝试发布次数，达到后进入死信不再重试
+	OutboxBroker        string // broker 投递目标使用的消息队列：log（只写日志）, redis（Redis Streams）
+	OutboxStreamMaxLen  int    // Redis Streams 每个主题保留的大约消息数
+
 	// 其他配置
-	Environment string
+	Environment string // 运行环境：development, test, staging, production
 	Debug       bool
 }
 
 var (
-	config     *Config
-	configOnce sync.Once
+	// current 当前生效的配置，热更新时整体替换，已获取的配置不会被修改
+	current atomic.Pointer[Config]
+
+	// activeLoader 生成当前配置的加载器，热更新时使用相同的命令行参数重新加载
+	activeLoader *loader
+
+	loadMu sync.Mutex
 )
 
-// GetConfig 获取全局配置实例
-func GetConfig() *Config {
-	configOnce.Do(func() {
-		config = &Config{
-			// 默认数据库配置
-			DBHost:     getEnv("DB_HOST", "localhost"),
-			DBPort:     getEnvAsInt("DB_PORT", 5432),
-			DBUser:     getEnv("DB_USER", "postgres"),
-			DBPassword: getEnv("DB_PASSWORD", "postgres"),
-			DBName:     getEnv("DB_NAME", "saas_account"),
-			DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
-
-			// 默认服务器配置
-			ServerHost: getEnv("SERVER_HOST", "0.0.0.0"),
-			ServerPort: getEnvAsInt("SERVER_PORT", 8080),
-
-			// 默认JWT配置
-			JWTSecret:     getEnv("JWT_SECRET", "your-secret-key"),
-			JWTExpiration: getEnvAsInt("JWT_EXPIRATION", 60*24), // 默认24小时
-
-			// 默认日志配置
-			LogLevel:  getEnv("LOG_LEVEL", "info"),
-			LogOutput: getEnv("LOG_OUTPUT", "console"),
-
-			// 默认Snowflake配置
-			SnowflakeWorkerID:     getEnvAsInt64("SNOWFLAKE_WORKER_ID", 1),
-			SnowflakeDatacenterID: getEnvAsInt64("SNOWFLAKE_DATACENTER_ID", 1),
-
-			// 默认其他配置
-			Environment: getEnv("ENVIRONMENT", "development"),
-			Debug:       getEnvAsBool("DEBUG", true),
-		}
+// defaults 默认配置
+func defaults() *Config {
+	return &Config{
+		// 默认数据库配置
+		DBHost:        "localhost",
+		DBPort:        5432,
+		DBUser:        "postgres",
+		DBPassword:    "postgres",
+		DBName:        "saas_account",
+		DBSSLMode:     "disable",
+		DBAutoMigrate: true,
 
-		// 尝试从配置文件加载
-		configFile := getEnv("CONFIG_FILE", "")
-		if configFile != "" {
-			loadConfigFromFile(configFile)
-		}
-	})
+		DBMaxOpenConns:     25,
+		DBMaxIdleConns:     10,
+		DBConnMaxLifetime:  30,
+		DBConnMaxIdleTime:  5,
+		DBStatementTimeout: 0,
+		DBLogLevel:         "warn",
+		DBSlowThreshold:    1000,
+
+		// 默认服务器配置
+		ServerHost:       "0.0.0.0",
+		ServerPort:       8080,
+		CORSAllowOrigins: []string{"*"},
+
+		ServerDrainTimeout: 20,
+		ShutdownTimeout:    30,
+		HealthCheckTimeout: 2000,
+
+		// 默认JWT配置
+		JWTSecret:     defaultJWTSecret,
+		JWTExpiration: 60 * 24, // 默认24小时
+
+		// 默认日志配置
+		LogLevel:  "info",
+		LogOutput: "console",
+
+		// 默认Snowflake配置
+		SnowflakeWorkerID:     1,
+		SnowflakeDatacenterID: 1,
+
+		// 默认回收站配置
+		TrashRetentionDays: 30,
+		TrashPurgeInterval: 60,
+
+		// 默认订阅配置
+		PaymentProvider:           "fake",
+		SubscriptionGraceDays:     7,
+		SubscriptionNotifyDays:    3,
+		SubscriptionCheckInterval: 10,
+
+		// 默认使用量配置
+		UsageRawRetentionDays:    90,
+		UsageHourlyRetentionDays: 35,
+		UsageCompactInterval:     60,
+		UsageIngestAsync:         false,
+		UsageIngestQueueSize:     10000,
+		UsageIngestBatchSize:     500,
+		UsageIngestFlushInterval: 1000,
+		UsageIdempotencyWindow:   24,
+
+		// 默认导出配置
+		ExportDir:            "data/exports",
+		ExportRetentionHours: 24,
+		ExportJobInterval:    10,
 
-	return config
+		// 默认账单配置
+		BillingInterval: 60,
+
+		// 默认限流配置
+		RateLimitStore:    "memory",
+		RateLimitKeyBy:    "app",
+		RateLimitFailOpen: true,
+		RedisAddr:         "localhost:6379",
+
+		// 默认通知配置
+		SMTPPort:           587,
+		SMTPFrom:           "noreply@example.com",
+		AlertCheckInterval: 5,
+
+		// 默认Webhook配置
+		WebhookMaxAttempts: 8,
+		WebhookTimeout:     10,
+		WebhookRetryBase:   30,
+		WebhookJobInterval: 5,
+
+		// 默认事件发件箱配置
+		OutboxSinks:         "webhook",
+		OutboxRelayInterval: 2,
+		OutboxRetryBase:     5,
+		OutboxMaxAttempts:   20,
+		OutboxBroker:        "log",
+		OutboxStreamMaxLen:  100000,
+
+		// 默认其他配置
+		Environment: EnvironmentDevelopment,
+		Debug:       true,
+	}
 }
 
-// 从配置文件加载配置
-func loadConfigFromFile(filePath string) {
-	file, err := os.Open(filePath)
+// Load 按 默认值 < 配置文件 < 环境变量 < 命令行参数 的顺序加载并校验配置，返回命令行参数中配置参数之后的部分
+// 配置文件路径由 -config 参数或 CONFIG_FILE 环境变量指定，支持 .yaml、.yml、.toml 和 .json
+func Load(args []string) ([]string, error) {
+	l := &loader{}
+	rest, err := l.parseFlags(args)
 	if err != nil {
-		return
+		return nil, err
 	}
-	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(config)
+	c, err := l.load()
 	if err != nil {
-		return
+		return nil, err
 	}
+
+	loadMu.Lock()
+	activeLoader = l
+	current.Store(c)
+	loadMu.Unlock()
+	return rest, nil
 }
 
-// 获取环境变量
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// GetConfig 获取当前生效的配置，未调用 Load 时从默认值、配置文件和环境变量加载
+func GetConfig() *Config {
+	if c := current.Load(); c != nil {
+		return c
 	}
-	return value
-}
 
-// 获取环境变量并转换为整数
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	if c := current.Load(); c != nil {
+		return c
 	}
-	value, err := strconv.Atoi(valueStr)
+
+	l := &loader{}
+	c, err := l.load()
 	if err != nil {
-		return defaultValue
+		log.Fatalf("加载配置失败: %v", err)
 	}
-	return value
+	activeLoader = l
+	current.Store(c)
+	return c
+}
+
+// DSN 主库连接串
+func (c *Config) DSN() string {
+	return c.dsn(c.DBHost, c.DBPort, c.DBStatementTimeout)
 }
 
-// 获取环境变量并转换为int64
-func getEnvAsInt64(key string, defaultValue int64) int64 {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
+// MigrationDSN 执行迁移使用的主库连接串，不设置语句超时，避免耗时的迁移（如创建索引、回填数据）被中断
+func (c *Config) MigrationDSN() string {
+	return c.dsn(c.DBHost, c.DBPort, 0)
+}
+
+// ReplicaDSNs 只读副本连接串，顺序与 DBReplicas 相同
+func (c *Config) ReplicaDSNs() []string {
+	dsns := make([]string, 0, len(c.DBReplicas))
+	for _, replica := range c.DBReplicas {
+		host, port, err := splitReplica(replica, c.DBPort)
+		if err != nil {
+			continue // 加载配置时已校验
+		}
+		dsns = append(dsns, c.dsn(host, port, c.DBStatementTimeout))
 	}
-	value, err := strconv.ParseInt(valueStr, 10, 64)
-	if err != nil {
-		return defaultValue
+	return dsns
+}
+
+// dsn 指定地址的连接串，statementTimeout 大于0时通过连接参数传给 PostgreSQL
+func (c *Config) dsn(host string, port int, statementTimeout int) string {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		host, port, c.DBUser, c.DBPassword, c.DBName, c.DBSSLMode)
+	if statementTimeout > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", statementTimeout)
 	}
-	return value
+	return dsn
 }
 
-// 获取环境变量并转换为布尔值
-func getEnvAsBool(key string, defaultValue bool) bool {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
+// TrustedProxyNets 可信代理的网段，顺序与 RateLimitTrustedProxies 相同
+func (c *Config) TrustedProxyNets() []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(c.RateLimitTrustedProxies))
+	for _, proxy := range c.RateLimitTrustedProxies {
+		ipNet, err := parseTrustedProxy(proxy)
+		if err != nil {
+			continue // 加载配置时已校验
+		}
+		nets = append(nets, ipNet)
 	}
-	value, err := strconv.ParseBool(valueStr)
-	if err != nil {
-		return defaultValue
+	return nets
+}
+
+// parseTrustedProxy 解析可信代理地址，单个IP视为只包含该IP的网段
+func parseTrustedProxy(proxy string) (*net.IPNet, error) {
+	if strings.Contains(proxy, "/") {
+		_, ipNet, err := net.ParseCIDR(proxy)
+		return ipNet, err
 	}
-	return value
+
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		return nil, fmt.Errorf("无效的IP %q", proxy)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
 }
 
-// 获取环境变量并转换为字符串切片
-func getEnvAsSlice(key string, defaultValue []string, sep string) []string {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
+// splitReplica 解析只读副本地址，未指定端口时使用主库端口
+func splitReplica(replica string, defaultPort int) (string, int, error) {
+	if !strings.Contains(replica, ":") {
+		return replica, defaultPort, nil
+	}
+
+	host, portStr, err := net.SplitHostPort(replica)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("无效的端口 %q", portStr)
 	}
-	return strings.Split(valueStr, sep)
+	return host, port, nil
 }
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"saas-account/metrics"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -11,41 +16,155 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// DB 主库连接，所有写操作和事务都使用主库
 var DB *gorm.DB
 
-// InitDB 初始化数据库连接
+var (
+	// replicas 只读副本连接，未配置或全部连接失败时为空
+	replicas []*gorm.DB
+
+	// replicaNext 轮询选择只读副本的计数器
+	replicaNext atomic.Uint64
+)
+
+// InitDB 初始化主库和只读副本的数据库连接
 func InitDB() {
-	// 从环境变量获取数据库配置
-	host := getEnv("DB_HOST", "localhost")
-	port := getEnv("DB_PORT", "5432")
-	user := getEnv("DB_USER", "postgres")
-	password := getEnv("DB_PASSWORD", "postgres")
-	dbname := getEnv("DB_NAME", "saas_account")
-	sslmode := getEnv("DB_SSLMODE", "disable")
-
-	// 构建DSN
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
+	cfg := GetConfig()
+
+	// 连接主库
+	var err error
+	DB, err = openDB(cfg, "primary", cfg.DSN())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// 连接只读副本，连接失败的副本不参与读请求，全部失败时读请求使用主库
+	replicas = nil
+	for i, dsn := range cfg.ReplicaDSNs() {
+		replica, err := openDB(cfg, fmt.Sprintf("replica-%d", i+1), dsn)
+		if err != nil {
+			log.Printf("Failed to connect to read replica %s, skipped: %v", cfg.DBReplicas[i], err)
+			continue
+		}
+		replicas = append(replicas, replica)
+	}
+
+	log.Printf("Database connected successfully (%d read replicas)", len(replicas))
+}
+
+// migrationPool 执行迁移使用的连接池名称
+const migrationPool = "migration"
+
+// OpenMigrationDB 打开执行迁移使用的主库连接，不设置语句超时，使用后由调用方用 CloseMigrationDB 关闭
+func OpenMigrationDB() (*gorm.DB, error) {
+	cfg := GetConfig()
+	return openDB(cfg, migrationPool, cfg.MigrationDSN())
+}
+
+// CloseMigrationDB 关闭执行迁移使用的连接，并注销它的连接池状态采集器
+func CloseMigrationDB(db *gorm.DB) error {
+	if err := metrics.UnregisterDB(migrationPool, db); err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// ReadDB 获取用于只读查询的连接，按轮询选择只读副本，没有可用副本时返回主库
+func ReadDB() *gorm.DB {
+	if len(replicas) == 0 {
+		return DB
+	}
+	return replicas[(replicaNext.Add(1)-1)%uint64(len(replicas))]
+}
+
+// PingDB 检查主库和全部只读副本是否可以连接，返回只读副本数
+func PingDB(ctx context.Context) (int, error) {
+	var errs []error
+	for i, db := range append([]*gorm.DB{DB}, replicas...) {
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.PingContext(ctx)
+		}
+		if err == nil {
+			continue
+		}
+		if i == 0 {
+			errs = append(errs, fmt.Errorf("主库: %w", err))
+		} else {
+			errs = append(errs, fmt.Errorf("只读副本 %d: %w", i, err))
+		}
+	}
+	return len(replicas), errors.Join(errs...)
+}
+
+// CloseDB 关闭主库和只读副本的连接池
+func CloseDB() error {
+	var errs []error
+	for _, db := range append([]*gorm.DB{DB}, replicas...) {
+		if db == nil {
+			continue
+		}
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
 
+// openDB 打开数据库连接，设置连接池并采集指标，name 为指标中的连接池名称
+func openDB(cfg *Config, name, dsn string) (*gorm.DB, error) {
 	// 配置GORM日志
 	newLogger := logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
 		logger.Config{
-			SlowThreshold:             time.Second, // 慢SQL阈值
-			LogLevel:                  logger.Info, // 日志级别
-			IgnoreRecordNotFoundError: true,        // 忽略记录未找到错误
-			Colorful:                  true,        // 彩色打印
+			SlowThreshold:             time.Duration(cfg.DBSlowThreshold) * time.Millisecond, // 慢SQL阈值
+			LogLevel:                  gormLogLevel(cfg.DBLogLevel),                          // 日志级别
+			IgnoreRecordNotFoundError: true,                                                  // 忽略记录未找到错误
+			Colorful:                  true,                                                  // 彩色打印
 		},
 	)
 
-	// 连接数据库
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: newLogger,
 	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return nil, err
+	}
+
+	// 配置连接池
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.DBConnMaxLifetime) * time.Minute)
+	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.DBConnMaxIdleTime) * time.Minute)
 
-	log.Println("Database connected and migrated successfully")
+	if err := metrics.RegisterDB(name, db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// gormLogLevel 将配置的SQL日志级别转换为GORM日志级别
+func gormLogLevel(level string) logger.LogLevel {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "info":
+		return logger.Info
+	default:
+		return logger.Warn
+	}
 }
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// field 配置项，配置文件中的键为 key，环境变量为 key 的大写形式，命令行参数为 key 的中划线形式
+type field struct {
+	key        string
+	target     interface{} // 指向 Config 字段的指针：*string, *int, *int64, *bool, *[]string
+	secret     bool        // 敏感配置，支持通过 <key>_file 从文件读取
+	reloadable bool        // 配置文件变更时可以热更新
+}
+
+// fields 全部配置项，target 指向 c 的字段
+func fields(c *Config) []field {
+	return []field{
+		// 数据库配置
+		{key: "db_host", target: &c.DBHost},
+		{key: "db_port", target: &c.DBPort},
+		{key: "db_user", target: &c.DBUser},
+		{key: "db_password", target: &c.DBPassword, secret: true},
+		{key: "db_name", target: &c.DBName},
+		{key: "db_sslmode", target: &c.DBSSLMode},
+		{key: "db_auto_migrate", target: &c.DBAutoMigrate},
+		{key: "db_max_open_conns", target: &c.DBMaxOpenConns},
+		{key: "db_max_idle_conns", target: &c.DBMaxIdleConns},
+		{key: "db_conn_max_lifetime", target: &c.DBConnMaxLifetime},
+		{key: "db_conn_max_idle_time", target: &c.DBConnMaxIdleTime},
+		{key: "db_statement_timeout", target: &c.DBStatementTimeout},
+		{key: "db_log_level", target: &c.DBLogLevel},
+		{key: "db_slow_threshold", target: &c.DBSlowThreshold},
+		{key: "db_replicas", target: &c.DBReplicas},
+
+		// 服务器配置
+		{key: "server_host", target: &c.ServerHost},
+		{key: "server_port", target: &c.ServerPort},
+		{key: "cors_allow_origins", target: &c.CORSAllowOrigins, reloadable: true},
+		{key: "server_drain_timeout", target: &c.ServerDrainTimeout},
+		{key: "shutdown_timeout", target: &c.ShutdownTimeout},
+		{key: "health_check_timeout", target: &c.HealthCheckTimeout},
+		{key: "metrics_token", target: &c.MetricsToken, secret: true, reloadable: true},
+
+		// JWT配置
+		{key: "jwt_secret", target: &c.JWTSecret, secret: true},
+		{key: "jwt_expiration", target: &c.JWTExpiration},
+
+		// 日志配置
+		{key: "log_level", target: &c.LogLevel, reloadable: true},
+		{key: "log_output", target: &c.LogOutput},
+
+		// Snowflake配置
+		{key: "snowflake_worker_id", target: &c.SnowflakeWorkerID},
+		{key: "snowflake_datacenter_id", target: &c.SnowflakeDatacenterID},
+
+		// 回收站配置
+		{key: "trash_retention_days", target: &c.TrashRetentionDays},
+		{key: "trash_purge_interval", target: &c.TrashPurgeInterval},
+
+		// 订阅配置
+		{key: "payment_provider", target: &c.PaymentProvider},
+		{key: "subscription_grace_days", target: &c.SubscriptionGraceDays},
+		{key: "subscription_notify_days", target: &c.SubscriptionNotifyDays},
+		{key: "subscription_check_interval", target: &c.SubscriptionCheckInterval},
+
+		// 使用量配置
+		{key: "usage_raw_retention_days", target: &c.UsageRawRetentionDays},
+		{key: "usage_hourly_retention_days", target: &c.UsageHourlyRetentionDays},
+		{key: "usage_compact_interval", target: &c.UsageCompactInterval},
+		{key: "usage_ingest_async", target: &c.UsageIngestAsync},
+		{key: "usage_ingest_queue_size", target: &c.UsageIngestQueueSize},
+		{key: "usage_ingest_batch_size", target: &c.UsageIngestBatchSize},
+		{key: "usage_ingest_flush_interval", target: &c.UsageIngestFlushInterval},
+		{key: "usage_idempotency_window", target: &c.UsageIdempotencyWindow},
+
+		// 导出配置
+		{key: "export_dir", target: &c.ExportDir},
+		{key: "export_retention_hours", target: &c.ExportRetentionHours},
+		{key: "export_job_interval", target: &c.ExportJobInterval},
+
+		// 账单配置
+		{key: "billing_interval", target: &c.BillingInterval},
+
+		// 限流配置
+		{key: "rate_limit_store", target: &c.RateLimitStore},
+		{key: "rate_limit_key_by", target: &c.RateLimitKeyBy},
+		{key: "rate_limit_trusted_proxies", target: &c.RateLimitTrustedProxies},
+		{key: "rate_limit_fail_open", target: &c.RateLimitFailOpen},
+		{key: "redis_addr", target: &c.RedisAddr},
+		{key: "redis_password", target: &c.RedisPassword, secret: true},
+		{key: "redis_db", target: &c.RedisDB},
+
+		// 通知配置
+		{key: "smtp_host", target: &c.SMTPHost},
+		{key: "smtp_port", target: &c.SMTPPort},
+		{key: "smtp_username", target: &c.SMTPUsername},
+		{key: "smtp_password", target: &c.SMTPPassword, secret: true},
+		{key: "smtp_from", target: &c.SMTPFrom},
+		{key: "alert_check_interval", target: &c.AlertCheckInterval},
+
+		// Webhook配置
+		{key: "webhook_max_attempts", target: &c.WebhookMaxAttempts},
+		{key: "webhook_timeout", target: &c.WebhookTimeout},
+		{key: "webhook_retry_base", target: &c.WebhookRetryBase},
+		{key: "webhook_job_interval", target: &c.WebhookJobInterval},
+
+		// 事件发件箱配置
+		{key: "outbox_sinks", target: &c.OutboxSinks},
+		{key: "outbox_relay_interval", target: &c.OutboxRelayInterval},
+		{key: "outbox_retry_base", target: &c.OutboxRetryBase},
+		{key: "outbox_max_attempts", target: &c.OutboxMaxAttempts},
+		{key: "outbox_broker", target: &c.OutboxBroker},
+		{key: "outbox_stream_max_len", target: &c.OutboxStreamMaxLen},
+
+		// 其他配置
+		{key: "environment", target: &c.Environment},
+		{key: "debug", target: &c.Debug},
+	}
+}
+
+// envName 配置项对应的环境变量名
+func envName(key string) string {
+	return strings.ToUpper(key)
+}
+
+// flagName 配置项对应的命令行参数名
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// set 将字符串形式的配置值写入配置项
+func (f field) set(value string) error {
+	value = strings.TrimSpace(value)
+
+	switch target := f.target.(type) {
+	case *string:
+		*target = value
+	case *int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q 不是有效的整数", value)
+		}
+		*target = v
+	case *int64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q 不是有效的整数", value)
+		}
+		*target = v
+	case *bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q 不是有效的布尔值", value)
+		}
+		*target = v
+	case *[]string:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*target = items
+	default:
+		return fmt.Errorf("不支持的配置类型 %T", f.target)
+	}
+	return nil
+}
+
+// setFromFile 从文件读取敏感配置的值，忽略末尾的换行
+func (f field) setFromFile(path string) error {
+	content, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+	return f.set(strings.TrimRight(string(content), "\r\n"))
+}
+
+// copyValue 将 src 中同一配置项的值复制到 f
+func (f field) copyValue(src field) {
+	switch target := f.target.(type) {
+	case *string:
+		*target = *src.target.(*string)
+	case *int:
+		*target = *src.target.(*int)
+	case *int64:
+		*target = *src.target.(*int64)
+	case *bool:
+		*target = *src.target.(*bool)
+	case *[]string:
+		*target = append([]string(nil), *src.target.(*[]string)...)
+	}
+}
+
+// equal 判断 f 与 other 中同一配置项的值是否相同
+func (f field) equal(other field) bool {
+	switch target := f.target.(type) {
+	case *string:
+		return *target == *other.target.(*string)
+	case *int:
+		return *target == *other.target.(*int)
+	case *int64:
+		return *target == *other.target.(*int64)
+	case *bool:
+		return *target == *other.target.(*bool)
+	case *[]string:
+		return strings.Join(*target, ",") == strings.Join(*other.target.(*[]string), ",")
+	}
+	return false
+}
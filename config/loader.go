@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loader 配置加载器，保存命令行参数以便热更新时按相同的优先级重新加载
+type loader struct {
+	configFile string            // -config 参数指定的配置文件
+	flags      map[string]string // 命令行参数中的配置项，键为配置项或 <配置项>_file
+}
+
+// parseFlags 解析命令行参数中的配置参数，遇到第一个非参数（如子命令）时停止，返回剩余参数
+func (l *loader) parseFlags(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("saas-account", flag.ContinueOnError)
+	fs.StringVar(&l.configFile, "config", "", "配置文件路径，支持 .yaml、.yml、.toml 和 .json")
+
+	keys := make(map[string]string)
+	for _, f := range fields(defaults()) {
+		fs.String(flagName(f.key), "", "覆盖配置项 "+f.key)
+		keys[flagName(f.key)] = f.key
+		if f.secret {
+			fs.String(flagName(f.key+"_file"), "", "从文件读取配置项 "+f.key)
+			keys[flagName(f.key+"_file")] = f.key + "_file"
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	l.flags = make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := keys[f.Name]; ok {
+			l.flags[key] = f.Value.String()
+		}
+	})
+	return fs.Args(), nil
+}
+
+// configPath 配置文件路径，-config 参数优先于 CONFIG_FILE 环境变量
+func (l *loader) configPath() string {
+	if l.configFile != "" {
+		return l.configFile
+	}
+	return os.Getenv("CONFIG_FILE")
+}
+
+// load 按 默认值 < 配置文件 < 环境变量 < 命令行参数 的顺序加载配置并校验
+func (l *loader) load() (*Config, error) {
+	c := defaults()
+	byKey := make(map[string]field)
+	for _, f := range fields(c) {
+		byKey[f.key] = f
+	}
+
+	var errs []error
+
+	// 配置文件
+	if path := l.configPath(); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取配置文件 %s 失败: %w", path, err)
+		}
+		errs = append(errs, applyValues(byKey, values, "配置文件", func(key string) string { return key })...)
+	}
+
+	// 环境变量，为空的环境变量视为未设置
+	env := make(map[string]string)
+	for _, f := range byKey {
+		if value := os.Getenv(envName(f.key)); value != "" {
+			env[f.key] = value
+		}
+		if value := os.Getenv(envName(f.key + "_file")); f.secret && value != "" {
+			env[f.key+"_file"] = value
+		}
+	}
+	errs = append(errs, applyValues(byKey, env, "环境变量", envName)...)
+
+	// 命令行参数
+	errs = append(errs, applyValues(byKey, l.flags, "命令行参数", func(key string) string { return "-" + flagName(key) })...)
+
+	// 一次报告全部错误，避免逐个修改后反复重启
+	if err := errors.Join(append(errs, c.Validate())...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// applyValues 将同一来源的配置值写入配置项，<配置项>_file 表示从文件读取敏感配置，name 用于在错误中显示配置项名称
+func applyValues(byKey map[string]field, values map[string]string, source string, name func(key string) string) []error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		var err error
+		if base, ok := strings.CutSuffix(key, "_file"); ok && byKey[base].secret {
+			if _, conflict := values[base]; conflict {
+				err = fmt.Errorf("不能同时设置 %s", name(base))
+			} else {
+				err = byKey[base].setFromFile(values[key])
+			}
+		} else if f, ok := byKey[key]; ok {
+			err = f.set(values[key])
+		} else {
+			err = errors.New("未知的配置项")
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", source, name(key), err))
+		}
+	}
+	return errs
+}
+
+// readConfigFile 读取配置文件，嵌套的配置以下划线连接，如 db.host 对应 db_host
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	default:
+		return nil, fmt.Errorf("不支持的配置文件格式 %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", raw, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// flatten 将嵌套的配置展开为以下划线连接的键，列表展开为逗号分隔的字符串
+func flatten(prefix string, raw map[string]interface{}, values map[string]string) error {
+	for key, value := range raw {
+		key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flatten(key, v, values); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			return fmt.Errorf("配置项 %s 的值为空", key)
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// validator 收集配置校验错误
+type validator struct {
+	errs []error
+}
+
+// check 条件不满足时记录配置项错误
+func (v *validator) check(ok bool, key, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("配置项 %s: %s", key, fmt.Sprintf(format, args...)))
+	}
+}
+
+// oneOf 配置值必须为可选值之一
+func (v *validator) oneOf(key, value string, options ...string) {
+	for _, option := range options {
+		if value == option {
+			return
+		}
+	}
+	v.check(false, key, "无效的值 %q，可选值为 %s", value, strings.Join(options, ", "))
+}
+
+// positive 配置值必须大于0
+func (v *validator) positive(key string, value int) {
+	v.check(value > 0, key, "必须大于0，当前为 %d", value)
+}
+
+// nonNegative 配置值不能为负数
+func (v *validator) nonNegative(key string, value int) {
+	v.check(value >= 0, key, "不能为负数，当前为 %d", value)
+}
+
+// port 配置值必须为有效端口
+func (v *validator) port(key string, value int) {
+	v.check(value > 0 && value <= 65535, key, "无效的端口 %d", value)
+}
+
+// Validate 校验配置，返回全部不合法的配置项
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	// 数据库配置
+	v.check(c.DBHost != "", "db_host", "不能为空")
+	v.port("db_port", c.DBPort)
+	v.check(c.DBUser != "", "db_user", "不能为空")
+	v.check(c.DBName != "", "db_name", "不能为空")
+	v.oneOf("db_sslmode", c.DBSSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	v.nonNegative("db_max_open_conns", c.DBMaxOpenConns)
+	v.nonNegative("db_max_idle_conns", c.DBMaxIdleConns)
+	v.check(c.DBMaxOpenConns == 0 || c.DBMaxIdleConns <= c.DBMaxOpenConns, "db_max_idle_conns",
+		"不能大于 db_max_open_conns（%d），当前为 %d", c.DBMaxOpenConns, c.DBMaxIdleConns)
+	v.nonNegative("db_conn_max_lifetime", c.DBConnMaxLifetime)
+	v.nonNegative("db_conn_max_idle_time", c.DBConnMaxIdleTime)
+	v.nonNegative("db_statement_timeout", c.DBStatementTimeout)
+	v.oneOf("db_log_level", strings.ToLower(c.DBLogLevel), "silent", "error", "warn", "info")
+	v.nonNegative("db_slow_threshold", c.DBSlowThreshold)
+	for _, replica := range c.DBReplicas {
+		host, _, err := splitReplica(replica, c.DBPort)
+		v.check(err == nil && host != "", "db_replicas", "无效的地址 %q", replica)
+	}
+
+	// 服务器配置
+	v.port("server_port", c.ServerPort)
+	v.positive("server_drain_timeout", c.ServerDrainTimeout)
+	v.positive("shutdown_timeout", c.ShutdownTimeout)
+	v.positive("health_check_timeout", c.HealthCheckTimeout)
+	v.check(c.ServerDrainTimeout <= c.ShutdownTimeout, "server_drain_timeout",
+		"不能大于 shutdown_timeout（%d），当前为 %d", c.ShutdownTimeout, c.ServerDrainTimeout)
+	for _, origin := range c.CORSAllowOrigins {
+		v.check(origin == "*" || strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"),
+			"cors_allow_origins", "无效的来源 %q，必须为 * 或以 http:// 、https:// 开头", origin)
+	}
+
+	// JWT配置
+	v.check(c.JWTSecret != "", "jwt_secret", "不能为空")
+	v.positive("jwt_expiration", c.JWTExpiration)
+
+	// 日志配置
+	v.oneOf("log_level", strings.ToLower(c.LogLevel), "debug", "info", "warn", "error", "fatal")
+	v.oneOf("log_output", strings.ToLower(c.LogOutput), "console", "file")
+
+	// Snowflake配置
+	v.check(c.SnowflakeWorkerID >= 0 && c.SnowflakeWorkerID <= 31, "snowflake_worker_id", "必须在0到31之间，当前为 %d", c.SnowflakeWorkerID)
+	v.check(c.SnowflakeDatacenterID >= 0 && c.SnowflakeDatacenterID <= 31, "snowflake_datacenter_id", "必须在0到31之间，当前为 %d", c.SnowflakeDatacenterID)
+
+	// 回收站配置
+	v.nonNegative("trash_retention_days", c.TrashRetentionDays)
+	v.positive("trash_purge_interval", c.TrashPurgeInterval)
+
+	// 订阅配置
+	v.oneOf("payment_provider", c.PaymentProvider, "fake")
+	v.nonNegative("subscription_grace_days", c.SubscriptionGraceDays)
+	v.nonNegative("subscription_notify_days", c.SubscriptionNotifyDays)
+	v.positive("subscription_check_interval", c.SubscriptionCheckInterval)
+
+	// 使用量配置
+	v.nonNegative("usage_raw_retention_days", c.UsageRawRetentionDays)
+	v.nonNegative("usage_hourly_retention_days", c.UsageHourlyRetentionDays)
+	v.positive("usage_compact_interval", c.UsageCompactInterval)
+	v.positive("usage_ingest_queue_size", c.UsageIngestQueueSize)
+	v.positive("usage_ingest_batch_size", c.UsageIngestBatchSize)
+	v.positive("usage_ingest_flush_interval", c.UsageIngestFlushInterval)
+	v.positive("usage_idempotency_window", c.UsageIdempotencyWindow)
+
+	// 导出配置
+	v.check(c.ExportDir != "", "export_dir", "不能为空")
+	v.positive("export_retention_hours", c.ExportRetentionHours)
+	v.positive("export_job_interval", c.ExportJobInterval)
+
+	// 账单配置
+	v.positive("billing_interval", c.BillingInterval)
+
+	// 限流配置
+	v.oneOf("rate_limit_store", c.RateLimitStore, "memory", "redis")
+	v.oneOf("rate_limit_key_by", c.RateLimitKeyBy, "app", "user", "ip")
+	for _, proxy := range c.RateLimitTrustedProxies {
+		_, err := parseTrustedProxy(proxy)
+		v.check(err == nil, "rate_limit_trusted_proxies", "无效的地址 %q，必须为IP或CIDR", proxy)
+	}
+	if c.RateLimitStore == "redis" {
+		v.check(c.RedisAddr != "", "redis_addr", "使用Redis限流存储时不能为空")
+	}
+	v.nonNegative("redis_db", c.RedisDB)
+
+	// 通知配置
+	v.port("smtp_port", c.SMTPPort)
+	v.positive("alert_check_interval", c.AlertCheckInterval)
+
+	// Webhook配置
+	v.positive("webhook_max_attempts", c.WebhookMaxAttempts)
+	v.positive("webhook_timeout", c.WebhookTimeout)
+	v.positive("webhook_retry_base", c.WebhookRetryBase)
+	v.positive("webhook_job_interval", c.WebhookJobInterval)
+
+	// 事件发件箱配置
+	for _, sink := range strings.Split(c.OutboxSinks, ",") {
+		v.oneOf("outbox_sinks", strings.TrimSpace(sink), "webhook", "memory", "broker")
+	}
+	v.positive("outbox_relay_interval", c.OutboxRelayInterval)
+	v.positive("outbox_retry_base", c.OutboxRetryBase)
+	v.positive("outbox_max_attempts", c.OutboxMaxAttempts)
+	v.oneOf("outbox_broker", c.OutboxBroker, "log", "redis")
+	if c.OutboxBroker == "redis" {
+		v.check(c.RedisAddr != "", "redis_addr", "使用Redis消息队列时不能为空")
+	}
+	v.positive("outbox_stream_max_len", c.OutboxStreamMaxLen)
+
+	// 其他配置
+	v.oneOf("environment", c.Environment, EnvironmentDevelopment, EnvironmentTest, EnvironmentStaging, EnvironmentProduction)
+
+	// 生产环境不允许使用只适用于本地开发的配置
+	if c.Environment == EnvironmentProduction {
+		v.check(c.JWTSecret != defaultJWTSecret, "jwt_secret", "生产环境不能使用默认密钥")
+		v.check(len(c.JWTSecret) >= 32, "jwt_secret", "生产环境的密钥长度不能少于32个字符")
+	}
+
+	return errors.Join(v.errs...)
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay 配置文件变更后等待的时间，编辑器保存时可能连续触发多个事件
+const reloadDelay = 200 * time.Millisecond
+
+var (
+	listeners   []func(*Config)
+	listenersMu sync.Mutex
+)
+
+// OnChange 注册配置热更新回调，回调参数为更新后的配置
+func OnChange(fn func(*Config)) {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+	listeners = append(listeners, fn)
+}
+
+// Watch 监听配置文件变更并热更新可热更新的配置项，没有配置文件时不监听，返回停止监听的函数
+// 其他配置项的变更需要重启才能生效，热更新时只记录日志
+func Watch() (func(), error) {
+	loadMu.Lock()
+	l := activeLoader
+	loadMu.Unlock()
+
+	path := ""
+	if l != nil {
+		path = l.configPath()
+	}
+	if path == "" {
+		return func() {}, nil
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// 监听所在目录而不是文件本身，编辑器和 Kubernetes ConfigMap 会通过重命名替换文件
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case <-done:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !affects(event, path) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, func() { reload(l) })
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("监听配置文件失败: %v", err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			watcher.Close()
+		})
+	}, nil
+}
+
+// affects 判断文件事件是否可能改变了配置文件
+func affects(event fsnotify.Event, path string) bool {
+	if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+		return false
+	}
+
+	// ConfigMap 通过替换 ..data 符号链接更新，事件中的文件名不是配置文件
+	name := filepath.Clean(event.Name)
+	return name == path || filepath.Base(name) == "..data"
+}
+
+// reload 重新加载配置，只替换可热更新的配置项，加载失败时保留当前配置
+func reload(l *loader) {
+	next, err := l.load()
+	if err != nil {
+		log.Printf("重新加载配置失败，继续使用当前配置: %v", err)
+		return
+	}
+
+	loadMu.Lock()
+	prev := current.Load()
+	updated := *prev
+	updated.CORSAllowOrigins = append([]string(nil), prev.CORSAllowOrigins...)
+
+	changed := false
+	nextFields := fields(next)
+	prevFields := fields(prev)
+	for i, f := range fields(&updated) {
+		if nextFields[i].equal(prevFields[i]) {
+			continue
+		}
+		if !f.reloadable {
+			log.Printf("配置项 %s 已修改，需要重启才能生效", f.key)
+			continue
+		}
+		f.copyValue(nextFields[i])
+		changed = true
+		log.Printf("配置项 %s 已热更新", f.key)
+	}
+
+	if changed {
+		current.Store(&updated)
+	}
+	loadMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	listenersMu.Lock()
+	fns := append([]func(*Config){}, listeners...)
+	listenersMu.Unlock()
+	for _, fn := range fns {
+		fn(&updated)
+	}
+}
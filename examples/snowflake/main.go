@@ -2,17 +2,18 @@ package main
 
 import (
 	"fmt"
+	"saas-account/logger"
 	"saas-account/utils"
 	"time"
 )
 
 func main() {
 	// 初始化日志
-	utils.InitLogger("debug", "console")
-	logger := utils.GetLogger()
+	logger.InitLogger("debug", "console")
+	log := logger.GetLogger()
 
 	// 生成ID
-	logger.Info("开始生成ID")
+	log.Info("开始生成ID")
 
 	// 生成10个ID
 	for i := 0; i < 10; i++ {
@@ -22,5 +23,5 @@ func main() {
 		time.Sleep(10 * time.Millisecond) // 等待一段时间，确保ID不同
 	}
 
-	logger.Info("ID生成完成")
+	log.Info("ID生成完成")
 }
@@ -0,0 +1,169 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"saas-account/config"
+	"sync"
+)
+
+// ErrInvalidName 无效的文件名
+var ErrInvalidName = errors.New("无效的文件名")
+
+// Writer 文件写入器，Close 后文件才会出现在存储中，Abort 放弃已写入的内容
+type Writer interface {
+	io.WriteCloser
+	Abort() error
+}
+
+// Store 文件存储接口
+type Store interface {
+	Create(name string) (Writer, error)
+	Open(name string) (io.ReadCloser, int64, error)
+	Remove(name string) error
+	Stat() error
+}
+
+// LocalStore 本地文件存储，文件写入完成后才对外可见
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore 创建本地文件存储
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{dir: dir}
+}
+
+// path 获取文件的本地路径，只允许存储目录下的文件名
+func (s *LocalStore) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+// Create 创建文件，先写入同目录下的临时文件，关闭写入器后才重命名为目标文件
+func (s *LocalStore) Create(name string) (Writer, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(s.dir, name+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &localWriter{file: file, path: path}, nil
+}
+
+// Open 打开文件，返回文件内容和大小
+func (s *LocalStore) Open(name string) (io.ReadCloser, int64, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// Remove 删除文件，文件不存在时不返回错误
+func (s *LocalStore) Remove(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Stat 检查存储目录是否可读，不写入任何文件；目录不存在时与 Create 一样创建目录
+func (s *LocalStore) Stat() error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	info, err := dir.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s 不是目录", s.dir)
+	}
+	_, err = dir.Readdirnames(1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// localWriter 本地文件写入器，先写入临时文件，关闭时重命名为目标文件
+type localWriter struct {
+	file *os.File
+	path string
+}
+
+// Write 写入数据
+func (w *localWriter) Write(p []byte) (int, error) {
+	return w.file.Write(p)
+}
+
+// Close 关闭临时文件并重命名为目标文件
+func (w *localWriter) Close() error {
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+	if err := os.Rename(w.file.Name(), w.path); err != nil {
+		os.Remove(w.file.Name())
+		return err
+	}
+	return nil
+}
+
+// Abort 关闭并删除临时文件，目标文件保持不变
+func (w *localWriter) Abort() error {
+	w.file.Close()
+	if err := os.Remove(w.file.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+var (
+	store     Store
+	storeOnce sync.Once
+)
+
+// GetStore 获取全局文件存储实例
+func GetStore() Store {
+	storeOnce.Do(func() {
+		store = NewLocalStore(config.GetConfig().ExportDir)
+	})
+	return store
+}
@@ -0,0 +1,103 @@
+package filestore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestLocalStoreCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		abort     bool
+		wantFile  bool
+		wantFiles int
+	}{
+		{name: "关闭后可见", abort: false, wantFile: true, wantFiles: 1},
+		{name: "放弃后不可见", abort: true, wantFile: false, wantFiles: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := NewLocalStore(dir)
+
+			w, err := store.Create("export.csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write([]byte("id,amount\n")); err != nil {
+				t.Fatal(err)
+			}
+
+			// 写入过程中目标文件不可见
+			if _, _, err := store.Open("export.csv"); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("写入过程中打开文件: %v, 期望文件不存在", err)
+			}
+
+			if tt.abort {
+				err = w.Abort()
+			} else {
+				err = w.Close()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, size, err := store.Open("export.csv")
+			if tt.wantFile {
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(r)
+				r.Close()
+				if string(data) != "id,amount\n" || size != int64(len(data)) {
+					t.Errorf("文件内容 = %q, 大小 = %d", data, size)
+				}
+			} else if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("打开文件: %v, 期望文件不存在", err)
+			}
+
+			// 不残留临时文件
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.wantFiles {
+				t.Errorf("存储目录中有 %d 个文件, 期望 %d", len(entries), tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestLocalStoreInvalidName(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+	for _, name := range []string{"", ".", "..", "../etc/passwd", "a/b.csv"} {
+		if _, err := store.Create(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Create(%q) 错误 = %v, 期望 %v", name, err, ErrInvalidName)
+		}
+	}
+}
+
+func TestLocalStoreStat(t *testing.T) {
+	dir := t.TempDir()
+
+	// 检查不写入文件，目录不存在时创建
+	store := NewLocalStore(dir + "/exports")
+	if err := store.Stat(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir + "/exports")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("检查后的目录内容 = %v, 错误 = %v, 期望为空", entries, err)
+	}
+
+	// 存储路径不是目录时检查失败
+	if err := os.WriteFile(dir+"/file", []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewLocalStore(dir + "/file").Stat(); err == nil {
+		t.Error("存储路径不是目录时没有返回错误")
+	}
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"saas-account/model"
+	"saas-account/service"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+)
+
+// AlertHandler 用量告警处理器
+type AlertHandler struct {
+	alertService service.AlertService
+}
+
+// NewAlertHandler 创建用量告警处理器
+func NewAlertHandler(alertService service.AlertService) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+	}
+}
+
+// alertRuleRequest 告警规则请求参数
+type alertRuleRequest struct {
+	Name          string          `json:"name"`
+	UsageType     string          `json:"usage_type"`
+	ThresholdType string          `json:"threshold_type"`
+	Threshold     int64           `json:"threshold"`
+	Channels      json.RawMessage `json:"channels"` // 发送渠道，如 [{"type":"email","target":"ops@example.com"}]
+	Enabled       *bool           `json:"enabled"`  // 是否启用，默认启用
+}
+
+// bindAlertRule 解析告警规则请求参数
+func bindAlertRule(c *app.RequestContext, appID int64) (*model.AlertRule, bool) {
+	var req alertRuleRequest
+	if err := c.BindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求参数")
+		return nil, false
+	}
+
+	rule := &model.AlertRule{
+		ApplicationId: appID,
+		Name:          req.Name,
+		UsageType:     req.UsageType,
+		ThresholdType: req.ThresholdType,
+		Threshold:     req.Threshold,
+		Channels:      string(req.Channels),
+		Enabled:       req.Enabled == nil || *req.Enabled,
+	}
+	return rule, true
+}
+
+// parseRuleParams 解析路径中的应用ID和告警规则ID
+func parseRuleParams(c *app.RequestContext) (int64, int64, bool) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return 0, 0, false
+	}
+
+	ruleID, err := strconv.ParseInt(c.Param("rule_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的告警规则ID")
+		return 0, 0, false
+	}
+
+	return appID, ruleID, true
+}
+
+// failAlert 根据错误类型返回告警相关的错误响应
+func failAlert(c *app.RequestContext, err error, notFound string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		NotFound(c, notFound)
+		return
+	}
+	BadRequest(c, err.Error())
+}
+
+// ListRules 获取应用的告警规则列表
+func (h *AlertHandler) ListRules(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	rules, err := h.alertService.ListRules(ctx, appID)
+	if err != nil {
+		failAlert(c, err, "应用不存在")
+		return
+	}
+
+	Success(c, rules)
+}
+
+// CreateRule 创建告警规则
+func (h *AlertHandler) CreateRule(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	rule, ok := bindAlertRule(c, appID)
+	if !ok {
+		return
+	}
+
+	if err := h.alertService.CreateRule(ctx, rule); err != nil {
+		failAlert(c, err, "应用不存在")
+		return
+	}
+
+	Success(c, rule)
+}
+
+// GetRule 获取告警规则
+func (h *AlertHandler) GetRule(ctx context.Context, c *app.RequestContext) {
+	appID, ruleID, ok := parseRuleParams(c)
+	if !ok {
+		return
+	}
+
+	rule, err := h.alertService.GetRule(ctx, appID, ruleID)
+	if err != nil {
+		failAlert(c, err, "告警规则不存在")
+		return
+	}
+
+	Success(c, rule)
+}
+
+// UpdateRule 更新告警规则
+func (h *AlertHandler) UpdateRule(ctx context.Context, c *app.RequestContext) {
+	appID, ruleID, ok := parseRuleParams(c)
+	if !ok {
+		return
+	}
+
+	rule, ok := bindAlertRule(c, appID)
+	if !ok {
+		return
+	}
+	rule.ID = ruleID
+
+	if err := h.alertService.UpdateRule(ctx, rule); err != nil {
+		failAlert(c, err, "告警规则不存在")
+		return
+	}
+
+	Success(c, rule)
+}
+
+// DeleteRule 删除告警规则
+func (h *AlertHandler) DeleteRule(ctx context.Context, c *app.RequestContext) {
+	appID, ruleID, ok := parseRuleParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.alertService.DeleteRule(ctx, appID, ruleID); err != nil {
+		failAlert(c, err, "告警规则不存在")
+		return
+	}
+
+	Success(c, nil)
+}
+
+// ListEvents 获取应用的告警记录
+func (h *AlertHandler) ListEvents(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	page, pageSize := getPagination(c)
+	events, total, err := h.alertService.ListEvents(ctx, appID, page, pageSize)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	SuccessWithPagination(c, events, total, page, pageSize)
+}
@@ -2,12 +2,16 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"saas-account/ingest"
 	"saas-account/model"
 	"saas-account/service"
 	"strconv"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
 )
 
 // ApplicationUsageHandler 应用使用记录处理器
@@ -58,13 +62,13 @@ func (h *ApplicationUsageHandler) Create(ctx context.Context, c *app.RequestCont
 // GetByID 根据ID获取应用使用记录
 func (h *ApplicationUsageHandler) GetByID(ctx context.Context, c *app.RequestContext) {
 	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的使用记录ID")
 		return
 	}
 
-	usage, err := h.usageService.GetByID(ctx, uint(id))
+	usage, err := h.usageService.GetByID(ctx, id)
 	if err != nil {
 		NotFound(c, "使用记录不存在")
 		return
@@ -76,7 +80,7 @@ func (h *ApplicationUsageHandler) GetByID(ctx context.Context, c *app.RequestCon
 // List 获取应用使用记录列表
 func (h *ApplicationUsageHandler) List(ctx context.Context, c *app.RequestContext) {
 	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
@@ -117,10 +121,10 @@ func (h *ApplicationUsageHandler) List(ctx context.Context, c *app.RequestContex
 		// 将结束日期设置为当天的最后一秒
 		endDate = endDate.Add(24*time.Hour - time.Second)
 
-		usages, total, err = h.usageService.GetByApplicationAndDateRange(ctx, uint(appID), startDate, endDate, page, pageSize)
+		usages, total, err = h.usageService.GetByApplicationAndDateRange(ctx, appID, startDate, endDate, page, pageSize)
 	} else {
 		// 否则查询所有记录
-		usages, total, err = h.usageService.GetByApplication(ctx, uint(appID), page, pageSize)
+		usages, total, err = h.usageService.GetByApplication(ctx, appID, page, pageSize)
 	}
 
 	if err != nil {
@@ -131,40 +135,97 @@ func (h *ApplicationUsageHandler) List(ctx context.Context, c *app.RequestContex
 	SuccessWithPagination(c, usages, total, page, pageSize)
 }
 
-// GetSummary 获取应用使用统计摘要
+// GetSummary 获取应用使用统计摘要，指定granularity时返回时间序列
 func (h *ApplicationUsageHandler) GetSummary(ctx context.Context, c *app.RequestContext) {
 	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
 	}
 
-	// 获取日期范围参数
+	startDate, endDate, granularity, ok := parseSummaryQuery(c)
+	if !ok {
+		return
+	}
+
+	if granularity != "" {
+		series, err := h.usageService.GetSeriesByApplication(ctx, int64(appID), granularity, startDate, endDate)
+		respondSummary(c, startDate, endDate, series, err, "应用不存在")
+		return
+	}
+
+	summary, err := h.usageService.GetSummaryByApplication(ctx, int64(appID), startDate, endDate)
+	respondSummary(c, startDate, endDate, summary, err, "应用不存在")
+}
+
+// GetOrganizationSummary 获取组织下所有应用的使用统计摘要，指定granularity时返回时间序列
+func (h *ApplicationUsageHandler) GetOrganizationSummary(ctx context.Context, c *app.RequestContext) {
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return
+	}
+
+	startDate, endDate, granularity, ok := parseSummaryQuery(c)
+	if !ok {
+		return
+	}
+
+	if granularity != "" {
+		series, err := h.usageService.GetSeriesByOrganization(ctx, orgID, granularity, startDate, endDate)
+		respondSummary(c, startDate, endDate, series, err, "组织不存在")
+		return
+	}
+
+	summary, err := h.usageService.GetSummaryByOrganization(ctx, orgID, startDate, endDate)
+	respondSummary(c, startDate, endDate, summary, err, "组织不存在")
+}
+
+// parseSummaryQuery 获取统计的日期范围和粒度参数，日期未指定时默认为过去30天，参数无效时直接响应错误
+// 日期按组织时区解释，包含结束日期当天
+func parseSummaryQuery(c *app.RequestContext) (time.Time, time.Time, string, bool) {
 	startDateStr := c.DefaultQuery("start_date", "")
 	endDateStr := c.DefaultQuery("end_date", "")
+	granularity := c.DefaultQuery("granularity", "")
 
-	var startDate, endDate time.Time
+	switch granularity {
+	case "", service.UsageGranularityHour, service.UsageGranularityDay, service.UsageGranularityMonth:
+	default:
+		BadRequest(c, "无效的统计粒度，可选值为hour, day, month")
+		return time.Time{}, time.Time{}, "", false
+	}
 
-	// 如果未指定日期范围，则默认为过去30天
 	if startDateStr == "" || endDateStr == "" {
-		endDate = time.Now()
-		startDate = endDate.AddDate(0, 0, -30)
-	} else {
-		var err1, err2 error
-		startDate, err1 = time.Parse("2006-01-02", startDateStr)
-		endDate, err2 = time.Parse("2006-01-02", endDateStr)
-		if err1 != nil || err2 != nil {
-			BadRequest(c, "无效的日期格式，请使用YYYY-MM-DD格式")
-			return
-		}
+		endDate := time.Now()
+		return endDate.AddDate(0, 0, -30), endDate, granularity, true
+	}
 
-		// 将结束日期设置为当天的最后一秒
-		endDate = endDate.Add(24*time.Hour - time.Second)
+	startDate, err1 := time.Parse("2006-01-02", startDateStr)
+	endDate, err2 := time.Parse("2006-01-02", endDateStr)
+	if err1 != nil || err2 != nil {
+		BadRequest(c, "无效的日期格式，请使用YYYY-MM-DD格式")
+		return time.Time{}, time.Time{}, "", false
 	}
+	if startDate.After(endDate) {
+		BadRequest(c, "开始日期不能晚于结束日期")
+		return time.Time{}, time.Time{}, "", false
+	}
+	if granularity == service.UsageGranularityHour && endDate.Sub(startDate) >= 31*24*time.Hour {
+		BadRequest(c, "按小时统计时日期范围不能超过31天")
+		return time.Time{}, time.Time{}, "", false
+	}
+
+	return startDate, endDate, granularity, true
+}
 
-	summary, err := h.usageService.GetSummaryByApplication(ctx, uint(appID), startDate, endDate)
+// respondSummary 响应使用统计结果
+func respondSummary(c *app.RequestContext, startDate, endDate time.Time, summary interface{}, err error, notFoundMessage string) {
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, notFoundMessage)
+			return
+		}
 		InternalServerError(c, err.Error())
 		return
 	}
@@ -176,56 +237,95 @@ func (h *ApplicationUsageHandler) GetSummary(ctx context.Context, c *app.Request
 	})
 }
 
-// RecordAPIUsage 记录API使用
-func (h *ApplicationUsageHandler) RecordAPIUsage(ctx context.Context, c *app.RequestContext) {
-	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+// maxUsageBatchEvents 单次批量上报的最大事件数
+const maxUsageBatchEvents = 1000
+
+// RecordBatch 批量记录使用事件，返回每个事件的处理结果
+func (h *ApplicationUsageHandler) RecordBatch(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
 	}
 
-	// 获取请求参数
 	var req struct {
-		UserID *uint `json:"user_id"`
-		Amount int64 `json:"amount"`
+		Events []service.UsageEvent `json:"events"`
 	}
 	if err := c.BindJSON(&req); err != nil {
 		BadRequest(c, "无效的请求参数")
 		return
 	}
 
-	// 验证必填字段
-	if req.Amount <= 0 {
-		BadRequest(c, "使用量必须大于0")
+	if len(req.Events) == 0 {
+		BadRequest(c, "使用事件不能为空")
+		return
+	}
+	if len(req.Events) > maxUsageBatchEvents {
+		BadRequest(c, fmt.Sprintf("单次最多上报%d个使用事件", maxUsageBatchEvents))
 		return
 	}
 
-	// 检查API限制
-	allowed, err := h.usageService.CheckAPILimit(ctx, uint(appID))
+	results, err := h.usageService.RecordBatch(ctx, appID, req.Events)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "应用不存在")
+			return
+		}
 		InternalServerError(c, err.Error())
 		return
 	}
 
-	if !allowed {
-		Forbidden(c, "已超过API使用限制")
+	counts := map[string]int{
+		service.UsageEventAccepted:  0,
+		service.UsageEventDuplicate: 0,
+		service.UsageEventRejected:  0,
+	}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	Success(c, map[string]interface{}{
+		"accepted":  counts[service.UsageEventAccepted],
+		"duplicate": counts[service.UsageEventDuplicate],
+		"rejected":  counts[service.UsageEventRejected],
+		"results":   results,
+	})
+}
+
+// RecordAPIUsage 记录API使用
+func (h *ApplicationUsageHandler) RecordAPIUsage(ctx context.Context, c *app.RequestContext) {
+	appIDStr := c.Param("app_id")
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
 		return
 	}
 
-	// 记录API使用
-	if err := h.usageService.RecordAPIUsage(ctx, uint(appID), req.UserID, req.Amount); err != nil {
-		Fail(c, 500, err.Error())
+	// 获取请求参数
+	var req struct {
+		UserID *int64 `json:"user_id"`
+		Amount int64  `json:"amount"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求参数")
 		return
 	}
 
-	Success(c, nil)
+	// 验证必填字段
+	if req.Amount <= 0 {
+		BadRequest(c, "使用量必须大于0")
+		return
+	}
+
+	// 记录API使用，配额检查与累加在服务内原子完成
+	err = h.usageService.RecordAPIUsage(ctx, appID, req.UserID, req.Amount)
+	h.respondQuota(ctx, c, int64(appID), "api_call", err, "已超过API使用限制")
 }
 
 // RecordStorageUsage 记录存储使用
 func (h *ApplicationUsageHandler) RecordStorageUsage(ctx context.Context, c *app.RequestContext) {
 	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
@@ -233,8 +333,8 @@ func (h *ApplicationUsageHandler) RecordStorageUsage(ctx context.Context, c *app
 
 	// 获取请求参数
 	var req struct {
-		UserID *uint `json:"user_id"`
-		Amount int64 `json:"amount"`
+		UserID *int64 `json:"user_id"`
+		Amount int64  `json:"amount"`
 	}
 	if err := c.BindJSON(&req); err != nil {
 		BadRequest(c, "无效的请求参数")
@@ -247,31 +347,45 @@ func (h *ApplicationUsageHandler) RecordStorageUsage(ctx context.Context, c *app
 		return
 	}
 
-	// 检查存储限制
-	allowed, err := h.usageService.CheckStorageLimit(ctx, uint(appID), req.Amount)
-	if err != nil {
-		InternalServerError(c, err.Error())
-		return
-	}
+	// 记录存储使用，配额检查与累加在服务内原子完成
+	err = h.usageService.RecordStorageUsage(ctx, appID, req.UserID, req.Amount)
+	h.respondQuota(ctx, c, int64(appID), "storage", err, "已超过存储使用限制")
+}
 
-	if !allowed {
-		Forbidden(c, "已超过存储使用限制")
+// respondQuota 根据记录结果响应，并在响应头中返回剩余配额
+func (h *ApplicationUsageHandler) respondQuota(ctx context.Context, c *app.RequestContext, appID int64, usageType string, err error, exceededMessage string) {
+	var quotaErr *service.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		setQuotaHeaders(c, quotaErr.Status)
+		TooManyRequests(c, exceededMessage)
 		return
 	}
-
-	// 记录存储使用
-	if err := h.usageService.RecordStorageUsage(ctx, uint(appID), req.UserID, req.Amount); err != nil {
+	if err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
 
+	// 未配置应用限制时不返回配额头
+	if status, err := h.usageService.GetQuotaStatus(ctx, appID, usageType); err == nil {
+		setQuotaHeaders(c, status)
+	}
+
 	Success(c, nil)
 }
 
+// setQuotaHeaders 设置配额相关响应头
+func setQuotaHeaders(c *app.RequestContext, status *service.QuotaStatus) {
+	c.Header("X-Quota-Limit", strconv.FormatInt(status.Limit, 10))
+	c.Header("X-Quota-Remaining", strconv.FormatInt(status.Remaining, 10))
+	if status.ResetAt > 0 {
+		c.Header("X-Quota-Reset", strconv.FormatInt(status.ResetAt, 10))
+	}
+}
+
 // RecordFeatureUsage 记录功能使用
 func (h *ApplicationUsageHandler) RecordFeatureUsage(ctx context.Context, c *app.RequestContext) {
 	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
@@ -279,7 +393,7 @@ func (h *ApplicationUsageHandler) RecordFeatureUsage(ctx context.Context, c *app
 
 	// 获取请求参数
 	var req struct {
-		UserID      *uint  `json:"user_id"`
+		UserID      *int64 `json:"user_id"`
 		FeatureName string `json:"feature_name"`
 		Amount      int64  `json:"amount"`
 	}
@@ -295,10 +409,28 @@ func (h *ApplicationUsageHandler) RecordFeatureUsage(ctx context.Context, c *app
 	}
 
 	// 记录功能使用
-	if err := h.usageService.RecordFeatureUsage(ctx, uint(appID), req.UserID, req.FeatureName, req.Amount); err != nil {
+	if err := h.usageService.RecordFeatureUsage(ctx, appID, req.UserID, req.FeatureName, req.Amount); err != nil {
+		if errors.Is(err, service.ErrFeatureNotIncluded) {
+			Forbidden(c, err.Error())
+			return
+		}
 		Fail(c, 500, err.Error())
 		return
 	}
 
 	Success(c, nil)
 }
+
+// GetIngesterStats 获取使用记录异步写入指标
+func (h *ApplicationUsageHandler) GetIngesterStats(ctx context.Context, c *app.RequestContext) {
+	ingester := ingest.GetIngester()
+	if ingester == nil {
+		Success(c, map[string]interface{}{"enabled": false})
+		return
+	}
+
+	Success(c, map[string]interface{}{
+		"enabled": true,
+		"stats":   ingester.Stats(),
+	})
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"saas-account/repository"
+	"saas-account/service"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// AuditHandler 审计日志处理器
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// NewAuditHandler 创建审计日志处理器
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// parseAuditOrgID 解析路径中的组织ID，平台级路由没有组织ID，返回0
+func parseAuditOrgID(c *app.RequestContext) (int64, bool) {
+	if c.Param("id") == "" {
+		return 0, true
+	}
+
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return 0, false
+	}
+	return orgID, true
+}
+
+// parseAuditInt 解析可选的整数查询参数，参数为空时返回0
+func parseAuditInt(c *app.RequestContext, name, invalid string) (int64, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		BadRequest(c, invalid)
+		return 0, false
+	}
+	return value, true
+}
+
+// List 查询审计日志，支持按操作者、操作、资源和时间范围（Unix秒）过滤
+func (h *AuditHandler) List(ctx context.Context, c *app.RequestContext) {
+	orgID, ok := parseAuditOrgID(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.AuditLogFilter{
+		OrganizationId: orgID,
+		ActorType:      c.Query("actor_type"),
+		ActorId:        c.Query("actor_id"),
+		Action:         c.Query("action"),
+		ResourceType:   c.Query("resource_type"),
+	}
+	if filter.ResourceId, ok = parseAuditInt(c, "resource_id", "无效的资源ID"); !ok {
+		return
+	}
+	if filter.From, ok = parseAuditInt(c, "from", "无效的起始时间"); !ok {
+		return
+	}
+	if filter.To, ok = parseAuditInt(c, "to", "无效的结束时间"); !ok {
+		return
+	}
+
+	page, pageSize := getPagination(c)
+	logs, total, err := h.auditService.List(ctx, filter, page, pageSize)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	SuccessWithPagination(c, logs, total, page, pageSize)
+}
+
+// Verify 校验审计日志哈希链是否完整
+func (h *AuditHandler) Verify(ctx context.Context, c *app.RequestContext) {
+	orgID, ok := parseAuditOrgID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.auditService.Verify(ctx, orgID)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	Success(c, result)
+}
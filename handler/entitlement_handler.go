@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"saas-account/service"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// EntitlementHandler 功能权益处理器
+type EntitlementHandler struct {
+	entitlementService service.EntitlementService
+}
+
+// NewEntitlementHandler 创建功能权益处理器
+func NewEntitlementHandler(entitlementService service.EntitlementService) *EntitlementHandler {
+	return &EntitlementHandler{
+		entitlementService: entitlementService,
+	}
+}
+
+// Check 检查单个功能是否可用
+func (h *EntitlementHandler) Check(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	// 数值型功能的需求数量
+	quantity, err := strconv.ParseInt(c.DefaultQuery("quantity", "0"), 10, 64)
+	if err != nil || quantity < 0 {
+		BadRequest(c, "无效的需求数量")
+		return
+	}
+
+	check, err := h.entitlementService.Check(ctx, appID, c.Param("feature"), quantity, c.Query("tier"))
+	if err != nil {
+		NotFound(c, "应用权益不存在")
+		return
+	}
+
+	Success(c, check)
+}
+
+// CheckAll 批量检查功能是否可用，features 参数以逗号分隔，为空时返回所有功能
+func (h *EntitlementHandler) CheckAll(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	var features []string
+	if featuresStr := c.Query("features"); featuresStr != "" {
+		for _, feature := range strings.Split(featuresStr, ",") {
+			if feature = strings.TrimSpace(feature); feature != "" {
+				features = append(features, feature)
+			}
+		}
+	}
+
+	checks, err := h.entitlementService.CheckAll(ctx, appID, features)
+	if err != nil {
+		NotFound(c, "应用权益不存在")
+		return
+	}
+
+	Success(c, checks)
+}
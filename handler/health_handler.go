@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"saas-account/buildinfo"
+	"saas-account/health"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// HealthHandler 健康检查处理器，供编排系统探测服务状态
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler 创建健康检查处理器
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// Live 存活检查，进程能处理请求即返回成功，不检查依赖
+func (h *HealthHandler) Live(ctx context.Context, c *app.RequestContext) {
+	Success(c, map[string]string{"status": health.StatusOK})
+}
+
+// Ready 就绪检查，数据库、迁移版本和依赖的存储全部可用时返回成功，否则返回503和每项检查的结果及失败原因
+// 失败原因只记录在日志中，不对外返回
+func (h *HealthHandler) Ready(ctx context.Context, c *app.RequestContext) {
+	report := h.checker.Ready(ctx)
+	if !report.Ready() {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Code:    503,
+			Message: "服务未就绪",
+			Data:    report,
+		})
+		return
+	}
+
+	Success(c, report)
+}
+
+// Version 获取构建信息
+func (h *HealthHandler) Version(ctx context.Context, c *app.RequestContext) {
+	Success(c, buildinfo.Get())
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"saas-account/service"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+)
+
+// InvoiceHandler 账单处理器
+type InvoiceHandler struct {
+	invoiceService service.InvoiceService
+}
+
+// NewInvoiceHandler 创建账单处理器
+func NewInvoiceHandler(invoiceService service.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: invoiceService,
+	}
+}
+
+// ListByOrganization 获取组织的账单列表
+func (h *InvoiceHandler) ListByOrganization(ctx context.Context, c *app.RequestContext) {
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return
+	}
+
+	page, pageSize := getPagination(c)
+	invoices, total, err := h.invoiceService.ListByOrganization(ctx, orgID, page, pageSize)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "组织不存在")
+			return
+		}
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	SuccessWithPagination(c, invoices, total, page, pageSize)
+}
+
+// Generate 生成或重新生成组织在计费周期的草稿账单
+func (h *InvoiceHandler) Generate(ctx context.Context, c *app.RequestContext) {
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return
+	}
+
+	var req struct {
+		Period string `json:"period"` // 计费周期，格式为YYYY-MM
+	}
+	if err := c.BindJSON(&req); err != nil || req.Period == "" {
+		BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	invoice, err := h.invoiceService.Generate(ctx, orgID, req.Period)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			NotFound(c, "组织不存在")
+		case errors.Is(err, service.ErrInvoiceIssued):
+			Fail(c, 409, err.Error())
+		default:
+			BadRequest(c, err.Error())
+		}
+		return
+	}
+
+	Success(c, invoice)
+}
+
+// GetByID 获取账单及其明细
+func (h *InvoiceHandler) GetByID(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的账单ID")
+		return
+	}
+
+	invoice, err := h.invoiceService.GetByID(ctx, id)
+	if err != nil {
+		NotFound(c, "账单不存在")
+		return
+	}
+
+	Success(c, invoice)
+}
+
+// Issue 出账
+func (h *InvoiceHandler) Issue(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的账单ID")
+		return
+	}
+
+	invoice, err := h.invoiceService.Issue(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "账单不存在")
+			return
+		}
+		BadRequest(c, err.Error())
+		return
+	}
+
+	Success(c, invoice)
+}
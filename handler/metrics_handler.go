@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"saas-account/metrics"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsHandler Prometheus指标处理器
+type MetricsHandler struct {
+	handler http.Handler
+}
+
+// NewMetricsHandler 创建Prometheus指标处理器
+func NewMetricsHandler() *MetricsHandler {
+	return &MetricsHandler{
+		handler: promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}),
+	}
+}
+
+// Metrics 输出Prometheus格式的指标
+func (h *MetricsHandler) Metrics(ctx context.Context, c *app.RequestContext) {
+	req, err := adaptor.GetCompatRequest(&c.Request)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	h.handler.ServeHTTP(adaptor.GetCompatResponseWriter(&c.Response), req.WithContext(ctx))
+}
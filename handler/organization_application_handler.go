@@ -56,20 +56,20 @@ func (h *OrganizationApplicationHandler) Create(ctx context.Context, c *app.Requ
 // GetByID 根据ID获取组织应用
 func (h *OrganizationApplicationHandler) GetByID(ctx context.Context, c *app.RequestContext) {
 	orgIDStr := c.Param("org_id")
-	_, err := strconv.ParseUint(orgIDStr, 10, 64)
+	_, err := strconv.ParseInt(orgIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的组织ID")
 		return
 	}
 
 	appIDStr := c.Param("id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
 	}
 
-	app, err := h.appService.GetByID(ctx, uint(appID))
+	app, err := h.appService.GetByID(ctx, appID)
 	if err != nil {
 		NotFound(c, "应用不存在")
 		return
@@ -84,7 +84,7 @@ func (h *OrganizationApplicationHandler) GetByID(ctx context.Context, c *app.Req
 // List 获取组织应用列表
 func (h *OrganizationApplicationHandler) List(ctx context.Context, c *app.RequestContext) {
 	orgIDStr := c.Param("org_id")
-	orgID, err := strconv.ParseUint(orgIDStr, 10, 64)
+	orgID, err := strconv.ParseInt(orgIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的组织ID")
 		return
@@ -105,7 +105,7 @@ func (h *OrganizationApplicationHandler) List(ctx context.Context, c *app.Reques
 	}
 
 	// 获取应用列表
-	apps, total, err := h.appService.GetByOrganization(ctx, uint(orgID), page, pageSize)
+	apps, total, err := h.appService.GetByOrganization(ctx, orgID, page, pageSize)
 	if err != nil {
 		InternalServerError(c, err.Error())
 		return
@@ -129,7 +129,7 @@ func (h *OrganizationApplicationHandler) Update(ctx context.Context, c *app.Requ
 	}
 
 	appIDStr := c.Param("id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
@@ -151,7 +151,7 @@ func (h *OrganizationApplicationHandler) Update(ctx context.Context, c *app.Requ
 	}
 
 	// 获取更新后的应用
-	updatedApp, err := h.appService.GetByID(ctx, uint(appID))
+	updatedApp, err := h.appService.GetByID(ctx, appID)
 	if err != nil {
 		InternalServerError(c, err.Error())
 		return
@@ -166,20 +166,20 @@ func (h *OrganizationApplicationHandler) Update(ctx context.Context, c *app.Requ
 // Delete 删除组织应用
 func (h *OrganizationApplicationHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	orgIDStr := c.Param("org_id")
-	_, err := strconv.ParseUint(orgIDStr, 10, 64)
+	_, err := strconv.ParseInt(orgIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的组织ID")
 		return
 	}
 
 	appIDStr := c.Param("id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
 	}
 
-	if err := h.appService.Delete(ctx, uint(appID)); err != nil {
+	if err := h.appService.Delete(ctx, appID); err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
@@ -190,20 +190,20 @@ func (h *OrganizationApplicationHandler) Delete(ctx context.Context, c *app.Requ
 // RegenerateAppSecret 重新生成应用密钥
 func (h *OrganizationApplicationHandler) RegenerateAppSecret(ctx context.Context, c *app.RequestContext) {
 	orgIDStr := c.Param("org_id")
-	_, err := strconv.ParseUint(orgIDStr, 10, 64)
+	_, err := strconv.ParseInt(orgIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的组织ID")
 		return
 	}
 
 	appIDStr := c.Param("id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
 	}
 
-	appSecret, err := h.appService.RegenerateAppSecret(ctx, uint(appID))
+	appSecret, err := h.appService.RegenerateAppSecret(ctx, appID)
 	if err != nil {
 		Fail(c, 500, err.Error())
 		return
@@ -215,9 +215,9 @@ func (h *OrganizationApplicationHandler) RegenerateAppSecret(ctx context.Context
 }
 
 // GetMembers 获取应用成员列表
-func (h *OrganizationApplicationHandler) GetMembers(c *app.RequestContext) {
+func (h *OrganizationApplicationHandler) GetMembers(ctx context.Context, c *app.RequestContext) {
 	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
@@ -238,7 +238,7 @@ func (h *OrganizationApplicationHandler) GetMembers(c *app.RequestContext) {
 	}
 
 	// 获取应用成员列表
-	members, total, err := h.appService.GetMembers(context.Background(), uint(appID), page, pageSize)
+	members, total, err := h.appService.GetMembers(ctx, appID, page, pageSize)
 	if err != nil {
 		InternalServerError(c, err.Error())
 		return
@@ -248,9 +248,9 @@ func (h *OrganizationApplicationHandler) GetMembers(c *app.RequestContext) {
 }
 
 // AddMember 添加应用成员
-func (h *OrganizationApplicationHandler) AddMember(c *app.RequestContext) {
+func (h *OrganizationApplicationHandler) AddMember(ctx context.Context, c *app.RequestContext) {
 	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
@@ -258,7 +258,7 @@ func (h *OrganizationApplicationHandler) AddMember(c *app.RequestContext) {
 
 	// 获取请求参数
 	var req struct {
-		UserID      uint   `json:"user_id"`
+		UserID      int64  `json:"user_id"`
 		Role        string `json:"role"`
 		Permissions string `json:"permissions"`
 	}
@@ -274,7 +274,7 @@ func (h *OrganizationApplicationHandler) AddMember(c *app.RequestContext) {
 	}
 
 	// 添加应用成员
-	if err := h.appService.AddMember(context.Background(), uint(appID), req.UserID, req.Role, req.Permissions); err != nil {
+	if err := h.appService.AddMember(ctx, appID, req.UserID, req.Role, req.Permissions); err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
@@ -283,16 +283,16 @@ func (h *OrganizationApplicationHandler) AddMember(c *app.RequestContext) {
 }
 
 // UpdateMember 更新应用成员
-func (h *OrganizationApplicationHandler) UpdateMember(c *app.RequestContext) {
+func (h *OrganizationApplicationHandler) UpdateMember(ctx context.Context, c *app.RequestContext) {
 	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
 	}
 
 	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的用户ID")
 		return
@@ -309,7 +309,7 @@ func (h *OrganizationApplicationHandler) UpdateMember(c *app.RequestContext) {
 	}
 
 	// 更新应用成员
-	if err := h.appService.UpdateMember(context.Background(), uint(appID), uint(userID), req.Role, req.Permissions); err != nil {
+	if err := h.appService.UpdateMember(ctx, appID, userID, req.Role, req.Permissions); err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
@@ -318,23 +318,23 @@ func (h *OrganizationApplicationHandler) UpdateMember(c *app.RequestContext) {
 }
 
 // RemoveMember 移除应用成员
-func (h *OrganizationApplicationHandler) RemoveMember(c *app.RequestContext) {
+func (h *OrganizationApplicationHandler) RemoveMember(ctx context.Context, c *app.RequestContext) {
 	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
 	}
 
 	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的用户ID")
 		return
 	}
 
 	// 移除应用成员
-	if err := h.appService.RemoveMember(context.Background(), uint(appID), uint(userID)); err != nil {
+	if err := h.appService.RemoveMember(ctx, appID, userID); err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
@@ -345,13 +345,13 @@ func (h *OrganizationApplicationHandler) RemoveMember(c *app.RequestContext) {
 // GetLimit 获取应用限制
 func (h *OrganizationApplicationHandler) GetLimit(ctx context.Context, c *app.RequestContext) {
 	appIDStr := c.Param("app_id")
-	appID, err := strconv.ParseUint(appIDStr, 10, 64)
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的应用ID")
 		return
 	}
 
-	limit, err := h.appService.GetLimit(ctx, uint(appID))
+	limit, err := h.appService.GetLimit(ctx, appID)
 	if err != nil {
 		NotFound(c, "应用限制不存在")
 		return
@@ -386,3 +386,37 @@ func (h *OrganizationApplicationHandler) SetLimit(ctx context.Context, c *app.Re
 
 	Success(c, limit)
 }
+
+// ChangePlan 变更应用套餐
+func (h *OrganizationApplicationHandler) ChangePlan(ctx context.Context, c *app.RequestContext) {
+	appIDStr := c.Param("app_id")
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	// 获取请求参数
+	var req struct {
+		PlanID int64 `json:"plan_id"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	// 验证必填字段
+	if req.PlanID == 0 {
+		BadRequest(c, "套餐ID不能为空")
+		return
+	}
+
+	// 变更套餐
+	limit, err := h.appService.ChangePlan(ctx, appID, req.PlanID)
+	if err != nil {
+		Fail(c, 500, err.Error())
+		return
+	}
+
+	Success(c, limit)
+}
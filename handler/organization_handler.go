@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"saas-account/model"
 	"saas-account/service"
 	"strconv"
@@ -36,15 +37,14 @@ func (h *OrganizationHandler) Create(ctx context.Context, c *app.RequestContext)
 	}
 
 	// 获取创建者ID
-	creatorIDStr := c.GetString("user_id")
-	creatorID, err := strconv.ParseUint(creatorIDStr, 10, 64)
-	if err != nil {
+	creatorID := c.GetInt64("user_id")
+	if creatorID == 0 {
 		Unauthorized(c, "未授权")
 		return
 	}
 
 	// 创建组织
-	if err := h.orgService.Create(context.Background(), &org, uint(creatorID)); err != nil {
+	if err := h.orgService.Create(context.Background(), &org, creatorID); err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
@@ -55,13 +55,13 @@ func (h *OrganizationHandler) Create(ctx context.Context, c *app.RequestContext)
 // GetByID 根据ID获取组织
 func (h *OrganizationHandler) GetByID(ctx context.Context, c *app.RequestContext) {
 	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的组织ID")
 		return
 	}
 
-	org, err := h.orgService.GetByID(context.Background(), uint(id))
+	org, err := h.orgService.GetByID(context.Background(), id)
 	if err != nil {
 		NotFound(c, "组织不存在")
 		return
@@ -116,12 +116,16 @@ func (h *OrganizationHandler) Update(ctx context.Context, c *app.RequestContext)
 
 	// 更新组织
 	if err := h.orgService.Update(ctx, &org); err != nil {
+		if errors.Is(err, service.ErrTimeZoneLocked) {
+			Fail(c, 409, err.Error())
+			return
+		}
 		Fail(c, 500, err.Error())
 		return
 	}
 
 	// 获取更新后的组织
-	updatedOrg, err := h.orgService.GetByID(ctx, uint(id))
+	updatedOrg, err := h.orgService.GetByID(ctx, id)
 	if err != nil {
 		InternalServerError(c, err.Error())
 		return
@@ -139,7 +143,7 @@ func (h *OrganizationHandler) Delete(ctx context.Context, c *app.RequestContext)
 		return
 	}
 
-	if err := h.orgService.Delete(ctx, uint(id)); err != nil {
+	if err := h.orgService.Delete(ctx, id); err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
@@ -150,7 +154,7 @@ func (h *OrganizationHandler) Delete(ctx context.Context, c *app.RequestContext)
 // GetMembers 获取组织成员列表
 func (h *OrganizationHandler) GetMembers(ctx context.Context, c *app.RequestContext) {
 	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的组织ID")
 		return
@@ -171,7 +175,7 @@ func (h *OrganizationHandler) GetMembers(ctx context.Context, c *app.RequestCont
 	}
 
 	// 获取组织成员列表
-	members, total, err := h.orgService.GetMembers(ctx, uint(id), page, pageSize)
+	members, total, err := h.orgService.GetMembers(ctx, id, page, pageSize)
 	if err != nil {
 		InternalServerError(c, err.Error())
 		return
@@ -183,7 +187,7 @@ func (h *OrganizationHandler) GetMembers(ctx context.Context, c *app.RequestCont
 // AddMember 添加组织成员
 func (h *OrganizationHandler) AddMember(ctx context.Context, c *app.RequestContext) {
 	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的组织ID")
 		return
@@ -191,7 +195,7 @@ func (h *OrganizationHandler) AddMember(ctx context.Context, c *app.RequestConte
 
 	// 获取请求参数
 	var req struct {
-		UserID uint   `json:"user_id"`
+		UserID int64  `json:"user_id"`
 		Role   string `json:"role"`
 	}
 	if err := c.BindJSON(&req); err != nil {
@@ -206,7 +210,7 @@ func (h *OrganizationHandler) AddMember(ctx context.Context, c *app.RequestConte
 	}
 
 	// 添加组织成员
-	if err := h.orgService.AddMember(ctx, uint(id), req.UserID, req.Role); err != nil {
+	if err := h.orgService.AddMember(ctx, id, req.UserID, req.Role); err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
@@ -217,14 +221,14 @@ func (h *OrganizationHandler) AddMember(ctx context.Context, c *app.RequestConte
 // UpdateMember 更新组织成员
 func (h *OrganizationHandler) UpdateMember(ctx context.Context, c *app.RequestContext) {
 	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的组织ID")
 		return
 	}
 
 	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的用户ID")
 		return
@@ -240,7 +244,7 @@ func (h *OrganizationHandler) UpdateMember(ctx context.Context, c *app.RequestCo
 	}
 
 	// 更新组织成员
-	if err := h.orgService.UpdateMember(ctx, uint(id), uint(userID), req.Role); err != nil {
+	if err := h.orgService.UpdateMember(ctx, id, userID, req.Role); err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
@@ -251,21 +255,21 @@ func (h *OrganizationHandler) UpdateMember(ctx context.Context, c *app.RequestCo
 // RemoveMember 移除组织成员
 func (h *OrganizationHandler) RemoveMember(ctx context.Context, c *app.RequestContext) {
 	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的组织ID")
 		return
 	}
 
 	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
 		BadRequest(c, "无效的用户ID")
 		return
 	}
 
 	// 移除组织成员
-	if err := h.orgService.RemoveMember(ctx, uint(id), uint(userID)); err != nil {
+	if err := h.orgService.RemoveMember(ctx, id, userID); err != nil {
 		Fail(c, 500, err.Error())
 		return
 	}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"saas-account/model"
+	"saas-account/service"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// PlanHandler 套餐处理器
+type PlanHandler struct {
+	planService service.PlanService
+}
+
+// NewPlanHandler 创建套餐处理器
+func NewPlanHandler(planService service.PlanService) *PlanHandler {
+	return &PlanHandler{
+		planService: planService,
+	}
+}
+
+// Create 创建套餐（已存在相同编码时创建新版本）
+func (h *PlanHandler) Create(ctx context.Context, c *app.RequestContext) {
+	var plan model.Plan
+	if err := c.BindJSON(&plan); err != nil {
+		BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	if err := h.planService.Create(ctx, &plan); err != nil {
+		Fail(c, 500, err.Error())
+		return
+	}
+
+	Success(c, plan)
+}
+
+// GetByID 根据ID获取套餐
+func (h *PlanHandler) GetByID(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的套餐ID")
+		return
+	}
+
+	plan, err := h.planService.GetByID(ctx, id)
+	if err != nil {
+		NotFound(c, "套餐不存在")
+		return
+	}
+
+	Success(c, plan)
+}
+
+// List 获取套餐列表，默认只返回在售套餐
+func (h *PlanHandler) List(ctx context.Context, c *app.RequestContext) {
+	page, pageSize := getPagination(c)
+	status := c.DefaultQuery("status", "active")
+	if status == "all" {
+		status = ""
+	}
+
+	plans, total, err := h.planService.List(ctx, status, page, pageSize)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	SuccessWithPagination(c, plans, total, page, pageSize)
+}
+
+// GetVersions 获取套餐的所有版本
+func (h *PlanHandler) GetVersions(ctx context.Context, c *app.RequestContext) {
+	plans, err := h.planService.GetVersions(ctx, c.Param("code"))
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	Success(c, plans)
+}
+
+// Deprecate 停售套餐
+func (h *PlanHandler) Deprecate(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的套餐ID")
+		return
+	}
+
+	if err := h.planService.Deprecate(ctx, id); err != nil {
+		Fail(c, 500, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
+
+// SetDefault 设置默认套餐
+func (h *PlanHandler) SetDefault(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的套餐ID")
+		return
+	}
+
+	if err := h.planService.SetDefault(ctx, id); err != nil {
+		Fail(c, 500, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/cloudwego/hertz/pkg/app"
 )
@@ -85,6 +86,17 @@ func InternalServerError(c *app.RequestContext, message string) {
 	})
 }
 
+// TooManyRequests 超出使用限制
+func TooManyRequests(c *app.RequestContext, message string) {
+	if message == "" {
+		message = "请求过多"
+	}
+	c.JSON(http.StatusTooManyRequests, Response{
+		Code:    429,
+		Message: message,
+	})
+}
+
 // Pagination 分页响应
 type Pagination struct {
 	Total    int64       `json:"total"`
@@ -106,3 +118,18 @@ func SuccessWithPagination(c *app.RequestContext, data interface{}, total int64,
 		},
 	})
 }
+
+// getPagination 从查询参数中获取分页参数
+func getPagination(c *app.RequestContext) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	return page, pageSize
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"saas-account/service"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+)
+
+// StorageHandler 存储用量处理器
+type StorageHandler struct {
+	storageService service.StorageService
+}
+
+// NewStorageHandler 创建存储用量处理器
+func NewStorageHandler(storageService service.StorageService) *StorageHandler {
+	return &StorageHandler{
+		storageService: storageService,
+	}
+}
+
+// GetStatus 获取应用当前的存储用量
+func (h *StorageHandler) GetStatus(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	status, err := h.storageService.GetStatus(ctx, appID)
+	if err != nil {
+		failStorage(c, err)
+		return
+	}
+
+	setQuotaHeaders(c, status.Quota)
+	Success(c, status)
+}
+
+// SetUsage 设置应用当前的存储占用
+func (h *StorageHandler) SetUsage(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	var req struct {
+		Bytes *int64 `json:"bytes"`
+	}
+	if err := c.BindJSON(&req); err != nil || req.Bytes == nil {
+		BadRequest(c, "无效的请求参数")
+		return
+	}
+	if *req.Bytes < 0 {
+		BadRequest(c, "存储占用不能为负数")
+		return
+	}
+
+	status, err := h.storageService.SetUsage(ctx, appID, *req.Bytes)
+	if err != nil {
+		failStorage(c, err)
+		return
+	}
+
+	setQuotaHeaders(c, status.Quota)
+	Success(c, status)
+}
+
+// AddUsage 增减应用的存储占用，delta 为负数表示释放存储
+func (h *StorageHandler) AddUsage(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	var req struct {
+		Delta int64 `json:"delta"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求参数")
+		return
+	}
+	if req.Delta == 0 {
+		BadRequest(c, "变更量不能为0")
+		return
+	}
+
+	status, err := h.storageService.AddUsage(ctx, appID, req.Delta)
+	if err != nil {
+		failStorage(c, err)
+		return
+	}
+
+	setQuotaHeaders(c, status.Quota)
+	Success(c, status)
+}
+
+// GetBilling 获取应用在日期区间内的存储计费用量
+func (h *StorageHandler) GetBilling(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	startDate, endDate, _, ok := parseSummaryQuery(c)
+	if !ok {
+		return
+	}
+
+	billing, err := h.storageService.GetBilling(ctx, appID, startDate, endDate)
+	if err != nil {
+		failStorage(c, err)
+		return
+	}
+
+	Success(c, map[string]interface{}{
+		"start_date": startDate.Format("2006-01-02"),
+		"end_date":   endDate.Format("2006-01-02"),
+		"billing":    billing,
+	})
+}
+
+// failStorage 存储用量操作失败响应
+func failStorage(c *app.RequestContext, err error) {
+	var quotaErr *service.QuotaExceededError
+	switch {
+	case errors.As(err, &quotaErr):
+		setQuotaHeaders(c, quotaErr.Status)
+		TooManyRequests(c, "已超过存储使用限制")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		NotFound(c, "应用不存在")
+	default:
+		InternalServerError(c, err.Error())
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"saas-account/service"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// SubscriptionHandler 订阅处理器
+type SubscriptionHandler struct {
+	subscriptionService service.SubscriptionService
+}
+
+// NewSubscriptionHandler 创建订阅处理器
+func NewSubscriptionHandler(subscriptionService service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		subscriptionService: subscriptionService,
+	}
+}
+
+// Get 获取应用订阅信息
+func (h *SubscriptionHandler) Get(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	limit, err := h.subscriptionService.Get(ctx, appID)
+	if err != nil {
+		NotFound(c, "应用订阅不存在")
+		return
+	}
+
+	Success(c, limit)
+}
+
+// Subscribe 订阅套餐
+func (h *SubscriptionHandler) Subscribe(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	// 获取请求参数
+	var req struct {
+		PlanID    int64 `json:"plan_id"`
+		AutoRenew bool  `json:"auto_renew"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	if req.PlanID == 0 {
+		BadRequest(c, "套餐ID不能为空")
+		return
+	}
+
+	// 客户端重试时携带相同的幂等键，避免重复扣款
+	idempotencyKey := string(c.GetHeader("Idempotency-Key"))
+	if len(idempotencyKey) > 128 {
+		BadRequest(c, "幂等键长度不能超过128")
+		return
+	}
+
+	limit, err := h.subscriptionService.Subscribe(ctx, appID, req.PlanID, req.AutoRenew, idempotencyKey)
+	if err != nil {
+		if errors.Is(err, service.ErrIdempotencyKeyReused) {
+			Fail(c, 409, err.Error())
+			return
+		}
+		Fail(c, 500, err.Error())
+		return
+	}
+
+	Success(c, limit)
+}
+
+// StartTrial 开始套餐试用
+func (h *SubscriptionHandler) StartTrial(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	// 获取请求参数
+	var req struct {
+		PlanID    int64 `json:"plan_id"`
+		AutoRenew bool  `json:"auto_renew"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	if req.PlanID == 0 {
+		BadRequest(c, "套餐ID不能为空")
+		return
+	}
+
+	limit, err := h.subscriptionService.StartTrial(ctx, appID, req.PlanID, req.AutoRenew)
+	if err != nil {
+		Fail(c, 500, err.Error())
+		return
+	}
+
+	Success(c, limit)
+}
+
+// Renew 手动续费
+func (h *SubscriptionHandler) Renew(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	limit, err := h.subscriptionService.Renew(ctx, appID)
+	if err != nil {
+		Fail(c, 500, err.Error())
+		return
+	}
+
+	Success(c, limit)
+}
+
+// SetAutoRenew 设置自动续费
+func (h *SubscriptionHandler) SetAutoRenew(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	// 获取请求参数
+	var req struct {
+		AutoRenew bool `json:"auto_renew"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求参数")
+		return
+	}
+
+	if err := h.subscriptionService.SetAutoRenew(ctx, appID, req.AutoRenew); err != nil {
+		Fail(c, 500, err.Error())
+		return
+	}
+
+	Success(c, nil)
+}
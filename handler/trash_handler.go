@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"saas-account/service"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+)
+
+// TrashHandler 回收站处理器
+type TrashHandler struct {
+	trashService service.TrashService
+}
+
+// NewTrashHandler 创建回收站处理器
+func NewTrashHandler(trashService service.TrashService) *TrashHandler {
+	return &TrashHandler{
+		trashService: trashService,
+	}
+}
+
+// trashItem 回收站条目，附带删除时间
+type trashItem struct {
+	DeletedAt int64       `json:"deleted_at"`
+	Data      interface{} `json:"data"`
+}
+
+// ListUsers 获取已删除的用户列表
+func (h *TrashHandler) ListUsers(ctx context.Context, c *app.RequestContext) {
+	page, pageSize := getPagination(c)
+
+	users, total, err := h.trashService.ListDeletedUsers(ctx, page, pageSize)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	items := make([]trashItem, 0, len(users))
+	for i := range users {
+		// 清除敏感信息
+		users[i].Password = ""
+		items = append(items, trashItem{DeletedAt: users[i].DeletedAt.Time.Unix(), Data: users[i]})
+	}
+
+	SuccessWithPagination(c, items, total, page, pageSize)
+}
+
+// RestoreUser 恢复已删除的用户
+func (h *TrashHandler) RestoreUser(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	if err := h.trashService.RestoreUser(ctx, id); err != nil {
+		failTrash(c, err, "已删除的用户不存在")
+		return
+	}
+
+	Success(c, nil)
+}
+
+// PurgeUser 彻底删除用户
+func (h *TrashHandler) PurgeUser(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	if err := h.trashService.PurgeUser(ctx, id); err != nil {
+		failTrash(c, err, "已删除的用户不存在")
+		return
+	}
+
+	Success(c, nil)
+}
+
+// ListOrganizations 获取已删除的组织列表
+func (h *TrashHandler) ListOrganizations(ctx context.Context, c *app.RequestContext) {
+	page, pageSize := getPagination(c)
+
+	orgs, total, err := h.trashService.ListDeletedOrganizations(ctx, page, pageSize)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	items := make([]trashItem, 0, len(orgs))
+	for i := range orgs {
+		items = append(items, trashItem{DeletedAt: orgs[i].DeletedAt.Time.Unix(), Data: orgs[i]})
+	}
+
+	SuccessWithPagination(c, items, total, page, pageSize)
+}
+
+// RestoreOrganization 恢复已删除的组织
+func (h *TrashHandler) RestoreOrganization(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return
+	}
+
+	if err := h.trashService.RestoreOrganization(ctx, id); err != nil {
+		failTrash(c, err, "已删除的组织不存在")
+		return
+	}
+
+	Success(c, nil)
+}
+
+// PurgeOrganization 彻底删除组织
+func (h *TrashHandler) PurgeOrganization(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return
+	}
+
+	if err := h.trashService.PurgeOrganization(ctx, id); err != nil {
+		failTrash(c, err, "已删除的组织不存在")
+		return
+	}
+
+	Success(c, nil)
+}
+
+// ListApplications 获取已删除的应用列表
+func (h *TrashHandler) ListApplications(ctx context.Context, c *app.RequestContext) {
+	page, pageSize := getPagination(c)
+
+	apps, total, err := h.trashService.ListDeletedApplications(ctx, page, pageSize)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	items := make([]trashItem, 0, len(apps))
+	for i := range apps {
+		// 隐藏应用密钥
+		apps[i].AppSecret = ""
+		items = append(items, trashItem{DeletedAt: apps[i].DeletedAt.Time.Unix(), Data: apps[i]})
+	}
+
+	SuccessWithPagination(c, items, total, page, pageSize)
+}
+
+// RestoreApplication 恢复已删除的应用
+func (h *TrashHandler) RestoreApplication(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	if err := h.trashService.RestoreApplication(ctx, id); err != nil {
+		failTrash(c, err, "已删除的应用不存在")
+		return
+	}
+
+	Success(c, nil)
+}
+
+// PurgeApplication 彻底删除应用
+func (h *TrashHandler) PurgeApplication(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	if err := h.trashService.PurgeApplication(ctx, id); err != nil {
+		failTrash(c, err, "已删除的应用不存在")
+		return
+	}
+
+	Success(c, nil)
+}
+
+// trashConflicts 恢复或彻底删除时与其他数据冲突的错误，返回409
+var trashConflicts = []error{
+	service.ErrRestoreEmailTaken,
+	service.ErrRestorePhoneTaken,
+	service.ErrRestoreOwnerMissing,
+	service.ErrRestoreOrgMissing,
+	service.ErrRestoreAppKeyTaken,
+	service.ErrPurgeOwnerOfLiveOrg,
+}
+
+// failTrash 回收站操作失败响应，记录不存在时返回404，与其他数据冲突时返回409
+func failTrash(c *app.RequestContext, err error, notFoundMessage string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		NotFound(c, notFoundMessage)
+		return
+	}
+	for _, conflict := range trashConflicts {
+		if errors.Is(err, conflict) {
+			Fail(c, 409, err.Error())
+			return
+		}
+	}
+	Fail(c, 500, err.Error())
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"saas-account/logger"
+	"saas-account/model"
+	"saas-account/service"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/http1/resp"
+	"gorm.io/gorm"
+)
+
+// UsageExportHandler 使用记录导出处理器
+type UsageExportHandler struct {
+	exportService service.UsageExportService
+}
+
+// NewUsageExportHandler 创建使用记录导出处理器
+func NewUsageExportHandler(exportService service.UsageExportService) *UsageExportHandler {
+	return &UsageExportHandler{
+		exportService: exportService,
+	}
+}
+
+// ExportApplication 导出应用的使用记录
+func (h *UsageExportHandler) ExportApplication(ctx context.Context, c *app.RequestContext) {
+	appID, err := strconv.ParseInt(c.Param("app_id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的应用ID")
+		return
+	}
+
+	req, ok := parseExportRequest(c)
+	if !ok {
+		return
+	}
+	req.ApplicationId = appID
+
+	h.export(ctx, c, req, fmt.Sprintf("usages-app-%d", appID))
+}
+
+// ExportOrganization 导出组织下所有应用的使用记录
+func (h *UsageExportHandler) ExportOrganization(ctx context.Context, c *app.RequestContext) {
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return
+	}
+
+	req, ok := parseExportRequest(c)
+	if !ok {
+		return
+	}
+	req.OrganizationId = orgID
+
+	h.export(ctx, c, req, fmt.Sprintf("usages-org-%d", orgID))
+}
+
+// GetJob 获取导出任务
+func (h *UsageExportHandler) GetJob(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的导出任务ID")
+		return
+	}
+
+	job, err := h.exportService.GetJob(ctx, id)
+	if err != nil {
+		NotFound(c, "导出任务不存在")
+		return
+	}
+
+	Success(c, exportJobView(job))
+}
+
+// Download 下载导出文件
+func (h *UsageExportHandler) Download(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的导出任务ID")
+		return
+	}
+
+	job, file, size, err := h.exportService.OpenDownload(ctx, id, c.Query("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, service.ErrInvalidExportToken):
+			NotFound(c, "导出任务不存在")
+		case errors.Is(err, service.ErrExportNotReady):
+			Fail(c, 409, err.Error())
+		default:
+			InternalServerError(c, err.Error())
+		}
+		return
+	}
+
+	c.Header("Content-Type", exportContentType(job.Format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, job.FileName))
+	// 响应发送完成后由框架关闭文件
+	c.SetBodyStream(file, int(size))
+}
+
+// export 同步流式导出，或在 async=true 时创建异步导出任务
+func (h *UsageExportHandler) export(ctx context.Context, c *app.RequestContext, req *service.UsageExportRequest, name string) {
+	if c.Query("async") == "true" {
+		job, err := h.exportService.CreateJob(ctx, req)
+		if err != nil {
+			failExport(c, err)
+			return
+		}
+		Success(c, exportJobView(job))
+		return
+	}
+
+	w := &exportStreamWriter{
+		c:           c,
+		contentType: exportContentType(req.Format),
+		fileName:    fmt.Sprintf("%s.%s", name, req.Format),
+	}
+	if err := h.exportService.Export(ctx, req, w); err != nil {
+		if !w.started {
+			failExport(c, err)
+			return
+		}
+		// 响应已开始发送，只能记录日志
+		logger.GetLogger().ErrorWithContext(ctx, "导出使用记录中断: %v", err)
+		return
+	}
+
+	// 没有任何记录时也返回空文件
+	w.start()
+}
+
+// parseExportRequest 获取导出参数，参数无效时直接响应错误
+func parseExportRequest(c *app.RequestContext) (*service.UsageExportRequest, bool) {
+	startDate, endDate, _, ok := parseSummaryQuery(c)
+	if !ok {
+		return nil, false
+	}
+
+	req := &service.UsageExportRequest{
+		Format:    c.DefaultQuery("format", service.ExportFormatCSV),
+		UsageType: c.Query("usage_type"),
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+	if req.Format != service.ExportFormatCSV && req.Format != service.ExportFormatNDJSON {
+		BadRequest(c, "无效的导出格式，可选值为csv, ndjson")
+		return nil, false
+	}
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseInt(userIDStr, 10, 64)
+		if err != nil {
+			BadRequest(c, "无效的用户ID")
+			return nil, false
+		}
+		req.UserId = &userID
+	}
+
+	return req, true
+}
+
+// exportJobView 导出任务响应，任务完成后附带下载链接
+func exportJobView(job *model.UsageExportJob) map[string]interface{} {
+	view := map[string]interface{}{
+		"job": job,
+	}
+	if job.Status == model.ExportStatusCompleted {
+		view["download_url"] = fmt.Sprintf("/api/v1/exports/%d/download?token=%s", job.ID, job.Token)
+	}
+	return view
+}
+
+// exportContentType 获取导出格式对应的内容类型
+func exportContentType(format string) string {
+	if format == service.ExportFormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// failExport 导出失败响应
+func failExport(c *app.RequestContext, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		NotFound(c, "应用或组织不存在")
+		return
+	}
+	BadRequest(c, err.Error())
+}
+
+// exportStreamWriter 分块发送导出内容的写入器，第一次写入时才开始发送响应
+type exportStreamWriter struct {
+	c           *app.RequestContext
+	contentType string
+	fileName    string
+	started     bool
+}
+
+// start 设置响应头并切换为分块传输
+func (w *exportStreamWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	w.c.Header("Content-Type", w.contentType)
+	w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.fileName))
+	w.c.Response.HijackWriter(resp.NewChunkedBodyWriter(&w.c.Response, w.c.GetWriter()))
+}
+
+// Write 写入并立即发送一个分块
+func (w *exportStreamWriter) Write(p []byte) (int, error) {
+	w.start()
+
+	n, err := w.c.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, w.c.Flush()
+}
@@ -89,14 +89,14 @@ func (h *UserHandler) GetByID(ctx context.Context, c *app.RequestContext) {
 	idStr := c.Param("id")
 	logger.Logger.InfoWithContext(reqCtx, "开始获取用户信息: ID=%s", idStr)
 
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		logger.Logger.WarnWithContext(reqCtx, "无效的用户ID: %s, 错误: %v", idStr, err)
 		BadRequest(c, "无效的用户ID")
 		return
 	}
 
-	user, err := h.userService.GetByID(reqCtx, uint(id))
+	user, err := h.userService.GetByID(reqCtx, id)
 	if err != nil {
 		logger.Logger.WarnWithContext(reqCtx, "用户不存在: ID=%d, 错误: %v", id, err)
 		NotFound(c, "用户不存在")
@@ -198,7 +198,7 @@ func (h *UserHandler) Update(ctx context.Context, c *app.RequestContext) {
 	}
 
 	// 获取更新后的用户
-	updatedUser, err := h.userService.GetByID(reqCtx, uint(id))
+	updatedUser, err := h.userService.GetByID(reqCtx, id)
 	if err != nil {
 		logger.Logger.ErrorWithContext(reqCtx, "获取更新后的用户失败: %v", err)
 		InternalServerError(c, err.Error())
@@ -220,7 +220,7 @@ func (h *UserHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	idStr := c.Param("id")
 	logger.Logger.InfoWithContext(reqCtx, "开始删除用户: ID=%s", idStr)
 
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		logger.Logger.WarnWithContext(reqCtx, "无效的用户ID: %s, 错误: %v", idStr, err)
 		BadRequest(c, "无效的用户ID")
@@ -228,7 +228,7 @@ func (h *UserHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	}
 
 	// 先获取用户信息，以便记录日志
-	user, err := h.userService.GetByID(reqCtx, uint(id))
+	user, err := h.userService.GetByID(reqCtx, id)
 	if err != nil {
 		logger.Logger.WarnWithContext(reqCtx, "要删除的用户不存在: ID=%d, 错误: %v", id, err)
 		NotFound(c, "用户不存在")
@@ -236,7 +236,7 @@ func (h *UserHandler) Delete(ctx context.Context, c *app.RequestContext) {
 	}
 
 	logger.Logger.InfoWithContext(reqCtx, "开始删除用户: ID=%d, 名称=%s", id, user.Name)
-	if err := h.userService.Delete(reqCtx, uint(id)); err != nil {
+	if err := h.userService.Delete(reqCtx, id); err != nil {
 		logger.Logger.ErrorWithContext(reqCtx, "删除用户失败: %v", err)
 		Fail(c, 500, err.Error())
 		return
@@ -254,7 +254,7 @@ func (h *UserHandler) ChangePassword(ctx context.Context, c *app.RequestContext)
 	idStr := c.Param("id")
 	logger.Logger.InfoWithContext(reqCtx, "开始修改用户密码: ID=%s", idStr)
 
-	id, err := strconv.ParseUint(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		logger.Logger.WarnWithContext(reqCtx, "无效的用户ID: %s, 错误: %v", idStr, err)
 		BadRequest(c, "无效的用户ID")
@@ -288,7 +288,7 @@ func (h *UserHandler) ChangePassword(ctx context.Context, c *app.RequestContext)
 
 	// 修改密码
 	logger.Logger.InfoWithContext(reqCtx, "开始修改用户密码: ID=%d", id)
-	if err := h.userService.ChangePassword(reqCtx, uint(id), req.OldPassword, req.NewPassword); err != nil {
+	if err := h.userService.ChangePassword(reqCtx, id, req.OldPassword, req.NewPassword); err != nil {
 		logger.Logger.ErrorWithContext(reqCtx, "修改密码失败: %v", err)
 		Fail(c, 500, err.Error())
 		return
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"saas-account/model"
+	"saas-account/service"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+)
+
+// WebhookHandler Webhook 处理器
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+// NewWebhookHandler 创建 Webhook 处理器
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// webhookRequest 订阅请求参数
+type webhookRequest struct {
+	URL         string          `json:"url"`
+	EventTypes  json.RawMessage `json:"event_types"` // 订阅的事件类型，如 ["user.created"]，["*"]表示全部
+	Description string          `json:"description"`
+	Enabled     *bool           `json:"enabled"` // 是否启用，默认启用
+}
+
+// webhookSecretResponse 创建订阅和轮换密钥的响应，签名密钥只在此时返回
+type webhookSecretResponse struct {
+	*model.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// parseWebhookOrgID 解析路径中的组织ID，平台级路由没有组织ID，返回0
+func parseWebhookOrgID(c *app.RequestContext) (int64, bool) {
+	if c.Param("id") == "" {
+		return 0, true
+	}
+
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return 0, false
+	}
+	return orgID, true
+}
+
+// parseWebhookParams 解析路径中的组织ID和指定名称的ID参数
+func parseWebhookParams(c *app.RequestContext, name, invalid string) (int64, int64, bool) {
+	orgID, ok := parseWebhookOrgID(c)
+	if !ok {
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		BadRequest(c, invalid)
+		return 0, 0, false
+	}
+	return orgID, id, true
+}
+
+// bindWebhook 解析订阅请求参数
+func bindWebhook(c *app.RequestContext, orgID int64) (*model.WebhookSubscription, bool) {
+	var req webhookRequest
+	if err := c.BindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求参数")
+		return nil, false
+	}
+
+	sub := &model.WebhookSubscription{
+		OrganizationId: orgID,
+		URL:            req.URL,
+		EventTypes:     string(req.EventTypes),
+		Description:    req.Description,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+	}
+	return sub, true
+}
+
+// failWebhook 根据错误类型返回 Webhook 相关的错误响应
+func failWebhook(c *app.RequestContext, err error, notFound string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		NotFound(c, notFound)
+		return
+	}
+	BadRequest(c, err.Error())
+}
+
+// ListSubscriptions 获取组织的 Webhook 订阅列表
+func (h *WebhookHandler) ListSubscriptions(ctx context.Context, c *app.RequestContext) {
+	orgID, ok := parseWebhookOrgID(c)
+	if !ok {
+		return
+	}
+
+	subs, err := h.webhookService.ListSubscriptions(ctx, orgID)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	Success(c, subs)
+}
+
+// CreateSubscription 创建 Webhook 订阅
+func (h *WebhookHandler) CreateSubscription(ctx context.Context, c *app.RequestContext) {
+	orgID, ok := parseWebhookOrgID(c)
+	if !ok {
+		return
+	}
+
+	sub, ok := bindWebhook(c, orgID)
+	if !ok {
+		return
+	}
+
+	secret, err := h.webhookService.CreateSubscription(ctx, sub)
+	if err != nil {
+		failWebhook(c, err, "组织不存在")
+		return
+	}
+
+	Success(c, webhookSecretResponse{WebhookSubscription: sub, Secret: secret})
+}
+
+// GetSubscription 获取 Webhook 订阅
+func (h *WebhookHandler) GetSubscription(ctx context.Context, c *app.RequestContext) {
+	orgID, subID, ok := parseWebhookParams(c, "webhook_id", "无效的订阅ID")
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookService.GetSubscription(ctx, orgID, subID)
+	if err != nil {
+		failWebhook(c, err, "订阅不存在")
+		return
+	}
+
+	Success(c, sub)
+}
+
+// UpdateSubscription 更新 Webhook 订阅
+func (h *WebhookHandler) UpdateSubscription(ctx context.Context, c *app.RequestContext) {
+	orgID, subID, ok := parseWebhookParams(c, "webhook_id", "无效的订阅ID")
+	if !ok {
+		return
+	}
+
+	sub, ok := bindWebhook(c, orgID)
+	if !ok {
+		return
+	}
+	sub.ID = subID
+
+	if err := h.webhookService.UpdateSubscription(ctx, sub); err != nil {
+		failWebhook(c, err, "订阅不存在")
+		return
+	}
+
+	Success(c, sub)
+}
+
+// DeleteSubscription 删除 Webhook 订阅
+func (h *WebhookHandler) DeleteSubscription(ctx context.Context, c *app.RequestContext) {
+	orgID, subID, ok := parseWebhookParams(c, "webhook_id", "无效的订阅ID")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(ctx, orgID, subID); err != nil {
+		failWebhook(c, err, "订阅不存在")
+		return
+	}
+
+	Success(c, nil)
+}
+
+// RotateSecret 轮换 Webhook 订阅的签名密钥
+func (h *WebhookHandler) RotateSecret(ctx context.Context, c *app.RequestContext) {
+	orgID, subID, ok := parseWebhookParams(c, "webhook_id", "无效的订阅ID")
+	if !ok {
+		return
+	}
+
+	secret, err := h.webhookService.RotateSecret(ctx, orgID, subID)
+	if err != nil {
+		failWebhook(c, err, "订阅不存在")
+		return
+	}
+
+	sub, err := h.webhookService.GetSubscription(ctx, orgID, subID)
+	if err != nil {
+		failWebhook(c, err, "订阅不存在")
+		return
+	}
+
+	Success(c, webhookSecretResponse{WebhookSubscription: sub, Secret: secret})
+}
+
+// ListDeliveries 获取组织的 Webhook 投递记录，可按订阅和状态筛选
+func (h *WebhookHandler) ListDeliveries(ctx context.Context, c *app.RequestContext) {
+	orgID, ok := parseWebhookOrgID(c)
+	if !ok {
+		return
+	}
+
+	var subID int64
+	if raw := c.Query("webhook_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			BadRequest(c, "无效的订阅ID")
+			return
+		}
+		subID = id
+	}
+
+	page, pageSize := getPagination(c)
+	deliveries, total, err := h.webhookService.ListDeliveries(ctx, orgID, subID, c.Query("status"), page, pageSize)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+
+	SuccessWithPagination(c, deliveries, total, page, pageSize)
+}
+
+// GetDelivery 获取 Webhook 投递记录
+func (h *WebhookHandler) GetDelivery(ctx context.Context, c *app.RequestContext) {
+	orgID, deliveryID, ok := parseWebhookParams(c, "delivery_id", "无效的投递记录ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.GetDelivery(ctx, orgID, deliveryID)
+	if err != nil {
+		failWebhook(c, err, "投递记录不存在")
+		return
+	}
+
+	Success(c, delivery)
+}
+
+// Redeliver 重新投递事件
+func (h *WebhookHandler) Redeliver(ctx context.Context, c *app.RequestContext) {
+	orgID, deliveryID, ok := parseWebhookParams(c, "delivery_id", "无效的投递记录ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(ctx, orgID, deliveryID)
+	if err != nil {
+		failWebhook(c, err, "投递记录不存在")
+		return
+	}
+
+	Success(c, delivery)
+}
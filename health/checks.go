@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"saas-account/config"
+	"saas-account/filestore"
+	"saas-account/migration"
+	"saas-account/ratelimit"
+	"saas-account/shutdown"
+)
+
+// Database 检查主库和只读副本是否可以连接
+func Database() Check {
+	return Check{
+		Name: "database",
+		Run: func(ctx context.Context) (string, error) {
+			replicas, err := config.PingDB(ctx)
+			if err != nil {
+				return "", WithReason(err, "数据库不可用")
+			}
+			return fmt.Sprintf("主库和 %d 个只读副本可用", replicas), nil
+		},
+	}
+}
+
+// Migrations 检查数据库是否已迁移到当前程序需要的版本
+func Migrations(migrator *migration.Migrator) Check {
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) (string, error) {
+			version, err := migrator.Check(ctx)
+			if errors.Is(err, migration.ErrMigrationPending) || errors.Is(err, migration.ErrMigrationModified) {
+				return "", WithReason(err, fmt.Sprintf("%s，当前版本 %d", err, version))
+			}
+			if err != nil {
+				return "", WithReason(err, "无法读取迁移记录")
+			}
+			return fmt.Sprintf("当前版本 %d", version), nil
+		},
+	}
+}
+
+// FileStore 检查导出文件存储是否可用，只读取存储目录，不写入文件
+func FileStore(store filestore.Store) Check {
+	return Check{
+		Name: "file_store",
+		Run: func(ctx context.Context) (string, error) {
+			if err := store.Stat(); err != nil {
+				return "", WithReason(err, "文件存储不可用")
+			}
+			return "可用", nil
+		},
+	}
+}
+
+// RateLimitStore 检查当前的令牌桶存储是否可用，不消耗令牌
+func RateLimitStore(getStore func() ratelimit.Store) Check {
+	return Check{
+		Name: "rate_limit_store",
+		Run: func(ctx context.Context) (string, error) {
+			if err := getStore().Ping(ctx); err != nil {
+				return "", WithReason(err, "限流存储不可用")
+			}
+			return "可用", nil
+		},
+	}
+}
+
+// Shutdown 服务开始关闭后不再就绪，使负载均衡在等待请求完成期间不再转发新请求
+func Shutdown() Check {
+	return Check{
+		Name: "shutdown",
+		Run: func(ctx context.Context) (string, error) {
+			if shutdown.ShuttingDown() {
+				err := errors.New("服务正在关闭")
+				return "", WithReason(err, err.Error())
+			}
+			return "运行中", nil
+		},
+	}
+}
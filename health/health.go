@@ -0,0 +1,188 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"saas-account/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 检查结果状态
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// Check 就绪检查项，返回检查通过时的说明（如当前迁移版本），说明会对外返回，不能包含内部地址等信息
+// 检查失败时用 WithReason 附加可对外返回的原因，没有附加原因的错误对外只返回"检查失败"
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Result 检查项结果，原始错误可能包含内部地址等信息，只记录在日志中，不对外返回
+type Result struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`           // ok, fail
+	Detail     string `json:"detail,omitempty"` // 检查通过时的说明
+	Reason     string `json:"reason,omitempty"` // 检查失败时可对外返回的原因
+	Error      string `json:"-"`                // 检查失败的原始错误
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// 没有附加原因时对外返回的失败原因
+const (
+	reasonFailed  = "检查失败"
+	reasonTimeout = "检查超时"
+)
+
+// reasonError 附加了可对外返回的原因的检查错误
+type reasonError struct {
+	reason string
+	err    error
+}
+
+func (e *reasonError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reasonError) Unwrap() error {
+	return e.err
+}
+
+// WithReason 为检查失败的错误附加可对外返回的原因，err 的内容只记录在日志中
+func WithReason(err error, reason string) error {
+	return &reasonError{reason: reason, err: err}
+}
+
+// publicReason 获取检查错误可对外返回的原因
+func publicReason(err error) string {
+	var re *reasonError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return reasonTimeout
+	}
+	return reasonFailed
+}
+
+// Report 就绪检查报告
+type Report struct {
+	Status    string   `json:"status"` // ready, not_ready
+	Checks    []Result `json:"checks"`
+	CheckedAt int64    `json:"checked_at"`
+}
+
+// Ready 是否就绪
+func (r *Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// Checker 就绪检查器，并发执行全部检查项，每项检查有独立的超时时间
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+
+	// mu 保护上一次检查的结果，只在就绪状态或失败原因变化时记录日志，避免探针请求刷屏
+	mu           sync.Mutex
+	checked      bool
+	lastFailures string
+}
+
+// NewChecker 创建就绪检查器
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Ready 执行全部检查项，任意一项失败时未就绪
+func (c *Checker) Ready(ctx context.Context) *Report {
+	report := &Report{
+		Status:    StatusReady,
+		Checks:    make([]Result, len(c.checks)),
+		CheckedAt: time.Now().Unix(),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusNotReady
+			failed = append(failed, fmt.Sprintf("%s: %s", result.Name, result.Error))
+		}
+	}
+
+	failures := strings.Join(failed, "; ")
+	c.mu.Lock()
+	changed := !c.checked || failures != c.lastFailures
+	c.checked, c.lastFailures = true, failures
+	c.mu.Unlock()
+	if changed {
+		if report.Ready() {
+			logger.GetLogger().Info("服务已就绪")
+		} else {
+			logger.GetLogger().Warn("服务未就绪: %s", failures)
+		}
+	}
+	return report
+}
+
+// run 在超时时间内执行检查项，检查项未响应 ctx 取消时也会按超时返回
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type outcome struct {
+		detail string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		detail, err := check.Run(ctx)
+		done <- outcome{detail: detail, err: err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-ctx.Done():
+		o.err = ctx.Err()
+	}
+
+	result := Result{
+		Name:       check.Name,
+		Status:     StatusOK,
+		Detail:     o.detail,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if o.err != nil {
+		result.Status = StatusFail
+		result.Detail = ""
+		result.Reason = publicReason(o.err)
+		result.Error = o.err.Error()
+		if errors.Is(o.err, context.DeadlineExceeded) {
+			result.Error = fmt.Sprintf("检查超时（%s）", c.timeout)
+		}
+	}
+	return result
+}
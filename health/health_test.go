@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckerReady(t *testing.T) {
+	checker := NewChecker(100*time.Millisecond,
+		Check{Name: "database", Run: func(ctx context.Context) (string, error) {
+			return "", WithReason(errors.New("dial tcp 10.0.0.5:5432: connection refused"), "数据库不可用")
+		}},
+		Check{Name: "migrations", Run: func(ctx context.Context) (string, error) {
+			return "当前版本 17", nil
+		}},
+		Check{Name: "file_store", Run: func(ctx context.Context) (string, error) {
+			return "", errors.New("stat /var/lib/exports: permission denied")
+		}},
+		Check{Name: "rate_limit_store", Run: func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}},
+	)
+
+	report := checker.Ready(context.Background())
+	if report.Ready() || report.Checks[0].Status != StatusFail || report.Checks[1].Status != StatusOK {
+		t.Fatalf("检查结果 = %+v", report)
+	}
+	if report.Checks[0].Error == "" {
+		t.Error("原始错误没有记录在检查结果中")
+	}
+
+	// 对外返回每项检查的说明和整理后的失败原因，不包含原始错误
+	wantReasons := []string{"数据库不可用", "", reasonFailed, reasonTimeout}
+	for i, want := range wantReasons {
+		if report.Checks[i].Reason != want {
+			t.Errorf("%s 的原因 = %q, 期望 %q", report.Checks[i].Name, report.Checks[i].Reason, want)
+		}
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{"10.0.0.5", "connection refused", "/var/lib/exports"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("报告 %s 包含 %q", data, leaked)
+		}
+	}
+	if !strings.Contains(string(data), "当前版本 17") {
+		t.Errorf("报告 %s 没有包含迁移版本", data)
+	}
+}
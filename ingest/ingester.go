@@ -0,0 +1,378 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"saas-account/config"
+	"saas-account/logger"
+	"saas-account/model"
+	"saas-account/repository"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrIngesterClosed 写入器已关闭
+var ErrIngesterClosed = errors.New("使用记录写入器已关闭")
+
+// Stats 写入器运行指标，用于观察积压情况
+type Stats struct {
+	QueueDepth      int   `json:"queue_depth"`       // 队列中待处理的事件数
+	QueueCapacity   int   `json:"queue_capacity"`    // 队列容量
+	Pending         int   `json:"pending"`           // 合并后等待写入的记录数
+	PendingCapacity int   `json:"pending_capacity"`  // 等待写入的记录数上限，达到后暂停读取队列
+	Enqueued        int64 `json:"enqueued"`          // 累计入队事件数
+	Coalesced       int64 `json:"coalesced"`         // 累计被合并的事件数
+	Blocked         int64 `json:"blocked"`           // 累计因队列已满而阻塞的入队次数
+	Flushed         int64 `json:"flushed"`           // 累计写入的记录数
+	Batches         int64 `json:"batches"`           // 累计写入批次数
+	FlushErrors     int64 `json:"flush_errors"`      // 累计写入失败次数
+	Dropped         int64 `json:"dropped"`           // 累计关闭超时后丢弃的记录数
+	LastFlushAt     int64 `json:"last_flush_at"`     // 最近一次写入时间
+	LastFlushMillis int64 `json:"last_flush_millis"` // 最近一次写入耗时（毫秒）
+}
+
+// coalesceKey 合并键，同一键的使用量在写入前累加为一条记录
+type coalesceKey struct {
+	applicationID  int64
+	organizationID int64
+	userID         int64
+	hasUser        bool
+	usageType      string
+	details        string
+	bucketStart    int64 // 组织时区下所在小时的开始时间，与汇总区间一致
+	location       string
+}
+
+// pendingUsage 等待写入的使用记录及其所属组织的时区
+type pendingUsage struct {
+	usage *model.ApplicationUsage
+	loc   *time.Location
+}
+
+// Ingester 使用记录缓冲写入器，批量合并写入使用记录和汇总
+type Ingester struct {
+	usageRepo     repository.ApplicationUsageRepository
+	rollupRepo    repository.UsageRollupRepository
+	txManager     repository.TransactionManager
+	queue         chan pendingUsage
+	batchSize     int
+	maxPending    int
+	flushInterval time.Duration
+
+	mu         sync.RWMutex
+	closed     bool
+	closing    chan struct{}  // 关闭时关闭，使暂停读取队列的写入协程立即开始写入剩余记录，并唤醒阻塞的提交
+	submitting sync.WaitGroup // 正在提交的请求，全部返回后才关闭队列
+	done       chan struct{}
+
+	// stopCtx 在关闭超时后取消，中止正在进行和等待重试的写入
+	stopCtx context.Context
+	stop    context.CancelFunc
+
+	// 以下字段仅由写入协程访问
+	pending  map[coalesceKey]pendingUsage
+	order    []coalesceKey
+	attempts int
+
+	pendingCount    int64
+	enqueued        int64
+	coalesced       int64
+	blocked         int64
+	flushed         int64
+	batches         int64
+	flushErrors     int64
+	dropped         int64
+	lastFlushAt     int64
+	lastFlushMillis int64
+}
+
+// NewIngester 创建并启动使用记录缓冲写入器，合并后等待写入的记录数上限与队列容量相同
+func NewIngester(
+	usageRepo repository.ApplicationUsageRepository,
+	rollupRepo repository.UsageRollupRepository,
+	queueSize, batchSize int,
+	flushInterval time.Duration,
+	txManager repository.TransactionManager,
+) *Ingester {
+	if queueSize <= 0 {
+		queueSize = 10000
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	i := &Ingester{
+		usageRepo:     usageRepo,
+		rollupRepo:    rollupRepo,
+		txManager:     txManager,
+		queue:         make(chan pendingUsage, queueSize),
+		batchSize:     batchSize,
+		maxPending:    max(queueSize, batchSize),
+		flushInterval: flushInterval,
+		closing:       make(chan struct{}),
+		done:          make(chan struct{}),
+		pending:       make(map[coalesceKey]pendingUsage),
+	}
+	i.stopCtx, i.stop = context.WithCancel(context.Background())
+	go i.run()
+	return i
+}
+
+// Submit 提交使用记录，loc 为所属组织的时区，用于计算汇总区间。记录复制后入队，合并时不修改调用方的记录
+// 队列已满时阻塞直到有空位、请求取消或写入器关闭；写入持续失败时待写入记录达到上限后不再读取队列，由此产生背压
+func (i *Ingester) Submit(ctx context.Context, usage *model.ApplicationUsage, loc *time.Location) error {
+	copied := *usage
+	item := pendingUsage{usage: &copied, loc: loc}
+
+	// 只在登记提交时持有读锁，阻塞等待入队时不持有，避免关闭时等待阻塞的提交
+	i.mu.RLock()
+	if i.closed {
+		i.mu.RUnlock()
+		return ErrIngesterClosed
+	}
+	i.submitting.Add(1)
+	i.mu.RUnlock()
+	defer i.submitting.Done()
+
+	select {
+	case i.queue <- item:
+		atomic.AddInt64(&i.enqueued, 1)
+		return nil
+	default:
+	}
+
+	// 队列已满，产生背压
+	atomic.AddInt64(&i.blocked, 1)
+	select {
+	case i.queue <- item:
+		atomic.AddInt64(&i.enqueued, 1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-i.closing:
+		return ErrIngesterClosed
+	}
+}
+
+// Close 停止接收新记录，等待正在进行的提交返回后关闭队列，并等待队列中的记录全部写入，写入失败时按刷新间隔重试
+// ctx 到期时放弃重试，未写入的记录记入日志和丢弃数
+func (i *Ingester) Close(ctx context.Context) error {
+	i.mu.Lock()
+	closing := !i.closed
+	if closing {
+		i.closed = true
+		close(i.closing)
+	}
+	i.mu.Unlock()
+
+	// 阻塞的提交在 closing 关闭后立即返回
+	if closing {
+		i.submitting.Wait()
+		close(i.queue)
+	}
+
+	select {
+	case <-i.done:
+		return nil
+	case <-ctx.Done():
+		i.stop()
+		<-i.done
+		return ctx.Err()
+	}
+}
+
+// Stats 获取写入器运行指标
+func (i *Ingester) Stats() Stats {
+	return Stats{
+		QueueDepth:      len(i.queue),
+		QueueCapacity:   cap(i.queue),
+		Pending:         int(atomic.LoadInt64(&i.pendingCount)),
+		PendingCapacity: i.maxPending,
+		Enqueued:        atomic.LoadInt64(&i.enqueued),
+		Coalesced:       atomic.LoadInt64(&i.coalesced),
+		Blocked:         atomic.LoadInt64(&i.blocked),
+		Flushed:         atomic.LoadInt64(&i.flushed),
+		Batches:         atomic.LoadInt64(&i.batches),
+		FlushErrors:     atomic.LoadInt64(&i.flushErrors),
+		Dropped:         atomic.LoadInt64(&i.dropped),
+		LastFlushAt:     atomic.LoadInt64(&i.lastFlushAt),
+		LastFlushMillis: atomic.LoadInt64(&i.lastFlushMillis),
+	}
+}
+
+// run 写入协程，按数量或时间阈值批量写入
+func (i *Ingester) run() {
+	defer close(i.done)
+
+	ticker := time.NewTicker(i.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		// 待写入记录达到上限时暂停读取队列，等待定时器重试写入
+		queue := i.queue
+		if len(i.pending) >= i.maxPending {
+			queue = nil
+		}
+
+		select {
+		case item, ok := <-queue:
+			if !ok {
+				// 队列已关闭，写入剩余记录后退出
+				i.drain(ticker)
+				return
+			}
+			i.add(item)
+			// 写入失败后只在定时器触发时重试，避免连续重试
+			if len(i.pending) >= i.batchSize && i.attempts == 0 {
+				i.tryFlush()
+			}
+		case <-ticker.C:
+			i.tryFlush()
+		case <-i.closing:
+			i.drain(ticker)
+			return
+		}
+	}
+}
+
+// drain 关闭时读取队列中剩余的记录并写入，失败时按刷新间隔重试，直到成功或关闭超时
+func (i *Ingester) drain(ticker *time.Ticker) {
+	for item := range i.queue {
+		i.add(item)
+	}
+
+	for {
+		if err := i.flush(); err == nil {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-i.stopCtx.Done():
+			lost := len(i.pending)
+			logger.GetLogger().Error("关闭时写入使用记录超时，丢弃记录: 记录数=%d", lost)
+			atomic.AddInt64(&i.dropped, int64(lost))
+			i.reset()
+			return
+		}
+	}
+}
+
+// add 将使用记录合并到待写入集合
+func (i *Ingester) add(item pendingUsage) {
+	usage := item.usage
+	key := coalesceKey{
+		applicationID:  usage.ApplicationId,
+		organizationID: usage.OrganizationId,
+		usageType:      usage.UsageType,
+		details:        usage.Details,
+		bucketStart:    repository.RollupBucket(model.RollupGranularityHour, usage.UsageDate, item.loc),
+		location:       item.loc.String(),
+	}
+	if usage.UserId != nil {
+		key.userID = *usage.UserId
+		key.hasUser = true
+	}
+
+	if merged, ok := i.pending[key]; ok {
+		existing := merged.usage
+		existing.UsageAmount += usage.UsageAmount
+		if usage.UsageDate < existing.UsageDate {
+			existing.UsageDate = usage.UsageDate
+		}
+		atomic.AddInt64(&i.coalesced, 1)
+		return
+	}
+
+	i.pending[key] = item
+	i.order = append(i.order, key)
+	atomic.StoreInt64(&i.pendingCount, int64(len(i.pending)))
+}
+
+// tryFlush 写入待写入的记录，失败时保留记录等待定时器重试，不丢弃；
+// 持续失败时待写入记录达到上限后暂停读取队列，由队列向提交方产生背压
+func (i *Ingester) tryFlush() {
+	err := i.flush()
+	if err == nil {
+		return
+	}
+
+	i.attempts++
+	logger.GetLogger().Error("批量写入使用记录失败，稍后重试: 记录数=%d, 连续失败次数=%d, 错误: %v", len(i.pending), i.attempts, err)
+}
+
+// flush 批量写入待写入的记录，失败时保留记录并返回错误
+func (i *Ingester) flush() error {
+	if len(i.pending) == 0 {
+		return nil
+	}
+
+	usages := make([]*model.ApplicationUsage, 0, len(i.order))
+	for _, key := range i.order {
+		usages = append(usages, i.pending[key].usage)
+	}
+
+	ctx, cancel := context.WithTimeout(i.stopCtx, 30*time.Second)
+	defer cancel()
+
+	// 原始记录和汇总在同一个事务中写入，任一失败时整批回滚后重试
+	start := time.Now()
+	err := i.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := i.usageRepo.CreateBatch(ctx, usages); err != nil {
+			return err
+		}
+		for _, key := range i.order {
+			item := i.pending[key]
+			if err := i.rollupRepo.Add(ctx, item.usage, item.loc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		atomic.AddInt64(&i.flushErrors, 1)
+		return err
+	}
+
+	atomic.AddInt64(&i.flushed, int64(len(usages)))
+	atomic.AddInt64(&i.batches, 1)
+	atomic.StoreInt64(&i.lastFlushAt, time.Now().Unix())
+	atomic.StoreInt64(&i.lastFlushMillis, time.Since(start).Milliseconds())
+	i.reset()
+	return nil
+}
+
+// reset 清空待写入集合
+func (i *Ingester) reset() {
+	i.pending = make(map[coalesceKey]pendingUsage)
+	i.order = i.order[:0]
+	i.attempts = 0
+	atomic.StoreInt64(&i.pendingCount, 0)
+}
+
+var (
+	ingester     *Ingester
+	ingesterOnce sync.Once
+)
+
+// GetIngester 获取全局使用记录写入器，未开启异步写入时返回nil
+func GetIngester() *Ingester {
+	ingesterOnce.Do(func() {
+		cfg := config.GetConfig()
+		if !cfg.UsageIngestAsync {
+			return
+		}
+		ingester = NewIngester(
+			repository.NewApplicationUsageRepository(),
+			repository.NewUsageRollupRepository(),
+			cfg.UsageIngestQueueSize,
+			cfg.UsageIngestBatchSize,
+			time.Duration(cfg.UsageIngestFlushInterval)*time.Millisecond,
+			repository.NewTransactionManager(),
+		)
+	})
+	return ingester
+}
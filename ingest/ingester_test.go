@@ -0,0 +1,347 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"saas-account/repository"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errWrite = errors.New("写入失败")
+
+// fakeUsageRepo 内存中的使用记录仓库，failures 为接下来需要失败的写入次数，小于0时一直失败
+type fakeUsageRepo struct {
+	repository.ApplicationUsageRepository
+	mu       sync.Mutex
+	failures int
+	usages   []*model.ApplicationUsage
+}
+
+func (r *fakeUsageRepo) CreateBatch(ctx context.Context, usages []*model.ApplicationUsage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures != 0 {
+		r.failures--
+		return errWrite
+	}
+	r.usages = append(r.usages, usages...)
+	return nil
+}
+
+func (r *fakeUsageRepo) written() []*model.ApplicationUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*model.ApplicationUsage(nil), r.usages...)
+}
+
+func (r *fakeUsageRepo) setFailures(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = n
+}
+
+// fakeRollupRepo 内存中的汇总仓库，记录累加的使用量，failures 为接下来需要失败的累加次数
+type fakeRollupRepo struct {
+	repository.UsageRollupRepository
+	mu       sync.Mutex
+	failures int
+	total    int64
+}
+
+func (r *fakeRollupRepo) Add(ctx context.Context, usage *model.ApplicationUsage, loc *time.Location) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures != 0 {
+		r.failures--
+		return errWrite
+	}
+	r.total += usage.UsageAmount
+	return nil
+}
+
+func (r *fakeRollupRepo) sum() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.total
+}
+
+// fakeTxManager 事务出错时恢复使用记录和汇总仓库在事务开始前的状态
+type fakeTxManager struct {
+	usageRepo  *fakeUsageRepo
+	rollupRepo *fakeRollupRepo
+}
+
+func (m *fakeTxManager) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	written, total := len(m.usageRepo.written()), m.rollupRepo.sum()
+	if err := fn(ctx); err != nil {
+		m.usageRepo.mu.Lock()
+		m.usageRepo.usages = m.usageRepo.usages[:written]
+		m.usageRepo.mu.Unlock()
+		m.rollupRepo.mu.Lock()
+		m.rollupRepo.total = total
+		m.rollupRepo.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// newTestIngester 创建使用内存仓库的写入器
+func newTestIngester(queueSize, batchSize int, flushInterval time.Duration) (*Ingester, *fakeUsageRepo, *fakeRollupRepo) {
+	usageRepo, rollupRepo := &fakeUsageRepo{}, &fakeRollupRepo{}
+	i := NewIngester(usageRepo, rollupRepo, queueSize, batchSize, flushInterval, &fakeTxManager{usageRepo, rollupRepo})
+	return i, usageRepo, rollupRepo
+}
+
+// newTestUsage 创建使用记录
+func newTestUsage(appID int64, usageType string, amount, date int64) *model.ApplicationUsage {
+	return &model.ApplicationUsage{ApplicationId: appID, OrganizationId: 1, UsageType: usageType, UsageAmount: amount, UsageDate: date}
+}
+
+// submitAll 提交使用记录
+func submitAll(t *testing.T, i *Ingester, usages ...*model.ApplicationUsage) {
+	t.Helper()
+	for _, usage := range usages {
+		if err := i.Submit(context.Background(), usage, time.UTC); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIngesterCoalesceAndClose(t *testing.T) {
+	i, usageRepo, rollupRepo := newTestIngester(100, 100, time.Hour)
+
+	hour := int64(1767225600)
+	submitAll(t, i,
+		newTestUsage(1, "api_call", 1, hour+10),
+		newTestUsage(1, "api_call", 2, hour+5),
+		newTestUsage(1, "api_call", 4, hour+3600),
+		newTestUsage(2, "api_call", 8, hour),
+	)
+	if err := i.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	usages := usageRepo.written()
+	if len(usages) != 3 {
+		t.Fatalf("写入记录数 = %d, 期望 3", len(usages))
+	}
+	if usages[0].UsageAmount != 3 || usages[0].UsageDate != hour+5 {
+		t.Errorf("合并后 = %+v, 期望使用量3、时间为最早的事件", usages[0])
+	}
+	if rollupRepo.total != 15 {
+		t.Errorf("汇总使用量 = %d, 期望 15", rollupRepo.total)
+	}
+
+	stats := i.Stats()
+	if stats.Enqueued != 4 || stats.Coalesced != 1 || stats.Flushed != 3 || stats.Pending != 0 || stats.Dropped != 0 {
+		t.Errorf("指标 = %+v", stats)
+	}
+	if err := i.Submit(context.Background(), newTestUsage(1, "api_call", 1, hour), time.UTC); err != ErrIngesterClosed {
+		t.Errorf("关闭后提交错误 = %v, 期望 %v", err, ErrIngesterClosed)
+	}
+}
+
+func TestIngesterRetriesUntilWritten(t *testing.T) {
+	i, usageRepo, _ := newTestIngester(100, 100, 10*time.Millisecond)
+	usageRepo.setFailures(5)
+	submitAll(t, i, newTestUsage(1, "api_call", 1, 0), newTestUsage(1, "storage", 1, 0))
+
+	// 运行期间连续失败时保留记录，按刷新间隔重试直到写入
+	deadline := time.Now().Add(5 * time.Second)
+	for len(usageRepo.written()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := i.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := i.Stats()
+	if stats.Dropped != 0 || stats.FlushErrors != 5 || len(usageRepo.written()) != 2 {
+		t.Errorf("指标 = %+v, 已写入 %d", stats, len(usageRepo.written()))
+	}
+}
+
+func TestIngesterCopiesSubmittedUsage(t *testing.T) {
+	i, usageRepo, _ := newTestIngester(100, 100, time.Hour)
+	first, second := newTestUsage(1, "api_call", 1, 0), newTestUsage(1, "api_call", 2, 0)
+	submitAll(t, i, first, second)
+	if err := i.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 合并只修改写入器内部的副本
+	usages := usageRepo.written()
+	if len(usages) != 1 || usages[0].UsageAmount != 3 || usages[0] == first {
+		t.Fatalf("写入记录 = %+v", usages)
+	}
+	if first.UsageAmount != 1 || second.UsageAmount != 2 {
+		t.Errorf("提交的记录被修改: %+v, %+v", first, second)
+	}
+}
+
+func TestIngesterCloseRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		timeout     time.Duration
+		wantErr     error
+		wantWritten int
+		wantDropped int64
+	}{
+		{name: "重试后写入", failures: 5, timeout: 5 * time.Second, wantWritten: 2},
+		{name: "超时后丢弃", failures: -1, timeout: 50 * time.Millisecond, wantErr: context.DeadlineExceeded, wantDropped: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, usageRepo, _ := newTestIngester(100, 100, 5*time.Millisecond)
+			submitAll(t, i, newTestUsage(1, "api_call", 1, 0), newTestUsage(2, "api_call", 1, 0))
+
+			// 关闭时写入失败，按刷新间隔重试直到写入或关闭超时
+			usageRepo.setFailures(tt.failures)
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			if err := i.Close(ctx); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("关闭错误 = %v, 期望 %v", err, tt.wantErr)
+			}
+
+			stats := i.Stats()
+			if len(usageRepo.written()) != tt.wantWritten || stats.Dropped != tt.wantDropped || stats.Pending != 0 {
+				t.Errorf("已写入 %d, 指标 = %+v", len(usageRepo.written()), stats)
+			}
+		})
+	}
+}
+
+func TestIngesterBackPressure(t *testing.T) {
+	i, usageRepo, _ := newTestIngester(2, 2, time.Hour)
+	usageRepo.setFailures(-1)
+	defer func() {
+		usageRepo.setFailures(0)
+		i.Close(context.Background())
+	}()
+
+	// 写入失败后待写入记录达到上限，队列填满后提交阻塞直到请求取消
+	var err error
+	for n := int64(0); n < 10 && err == nil; n++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err = i.Submit(ctx, newTestUsage(n, "api_call", 1, 0), time.UTC)
+		cancel()
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("提交错误 = %v, 期望阻塞到请求取消", err)
+	}
+
+	stats := i.Stats()
+	if stats.Pending > stats.PendingCapacity || stats.QueueDepth != stats.QueueCapacity || stats.Blocked == 0 {
+		t.Errorf("指标 = %+v, 期望待写入记录不超过上限且队列已满", stats)
+	}
+}
+
+func TestIngesterCloseWakesBlockedSubmit(t *testing.T) {
+	i, usageRepo, _ := newTestIngester(1, 1, time.Hour)
+	usageRepo.setFailures(-1)
+
+	// 队列已满后提交阻塞
+	errs := make(chan error, 10)
+	for n := int64(0); n < 10; n++ {
+		go func(n int64) {
+			errs <- i.Submit(context.Background(), newTestUsage(n, "api_call", 1, 0), time.UTC)
+		}(n)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for i.Stats().Blocked == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 关闭时阻塞的提交立即返回，不会向已关闭的队列发送
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := i.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("关闭错误 = %v, 期望写入一直失败时超时", err)
+	}
+	closed := 0
+	for n := 0; n < 10; n++ {
+		if err := <-errs; errors.Is(err, ErrIngesterClosed) {
+			closed++
+		} else if err != nil {
+			t.Errorf("提交错误 = %v", err)
+		}
+	}
+	if closed == 0 {
+		t.Error("没有提交因写入器关闭而返回")
+	}
+}
+
+func TestIngesterCoalesceByLocalHour(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("缺少时区数据:", err)
+	}
+	utc := func(hour, min int) int64 { return time.Date(2026, 1, 1, hour, min, 0, 0, time.UTC).Unix() }
+
+	tests := []struct {
+		name  string
+		loc   *time.Location
+		dates []int64
+		want  int
+	}{
+		{name: "UTC同一小时", loc: time.UTC, dates: []int64{utc(10, 20), utc(10, 40)}, want: 1},
+		// 10:20 和 10:40 UTC 分别是印度时间 15:50 和 16:10，属于两个本地小时
+		{name: "半小时时区跨本地小时", loc: kolkata, dates: []int64{utc(10, 20), utc(10, 40)}, want: 2},
+		// 10:40 和 11:20 UTC 都是印度时间 16 点
+		{name: "半小时时区同一本地小时", loc: kolkata, dates: []int64{utc(10, 40), utc(11, 20)}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, usageRepo, _ := newTestIngester(100, 100, time.Hour)
+			for _, date := range tt.dates {
+				if err := i.Submit(context.Background(), newTestUsage(1, "api_call", 1, date), tt.loc); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := i.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			usages := usageRepo.written()
+			if len(usages) != tt.want {
+				t.Fatalf("写入记录数 = %d, 期望 %d", len(usages), tt.want)
+			}
+			// 合并后的记录仍属于各自事件所在的本地小时
+			for _, usage := range usages {
+				bucket := repository.RollupBucket(model.RollupGranularityHour, usage.UsageDate, tt.loc)
+				for _, date := range tt.dates {
+					if repository.RollupBucket(model.RollupGranularityHour, date, tt.loc) == bucket && date < usage.UsageDate {
+						t.Errorf("记录时间 %d 晚于同一本地小时的事件 %d", usage.UsageDate, date)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIngesterRollupFailureRollsBack(t *testing.T) {
+	i, usageRepo, rollupRepo := newTestIngester(100, 100, 5*time.Millisecond)
+
+	// 累加汇总失败时，整批记录和已累加的汇总一起回滚，下次重试时写入
+	rollupRepo.mu.Lock()
+	rollupRepo.failures = 1
+	rollupRepo.mu.Unlock()
+	submitAll(t, i, newTestUsage(1, "api_call", 1, 0), newTestUsage(2, "api_call", 2, 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := i.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := i.Stats()
+	if len(usageRepo.written()) != 2 || rollupRepo.sum() != 3 || stats.FlushErrors != 1 || stats.Dropped != 0 {
+		t.Errorf("已写入 %d, 汇总 %d, 指标 = %+v", len(usageRepo.written()), rollupRepo.sum(), stats)
+	}
+}
@@ -0,0 +1,37 @@
+package job
+
+import (
+	"context"
+	"saas-account/logger"
+	"saas-account/service"
+)
+
+// AlertJob 用量告警检查任务，定期检查当前用量，补充记录使用量时未触发的告警
+type AlertJob struct {
+	alertService service.AlertService
+}
+
+// NewAlertJob 创建用量告警检查任务
+func NewAlertJob(alertService service.AlertService) *AlertJob {
+	return &AlertJob{
+		alertService: alertService,
+	}
+}
+
+// Name 任务名称
+func (j *AlertJob) Name() string {
+	return "usage_alert"
+}
+
+// Run 执行检查
+func (j *AlertJob) Run(ctx context.Context) error {
+	fired, err := j.alertService.EvaluateAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	if fired > 0 {
+		logger.GetLogger().Info("用量告警检查完成，共发送 %d 条告警", fired)
+	}
+	return nil
+}
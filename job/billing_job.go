@@ -0,0 +1,37 @@
+package job
+
+import (
+	"context"
+	"saas-account/logger"
+	"saas-account/service"
+)
+
+// BillingJob 账单任务，计费周期结束后为所有组织生成并出账上一个周期的账单
+type BillingJob struct {
+	invoiceService service.InvoiceService
+}
+
+// NewBillingJob 创建账单任务
+func NewBillingJob(invoiceService service.InvoiceService) *BillingJob {
+	return &BillingJob{
+		invoiceService: invoiceService,
+	}
+}
+
+// Name 任务名称
+func (j *BillingJob) Name() string {
+	return "billing"
+}
+
+// Run 执行出账
+func (j *BillingJob) Run(ctx context.Context) error {
+	issued, err := j.invoiceService.ProcessPreviousPeriod(ctx)
+	if err != nil {
+		return err
+	}
+
+	if issued > 0 {
+		logger.GetLogger().Info("账单出账完成，共出账 %d 张账单", issued)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package job
+
+import (
+	"context"
+	"saas-account/logger"
+	"saas-account/service"
+)
+
+// OutboxRelayJob 发件箱中继任务，将已提交的领域事件发布到投递目标
+type OutboxRelayJob struct {
+	relay service.OutboxRelay
+}
+
+// NewOutboxRelayJob 创建发件箱中继任务
+func NewOutboxRelayJob(relay service.OutboxRelay) *OutboxRelayJob {
+	return &OutboxRelayJob{
+		relay: relay,
+	}
+}
+
+// Name 任务名称
+func (j *OutboxRelayJob) Name() string {
+	return "outbox_relay"
+}
+
+// Run 执行中继
+func (j *OutboxRelayJob) Run(ctx context.Context) error {
+	published, err := j.relay.Relay(ctx)
+	if err != nil {
+		return err
+	}
+
+	if published > 0 {
+		logger.GetLogger().Info("发件箱中继完成，共发布 %d 个事件", published)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"saas-account/audit"
+	"saas-account/logger"
+	"sync"
+	"time"
+)
+
+// Job 定时任务接口
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// entry 调度条目
+type entry struct {
+	job      Job
+	interval time.Duration
+}
+
+// Scheduler 定时任务调度器，按固定间隔执行已注册的任务
+type Scheduler struct {
+	entries []entry
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewScheduler 创建定时任务调度器
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every 注册按固定间隔执行的任务，需在Start之前调用
+func (s *Scheduler) Every(interval time.Duration, job Job) {
+	s.entries = append(s.entries, entry{job: job, interval: interval})
+}
+
+// Start 启动所有已注册的任务
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.loop(ctx, e)
+	}
+}
+
+// Stop 停止所有任务，并等待正在执行的任务结束，ctx 到期时不再等待并返回错误
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待正在执行的定时任务结束超时: %w", ctx.Err())
+	}
+}
+
+// loop 按间隔循环执行任务
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	defer s.wg.Done()
+
+	// 定时任务产生的审计日志以系统身份记录
+	ctx = audit.WithSystemActor(ctx, e.job.Name())
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.job.Run(ctx); err != nil {
+				logger.GetLogger().Error("定时任务执行失败: %s, 错误: %v", e.job.Name(), err)
+			}
+		}
+	}
+}
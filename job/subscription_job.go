@@ -0,0 +1,28 @@
+package job
+
+import (
+	"context"
+	"saas-account/service"
+)
+
+// SubscriptionJob 订阅检查任务，发送到期提醒并处理到期订阅
+type SubscriptionJob struct {
+	subscriptionService service.SubscriptionService
+}
+
+// NewSubscriptionJob 创建订阅检查任务
+func NewSubscriptionJob(subscriptionService service.SubscriptionService) *SubscriptionJob {
+	return &SubscriptionJob{
+		subscriptionService: subscriptionService,
+	}
+}
+
+// Name 任务名称
+func (j *SubscriptionJob) Name() string {
+	return "subscription"
+}
+
+// Run 执行订阅检查
+func (j *SubscriptionJob) Run(ctx context.Context) error {
+	return j.subscriptionService.ProcessExpirations(ctx)
+}
@@ -0,0 +1,40 @@
+package job
+
+import (
+	"context"
+	"saas-account/logger"
+	"saas-account/service"
+	"time"
+)
+
+// TrashPurgeJob 回收站清理任务，彻底删除超过保留期限的软删除数据
+type TrashPurgeJob struct {
+	trashService service.TrashService
+	retention    time.Duration
+}
+
+// NewTrashPurgeJob 创建回收站清理任务
+func NewTrashPurgeJob(trashService service.TrashService, retention time.Duration) *TrashPurgeJob {
+	return &TrashPurgeJob{
+		trashService: trashService,
+		retention:    retention,
+	}
+}
+
+// Name 任务名称
+func (j *TrashPurgeJob) Name() string {
+	return "trash_purge"
+}
+
+// Run 执行清理
+func (j *TrashPurgeJob) Run(ctx context.Context) error {
+	purged, err := j.trashService.PurgeExpired(ctx, j.retention)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		logger.GetLogger().Info("回收站清理完成，共彻底删除 %d 条记录", purged)
+	}
+	return nil
+}
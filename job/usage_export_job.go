@@ -0,0 +1,44 @@
+package job
+
+import (
+	"context"
+	"saas-account/logger"
+	"saas-account/service"
+)
+
+// UsageExportJob 使用记录导出任务，执行等待中的异步导出并清理过期的导出文件
+type UsageExportJob struct {
+	exportService service.UsageExportService
+}
+
+// NewUsageExportJob 创建使用记录导出任务
+func NewUsageExportJob(exportService service.UsageExportService) *UsageExportJob {
+	return &UsageExportJob{
+		exportService: exportService,
+	}
+}
+
+// Name 任务名称
+func (j *UsageExportJob) Name() string {
+	return "usage_export"
+}
+
+// Run 执行导出和清理
+func (j *UsageExportJob) Run(ctx context.Context) error {
+	processed, err := j.exportService.ProcessPending(ctx)
+	if err != nil {
+		return err
+	}
+	if processed > 0 {
+		logger.GetLogger().Info("使用记录导出完成，共完成 %d 个导出任务", processed)
+	}
+
+	purged, err := j.exportService.PurgeExpired(ctx)
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		logger.GetLogger().Info("清理过期导出文件完成，共删除 %d 个导出任务", purged)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package job
+
+import (
+	"context"
+	"saas-account/logger"
+	"saas-account/service"
+	"time"
+)
+
+// UsageRetentionJob 使用记录清理任务，删除超过保留期限的原始使用记录和小时汇总
+type UsageRetentionJob struct {
+	usageService    service.ApplicationUsageService
+	rawRetention    time.Duration
+	hourlyRetention time.Duration
+}
+
+// NewUsageRetentionJob 创建使用记录清理任务
+func NewUsageRetentionJob(usageService service.ApplicationUsageService, rawRetention, hourlyRetention time.Duration) *UsageRetentionJob {
+	return &UsageRetentionJob{
+		usageService:    usageService,
+		rawRetention:    rawRetention,
+		hourlyRetention: hourlyRetention,
+	}
+}
+
+// Name 任务名称
+func (j *UsageRetentionJob) Name() string {
+	return "usage_retention"
+}
+
+// Run 执行清理
+func (j *UsageRetentionJob) Run(ctx context.Context) error {
+	purged, err := j.usageService.PurgeExpired(ctx, j.rawRetention, j.hourlyRetention)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		logger.GetLogger().Info("使用记录清理完成，共删除 %d 条记录", purged)
+	}
+	return nil
+}
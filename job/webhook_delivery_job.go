@@ -0,0 +1,37 @@
+package job
+
+import (
+	"context"
+	"saas-account/logger"
+	"saas-account/service"
+)
+
+// WebhookDeliveryJob Webhook 投递任务，发送新事件并重试失败的投递
+type WebhookDeliveryJob struct {
+	webhookService service.WebhookService
+}
+
+// NewWebhookDeliveryJob 创建 Webhook 投递任务
+func NewWebhookDeliveryJob(webhookService service.WebhookService) *WebhookDeliveryJob {
+	return &WebhookDeliveryJob{
+		webhookService: webhookService,
+	}
+}
+
+// Name 任务名称
+func (j *WebhookDeliveryJob) Name() string {
+	return "webhook_delivery"
+}
+
+// Run 执行投递
+func (j *WebhookDeliveryJob) Run(ctx context.Context) error {
+	delivered, err := j.webhookService.ProcessDue(ctx)
+	if err != nil {
+		return err
+	}
+
+	if delivered > 0 {
+		logger.GetLogger().Info("Webhook 投递完成，共成功投递 %d 个事件", delivered)
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"runtime"
 	"saas-account/config"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -31,7 +32,7 @@ const (
 
 // Logg 日志记录器
 type Logg struct {
-	level  LogLevel
+	level  atomic.Int32 // 日志级别，支持运行时修改
 	logger *log.Logger
 	output io.Writer
 }
@@ -41,24 +42,26 @@ var (
 	Logger *Logg
 )
 
-// InitLogger 初始化日志记录器
-func InitLogger(level string, output string) {
-	var logLevel LogLevel
+// parseLevel 解析日志级别，无法识别时为INFO
+func parseLevel(level string) LogLevel {
 	switch strings.ToLower(level) {
 	case "debug":
-		logLevel = DEBUG
+		return DEBUG
 	case "info":
-		logLevel = INFO
+		return INFO
 	case "warn":
-		logLevel = WARN
+		return WARN
 	case "error":
-		logLevel = ERROR
+		return ERROR
 	case "fatal":
-		logLevel = FATAL
+		return FATAL
 	default:
-		logLevel = INFO
+		return INFO
 	}
+}
 
+// InitLogger 初始化日志记录器
+func InitLogger(level string, output string) {
 	var logOutput io.Writer
 	switch strings.ToLower(output) {
 	case "file":
@@ -82,10 +85,10 @@ func InitLogger(level string, output string) {
 	}
 
 	Logger = &Logg{
-		level:  logLevel,
 		logger: log.New(logOutput, "", log.LstdFlags),
 		output: logOutput,
 	}
+	Logger.SetLevel(level)
 }
 
 // GetLogger 获取全局日志记录器
@@ -98,65 +101,75 @@ func GetLogger() *Logg {
 	return Logger
 }
 
+// SetLevel 修改日志级别，用于配置热更新
+func (l *Logg) SetLevel(level string) {
+	l.level.Store(int32(parseLevel(level)))
+}
+
+// enabled 判断指定级别的日志是否需要记录
+func (l *Logg) enabled(level LogLevel) bool {
+	return LogLevel(l.level.Load()) <= level
+}
+
 // Debug 记录调试级别日志
 func (l *Logg) Debug(format string, v ...interface{}) {
-	if l.level <= DEBUG {
+	if l.enabled(DEBUG) {
 		l.log("DEBUG", format, v...)
 	}
 }
 
 // DebugWithContext 记录带上下文的调试级别日志
 func (l *Logg) DebugWithContext(ctx context.Context, format string, v ...interface{}) {
-	if l.level <= DEBUG {
+	if l.enabled(DEBUG) {
 		l.logWithContext(ctx, "DEBUG", format, v...)
 	}
 }
 
 // Info 记录信息级别日志
 func (l *Logg) Info(format string, v ...interface{}) {
-	if l.level <= INFO {
+	if l.enabled(INFO) {
 		l.log("INFO", format, v...)
 	}
 }
 
 // InfoWithContext 记录带上下文的信息级别日志
 func (l *Logg) InfoWithContext(ctx context.Context, format string, v ...interface{}) {
-	if l.level <= INFO {
+	if l.enabled(INFO) {
 		l.logWithContext(ctx, "INFO", format, v...)
 	}
 }
 
 // Warn 记录警告级别日志
 func (l *Logg) Warn(format string, v ...interface{}) {
-	if l.level <= WARN {
+	if l.enabled(WARN) {
 		l.log("WARN", format, v...)
 	}
 }
 
 // WarnWithContext 记录带上下文的警告级别日志
 func (l *Logg) WarnWithContext(ctx context.Context, format string, v ...interface{}) {
-	if l.level <= WARN {
+	if l.enabled(WARN) {
 		l.logWithContext(ctx, "WARN", format, v...)
 	}
 }
 
 // Error 记录错误级别日志
 func (l *Logg) Error(format string, v ...interface{}) {
-	if l.level <= ERROR {
+	if l.enabled(ERROR) {
 		l.log("ERROR", format, v...)
 	}
 }
 
 // ErrorWithContext 记录带上下文的错误级别日志
 func (l *Logg) ErrorWithContext(ctx context.Context, format string, v ...interface{}) {
-	if l.level <= ERROR {
+	if l.enabled(ERROR) {
 		l.logWithContext(ctx, "ERROR", format, v...)
 	}
 }
 
 // Fatal 记录致命错误级别日志，并终止程序
 func (l *Logg) Fatal(format string, v ...interface{}) {
-	if l.level <= FATAL {
+	if l.enabled(FATAL) {
 		l.log("FATAL", format, v...)
 	}
 	os.Exit(1)
@@ -164,7 +177,7 @@ func (l *Logg) Fatal(format string, v ...interface{}) {
 
 // FatalWithContext 记录带上下文的致命错误级别日志，并终止程序
 func (l *Logg) FatalWithContext(ctx context.Context, format string, v ...interface{}) {
-	if l.level <= FATAL {
+	if l.enabled(FATAL) {
 		l.logWithContext(ctx, "FATAL", format, v...)
 	}
 	os.Exit(1)
@@ -233,6 +246,10 @@ func (l *Logg) logWithContext(ctx context.Context, level, format string, v ...in
 
 // Close 关闭日志记录器
 func (l *Logg) Close() {
+	// 写入文件时先将内容刷到磁盘
+	if file, ok := l.output.(*os.File); ok && file != os.Stdout {
+		file.Sync()
+	}
 	if closer, ok := l.output.(io.Closer); ok {
 		closer.Close()
 	}
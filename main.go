@@ -1,16 +1,42 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/cloudwego/hertz/pkg/app/server"
+	"os"
+	"os/signal"
 	"saas-account/config"
+	"saas-account/filestore"
+	"saas-account/ingest"
+	"saas-account/job"
 	"saas-account/logger"
 	"saas-account/middleware"
+	"saas-account/migration"
+	"saas-account/notify"
+	"saas-account/outbox"
+	"saas-account/payment"
+	"saas-account/repository"
 	"saas-account/router"
+	"saas-account/service"
+	"saas-account/shutdown"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func main() {
-	// 加载配置
+	// 加载配置，配置不合法时拒绝启动
+	args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败:\n%v\n", err)
+		os.Exit(1)
+	}
 	appConfig := config.GetConfig()
 
 	// 初始化日志
@@ -18,8 +44,190 @@ func main() {
 	logger := logger.GetLogger()
 	defer logger.Close()
 
-	// 初始化数据库
+	// 管理子命令，执行完成后退出
+	if len(args) > 0 {
+		code := runCommand(args[0], args[1:])
+		logger.Close()
+		os.Exit(code)
+	}
+
+	// 配置文件变更时热更新日志级别，CORS中间件每次请求时读取最新配置
+	config.OnChange(func(c *config.Config) {
+		logger.SetLevel(c.LogLevel)
+	})
+	stopWatch, err := config.Watch()
+	if err != nil {
+		logger.Warn("监听配置文件失败，配置不会热更新: %v", err)
+	} else {
+		shutdown.Register("config-watcher", func(ctx context.Context) error {
+			stopWatch()
+			return nil
+		})
+	}
+
+	// 初始化数据库，关闭时最后关闭连接池
 	config.InitDB()
+	shutdown.Register("database", func(ctx context.Context) error {
+		return config.CloseDB()
+	})
+
+	// 启动时执行未执行的数据库迁移，多个实例同时启动时由迁移锁保证只执行一次
+	// 迁移使用不设置语句超时的单独连接，执行完成后关闭
+	if appConfig.DBAutoMigrate {
+		migrationDB, err := config.OpenMigrationDB()
+		if err != nil {
+			logger.Fatal("连接迁移数据库失败: %v", err)
+		}
+		_, err = migration.NewMigrator(migrationDB).Up(context.Background())
+		if closeErr := config.CloseMigrationDB(migrationDB); closeErr != nil {
+			logger.Warn("关闭迁移数据库连接失败: %v", closeErr)
+		}
+		if err != nil {
+			logger.Fatal("数据库迁移失败: %v", err)
+		}
+	}
+
+	// 启动定时任务
+	scheduler := job.NewScheduler()
+	trashService := service.NewTrashService(
+		repository.NewUserRepository(),
+		repository.NewOrganizationRepository(),
+		repository.NewOrganizationApplicationRepository(),
+		repository.NewOrganizationMemberRepository(),
+		repository.NewTransactionManager(),
+		repository.NewOrganizationApplicationMemberRepository(),
+	)
+	scheduler.Every(
+		time.Duration(appConfig.TrashPurgeInterval)*time.Minute,
+		job.NewTrashPurgeJob(trashService, time.Duration(appConfig.TrashRetentionDays)*24*time.Hour),
+	)
+	webhookService := service.NewWebhookService(
+		repository.NewWebhookRepository(),
+		repository.NewOrganizationRepository(),
+		time.Duration(appConfig.WebhookTimeout)*time.Second,
+		appConfig.WebhookMaxAttempts,
+		time.Duration(appConfig.WebhookRetryBase)*time.Second,
+	)
+	scheduler.Every(
+		time.Duration(appConfig.WebhookJobInterval)*time.Second,
+		job.NewWebhookDeliveryJob(webhookService),
+	)
+	outbox.RegisterSink("webhook", webhookService)
+	scheduler.Every(
+		time.Duration(appConfig.OutboxRelayInterval)*time.Second,
+		job.NewOutboxRelayJob(service.NewOutboxRelay(
+			repository.NewOutboxRepository(),
+			strings.Split(appConfig.OutboxSinks, ","),
+			time.Duration(appConfig.OutboxRetryBase)*time.Second,
+			appConfig.OutboxMaxAttempts,
+		)),
+	)
+	eventPublisher := service.NewOutboxPublisher(repository.NewOutboxRepository())
+	txManager := repository.NewTransactionManager()
+	auditService := service.NewAuditService(repository.NewAuditLogRepository(), txManager)
+	subscriptionService := service.NewSubscriptionService(
+		repository.NewOrganizationApplicationRepository(),
+		repository.NewOrganizationApplicationMemberRepository(),
+		repository.NewOrganizationApplicationLimitRepository(),
+		repository.NewPlanRepository(),
+		repository.NewPlanChangeRepository(),
+		payment.GetProvider(),
+		notify.GetNotifier(),
+		time.Duration(appConfig.SubscriptionGraceDays)*24*time.Hour,
+		time.Duration(appConfig.SubscriptionNotifyDays)*24*time.Hour,
+		eventPublisher,
+		txManager,
+		auditService,
+		repository.NewSubscriptionPaymentRepository(),
+		repository.NewStorageGaugeRepository(),
+	)
+	scheduler.Every(
+		time.Duration(appConfig.SubscriptionCheckInterval)*time.Minute,
+		job.NewSubscriptionJob(subscriptionService),
+	)
+	alertService := service.NewAlertService(
+		repository.NewAlertRepository(),
+		repository.NewOrganizationApplicationRepository(),
+		repository.NewOrganizationRepository(),
+		repository.NewOrganizationApplicationLimitRepository(),
+		repository.NewUsageCounterRepository(),
+		repository.NewStorageGaugeRepository(),
+		notify.GetNotifier(),
+		eventPublisher,
+		txManager,
+	)
+	scheduler.Every(
+		time.Duration(appConfig.AlertCheckInterval)*time.Minute,
+		job.NewAlertJob(alertService),
+	)
+	storageService := service.NewStorageService(
+		repository.NewStorageGaugeRepository(),
+		repository.NewOrganizationApplicationRepository(),
+		repository.NewOrganizationRepository(),
+		repository.NewOrganizationApplicationLimitRepository(),
+		notify.GetNotifier(),
+		alertService,
+		repository.NewTransactionManager(),
+	)
+	usageService := service.NewApplicationUsageService(
+		repository.NewApplicationUsageRepository(),
+		repository.NewUsageRollupRepository(),
+		repository.NewOrganizationApplicationRepository(),
+		repository.NewOrganizationRepository(),
+		repository.NewOrganizationApplicationLimitRepository(),
+		repository.NewUsageCounterRepository(),
+		notify.GetNotifier(),
+		ingest.GetIngester(),
+		repository.NewUsageIdempotencyRepository(),
+		time.Duration(appConfig.UsageIdempotencyWindow)*time.Hour,
+		storageService,
+		alertService,
+		repository.NewTransactionManager(),
+	)
+	scheduler.Every(
+		time.Duration(appConfig.UsageCompactInterval)*time.Minute,
+		job.NewUsageRetentionJob(
+			usageService,
+			time.Duration(appConfig.UsageRawRetentionDays)*24*time.Hour,
+			time.Duration(appConfig.UsageHourlyRetentionDays)*24*time.Hour,
+		),
+	)
+	exportService := service.NewUsageExportService(
+		repository.NewApplicationUsageRepository(),
+		repository.NewUsageExportJobRepository(),
+		repository.NewOrganizationApplicationRepository(),
+		repository.NewOrganizationRepository(),
+		filestore.GetStore(),
+		time.Duration(appConfig.ExportRetentionHours)*time.Hour,
+	)
+	scheduler.Every(
+		time.Duration(appConfig.ExportJobInterval)*time.Second,
+		job.NewUsageExportJob(exportService),
+	)
+	invoiceService := service.NewInvoiceService(
+		repository.NewInvoiceRepository(),
+		repository.NewOrganizationRepository(),
+		repository.NewOrganizationApplicationRepository(),
+		repository.NewOrganizationApplicationMemberRepository(),
+		repository.NewOrganizationApplicationLimitRepository(),
+		repository.NewPlanRepository(),
+		repository.NewPlanChangeRepository(),
+		repository.NewUsageRollupRepository(),
+		storageService,
+		repository.NewTransactionManager(),
+	)
+	scheduler.Every(
+		time.Duration(appConfig.BillingInterval)*time.Minute,
+		job.NewBillingJob(invoiceService),
+	)
+
+	// 关闭时在请求处理完成、定时任务停止之后写入缓冲中的使用记录
+	if ingester := ingest.GetIngester(); ingester != nil {
+		shutdown.Register("usage-ingester", ingester.Close)
+	}
+
+	scheduler.Start()
+	shutdown.Register("scheduler", scheduler.Stop)
 
 	// 创建Hertz服务器
 	serverAddr := fmt.Sprintf("%s:%d", appConfig.ServerHost, appConfig.ServerPort)
@@ -31,7 +239,66 @@ func main() {
 	// 注册路由
 	router.RegisterRoutes(h)
 
-	// 启动服务器
+	// 关闭时最先停止接收新连接，并等待正在处理的请求完成
+	shutdown.Register("http", func(ctx context.Context) error {
+		drainCtx, cancel := context.WithTimeout(ctx, time.Duration(appConfig.ServerDrainTimeout)*time.Second)
+		defer cancel()
+		if err := h.Shutdown(drainCtx); err != nil {
+			return err
+		}
+		if err := drainCtx.Err(); err != nil {
+			return fmt.Errorf("等待正在处理的请求完成超时: %w", err)
+		}
+		return nil
+	})
+
+	// 启动服务器，收到 SIGINT 或 SIGTERM 后开始关闭
 	logger.Info("Server starting on %s", serverAddr)
-	h.Spin()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- h.Run()
+	}()
+
+	signalCtx, stopSignal := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	code := 0
+	select {
+	case <-signalCtx.Done():
+		logger.Info("收到退出信号，开始关闭服务")
+	case err := <-serveErr:
+		logger.Error("服务器异常退出: %v", err)
+		code = 1
+	}
+	// 关闭过程中再次收到信号时立即退出
+	stopSignal()
+
+	if !gracefulShutdown(time.Duration(appConfig.ShutdownTimeout) * time.Second) {
+		code = 1
+	}
+	logger.Close()
+	os.Exit(code)
+}
+
+// gracefulShutdown 按注册的相反顺序停止各子系统并记录每一步的结果，全部成功时返回true
+func gracefulShutdown(timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	appLogger := logger.GetLogger()
+	ok := true
+	for _, result := range shutdown.Run(ctx) {
+		duration := result.Duration.Round(time.Millisecond)
+		if result.Err != nil {
+			ok = false
+			appLogger.Error("停止 %s 失败，耗时 %s: %v", result.Name, duration, result.Err)
+			continue
+		}
+		appLogger.Info("已停止 %s，耗时 %s", result.Name, duration)
+	}
+
+	if ok {
+		appLogger.Info("服务已关闭")
+	} else {
+		appLogger.Warn("服务已关闭，部分子系统未能正常停止")
+	}
+	return ok
 }
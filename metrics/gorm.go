@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// startTimeKey SQL开始执行时间在 gorm.DB 实例中的键
+const startTimeKey = "metrics:start_time"
+
+// RegisterDB 采集连接池的SQL耗时和连接池状态，pool 为连接池名称，如 primary、replica-1
+func RegisterDB(pool string, db *gorm.DB) error {
+	if err := db.Use(&gormPlugin{pool: pool}); err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	// 连接池状态指标为 go_sql_*，以 db_name 标签区分连接池；重新连接时替换已注册的采集器
+	collector := collectors.NewDBStatsCollector(sqlDB, pool)
+	if err := Registry.Register(collector); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if !errors.As(err, &registered) {
+			return err
+		}
+		Registry.Unregister(registered.ExistingCollector)
+		return Registry.Register(collector)
+	}
+	return nil
+}
+
+// UnregisterDB 注销连接池状态采集器，关闭临时连接池前调用，避免继续采集已关闭的连接池
+func UnregisterDB(pool string, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	Registry.Unregister(collectors.NewDBStatsCollector(sqlDB, pool))
+	return nil
+}
+
+// gormPlugin 记录每条SQL执行耗时的GORM插件
+type gormPlugin struct {
+	pool string
+}
+
+// Name 插件名称
+func (p *gormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize 在各类操作前后注册回调
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("metrics:before_create", before),
+		cb.Create().After("*").Register("metrics:after_create", p.after("create")),
+		cb.Query().Before("*").Register("metrics:before_query", before),
+		cb.Query().After("*").Register("metrics:after_query", p.after("query")),
+		cb.Update().Before("*").Register("metrics:before_update", before),
+		cb.Update().After("*").Register("metrics:after_update", p.after("update")),
+		cb.Delete().Before("*").Register("metrics:before_delete", before),
+		cb.Delete().After("*").Register("metrics:after_delete", p.after("delete")),
+		cb.Row().Before("*").Register("metrics:before_row", before),
+		cb.Row().After("*").Register("metrics:after_row", p.after("row")),
+		cb.Raw().Before("*").Register("metrics:before_raw", before),
+		cb.Raw().After("*").Register("metrics:after_raw", p.after("raw")),
+	)
+}
+
+// before 记录开始执行时间
+func before(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+// after 记录执行耗时，未找到记录不视为错误
+func (p *gormPlugin) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		status := "ok"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = "error"
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		DBQueryDuration.WithLabelValues(p.pool, operation, table, status).Observe(time.Since(start).Seconds())
+	}
+}
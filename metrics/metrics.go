@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// namespace 指标名称前缀
+const namespace = "saas_account"
+
+// Registry 指标注册表，/metrics 只输出该注册表中的指标
+var Registry = prometheus.NewRegistry()
+
+// HTTP指标
+var (
+	// HTTPRequests 按路由模板、方法和状态码统计的请求数
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP请求数",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration 按路由模板、方法和状态码统计的请求耗时
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求耗时（秒）",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestsInFlight 正在处理的请求数
+	HTTPRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "正在处理的HTTP请求数",
+	})
+)
+
+// 数据库指标
+var (
+	// DBQueryDuration 按连接池、操作类型、表名和结果统计的SQL耗时
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "SQL执行耗时（秒）",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"pool", "operation", "table", "status"})
+)
+
+// ID生成指标
+var (
+	// SnowflakeIDsGenerated 生成的Snowflake ID数
+	SnowflakeIDsGenerated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snowflake_ids_generated_total",
+		Help:      "生成的Snowflake ID数",
+	})
+
+	// SnowflakeClockRollbacks 因时钟回退导致生成ID失败的次数
+	SnowflakeClockRollbacks = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snowflake_clock_rollback_errors_total",
+		Help:      "因时钟回退导致生成Snowflake ID失败的次数",
+	})
+)
+
+// 业务指标
+var (
+	// UsersCreated 创建的用户数
+	UsersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_created_total",
+		Help:      "创建的用户数",
+	})
+
+	// Logins 按结果（success, failure）统计的登录数，即签发访问令牌的次数
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "登录（签发访问令牌）次数",
+	}, []string{"result"})
+
+	// UsageEventsRecorded 按使用类型统计的已记录使用事件数
+	UsageEventsRecorded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "usage_events_recorded_total",
+		Help:      "已记录的使用事件数",
+	}, []string{"usage_type"})
+
+	// QuotaRejections 按使用类型统计的因超出配额被拒绝的请求数
+	QuotaRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_rejections_total",
+		Help:      "因超出配额被拒绝的请求数",
+	}, []string{"usage_type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+
+		HTTPRequests,
+		HTTPRequestDuration,
+		HTTPRequestsInFlight,
+
+		DBQueryDuration,
+
+		SnowflakeIDsGenerated,
+		SnowflakeClockRollbacks,
+
+		UsersCreated,
+		Logins,
+		UsageEventsRecorded,
+		QuotaRejections,
+	)
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"saas-account/audit"
+	"saas-account/utils"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// AppKeyHeader 应用调用时携带AppKey的请求头
+const AppKeyHeader = "X-App-Key"
+
+// AuditContext 中间件，将操作者、请求ID、客户端IP和UA写入请求的 context.Context，供审计日志使用
+// 需要放在 RequestID 之后
+func AuditContext() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		metadata := audit.Metadata{
+			ActorType: audit.ActorAnonymous,
+			RequestId: GetRequestID(ctx),
+			TraceId:   GetTraceID(ctx),
+			IP:        ctx.ClientIP(),
+			UserAgent: string(ctx.Request.Header.UserAgent()),
+		}
+
+		// 携带有效JWT令牌时操作者为用户，否则携带AppKey时为应用
+		authHeader := string(ctx.Request.Header.Peek("Authorization"))
+		if token, ok := strings.CutPrefix(authHeader, "Bearer "); ok {
+			if claims, err := utils.ParseToken(token); err == nil {
+				metadata.ActorType = audit.ActorUser
+				metadata.ActorId = fmt.Sprintf("%d", claims.UserID)
+			}
+		}
+		if metadata.ActorType == audit.ActorAnonymous {
+			if appKey := string(ctx.Request.Header.Peek(AppKeyHeader)); appKey != "" {
+				metadata.ActorType = audit.ActorApp
+				metadata.ActorId = appKey
+			}
+		}
+
+		c = WithRequestContext(c, ctx)
+		ctx.Next(audit.WithMetadata(c, metadata))
+	}
+}
@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
+	"saas-account/config"
 	"saas-account/utils"
 	"strings"
 
@@ -12,49 +14,10 @@ import (
 // Auth 中间件，验证JWT令牌
 func Auth() app.HandlerFunc {
 	return func(c context.Context, ctx *app.RequestContext) {
-		// 获取Authorization头
-		authHeader := string(ctx.Request.Header.Peek("Authorization"))
-		if authHeader == "" {
-			ctx.JSON(http.StatusUnauthorized, map[string]interface{}{
-				"code":       401,
-				"message":    "Authorization header is required",
-				"request_id": GetRequestID(ctx),
-			})
-			ctx.Abort()
-			return
-		}
-
-		// 检查Authorization头格式
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			ctx.JSON(http.StatusUnauthorized, map[string]interface{}{
-				"code":       401,
-				"message":    "Authorization header format must be Bearer {token}",
-				"request_id": GetRequestID(ctx),
-			})
-			ctx.Abort()
-			return
-		}
-
-		// 解析JWT令牌
-		tokenString := parts[1]
-		claims, err := utils.ParseToken(tokenString)
-		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, map[string]interface{}{
-				"code":       401,
-				"message":    "Invalid or expired token",
-				"request_id": GetRequestID(ctx),
-			})
-			ctx.Abort()
+		if !authenticate(ctx) {
 			return
 		}
 
-		// 将用户信息存储在上下文中
-		ctx.Set("user_id", claims.UserID)
-		ctx.Set("username", claims.Username)
-		ctx.Set("email", claims.Email)
-		ctx.Set("role", claims.Role)
-
 		// 继续处理请求
 		ctx.Next(c)
 	}
@@ -63,9 +26,8 @@ func Auth() app.HandlerFunc {
 // AdminAuth 中间件，验证用户是否为管理员
 func AdminAuth() app.HandlerFunc {
 	return func(c context.Context, ctx *app.RequestContext) {
-		// 先执行Auth中间件
-		Auth()(c, ctx)
-		if ctx.IsAborted() {
+		// 先验证JWT令牌
+		if !authenticate(ctx) {
 			return
 		}
 
@@ -85,3 +47,72 @@ func AdminAuth() app.HandlerFunc {
 		ctx.Next(c)
 	}
 }
+
+// MetricsAuth 中间件，配置了 metrics_token 时要求抓取请求携带 Authorization: Bearer {metrics_token}
+// 每次请求读取最新配置，令牌支持热更新
+func MetricsAuth() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		token := config.GetConfig().MetricsToken
+		authHeader := string(ctx.Request.Header.Peek("Authorization"))
+		if token != "" && subtle.ConstantTimeCompare([]byte(authHeader), []byte("Bearer "+token)) != 1 {
+			ctx.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"code":       401,
+				"message":    "Invalid metrics token",
+				"request_id": GetRequestID(ctx),
+			})
+			ctx.Abort()
+			return
+		}
+
+		// 继续处理请求
+		ctx.Next(c)
+	}
+}
+
+// authenticate 验证JWT令牌并将用户信息存储在上下文中，失败时中止请求并返回false
+func authenticate(ctx *app.RequestContext) bool {
+	// 获取Authorization头
+	authHeader := string(ctx.Request.Header.Peek("Authorization"))
+	if authHeader == "" {
+		ctx.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"code":       401,
+			"message":    "Authorization header is required",
+			"request_id": GetRequestID(ctx),
+		})
+		ctx.Abort()
+		return false
+	}
+
+	// 检查Authorization头格式
+	parts := strings.SplitN(authHeader, " ", 2)
+	if !(len(parts) == 2 && parts[0] == "Bearer") {
+		ctx.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"code":       401,
+			"message":    "Authorization header format must be Bearer {token}",
+			"request_id": GetRequestID(ctx),
+		})
+		ctx.Abort()
+		return false
+	}
+
+	// 解析JWT令牌
+	tokenString := parts[1]
+	claims, err := utils.ParseToken(tokenString)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, map[string]interface{}{
+			"code":       401,
+			"message":    "Invalid or expired token",
+			"request_id": GetRequestID(ctx),
+		})
+		ctx.Abort()
+		return false
+	}
+
+	// 将用户信息存储在上下文中
+	ctx.Set("user_id", claims.UserID)
+	ctx.Set("username", claims.Username)
+	ctx.Set("email", claims.Email)
+	ctx.Set("role", claims.Role)
+
+	return true
+}
@@ -1,12 +1,23 @@
 package middleware
 
 import (
-	"github.com/cloudwego/hertz/pkg/app"
+	"saas-account/config"
 
+	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/hertz-contrib/cors"
 )
 
-// CORS 中间件，处理跨域请求
+// CORS 中间件，处理跨域请求，允许的来源每次请求时从配置读取，支持热更新
 func CORS() app.HandlerFunc {
-	return cors.New(cors.DefaultConfig())
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AddAllowHeaders(ReadYourWritesHeader)
+	corsConfig.AllowOriginFunc = func(origin string) bool {
+		for _, allowed := range config.GetConfig().CORSAllowOrigins {
+			if allowed == "*" || allowed == origin {
+				return true
+			}
+		}
+		return false
+	}
+	return cors.New(corsConfig)
 }
@@ -8,9 +8,24 @@ import (
 	"github.com/cloudwego/hertz/pkg/app"
 )
 
+// quietPaths 不记录请求日志的路径，只在启动时写入
+var quietPaths = make(map[string]bool)
+
+// SkipRequestLog 不记录指定路径的请求日志，用于频繁的健康检查探针，需在服务启动前调用
+func SkipRequestLog(paths ...string) {
+	for _, path := range paths {
+		quietPaths[path] = true
+	}
+}
+
 // Logger 中间件，记录请求和响应信息
 func Logger() app.HandlerFunc {
 	return func(c context.Context, ctx *app.RequestContext) {
+		if quietPaths[string(ctx.Request.URI().Path())] {
+			ctx.Next(c)
+			return
+		}
+
 		// 获取请求开始时间
 		start := time.Now()
 
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"saas-account/metrics"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// unmatchedRoute 未匹配到路由的请求使用的路由标签，避免任意路径产生大量指标
+const unmatchedRoute = "unmatched"
+
+// Metrics 中间件，按路由模板、方法和状态码统计请求数和耗时
+func Metrics() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		start := time.Now()
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+
+		// 继续处理请求
+		ctx.Next(c)
+
+		// 使用路由模板（如 /api/v1/users/:id）而不是实际路径
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := string(ctx.Request.Method())
+		status := strconv.Itoa(ctx.Response.StatusCode())
+
+		metrics.HTTPRequests.WithLabelValues(method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
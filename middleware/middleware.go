@@ -10,7 +10,10 @@ func RegisterMiddlewares(h *server.Hertz) {
 	h.Use(
 		Recovery(),   // 恢复中间件，必须放在最前面
 		RequestID(),  // 请求ID中间件
+		AuditContext(), // 审计上下文中间件
+		ReadConsistency(), // 读一致性中间件
 		Logger(),     // 日志中间件
+		Metrics(),    // 指标中间件
 		ErrorLogger(), // 错误日志中间件
 		CORS(),       // CORS中间件
 	)
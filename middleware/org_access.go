@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"saas-account/logger"
+	"saas-account/repository"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+)
+
+// 组织成员角色
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// OrgLookupFunc 根据路径参数中的资源ID查询资源所属的组织ID，资源不存在时返回 gorm.ErrRecordNotFound
+type OrgLookupFunc func(ctx context.Context, id int64) (int64, error)
+
+// OrgAccess 中间件，验证JWT令牌，并要求当前用户是请求所属组织的有效成员
+// param 为路径参数名，lookup 为nil时该参数即组织ID，否则通过 lookup 查询资源所属的组织
+// 指定 roles 时成员角色必须是其中之一；平台管理员可以访问所有组织
+func OrgAccess(members repository.OrganizationMemberRepository, param string, lookup OrgLookupFunc, roles ...string) app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		if !authenticate(ctx) {
+			return
+		}
+
+		// 平台管理员不受组织成员身份限制
+		if role, _ := ctx.Get("role"); role == "admin" {
+			ctx.Next(c)
+			return
+		}
+
+		id, err := strconv.ParseInt(ctx.Param(param), 10, 64)
+		if err != nil {
+			// 由处理器返回参数错误
+			ctx.Next(c)
+			return
+		}
+
+		orgID := id
+		if lookup != nil {
+			orgID, err = lookup(c, id)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				abortWithStatus(ctx, http.StatusNotFound, "Resource not found")
+				return
+			}
+			if err != nil {
+				logger.GetLogger().ErrorWithContext(c, "查询资源所属组织失败: %s=%d, 错误: %v", param, id, err)
+				abortWithStatus(ctx, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+		}
+
+		member, err := members.GetByOrganizationAndUser(c, orgID, ctx.GetInt64("user_id"))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.GetLogger().ErrorWithContext(c, "查询组织成员失败: 组织=%d, 错误: %v", orgID, err)
+			abortWithStatus(ctx, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if err != nil || member.Status != "active" || !hasRole(member.Role, roles) {
+			abortWithStatus(ctx, http.StatusForbidden, "Organization access denied")
+			return
+		}
+
+		ctx.Next(c)
+	}
+}
+
+// hasRole 角色是否在允许的角色中，未指定允许的角色时任何角色都可以
+func hasRole(role string, roles []string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// abortWithStatus 返回错误响应并中止请求
+func abortWithStatus(ctx *app.RequestContext, status int, message string) {
+	ctx.JSON(status, map[string]interface{}{
+		"code":       status,
+		"message":    message,
+		"request_id": GetRequestID(ctx),
+	})
+	ctx.Abort()
+}
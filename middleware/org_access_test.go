@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"saas-account/model"
+	"saas-account/repository"
+	"saas-account/utils"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+	"gorm.io/gorm"
+)
+
+// fakeMemberRepository 内存中的组织成员仓库，键为 组织ID, 用户ID
+type fakeMemberRepository struct {
+	repository.OrganizationMemberRepository
+	members map[[2]int64]*model.OrganizationMember
+	err     error
+}
+
+func (r *fakeMemberRepository) GetByOrganizationAndUser(ctx context.Context, orgID, userID int64) (*model.OrganizationMember, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	member, ok := r.members[[2]int64{orgID, userID}]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return member, nil
+}
+
+// bearer 生成指定用户的访问令牌请求头
+func bearer(t *testing.T, userID int64, role string) ut.Header {
+	t.Helper()
+	token, err := utils.GenerateToken(userID, "user", "user@example.com", role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ut.Header{Key: "Authorization", Value: "Bearer " + token}
+}
+
+func TestOrgAccess(t *testing.T) {
+	members := &fakeMemberRepository{members: map[[2]int64]*model.OrganizationMember{
+		{1, 10}: {OrganizationId: 1, UserId: 10, Role: OrgRoleOwner, Status: "active"},
+		{1, 11}: {OrganizationId: 1, UserId: 11, Role: OrgRoleMember, Status: "active"},
+		{1, 12}: {OrganizationId: 1, UserId: 12, Role: OrgRoleAdmin, Status: "inactive"},
+		{2, 11}: {OrganizationId: 2, UserId: 11, Role: OrgRoleAdmin, Status: "active"},
+	}}
+	// 资源 100 属于组织 1，资源 200 属于组织 2
+	lookup := func(ctx context.Context, id int64) (int64, error) {
+		switch id {
+		case 100:
+			return 1, nil
+		case 200:
+			return 2, nil
+		case 500:
+			return 0, errors.New("db down")
+		}
+		return 0, gorm.ErrRecordNotFound
+	}
+
+	engine := route.NewEngine(config.NewOptions(nil))
+	ok := func(c context.Context, ctx *app.RequestContext) { ctx.Status(http.StatusOK) }
+	engine.GET("/orgs/:id", OrgAccess(members, "id", nil), ok)
+	engine.GET("/orgs/:id/admin", OrgAccess(members, "id", nil, OrgRoleOwner, OrgRoleAdmin), ok)
+	engine.GET("/resources/:rid", OrgAccess(members, "rid", lookup), ok)
+
+	tests := []struct {
+		name   string
+		path   string
+		header *ut.Header
+		want   int
+	}{
+		{name: "未登录", path: "/orgs/1", want: http.StatusUnauthorized},
+		{name: "组织成员", path: "/orgs/1", header: ptr(bearer(t, 11, "user")), want: http.StatusOK},
+		{name: "非组织成员", path: "/orgs/2", header: ptr(bearer(t, 10, "user")), want: http.StatusForbidden},
+		{name: "成员已停用", path: "/orgs/1", header: ptr(bearer(t, 12, "user")), want: http.StatusForbidden},
+		{name: "平台管理员", path: "/orgs/2", header: ptr(bearer(t, 99, "admin")), want: http.StatusOK},
+		{name: "拥有者访问管理接口", path: "/orgs/1/admin", header: ptr(bearer(t, 10, "user")), want: http.StatusOK},
+		{name: "普通成员访问管理接口", path: "/orgs/1/admin", header: ptr(bearer(t, 11, "user")), want: http.StatusForbidden},
+		{name: "其他组织的管理员", path: "/orgs/2/admin", header: ptr(bearer(t, 11, "user")), want: http.StatusOK},
+		{name: "资源所属组织的成员", path: "/resources/100", header: ptr(bearer(t, 10, "user")), want: http.StatusOK},
+		{name: "其他组织的资源", path: "/resources/200", header: ptr(bearer(t, 10, "user")), want: http.StatusForbidden},
+		{name: "资源不存在", path: "/resources/300", header: ptr(bearer(t, 10, "user")), want: http.StatusNotFound},
+		{name: "查询资源失败", path: "/resources/500", header: ptr(bearer(t, 10, "user")), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []ut.Header
+			if tt.header != nil {
+				headers = append(headers, *tt.header)
+			}
+			resp := ut.PerformRequest(engine, http.MethodGet, tt.path, nil, headers...).Result()
+			if resp.StatusCode() != tt.want {
+				t.Errorf("状态码 = %d, 期望 %d: %s", resp.StatusCode(), tt.want, resp.Body())
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
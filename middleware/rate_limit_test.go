@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"saas-account/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+func TestRateLimit(t *testing.T) {
+	store := ratelimit.Store(ratelimit.NewMemoryStore(time.Minute))
+	policy := func(ctx context.Context, appID int64) (*ratelimit.Policy, error) {
+		if appID == 2 {
+			return nil, nil
+		}
+		return &ratelimit.Policy{Burst: 1, PerMinute: 1}, nil
+	}
+
+	engine := route.NewEngine(config.NewOptions(nil))
+	ok := func(c context.Context, ctx *app.RequestContext) { ctx.Status(http.StatusOK) }
+	engine.GET("/apps/:app_id", Auth(), RateLimit(func() ratelimit.Store { return store }, policy, "user", RateLimitClientIP(nil), true), ok)
+
+	get := func(path string, userID int64) int {
+		return ut.PerformRequest(engine, http.MethodGet, path, nil, bearer(t, userID, "user")).Result().StatusCode()
+	}
+
+	if code := get("/apps/1", 10); code != http.StatusOK {
+		t.Fatalf("第一次请求状态码 = %d", code)
+	}
+	if code := get("/apps/1", 10); code != http.StatusTooManyRequests {
+		t.Errorf("令牌用完后状态码 = %d, 期望 429", code)
+	}
+
+	// 按用户限流，同一IP的其他用户有独立的令牌桶
+	if code := get("/apps/1", 11); code != http.StatusOK {
+		t.Errorf("其他用户状态码 = %d, 期望 200", code)
+	}
+
+	// 没有限流策略的应用不限流
+	for i := 0; i < 3; i++ {
+		if code := get("/apps/2", 10); code != http.StatusOK {
+			t.Errorf("不限流的应用状态码 = %d, 期望 200", code)
+		}
+	}
+
+	// 更换存储后，已注册的中间件使用新的存储
+	store = ratelimit.NewMemoryStore(time.Minute)
+	if code := get("/apps/1", 10); code != http.StatusOK {
+		t.Errorf("更换存储后状态码 = %d, 期望 200", code)
+	}
+}
+
+// failingStore 总是返回错误的令牌桶存储
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, policy ratelimit.Policy, cost int64, now time.Time) (*ratelimit.Result, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func (failingStore) Ping(ctx context.Context) error {
+	return errors.New("store unavailable")
+}
+
+func TestRateLimitClientIP(t *testing.T) {
+	policy := func(ctx context.Context, appID int64) (*ratelimit.Policy, error) {
+		return &ratelimit.Policy{Burst: 1, PerMinute: 1}, nil
+	}
+	ok := func(c context.Context, ctx *app.RequestContext) { ctx.Status(http.StatusOK) }
+	_, all, _ := net.ParseCIDR("0.0.0.0/0")
+
+	tests := []struct {
+		name           string
+		trustedProxies []*net.IPNet
+		wantSecond     int
+	}{
+		// 请求不是来自可信代理时忽略 X-Forwarded-For，伪造的请求头不能换到新的令牌桶
+		{name: "不信任代理时按连接地址限流", trustedProxies: nil, wantSecond: http.StatusTooManyRequests},
+		{name: "来自可信代理时按转发的客户端IP限流", trustedProxies: []*net.IPNet{all}, wantSecond: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := ratelimit.NewMemoryStore(time.Minute)
+			engine := route.NewEngine(config.NewOptions(nil))
+			engine.GET("/apps/:app_id", RateLimit(func() ratelimit.Store { return store }, policy, "ip", RateLimitClientIP(tt.trustedProxies), true), ok)
+
+			get := func(forwardedFor string) int {
+				header := ut.Header{Key: "X-Forwarded-For", Value: forwardedFor}
+				return ut.PerformRequest(engine, http.MethodGet, "/apps/1", nil, header).Result().StatusCode()
+			}
+			if code := get("203.0.113.1"); code != http.StatusOK {
+				t.Fatalf("第一次请求状态码 = %d", code)
+			}
+			if code := get("203.0.113.2"); code != tt.wantSecond {
+				t.Errorf("更换 X-Forwarded-For 后状态码 = %d, 期望 %d", code, tt.wantSecond)
+			}
+		})
+	}
+}
+
+func TestRateLimitStoreFailure(t *testing.T) {
+	policy := func(ctx context.Context, appID int64) (*ratelimit.Policy, error) {
+		return &ratelimit.Policy{Burst: 1, PerMinute: 1}, nil
+	}
+	ok := func(c context.Context, ctx *app.RequestContext) { ctx.Status(http.StatusOK) }
+
+	tests := []struct {
+		name     string
+		failOpen bool
+		want     int
+	}{
+		{name: "配置放行时访问存储失败放行请求", failOpen: true, want: http.StatusOK},
+		{name: "配置拒绝时访问存储失败返回503", failOpen: false, want: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := route.NewEngine(config.NewOptions(nil))
+			engine.GET("/apps/:app_id", RateLimit(func() ratelimit.Store { return failingStore{} }, policy, "app", RateLimitClientIP(nil), tt.failOpen), ok)
+
+			if code := ut.PerformRequest(engine, http.MethodGet, "/apps/1", nil).Result().StatusCode(); code != tt.want {
+				t.Errorf("状态码 = %d, 期望 %d", code, tt.want)
+			}
+		})
+	}
+}
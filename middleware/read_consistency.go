@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"saas-account/repository"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// ReadYourWritesHeader 要求本次请求的只读查询使用主库的请求头，用于刚写入后立即读取的场景
+const ReadYourWritesHeader = "X-Read-Your-Writes"
+
+// ReadConsistency 中间件，决定请求中的只读查询是否可以使用只读副本
+// 写请求（非 GET、HEAD、OPTIONS）和携带 X-Read-Your-Writes: true 的请求只读主库
+func ReadConsistency() app.HandlerFunc {
+	return func(c context.Context, ctx *app.RequestContext) {
+		primary := false
+		switch string(ctx.Method()) {
+		case consts.MethodGet, consts.MethodHead, consts.MethodOptions:
+			primary, _ = strconv.ParseBool(string(ctx.Request.Header.Peek(ReadYourWritesHeader)))
+		default:
+			primary = true
+		}
+
+		if primary {
+			c = repository.WithPrimary(c)
+		}
+		ctx.Next(c)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"saas-account/config"
+	"saas-account/migration"
+	"strconv"
+	"time"
+)
+
+// migrateUsage migrate 子命令用法
+const migrateUsage = `用法: saas-account migrate <命令>
+
+命令:
+  up            执行全部未执行的迁移
+  down [n]      回滚最近执行的 n 个迁移，默认为1
+  to <version>  迁移到指定版本，0表示回滚全部迁移
+  status        查看迁移执行状态
+`
+
+// migrationRunner migrate 子命令使用的迁移器
+type migrationRunner interface {
+	Up(ctx context.Context) (int, error)
+	Down(ctx context.Context, steps int) (int, error)
+	To(ctx context.Context, version int64) (int, error)
+	Status(ctx context.Context) ([]migration.Status, error)
+}
+
+// newMigrator 使用不设置语句超时的连接创建迁移器，测试时替换为内存实现
+var newMigrator = func() migrationRunner {
+	db, err := config.OpenMigrationDB()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	return migration.NewMigrator(db)
+}
+
+// runMigrate 执行 migrate 子命令，返回进程退出码
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, migrateUsage)
+		return 2
+	}
+
+	ctx := context.Background()
+	migrator := newMigrator()
+
+	var count int
+	var err error
+	switch args[0] {
+	case "up":
+		count, err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil {
+				fmt.Fprintf(stderr, "无效的回滚数量: %s\n", args[1])
+				return 2
+			}
+		}
+		count, err = migrator.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			fmt.Fprint(stderr, migrateUsage)
+			return 2
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil {
+			fmt.Fprintf(stderr, "无效的迁移版本号: %s\n", args[1])
+			return 2
+		}
+		count, err = migrator.To(ctx, version)
+	case "status":
+		return printMigrateStatus(ctx, migrator)
+	default:
+		fmt.Fprint(stderr, migrateUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "迁移失败: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "完成，共变更 %d 个迁移\n", count)
+	return 0
+}
+
+// printMigrateStatus 输出迁移执行状态
+func printMigrateStatus(ctx context.Context, migrator migrationRunner) int {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "获取迁移状态失败: %v\n", err)
+		return 1
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		appliedAt := ""
+		if status.Applied {
+			state = "applied"
+			appliedAt = time.Unix(status.AppliedAt, 0).Format(time.RFC3339)
+		}
+		switch {
+		case status.Modified:
+			state += " (modified)"
+		case status.Missing:
+			state += " (missing)"
+		}
+		fmt.Fprintf(stdout, "%04d  %-40s  %-20s  %s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return 0
+}
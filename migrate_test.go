@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"saas-account/migration"
+	"strings"
+	"testing"
+)
+
+// fakeMigrator 记录调用参数的迁移器
+type fakeMigrator struct {
+	calls    []string
+	steps    int
+	version  int64
+	statuses []migration.Status
+	err      error
+}
+
+func (m *fakeMigrator) Up(ctx context.Context) (int, error) {
+	m.calls = append(m.calls, "up")
+	return 3, m.err
+}
+
+func (m *fakeMigrator) Down(ctx context.Context, steps int) (int, error) {
+	m.calls = append(m.calls, "down")
+	m.steps = steps
+	return steps, m.err
+}
+
+func (m *fakeMigrator) To(ctx context.Context, version int64) (int, error) {
+	m.calls = append(m.calls, "to")
+	m.version = version
+	return 1, m.err
+}
+
+func (m *fakeMigrator) Status(ctx context.Context) ([]migration.Status, error) {
+	m.calls = append(m.calls, "status")
+	return m.statuses, m.err
+}
+
+// useFakeMigrator 替换 migrate 子命令使用的迁移器
+func useFakeMigrator(t *testing.T, m *fakeMigrator) {
+	orig := newMigrator
+	newMigrator = func() migrationRunner { return m }
+	t.Cleanup(func() { newMigrator = orig })
+}
+
+func TestMigrateCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		err      error
+		wantCode int
+		wantCall string
+		wantOut  string
+	}{
+		{name: "无参数", args: nil, wantCode: 2},
+		{name: "up", args: []string{"up"}, wantCode: 0, wantCall: "up", wantOut: "共变更 3 个迁移"},
+		{name: "down默认1个", args: []string{"down"}, wantCode: 0, wantCall: "down", wantOut: "共变更 1 个迁移"},
+		{name: "down指定数量", args: []string{"down", "2"}, wantCode: 0, wantCall: "down", wantOut: "共变更 2 个迁移"},
+		{name: "down无效数量", args: []string{"down", "x"}, wantCode: 2},
+		{name: "to", args: []string{"to", "5"}, wantCode: 0, wantCall: "to"},
+		{name: "to缺少版本", args: []string{"to"}, wantCode: 2},
+		{name: "to无效版本", args: []string{"to", "v5"}, wantCode: 2},
+		{name: "迁移失败", args: []string{"up"}, err: errors.New("boom"), wantCode: 1, wantCall: "up"},
+		{name: "未知命令", args: []string{"redo"}, wantCode: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &fakeMigrator{err: tt.err}
+			useFakeMigrator(t, m)
+
+			code, out, _ := runTestCommand(t, append([]string{"migrate"}, tt.args...)...)
+			if code != tt.wantCode {
+				t.Fatalf("退出码 = %d, 期望 %d", code, tt.wantCode)
+			}
+			if tt.wantCall != "" && (len(m.calls) != 1 || m.calls[0] != tt.wantCall) {
+				t.Errorf("调用 = %v, 期望 [%s]", m.calls, tt.wantCall)
+			}
+			if tt.wantCall == "" && len(m.calls) != 0 {
+				t.Errorf("不应执行迁移，实际调用 %v", m.calls)
+			}
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("输出 = %q, 期望包含 %q", out, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestMigrateCommandArguments(t *testing.T) {
+	m := &fakeMigrator{}
+	useFakeMigrator(t, m)
+
+	if code, _, _ := runTestCommand(t, "migrate", "down", "4"); code != 0 || m.steps != 4 {
+		t.Errorf("down 4: 退出码 = %d, 回滚数量 = %d", code, m.steps)
+	}
+	if code, _, _ := runTestCommand(t, "migrate", "to", "0"); code != 0 || m.version != 0 {
+		t.Errorf("to 0: 退出码 = %d, 版本 = %d", code, m.version)
+	}
+}
+
+func TestMigrateStatus(t *testing.T) {
+	m := &fakeMigrator{statuses: []migration.Status{
+		{Version: 1, Name: "init", Applied: true, AppliedAt: 1700000000},
+		{Version: 2, Name: "usage_rollups", Applied: true, AppliedAt: 1700000000, Modified: true},
+		{Version: 3, Name: "outbox"},
+	}}
+	useFakeMigrator(t, m)
+
+	code, out, _ := runTestCommand(t, "migrate", "status")
+	if code != 0 {
+		t.Fatalf("退出码 = %d, 期望 0", code)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("输出 %d 行, 期望 3 行:\n%s", len(lines), out)
+	}
+	for i, want := range []string{"applied", "applied (modified)", "pending"} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("第 %d 行 = %q, 期望包含 %q", i+1, lines[i], want)
+		}
+	}
+}
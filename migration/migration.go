@@ -0,0 +1,117 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Migration 一个版本的数据库迁移，由 sql 目录下的 <版本>_<名称>.up.sql 和 <版本>_<名称>.down.sql 组成
+type Migration struct {
+	Version  int64  // 版本号，按从小到大的顺序执行
+	Name     string // 迁移名称
+	Up       string // 升级SQL
+	Down     string // 回滚SQL
+	Checksum string // 升级SQL的SHA-256，用于发现已执行的迁移文件被修改
+}
+
+// Load 加载嵌入的全部迁移，按版本号升序排列
+func Load() ([]*Migration, error) {
+	return load(files, "sql")
+}
+
+// load 从指定目录加载迁移文件
+func load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		version, name, direction, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("迁移版本 %d 存在不同的名称: %s, %s", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("迁移 %d_%s 缺少升级文件", m.Version, m.Name)
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("迁移 %d_%s 缺少回滚文件", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// parseFileName 解析迁移文件名，格式为 <版本>_<名称>.<up|down>.sql
+func parseFileName(fileName string) (int64, string, string, error) {
+	base := strings.TrimSuffix(fileName, ".sql")
+	if base == fileName {
+		return 0, "", "", fmt.Errorf("无效的迁移文件名: %s", fileName)
+	}
+
+	var direction string
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("迁移文件名缺少 .up 或 .down 后缀: %s", fileName)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	versionPart, name, ok := strings.Cut(base, "_")
+	if !ok || name == "" {
+		return 0, "", "", fmt.Errorf("迁移文件名缺少名称: %s", fileName)
+	}
+
+	version, err := strconv.ParseInt(versionPart, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("无效的迁移版本号: %s", fileName)
+	}
+	return version, name, direction, nil
+}
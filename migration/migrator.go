@@ -0,0 +1,306 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"saas-account/logger"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockName 迁移使用的 advisory lock 名称，多个实例同时启动时只有一个实例执行迁移
+const lockName = "saas-account:schema_migrations"
+
+// Check 发现的迁移问题，错误内容只包含迁移版本和名称，可以对外返回
+var (
+	ErrMigrationPending  = errors.New("存在未执行的迁移")
+	ErrMigrationModified = errors.New("迁移执行后已被修改")
+)
+
+// schemaMigration 已执行的迁移记录
+type schemaMigration struct {
+	Version   int64  `gorm:"primarykey;autoIncrement:false"`
+	Name      string `gorm:"size:255;not null"`
+	Checksum  string `gorm:"size:64;not null"`
+	AppliedAt int64  `gorm:"not null"`
+}
+
+// TableName 迁移记录表名
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`    // 是否已执行
+	AppliedAt int64  `json:"applied_at"` // 执行时间
+	Modified  bool   `json:"modified"`   // 执行后迁移文件被修改
+	Missing   bool   `json:"missing"`    // 数据库中已执行，但当前程序中不存在该迁移
+}
+
+// Migrator 数据库迁移执行器
+type Migrator struct {
+	db *gorm.DB
+}
+
+// NewMigrator 创建数据库迁移执行器
+func NewMigrator(db *gorm.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+// Up 执行全部未执行的迁移，返回执行的迁移数
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.migrate(ctx, func(applied []int64) int64 {
+		return math.MaxInt64
+	})
+}
+
+// Down 回滚最近执行的 steps 个迁移，返回回滚的迁移数
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("回滚数量必须大于0")
+	}
+
+	return m.migrate(ctx, func(applied []int64) int64 {
+		if steps >= len(applied) {
+			return 0
+		}
+		return applied[len(applied)-steps-1]
+	})
+}
+
+// To 迁移到指定版本：执行不超过该版本的未执行迁移，回滚高于该版本的已执行迁移，返回变更的迁移数
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+	if version < 0 {
+		return 0, fmt.Errorf("无效的迁移版本号: %d", version)
+	}
+
+	return m.migrate(ctx, func(applied []int64) int64 {
+		return version
+	})
+}
+
+// Status 获取全部迁移的执行状态，按版本号升序排列
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	err = m.withLock(ctx, func(conn *gorm.DB) error {
+		records, err := loadApplied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if record, ok := records[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = record.AppliedAt
+				status.Modified = record.Checksum != migration.Checksum
+				delete(records, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+
+		for _, record := range records {
+			statuses = append(statuses, Status{
+				Version:   record.Version,
+				Name:      record.Name,
+				Applied:   true,
+				AppliedAt: record.AppliedAt,
+				Missing:   true,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Check 检查当前程序中的迁移是否已全部执行且执行后未被修改，返回数据库中已执行的最高版本
+// 不获取迁移锁，可用于就绪检查；数据库中存在当前程序没有的更高版本时（滚动发布中新版本已迁移）不视为错误
+func (m *Migrator) Check(ctx context.Context) (int64, error) {
+	migrations, err := Load()
+	if err != nil {
+		return 0, err
+	}
+
+	records, err := loadApplied(m.db.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	var current int64
+	for version := range records {
+		current = max(current, version)
+	}
+
+	var pending []int64
+	for _, migration := range migrations {
+		record, ok := records[migration.Version]
+		if !ok {
+			pending = append(pending, migration.Version)
+			continue
+		}
+		if record.Checksum != migration.Checksum {
+			return current, fmt.Errorf("%w: %d_%s", ErrMigrationModified, migration.Version, migration.Name)
+		}
+	}
+	if len(pending) > 0 {
+		return current, fmt.Errorf("%w: %v", ErrMigrationPending, pending)
+	}
+	return current, nil
+}
+
+// migrate 在迁移锁内迁移到 target 计算出的目标版本，target 的参数为升序排列的已执行版本号
+func (m *Migrator) migrate(ctx context.Context, target func(applied []int64) int64) (int, error) {
+	migrations, err := Load()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = m.withLock(ctx, func(conn *gorm.DB) error {
+		records, err := loadApplied(conn)
+		if err != nil {
+			return err
+		}
+
+		// 已执行的迁移文件不允许修改，否则不同环境的表结构会不一致
+		byVersion := make(map[int64]*Migration, len(migrations))
+		for _, migration := range migrations {
+			byVersion[migration.Version] = migration
+			if record, ok := records[migration.Version]; ok && record.Checksum != migration.Checksum {
+				return fmt.Errorf("迁移 %d_%s 执行后被修改，校验和不一致", migration.Version, migration.Name)
+			}
+		}
+
+		applied := make([]int64, 0, len(records))
+		for version := range records {
+			applied = append(applied, version)
+		}
+		sort.Slice(applied, func(i, j int) bool {
+			return applied[i] < applied[j]
+		})
+		version := target(applied)
+
+		// 回滚高于目标版本的迁移，从新到旧
+		for i := len(applied) - 1; i >= 0 && applied[i] > version; i-- {
+			migration, ok := byVersion[applied[i]]
+			if !ok {
+				return fmt.Errorf("迁移 %d_%s 不存在于当前程序中，无法回滚", applied[i], records[applied[i]].Name)
+			}
+			if err := down(conn, migration); err != nil {
+				return err
+			}
+			count++
+		}
+
+		// 执行不超过目标版本的未执行迁移，从旧到新
+		for _, migration := range migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+			if err := up(conn, migration); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// withLock 在同一个数据库连接上持有迁移锁并执行 fn，会话级的 advisory lock 要求加锁和解锁使用同一个连接
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(hashtext(?))", lockName).Error; err != nil {
+			return fmt.Errorf("获取迁移锁失败: %w", err)
+		}
+		// 连接会归还到连接池，即使 ctx 已取消也必须释放锁
+		defer conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(hashtext(?))", lockName)
+
+		err := conn.Exec(`
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version    bigint PRIMARY KEY,
+				name       varchar(255) NOT NULL,
+				checksum   varchar(64) NOT NULL,
+				applied_at bigint NOT NULL
+			)`).Error
+		if err != nil {
+			return err
+		}
+
+		return fn(conn)
+	})
+}
+
+// loadApplied 获取已执行的迁移记录
+func loadApplied(conn *gorm.DB) (map[int64]schemaMigration, error) {
+	// 使用新会话查询，避免查询结果留在连接的语句中，影响之后在同一连接上的删除条件
+	var records []schemaMigration
+	if err := conn.Session(&gorm.Session{NewDB: true}).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]schemaMigration, len(records))
+	for _, record := range records {
+		result[record.Version] = record
+	}
+	return result, nil
+}
+
+// up 在事务中执行迁移并写入迁移记录
+func up(conn *gorm.DB, migration *Migration) error {
+	logger.GetLogger().Info("执行数据库迁移: %d_%s", migration.Version, migration.Name)
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(migration.Up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now().Unix(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("执行迁移 %d_%s 失败: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// down 在事务中回滚迁移并删除迁移记录
+func down(conn *gorm.DB, migration *Migration) error {
+	logger.GetLogger().Info("回滚数据库迁移: %d_%s", migration.Version, migration.Name)
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(migration.Down).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&schemaMigration{}, migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("回滚迁移 %d_%s 失败: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package migration
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newMockMigrator 创建连接到 sqlmock 的迁移器
+func newMockMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMigrator(db), mock
+}
+
+// expectLocked 期望获取迁移锁、创建迁移记录表并读取已执行的迁移
+func expectLocked(mock sqlmock.Sqlmock, applied ...*Migration) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock(hashtext($1))")).
+		WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"})
+	for _, m := range applied {
+		rows.AddRow(m.Version, m.Name, m.Checksum, 1)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "schema_migrations"`)).WillReturnRows(rows)
+}
+
+// expectUnlocked 期望释放迁移锁
+func expectUnlocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock(hashtext($1))")).
+		WithArgs(lockName).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigratorUp(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, pending := migrations[:len(migrations)-1], migrations[len(migrations)-1]
+
+	migrator, mock := newMockMigrator(t)
+	expectLocked(mock, applied...)
+	// 只执行未执行的迁移，迁移和迁移记录在同一事务中写入
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(pending.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "schema_migrations"`)).
+		WithArgs(pending.Version, pending.Name, pending.Checksum, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlocked(mock)
+
+	count, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("执行的迁移数 = %d, 期望 1", count)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMigratorDown(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := migrations[len(migrations)-1]
+
+	migrator, mock := newMockMigrator(t)
+	expectLocked(mock, migrations...)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(last.Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "schema_migrations"`)).
+		WithArgs(last.Version).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlocked(mock)
+
+	count, err := migrator.Down(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("回滚的迁移数 = %d, 期望 1", count)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMigratorRejectsModified(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := *migrations[0]
+	modified.Checksum = "modified"
+
+	// 已执行的迁移文件被修改时不执行任何迁移，并释放迁移锁
+	migrator, mock := newMockMigrator(t)
+	expectLocked(mock, &modified)
+	expectUnlocked(mock)
+
+	if _, err := migrator.Up(context.Background()); err == nil || !strings.Contains(err.Error(), "校验和不一致") {
+		t.Errorf("错误 = %v, 期望校验和不一致", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 嵌入的迁移版本号从1开始连续
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Fatalf("第%d个迁移版本号 = %d, 期望连续", i+1, m.Version)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{name: "缺少回滚文件", files: fstest.MapFS{"sql/0001_a.up.sql": {Data: []byte("SELECT 1")}}, wantErr: "缺少回滚文件"},
+		{name: "缺少方向", files: fstest.MapFS{"sql/0001_a.sql": {Data: []byte("SELECT 1")}}, wantErr: ".up 或 .down"},
+		{name: "无效版本号", files: fstest.MapFS{"sql/x_a.up.sql": {Data: []byte("SELECT 1")}}, wantErr: "无效的迁移版本号"},
+		{name: "同一版本名称不同", files: fstest.MapFS{
+			"sql/0001_a.up.sql":   {Data: []byte("SELECT 1")},
+			"sql/0001_b.down.sql": {Data: []byte("SELECT 1")},
+		}, wantErr: "不同的名称"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := load(tt.files, "sql"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 = %v, 期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserUniqueIndexesPartial(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 最后创建的用户唯一索引排除已删除的用户，手机号索引排除空手机号
+	want := map[string]string{
+		"idx_users_email": "WHERE deleted_at IS NULL",
+		"idx_users_phone": "WHERE phone <> '' AND deleted_at IS NULL",
+	}
+	for index, where := range want {
+		var last string
+		for _, m := range migrations {
+			for _, stmt := range strings.Split(m.Up, ";") {
+				if strings.Contains(stmt, "CREATE UNIQUE INDEX IF NOT EXISTS "+index+" ") {
+					last = stmt
+				}
+			}
+		}
+		if !strings.Contains(last, where) {
+			t.Errorf("%s = %q, 期望包含 %q", index, strings.TrimSpace(last), where)
+		}
+	}
+}
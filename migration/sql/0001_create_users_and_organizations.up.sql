@@ -0,0 +1,46 @@
+-- 用户、组织和组织成员
+-- 使用 IF NOT EXISTS，已经通过 AutoMigrate 建表的数据库可以直接纳入迁移管理
+
+CREATE TABLE IF NOT EXISTS users (
+    id         bigserial PRIMARY KEY,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at timestamptz,
+    name       varchar(100) NOT NULL,
+    email      varchar(100),
+    phone      varchar(20),
+    password   varchar(100) NOT NULL,
+    avatar     varchar(255),
+    status     varchar(20) DEFAULT 'active'
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone ON users (phone);
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id          bigserial PRIMARY KEY,
+    created_at  bigint,
+    updated_at  bigint,
+    deleted_at  timestamptz,
+    name        varchar(100) NOT NULL,
+    description varchar(500),
+    logo        varchar(255),
+    website     varchar(255),
+    status      varchar(20) DEFAULT 'active',
+    owner_id    bigint NOT NULL,
+    time_zone   varchar(64) DEFAULT 'UTC'
+);
+CREATE INDEX IF NOT EXISTS idx_organizations_deleted_at ON organizations (deleted_at);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    organization_id bigint NOT NULL,
+    user_id         bigint NOT NULL,
+    role            varchar(50) NOT NULL DEFAULT 'member',
+    status          varchar(20) DEFAULT 'active'
+);
+CREATE INDEX IF NOT EXISTS idx_organization_members_deleted_at ON organization_members (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_org_user ON organization_members (organization_id, user_id);
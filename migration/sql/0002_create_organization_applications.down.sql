@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS organization_application_limits;
+DROP TABLE IF EXISTS organization_application_members;
+DROP TABLE IF EXISTS organization_applications;
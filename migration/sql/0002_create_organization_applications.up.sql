@@ -0,0 +1,61 @@
+-- 组织应用、应用成员和应用限制
+
+CREATE TABLE IF NOT EXISTS organization_applications (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    organization_id bigint NOT NULL,
+    name            varchar(100) NOT NULL,
+    description     varchar(500),
+    app_key         varchar(100),
+    app_secret      varchar(100),
+    status          varchar(20) DEFAULT 'active',
+    type            varchar(50) NOT NULL,
+    config          jsonb
+);
+CREATE INDEX IF NOT EXISTS idx_organization_applications_deleted_at ON organization_applications (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_organization_applications_organization_id ON organization_applications (organization_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_applications_app_key ON organization_applications (app_key);
+
+CREATE TABLE IF NOT EXISTS organization_application_members (
+    id             bigserial PRIMARY KEY,
+    created_at     bigint,
+    updated_at     bigint,
+    deleted_at     timestamptz,
+    application_id bigint NOT NULL,
+    member_id      bigint NOT NULL,
+    role           varchar(50) NOT NULL DEFAULT 'user',
+    status         varchar(20) DEFAULT 'active',
+    permissions    jsonb
+);
+CREATE INDEX IF NOT EXISTS idx_organization_application_members_deleted_at ON organization_application_members (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_app_user ON organization_application_members (application_id, member_id);
+
+CREATE TABLE IF NOT EXISTS organization_application_limits (
+    id                          bigserial PRIMARY KEY,
+    created_at                  bigint,
+    updated_at                  bigint,
+    deleted_at                  timestamptz,
+    organization_application_id bigint NOT NULL,
+    plan_id                     bigint,
+    plan_name                   varchar(100) NOT NULL,
+    plan_version                bigint DEFAULT 0,
+    max_users                   bigint DEFAULT 5,
+    max_storage                 bigint DEFAULT 1073741824,
+    max_requests                bigint DEFAULT 10000,
+    enforcement_mode            varchar(20) DEFAULT 'hard',
+    overage_percent             bigint DEFAULT 0,
+    rate_limit_burst            bigint NOT NULL DEFAULT 0,
+    rate_limit_per_min          bigint NOT NULL DEFAULT 0,
+    features                    jsonb,
+    expires_at                  bigint,
+    auto_renew                  boolean DEFAULT false,
+    subscription_status         varchar(20) DEFAULT 'active',
+    trial_ends_at               bigint DEFAULT 0,
+    grace_ends_at               bigint DEFAULT 0,
+    expiry_notified_at          bigint DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_organization_application_limits_deleted_at ON organization_application_limits (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_application_limits_organization_application_id ON organization_application_limits (organization_application_id);
+CREATE INDEX IF NOT EXISTS idx_organization_application_limits_plan_id ON organization_application_limits (plan_id);
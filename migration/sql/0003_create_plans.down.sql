@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS plan_changes;
+DROP TABLE IF EXISTS plans;
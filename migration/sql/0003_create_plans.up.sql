@@ -0,0 +1,43 @@
+-- 套餐和套餐变更记录
+
+CREATE TABLE IF NOT EXISTS plans (
+    id                 bigserial PRIMARY KEY,
+    created_at         bigint,
+    updated_at         bigint,
+    deleted_at         timestamptz,
+    code               varchar(50) NOT NULL,
+    version            bigint NOT NULL,
+    name               varchar(100) NOT NULL,
+    description        varchar(500),
+    max_users          bigint NOT NULL,
+    max_storage        bigint NOT NULL,
+    max_requests       bigint NOT NULL,
+    enforcement_mode   varchar(20) DEFAULT 'hard',
+    overage_percent    bigint DEFAULT 0,
+    rate_limit_burst   bigint NOT NULL DEFAULT 0,
+    rate_limit_per_min bigint NOT NULL DEFAULT 0,
+    features           jsonb,
+    price              bigint NOT NULL DEFAULT 0,
+    price_components   jsonb,
+    currency           varchar(10) DEFAULT 'CNY',
+    billing_cycle      varchar(20) DEFAULT 'monthly',
+    trial_days         bigint NOT NULL DEFAULT 0,
+    level              bigint NOT NULL DEFAULT 0,
+    is_default         boolean DEFAULT false,
+    status             varchar(20) DEFAULT 'active'
+);
+CREATE INDEX IF NOT EXISTS idx_plans_deleted_at ON plans (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_plan_code_version ON plans (code, version);
+
+CREATE TABLE IF NOT EXISTS plan_changes (
+    id             bigserial PRIMARY KEY,
+    created_at     bigint,
+    updated_at     bigint,
+    deleted_at     timestamptz,
+    application_id bigint NOT NULL,
+    from_plan_id   bigint NOT NULL DEFAULT 0,
+    to_plan_id     bigint NOT NULL DEFAULT 0,
+    changed_at     bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_plan_changes_deleted_at ON plan_changes (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_plan_change_app_time ON plan_changes (application_id, changed_at);
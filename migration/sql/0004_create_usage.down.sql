@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS storage_snapshots;
+DROP TABLE IF EXISTS storage_gauges;
+DROP TABLE IF EXISTS usage_export_jobs;
+DROP TABLE IF EXISTS usage_idempotency_keys;
+DROP TABLE IF EXISTS usage_rollups;
+DROP TABLE IF EXISTS usage_counters;
+DROP TABLE IF EXISTS application_usages;
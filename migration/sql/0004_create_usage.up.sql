@@ -0,0 +1,125 @@
+-- 使用记录、计数器、汇总、幂等键、导出任务和存储用量
+
+CREATE TABLE IF NOT EXISTS application_usages (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    application_id  bigint NOT NULL,
+    user_id         bigint,
+    usage_type      varchar(50) NOT NULL,
+    usage_amount    bigint NOT NULL,
+    usage_date      bigint NOT NULL,
+    ingested_at     bigint NOT NULL DEFAULT 0,
+    details         jsonb,
+    organization_id bigint NOT NULL,
+    member_id       bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_application_usages_deleted_at ON application_usages (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_application_usages_application_id ON application_usages (application_id);
+CREATE INDEX IF NOT EXISTS idx_application_usages_user_id ON application_usages (user_id);
+CREATE INDEX IF NOT EXISTS idx_application_usages_usage_date ON application_usages (usage_date);
+CREATE INDEX IF NOT EXISTS idx_application_usages_organization_id ON application_usages (organization_id);
+CREATE INDEX IF NOT EXISTS idx_application_usages_member_id ON application_usages (member_id);
+
+CREATE TABLE IF NOT EXISTS usage_counters (
+    id             bigserial PRIMARY KEY,
+    created_at     bigint,
+    updated_at     bigint,
+    deleted_at     timestamptz,
+    application_id bigint NOT NULL,
+    usage_type     varchar(50) NOT NULL,
+    window_start   bigint NOT NULL,
+    amount         bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_usage_counters_deleted_at ON usage_counters (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_counter_window ON usage_counters (application_id, usage_type, window_start);
+
+CREATE TABLE IF NOT EXISTS usage_rollups (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    application_id  bigint NOT NULL,
+    organization_id bigint NOT NULL,
+    usage_type      varchar(50) NOT NULL,
+    granularity     varchar(10) NOT NULL,
+    bucket_start    bigint NOT NULL,
+    amount          bigint NOT NULL DEFAULT 0,
+    count           bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_usage_rollups_deleted_at ON usage_rollups (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_rollup_bucket ON usage_rollups (application_id, usage_type, granularity, bucket_start);
+CREATE INDEX IF NOT EXISTS idx_usage_rollups_organization_id ON usage_rollups (organization_id);
+CREATE INDEX IF NOT EXISTS idx_usage_rollups_bucket_start ON usage_rollups (bucket_start);
+
+CREATE TABLE IF NOT EXISTS usage_idempotency_keys (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    application_id  bigint NOT NULL,
+    idempotency_key varchar(128) NOT NULL,
+    usage_type      varchar(50) NOT NULL,
+    usage_amount    bigint NOT NULL,
+    expires_at      bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_usage_idempotency_keys_deleted_at ON usage_idempotency_keys (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_idempotency_key ON usage_idempotency_keys (application_id, idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_usage_idempotency_keys_expires_at ON usage_idempotency_keys (expires_at);
+
+CREATE TABLE IF NOT EXISTS usage_export_jobs (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    organization_id bigint NOT NULL,
+    application_id  bigint NOT NULL DEFAULT 0,
+    format          varchar(10) NOT NULL,
+    usage_type      varchar(50),
+    user_id         bigint,
+    start_date      bigint NOT NULL,
+    end_date        bigint NOT NULL,
+    status          varchar(20) DEFAULT 'pending',
+    file_name       varchar(255),
+    file_size       bigint DEFAULT 0,
+    row_count       bigint DEFAULT 0,
+    error           varchar(500),
+    token           varchar(64) NOT NULL,
+    completed_at    bigint DEFAULT 0,
+    expires_at      bigint DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_usage_export_jobs_deleted_at ON usage_export_jobs (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_usage_export_jobs_organization_id ON usage_export_jobs (organization_id);
+CREATE INDEX IF NOT EXISTS idx_usage_export_jobs_application_id ON usage_export_jobs (application_id);
+CREATE INDEX IF NOT EXISTS idx_usage_export_jobs_status ON usage_export_jobs (status);
+CREATE INDEX IF NOT EXISTS idx_usage_export_jobs_expires_at ON usage_export_jobs (expires_at);
+
+CREATE TABLE IF NOT EXISTS storage_gauges (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    application_id  bigint NOT NULL,
+    organization_id bigint NOT NULL,
+    current_bytes   bigint NOT NULL DEFAULT 0,
+    peak_bytes      bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_storage_gauges_deleted_at ON storage_gauges (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_storage_gauges_application_id ON storage_gauges (application_id);
+CREATE INDEX IF NOT EXISTS idx_storage_gauges_organization_id ON storage_gauges (organization_id);
+
+CREATE TABLE IF NOT EXISTS storage_snapshots (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    application_id  bigint NOT NULL,
+    organization_id bigint NOT NULL,
+    bucket_start    bigint NOT NULL,
+    max_bytes       bigint NOT NULL DEFAULT 0,
+    last_bytes      bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_storage_snapshots_deleted_at ON storage_snapshots (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_storage_snapshot_bucket ON storage_snapshots (application_id, bucket_start);
+CREATE INDEX IF NOT EXISTS idx_storage_snapshots_organization_id ON storage_snapshots (organization_id);
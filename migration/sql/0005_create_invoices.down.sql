@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS invoice_line_items;
+DROP TABLE IF EXISTS invoices;
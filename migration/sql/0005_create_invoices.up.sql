@@ -0,0 +1,41 @@
+-- 账单和账单明细
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    organization_id bigint NOT NULL,
+    number          varchar(50) NOT NULL,
+    period_start    bigint NOT NULL,
+    period_end      bigint NOT NULL,
+    currency        varchar(10) DEFAULT 'CNY',
+    total           bigint NOT NULL DEFAULT 0,
+    status          varchar(20) DEFAULT 'draft',
+    issued_at       bigint DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_invoices_deleted_at ON invoices (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoice_period ON invoices (organization_id, period_start);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_number ON invoices (number);
+
+CREATE TABLE IF NOT EXISTS invoice_line_items (
+    id             bigserial PRIMARY KEY,
+    created_at     bigint,
+    updated_at     bigint,
+    deleted_at     timestamptz,
+    invoice_id     bigint NOT NULL,
+    application_id bigint NOT NULL,
+    plan_id        bigint NOT NULL,
+    plan_version   bigint NOT NULL,
+    type           varchar(20) NOT NULL,
+    description    varchar(255),
+    metric         varchar(100),
+    quantity       bigint NOT NULL DEFAULT 0,
+    amount         bigint NOT NULL DEFAULT 0,
+    period_start   bigint NOT NULL,
+    period_end     bigint NOT NULL,
+    CONSTRAINT fk_invoices_line_items FOREIGN KEY (invoice_id) REFERENCES invoices (id)
+);
+CREATE INDEX IF NOT EXISTS idx_invoice_line_items_deleted_at ON invoice_line_items (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_invoice_line_items_invoice_id ON invoice_line_items (invoice_id);
+CREATE INDEX IF NOT EXISTS idx_invoice_line_items_application_id ON invoice_line_items (application_id);
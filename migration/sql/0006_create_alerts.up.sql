@@ -0,0 +1,35 @@
+-- 用量告警规则和告警记录
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id             bigserial PRIMARY KEY,
+    created_at     bigint,
+    updated_at     bigint,
+    deleted_at     timestamptz,
+    application_id bigint NOT NULL,
+    name           varchar(100),
+    usage_type     varchar(50) NOT NULL,
+    threshold_type varchar(20) NOT NULL,
+    threshold      bigint NOT NULL,
+    channels       jsonb,
+    enabled        boolean NOT NULL,
+    last_fired_at  bigint DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_deleted_at ON alert_rules (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_application_id ON alert_rules (application_id);
+
+CREATE TABLE IF NOT EXISTS alert_events (
+    id             bigserial PRIMARY KEY,
+    created_at     bigint,
+    updated_at     bigint,
+    deleted_at     timestamptz,
+    rule_id        bigint NOT NULL,
+    application_id bigint NOT NULL,
+    usage_type     varchar(50) NOT NULL,
+    period_start   bigint NOT NULL,
+    used           bigint NOT NULL,
+    quota          bigint NOT NULL,
+    threshold      bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_alert_events_deleted_at ON alert_events (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alert_event_period ON alert_events (rule_id, period_start);
+CREATE INDEX IF NOT EXISTS idx_alert_events_application_id ON alert_events (application_id);
@@ -0,0 +1,42 @@
+-- Webhook 订阅和投递记录
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    organization_id bigint NOT NULL,
+    url             varchar(500) NOT NULL,
+    event_types     jsonb,
+    secret          varchar(100) NOT NULL,
+    description     varchar(255),
+    enabled         boolean NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_organization_id ON webhook_subscriptions (organization_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    subscription_id bigint NOT NULL,
+    organization_id bigint NOT NULL,
+    event_id        varchar(50) NOT NULL,
+    event_type      varchar(100) NOT NULL,
+    payload         text NOT NULL,
+    status          varchar(20) NOT NULL,
+    attempts        bigint NOT NULL DEFAULT 0,
+    next_attempt_at bigint NOT NULL,
+    last_attempt_at bigint DEFAULT 0,
+    response_status bigint DEFAULT 0,
+    response_body   varchar(1000),
+    last_error      varchar(500),
+    duration_ms     bigint DEFAULT 0,
+    redelivery_of   bigint DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_organization_id ON webhook_deliveries (organization_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_due ON webhook_deliveries (status, next_attempt_at);
@@ -0,0 +1,21 @@
+-- 事件发件箱
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    aggregate_type  varchar(50) NOT NULL,
+    aggregate_id    bigint NOT NULL,
+    organization_id bigint NOT NULL DEFAULT 0,
+    event_type      varchar(100) NOT NULL,
+    payload         jsonb NOT NULL,
+    status          varchar(20) NOT NULL,
+    attempts        bigint NOT NULL DEFAULT 0,
+    next_attempt_at bigint NOT NULL DEFAULT 0,
+    published_at    bigint DEFAULT 0,
+    last_error      varchar(500)
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_deleted_at ON outbox_events (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_aggregate ON outbox_events (aggregate_type, aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_status ON outbox_events (status);
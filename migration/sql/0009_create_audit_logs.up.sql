@@ -0,0 +1,44 @@
+-- 审计日志，只允许追加，通过触发器禁止修改和删除
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id              bigserial PRIMARY KEY,
+    organization_id bigint NOT NULL,
+    sequence        bigint NOT NULL,
+    actor_type      varchar(20) NOT NULL,
+    actor_id        varchar(100),
+    action          varchar(100) NOT NULL,
+    resource_type   varchar(50) NOT NULL,
+    resource_id     bigint NOT NULL,
+    before          text,
+    after           text,
+    changes         text,
+    request_id      varchar(50),
+    trace_id        varchar(50),
+    ip              varchar(50),
+    user_agent      varchar(255),
+    created_at      bigint NOT NULL,
+    prev_hash       varchar(64) NOT NULL,
+    hash            varchar(64) NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_chain ON audit_logs (organization_id, sequence);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_resource_id ON audit_logs (resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_request_id ON audit_logs (request_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_no_modify ON audit_logs;
+CREATE TRIGGER audit_logs_no_modify
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+CREATE TRIGGER audit_logs_no_truncate
+    BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
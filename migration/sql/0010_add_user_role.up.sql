@@ -0,0 +1,3 @@
+-- 用户平台角色
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar(20) DEFAULT 'user';
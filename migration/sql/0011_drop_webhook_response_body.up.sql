@@ -0,0 +1,3 @@
+-- 不再保存订阅方的响应内容，避免通过投递记录读取内网服务的响应
+
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS response_body;
@@ -0,0 +1 @@
+ALTER TABLE usage_export_jobs DROP COLUMN IF EXISTS claimed_at;
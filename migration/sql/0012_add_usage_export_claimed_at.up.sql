@@ -0,0 +1,3 @@
+-- 导出任务领取时间，执行中的任务超过租约时长后可以被其他实例重新领取
+
+ALTER TABLE usage_export_jobs ADD COLUMN IF NOT EXISTS claimed_at bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,18 @@
+-- 合并各功能的汇总后删除 feature_name 列
+
+CREATE TEMP TABLE usage_rollup_features ON COMMIT DROP AS
+SELECT application_id, MIN(organization_id) AS organization_id, granularity, bucket_start,
+       SUM(amount) AS amount, SUM(count) AS count, MIN(created_at) AS created_at, MAX(updated_at) AS updated_at
+FROM usage_rollups
+WHERE usage_type = 'feature'
+GROUP BY application_id, granularity, bucket_start;
+
+DELETE FROM usage_rollups WHERE usage_type = 'feature';
+
+DROP INDEX IF EXISTS idx_usage_rollup_bucket;
+ALTER TABLE usage_rollups DROP COLUMN IF EXISTS feature_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_rollup_bucket ON usage_rollups (application_id, usage_type, granularity, bucket_start);
+
+INSERT INTO usage_rollups (application_id, organization_id, usage_type, granularity, bucket_start, amount, count, created_at, updated_at)
+SELECT application_id, organization_id, 'feature', granularity, bucket_start, amount, count, created_at, updated_at
+FROM usage_rollup_features;
@@ -0,0 +1,46 @@
+-- 功能使用量按功能名称分别汇总，账单按汇总计量功能用量，不依赖会被定期清理的原始使用记录
+
+ALTER TABLE usage_rollups ADD COLUMN IF NOT EXISTS feature_name varchar(100) NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS idx_usage_rollup_bucket;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_rollup_bucket ON usage_rollups (application_id, usage_type, feature_name, granularity, bucket_start);
+
+-- 用仍保留的原始使用记录按功能名称拆分已有的功能汇总，原始记录已清理的部分保留在 feature_name 为空的汇总中
+CREATE TEMP TABLE usage_rollup_features ON COMMIT DROP AS
+SELECT u.application_id,
+       u.organization_id,
+       u.details->>'feature_name' AS feature_name,
+       g.granularity,
+       EXTRACT(EPOCH FROM date_trunc(g.granularity, to_timestamp(u.usage_date) AT TIME ZONE z.time_zone) AT TIME ZONE z.time_zone)::bigint AS bucket_start,
+       SUM(u.usage_amount) AS amount,
+       COUNT(*) AS count
+FROM application_usages u
+JOIN organizations o ON o.id = u.organization_id
+CROSS JOIN LATERAL (SELECT COALESCE(NULLIF(o.time_zone, ''), 'UTC') AS time_zone) z
+CROSS JOIN (VALUES ('hour'), ('day')) AS g (granularity)
+WHERE u.usage_type = 'feature'
+  AND u.deleted_at IS NULL
+  AND COALESCE(u.details->>'feature_name', '') <> ''
+GROUP BY 1, 2, 3, 4, 5;
+
+UPDATE usage_rollups r
+SET amount = r.amount - f.amount, count = r.count - f.count
+FROM (
+    SELECT application_id, granularity, bucket_start, SUM(amount) AS amount, SUM(count) AS count
+    FROM usage_rollup_features
+    GROUP BY 1, 2, 3
+) f
+WHERE r.application_id = f.application_id
+  AND r.usage_type = 'feature'
+  AND r.feature_name = ''
+  AND r.granularity = f.granularity
+  AND r.bucket_start = f.bucket_start;
+
+DELETE FROM usage_rollups WHERE usage_type = 'feature' AND feature_name = '' AND amount <= 0;
+
+INSERT INTO usage_rollups (application_id, organization_id, usage_type, feature_name, granularity, bucket_start, amount, count, created_at, updated_at)
+SELECT application_id, organization_id, 'feature', feature_name, granularity, bucket_start, amount, count,
+       EXTRACT(EPOCH FROM now())::bigint, EXTRACT(EPOCH FROM now())::bigint
+FROM usage_rollup_features
+ON CONFLICT (application_id, usage_type, feature_name, granularity, bucket_start)
+DO UPDATE SET amount = usage_rollups.amount + EXCLUDED.amount, count = usage_rollups.count + EXCLUDED.count;
@@ -0,0 +1 @@
+-- 补齐的汇总与之后累加的使用量无法区分，回滚时保留
@@ -0,0 +1,28 @@
+-- 用仍保留的原始使用记录补齐汇总，汇总上线前记录的使用量之前只存在于原始记录中
+-- 同一汇总区间的原始记录合计大于已有汇总时以原始记录为准；原始记录已部分清理的区间合计偏小，保留已有汇总
+
+CREATE TEMP TABLE usage_rollup_backfill ON COMMIT DROP AS
+SELECT u.application_id,
+       MIN(u.organization_id) AS organization_id,
+       u.usage_type,
+       CASE WHEN u.usage_type = 'feature' THEN COALESCE(u.details->>'feature_name', '') ELSE '' END AS feature_name,
+       g.granularity,
+       EXTRACT(EPOCH FROM date_trunc(g.granularity, to_timestamp(u.usage_date) AT TIME ZONE z.time_zone) AT TIME ZONE z.time_zone)::bigint AS bucket_start,
+       SUM(u.usage_amount) AS amount,
+       COUNT(*) AS count
+FROM application_usages u
+JOIN organizations o ON o.id = u.organization_id
+CROSS JOIN LATERAL (SELECT COALESCE(NULLIF(o.time_zone, ''), 'UTC') AS time_zone) z
+CROSS JOIN (VALUES ('hour'), ('day')) AS g (granularity)
+WHERE u.deleted_at IS NULL
+GROUP BY u.application_id, 3, 4, 5, 6;
+
+INSERT INTO usage_rollups (application_id, organization_id, usage_type, feature_name, granularity, bucket_start, amount, count, created_at, updated_at)
+SELECT application_id, organization_id, usage_type, feature_name, granularity, bucket_start, amount, count,
+       EXTRACT(EPOCH FROM now())::bigint, EXTRACT(EPOCH FROM now())::bigint
+FROM usage_rollup_backfill
+ON CONFLICT (application_id, usage_type, feature_name, granularity, bucket_start)
+DO UPDATE SET amount = GREATEST(usage_rollups.amount, EXCLUDED.amount),
+              count = GREATEST(usage_rollups.count, EXCLUDED.count),
+              updated_at = EXCLUDED.updated_at
+WHERE EXCLUDED.amount > usage_rollups.amount;
@@ -0,0 +1,19 @@
+-- 订阅扣款记录，扣款前创建，防止重试时重复扣款，套餐生效失败时用于退款
+
+CREATE TABLE IF NOT EXISTS subscription_payments (
+    id              bigserial PRIMARY KEY,
+    created_at      bigint,
+    updated_at      bigint,
+    deleted_at      timestamptz,
+    application_id  bigint NOT NULL,
+    plan_id         bigint NOT NULL,
+    idempotency_key varchar(200) NOT NULL,
+    amount          bigint NOT NULL,
+    currency        varchar(10),
+    attempts        bigint NOT NULL DEFAULT 0,
+    status          varchar(20) NOT NULL DEFAULT 'pending',
+    transaction_id  varchar(100)
+);
+CREATE INDEX IF NOT EXISTS idx_subscription_payments_deleted_at ON subscription_payments (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_subscription_payments_application_id ON subscription_payments (application_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_payments_idempotency_key ON subscription_payments (idempotency_key);
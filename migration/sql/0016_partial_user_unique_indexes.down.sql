@@ -0,0 +1,6 @@
+-- 存在重复的邮箱或手机号时回滚失败，需要先清理重复的用户
+
+DROP INDEX IF EXISTS idx_users_email;
+DROP INDEX IF EXISTS idx_users_phone;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone ON users (phone);
@@ -0,0 +1,6 @@
+-- 邮箱和手机号只在未删除的用户中唯一，手机号为空的用户不参与唯一校验，回收站中的用户不占用邮箱和手机号
+
+DROP INDEX IF EXISTS idx_users_email;
+DROP INDEX IF EXISTS idx_users_phone;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone ON users (phone) WHERE phone <> '' AND deleted_at IS NULL;
@@ -0,0 +1,26 @@
+-- 发件箱事件按聚合内序号发布，序号在写入事件的事务中通过锁定聚合的序号行分配，与提交顺序一致
+
+CREATE TABLE IF NOT EXISTS outbox_sequences (
+    aggregate_type varchar(50) NOT NULL,
+    aggregate_id   bigint NOT NULL,
+    last_sequence  bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (aggregate_type, aggregate_id)
+);
+
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS sequence bigint NOT NULL DEFAULT 0;
+
+-- 已有事件按ID顺序编号
+UPDATE outbox_events e SET sequence = s.sequence
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY aggregate_type, aggregate_id ORDER BY id) AS sequence
+    FROM outbox_events
+) s
+WHERE e.id = s.id;
+
+INSERT INTO outbox_sequences (aggregate_type, aggregate_id, last_sequence)
+SELECT aggregate_type, aggregate_id, MAX(sequence)
+FROM outbox_events
+GROUP BY aggregate_type, aggregate_id
+ON CONFLICT (aggregate_type, aggregate_id) DO UPDATE SET last_sequence = EXCLUDED.last_sequence;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events (aggregate_type, aggregate_id, sequence) WHERE status = 'pending';
@@ -0,0 +1,8 @@
+-- 未删除的套餐中最多只有一个默认套餐，并发设置默认套餐时后提交的事务失败
+
+-- 已有多个默认套餐时只保留最新创建的一个
+UPDATE plans SET is_default = false
+WHERE is_default AND deleted_at IS NULL
+  AND id <> (SELECT MAX(id) FROM plans WHERE is_default AND deleted_at IS NULL);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_plans_default ON plans (is_default) WHERE is_default AND deleted_at IS NULL;
@@ -0,0 +1,5 @@
+-- 存在平台级日志链时回滚失败，同一组织下多条日志链的序号会重复
+
+DROP INDEX IF EXISTS idx_audit_chain;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_chain ON audit_logs (organization_id, sequence);
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS chain;
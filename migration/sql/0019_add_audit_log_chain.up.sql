@@ -0,0 +1,7 @@
+-- 平台级审计日志按资源分链，不同用户的操作不再争用同一把日志链锁
+-- 已有日志属于空日志链，组织级日志的日志链始终为空
+
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS chain varchar(100) NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS idx_audit_chain;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_chain ON audit_logs (organization_id, chain, sequence);
@@ -0,0 +1,38 @@
+package model
+
+// 告警阈值类型
+const (
+	AlertThresholdPercent  = "percent"  // 配额的百分比
+	AlertThresholdAbsolute = "absolute" // 使用量的绝对值
+)
+
+// AlertChannel 告警发送渠道，存储于告警规则的 Channels 字段中
+type AlertChannel struct {
+	Type   string `json:"type"`   // 渠道类型：webhook, email
+	Target string `json:"target"` // 接收地址：webhook 为URL，email 为邮箱，多个邮箱以逗号分隔
+}
+
+// AlertRule 用量告警规则模型，使用量达到阈值时在每个统计周期内只告警一次
+type AlertRule struct {
+	Base
+	ApplicationId int64  `gorm:"not null;index" json:"application_id"`   // 组织应用ID
+	Name          string `gorm:"size:100" json:"name"`                   // 规则名称
+	UsageType     string `gorm:"size:50;not null" json:"usage_type"`     // 使用类型：api_call, storage
+	ThresholdType string `gorm:"size:20;not null" json:"threshold_type"` // 阈值类型：percent, absolute
+	Threshold     int64  `gorm:"not null" json:"threshold"`              // 阈值，percent 为配额百分比，absolute 为使用量
+	Channels      string `gorm:"type:jsonb" json:"channels"`             // 发送渠道，JSON格式
+	Enabled       bool   `gorm:"not null" json:"enabled"`                // 是否启用
+	LastFiredAt   int64  `gorm:"default:0" json:"last_fired_at"`         // 最近一次告警时间
+}
+
+// AlertEvent 用量告警记录模型，同一规则在同一统计周期内只有一条记录
+type AlertEvent struct {
+	Base
+	RuleId        int64  `gorm:"not null;index:idx_alert_event_period,unique" json:"rule_id"`      // 告警规则ID
+	ApplicationId int64  `gorm:"not null;index" json:"application_id"`                             // 组织应用ID
+	UsageType     string `gorm:"size:50;not null" json:"usage_type"`                               // 使用类型
+	PeriodStart   int64  `gorm:"not null;index:idx_alert_event_period,unique" json:"period_start"` // 统计周期开始时间
+	Used          int64  `gorm:"not null" json:"used"`                                             // 告警时的使用量
+	Quota         int64  `gorm:"not null" json:"quota"`                                            // 告警时的配额，-1表示不限
+	Threshold     int64  `gorm:"not null" json:"threshold"`                                        // 触发告警的使用量阈值
+}
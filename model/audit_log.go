@@ -0,0 +1,25 @@
+package model
+
+// AuditLog 审计日志模型，只追加不修改，同一组织的日志通过哈希链接，任何篡改都会使之后的哈希校验失败
+// 不包含软删除字段，内容字段使用 text 而不是 jsonb，以保证校验时使用与写入时完全相同的内容
+type AuditLog struct {
+	ID             int64  `gorm:"primarykey" json:"id"`
+	OrganizationId int64  `gorm:"not null;uniqueIndex:idx_audit_chain" json:"organization_id"` // 组织ID，0表示平台级操作（如用户）
+	Chain          string `gorm:"size:100;not null;uniqueIndex:idx_audit_chain" json:"chain"`  // 日志链，组织级日志为空，平台级日志按资源分链，如 user:1
+	Sequence       int64  `gorm:"not null;uniqueIndex:idx_audit_chain" json:"sequence"`        // 日志链内的序号，从1开始连续递增
+	ActorType      string `gorm:"size:20;not null" json:"actor_type"`                          // 操作者类型：user, app, system, anonymous
+	ActorId        string `gorm:"size:100;index" json:"actor_id"`                              // 操作者标识
+	Action         string `gorm:"size:100;not null;index" json:"action"`                       // 操作，如 organization.update
+	ResourceType   string `gorm:"size:50;not null" json:"resource_type"`                       // 资源类型
+	ResourceId     int64  `gorm:"not null;index" json:"resource_id"`                           // 资源ID
+	Before         string `gorm:"type:text" json:"before"`                                     // 变更前的资源，JSON格式
+	After          string `gorm:"type:text" json:"after"`                                      // 变更后的资源，JSON格式
+	Changes        string `gorm:"type:text" json:"changes"`                                    // 变更的字段，JSON格式：{"字段":{"from":旧值,"to":新值}}
+	RequestId      string `gorm:"size:50;index" json:"request_id"`                             // 请求ID
+	TraceId        string `gorm:"size:50" json:"trace_id"`                                     // 跟踪ID
+	IP             string `gorm:"size:50" json:"ip"`                                           // 客户端IP
+	UserAgent      string `gorm:"size:255" json:"user_agent"`                                  // 客户端UA
+	CreatedAt      int64  `gorm:"not null;index" json:"created_at"`                            // 记录时间
+	PrevHash       string `gorm:"size:64;not null" json:"prev_hash"`                           // 上一条日志的哈希，第一条为空
+	Hash           string `gorm:"size:64;not null" json:"hash"`                                // 本条日志的哈希
+}
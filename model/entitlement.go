@@ -0,0 +1,20 @@
+package model
+
+// 功能权益类型
+const (
+	EntitlementTypeBoolean = "boolean" // 开关型，由 Enabled 决定是否可用
+	EntitlementTypeNumeric = "numeric" // 数值上限型，Limit 为上限，-1 表示不限
+	EntitlementTypeEnum    = "enum"    // 等级型，Value 为当前等级，Options 按从低到高排列
+)
+
+// Entitlement 功能权益，存储于套餐和应用限制的 Features 字段中
+type Entitlement struct {
+	Type    string   `json:"type"`              // 权益类型：boolean, numeric, enum
+	Enabled bool     `json:"enabled,omitempty"` // 是否开启（boolean）
+	Limit   int64    `json:"limit,omitempty"`   // 数值上限（numeric），-1 表示不限
+	Value   string   `json:"value,omitempty"`   // 当前等级（enum）
+	Options []string `json:"options,omitempty"` // 可选等级，从低到高（enum）
+}
+
+// Entitlements 功能权益集合，键为功能名称
+type Entitlements map[string]Entitlement
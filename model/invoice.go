@@ -0,0 +1,37 @@
+package model
+
+// 账单状态
+const (
+	InvoiceStatusDraft  = "draft"
+	InvoiceStatusIssued = "issued"
+)
+
+// Invoice 账单模型，记录组织在一个月度计费周期内的应付金额
+type Invoice struct {
+	Base
+	OrganizationId int64             `gorm:"not null;index:idx_invoice_period,unique" json:"organization_id"` // 组织ID
+	Number         string            `gorm:"size:50;not null;uniqueIndex" json:"number"`                      // 账单编号
+	PeriodStart    int64             `gorm:"not null;index:idx_invoice_period,unique" json:"period_start"`    // 计费周期开始时间（含）
+	PeriodEnd      int64             `gorm:"not null" json:"period_end"`                                      // 计费周期结束时间（不含）
+	Currency       string            `gorm:"size:10;default:'CNY'" json:"currency"`                           // 币种
+	Total          int64             `gorm:"not null;default:0" json:"total"`                                 // 应付金额（分）
+	Status         string            `gorm:"size:20;default:'draft'" json:"status"`                           // 账单状态：draft, issued
+	IssuedAt       int64             `gorm:"default:0" json:"issued_at"`                                      // 出账时间
+	LineItems      []InvoiceLineItem `gorm:"foreignKey:InvoiceId" json:"line_items,omitempty"`                // 账单明细
+}
+
+// InvoiceLineItem 账单明细模型，记录一个应用在一段套餐期间内某项价格组成的费用
+type InvoiceLineItem struct {
+	Base
+	InvoiceId     int64  `gorm:"not null;index" json:"invoice_id"`     // 账单ID
+	ApplicationId int64  `gorm:"not null;index" json:"application_id"` // 组织应用ID
+	PlanId        int64  `gorm:"not null" json:"plan_id"`              // 套餐ID
+	PlanVersion   int    `gorm:"not null" json:"plan_version"`         // 套餐版本号
+	Type          string `gorm:"size:20;not null" json:"type"`         // 价格类型：flat, per_seat, per_unit
+	Description   string `gorm:"size:255" json:"description"`          // 明细描述
+	Metric        string `gorm:"size:100" json:"metric"`               // 计量项
+	Quantity      int64  `gorm:"not null;default:0" json:"quantity"`   // 计费数量
+	Amount        int64  `gorm:"not null;default:0" json:"amount"`     // 金额（分）
+	PeriodStart   int64  `gorm:"not null" json:"period_start"`         // 本明细的计费开始时间（含）
+	PeriodEnd     int64  `gorm:"not null" json:"period_end"`           // 本明细的计费结束时间（不含）
+}
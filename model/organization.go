@@ -9,4 +9,5 @@ type Organization struct {
 	Website     string `gorm:"size:255" json:"website"`                // 组织网站
 	Status      string `gorm:"size:20;default:'active'" json:"status"` // 组织状态：active, inactive, suspended
 	OwnerId     int64  `gorm:"not null" json:"owner_id"`               // 组织拥有者ID
+	TimeZone    string `gorm:"size:64;default:'UTC'" json:"time_zone"` // 组织时区（IANA名称），用于按天/月统计和配额窗口，已有使用量统计后不能修改
 }
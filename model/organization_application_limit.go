@@ -4,11 +4,21 @@ package model
 type OrganizationApplicationLimit struct {
 	Base
 	OrganizationApplicationId int64  `gorm:"not null;uniqueIndex" json:"organization_application_id"` // 组织应用ID
+	PlanId                    int64  `gorm:"index" json:"plan_id"`                                    // 套餐ID，为0表示自定义限制
 	PlanName                  string `gorm:"size:100;not null" json:"plan_name"`                      // 套餐名称
+	PlanVersion               int    `gorm:"default:0" json:"plan_version"`                           // 套餐版本号
 	MaxUsers                  int    `gorm:"default:5" json:"max_users"`                              // 最大用户数
 	MaxStorage                int64  `gorm:"default:1073741824" json:"max_storage"`                   // 最大存储空间（字节）
 	MaxRequests               int    `gorm:"default:10000" json:"max_requests"`                       // 最大请求数/天
+	EnforcementMode           string `gorm:"size:20;default:'hard'" json:"enforcement_mode"`          // 配额执行模式：hard, soft, notify
+	OveragePercent            int    `gorm:"default:0" json:"overage_percent"`                        // soft模式下允许超出配额的百分比，0表示不限
+	RateLimitBurst            int    `gorm:"not null;default:0" json:"rate_limit_burst"`              // 限流令牌桶容量（允许的最大突发请求数），0表示不限流
+	RateLimitPerMin           int    `gorm:"not null;default:0" json:"rate_limit_per_min"`            // 限流令牌桶每分钟补充的令牌数
 	Features                  string `gorm:"type:jsonb" json:"features"`                              // 功能特性，JSON格式
 	ExpiresAt                 int64  `json:"expires_at"`                                              // 过期时间
 	AutoRenew                 bool   `gorm:"default:false" json:"auto_renew"`                         // 是否自动续费
+	SubscriptionStatus        string `gorm:"size:20;default:'active'" json:"subscription_status"`     // 订阅状态：trialing, active, grace
+	TrialEndsAt               int64  `gorm:"default:0" json:"trial_ends_at"`                          // 试用结束时间，非0表示已使用过试用
+	GraceEndsAt               int64  `gorm:"default:0" json:"grace_ends_at"`                          // 宽限期结束时间
+	ExpiryNotifiedAt          int64  `gorm:"default:0" json:"expiry_notified_at"`                     // 到期提醒发送时间
 }
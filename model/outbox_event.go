@@ -0,0 +1,24 @@
+package model
+
+// 发件箱事件状态
+const (
+	OutboxEventPending   = "pending"   // 等待发布或等待重试
+	OutboxEventPublished = "published" // 已发布到所有投递目标
+	OutboxEventDead      = "dead"      // 达到最大尝试次数后不再重试，需人工处理
+)
+
+// OutboxEvent 发件箱事件模型，与状态变更在同一事务中写入，由中继按聚合内序号顺序发布
+type OutboxEvent struct {
+	Base
+	AggregateType  string `gorm:"size:50;not null;index:idx_outbox_aggregate" json:"aggregate_type"` // 聚合类型：user, organization, application
+	AggregateId    int64  `gorm:"not null;index:idx_outbox_aggregate" json:"aggregate_id"`           // 聚合ID
+	Sequence       int64  `gorm:"not null;default:0" json:"sequence"`                                // 聚合内序号，在写入事件的事务中分配，与提交顺序一致
+	OrganizationId int64  `gorm:"not null;default:0" json:"organization_id"`                         // 所属组织ID，0表示不属于任何组织
+	EventType      string `gorm:"size:100;not null" json:"event_type"`                               // 事件类型
+	Payload        string `gorm:"type:jsonb;not null" json:"payload"`                                // 事件数据
+	Status         string `gorm:"size:20;not null;index" json:"status"`                              // 状态：pending, published, dead
+	Attempts       int    `gorm:"not null;default:0" json:"attempts"`                                // 已尝试发布次数
+	NextAttemptAt  int64  `gorm:"not null;default:0" json:"next_attempt_at"`                         // 下次尝试时间
+	PublishedAt    int64  `gorm:"default:0" json:"published_at"`                                     // 发布时间
+	LastError      string `gorm:"size:500" json:"last_error"`                                        // 最近一次发布错误
+}
@@ -0,0 +1,33 @@
+package model
+
+// Plan 套餐模型，记录套餐目录中某一版本的配额、功能与价格
+type Plan struct {
+	Base
+	Code            string `gorm:"size:50;not null;index:idx_plan_code_version,unique" json:"code"` // 套餐编码，如 free、pro
+	Version         int    `gorm:"not null;index:idx_plan_code_version,unique" json:"version"`      // 套餐版本号
+	Name            string `gorm:"size:100;not null" json:"name"`                                   // 套餐名称
+	Description     string `gorm:"size:500" json:"description"`                                     // 套餐描述
+	MaxUsers        int    `gorm:"not null" json:"max_users"`                                       // 最大用户数
+	MaxStorage      int64  `gorm:"not null" json:"max_storage"`                                     // 最大存储空间（字节）
+	MaxRequests     int    `gorm:"not null" json:"max_requests"`                                    // 最大请求数/天
+	EnforcementMode string `gorm:"size:20;default:'hard'" json:"enforcement_mode"`                  // 配额执行模式：hard（超限拒绝）, soft（允许超额）, notify（仅通知）
+	OveragePercent  int    `gorm:"default:0" json:"overage_percent"`                                // soft模式下允许超出配额的百分比，0表示不限
+	RateLimitBurst  int    `gorm:"not null;default:0" json:"rate_limit_burst"`                      // 限流令牌桶容量（允许的最大突发请求数），0表示不限流
+	RateLimitPerMin int    `gorm:"not null;default:0" json:"rate_limit_per_min"`                    // 限流令牌桶每分钟补充的令牌数
+	Features        string `gorm:"type:jsonb" json:"features"`                                      // 功能特性，JSON格式
+	Price           int64  `gorm:"not null;default:0" json:"price"`                                 // 价格（分），订阅时预付
+	PriceComponents string `gorm:"type:jsonb" json:"price_components"`                              // 按月出账的价格组成，JSON格式，与预付价格分开计算
+	Currency        string `gorm:"size:10;default:'CNY'" json:"currency"`                           // 币种
+	BillingCycle    string `gorm:"size:20;default:'monthly'" json:"billing_cycle"`                  // 计费周期：monthly, yearly
+	TrialDays       int    `gorm:"not null;default:0" json:"trial_days"`                            // 试用天数，0表示不支持试用
+	Level           int    `gorm:"not null;default:0" json:"level"`                                 // 套餐等级，用于判断升级或降级
+	IsDefault       bool   `gorm:"default:false" json:"is_default"`                                 // 是否为新应用的默认套餐
+	Status          string `gorm:"size:20;default:'active'" json:"status"`                          // 套餐状态：active, deprecated（已停售，老用户保留）
+}
+
+// 配额执行模式
+const (
+	EnforcementModeHard   = "hard"   // 超出配额时拒绝
+	EnforcementModeSoft   = "soft"   // 允许超额使用，超额部分单独记录
+	EnforcementModeNotify = "notify" // 仅在超出配额时通知
+)
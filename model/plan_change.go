@@ -0,0 +1,10 @@
+package model
+
+// PlanChange 应用套餐变更记录，用于账单按变更时间折算
+type PlanChange struct {
+	Base
+	ApplicationId int64 `gorm:"not null;index:idx_plan_change_app_time" json:"application_id"` // 组织应用ID
+	FromPlanId    int64 `gorm:"not null;default:0" json:"from_plan_id"`                        // 变更前的套餐ID，0表示自定义限制或无套餐
+	ToPlanId      int64 `gorm:"not null;default:0" json:"to_plan_id"`                          // 变更后的套餐ID，0表示自定义限制
+	ChangedAt     int64 `gorm:"not null;index:idx_plan_change_app_time" json:"changed_at"`     // 变更时间
+}
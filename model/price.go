@@ -0,0 +1,26 @@
+package model
+
+// 价格组成类型
+const (
+	PriceTypeFlat    = "flat"     // 固定费用，按计费周期内的使用天数折算
+	PriceTypePerSeat = "per_seat" // 按应用成员数计费
+	PriceTypePerUnit = "per_unit" // 按用量计费，支持阶梯价格
+)
+
+// PriceTier 阶梯价格，按累进方式计算
+type PriceTier struct {
+	UpTo       int64 `json:"up_to"`                 // 本阶梯的用量上限（计费单位），0表示不限
+	UnitAmount int64 `json:"unit_amount"`           // 本阶梯每个计费单位的价格（分）
+	FlatAmount int64 `json:"flat_amount,omitempty"` // 用量进入本阶梯时收取的固定费用（分）
+}
+
+// PriceComponent 价格组成，存储于套餐的 PriceComponents 字段中
+type PriceComponent struct {
+	Type       string      `json:"type"`                  // 价格类型：flat, per_seat, per_unit
+	Name       string      `json:"name"`                  // 账单明细中显示的名称
+	Metric     string      `json:"metric,omitempty"`      // 计量项（per_unit）：api_call, storage（GB月）或功能名称
+	UnitAmount int64       `json:"unit_amount,omitempty"` // 单价（分），flat 为固定费用，per_seat 为每个成员的价格
+	UnitSize   int64       `json:"unit_size,omitempty"`   // 每个计费单位包含的用量（per_unit），默认为1
+	Included   int64       `json:"included,omitempty"`    // 免费的计费单位数（per_unit）
+	Tiers      []PriceTier `json:"tiers,omitempty"`       // 阶梯价格（per_unit），为空时按 UnitAmount 计费
+}
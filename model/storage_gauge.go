@@ -0,0 +1,20 @@
+package model
+
+// StorageGauge 存储用量模型，记录应用当前的存储占用，区别于只增不减的使用量计数
+type StorageGauge struct {
+	Base
+	ApplicationId  int64 `gorm:"not null;uniqueIndex" json:"application_id"` // 组织应用ID
+	OrganizationId int64 `gorm:"not null;index" json:"organization_id"`      // 组织ID
+	CurrentBytes   int64 `gorm:"not null;default:0" json:"current_bytes"`    // 当前存储占用（字节）
+	PeakBytes      int64 `gorm:"not null;default:0" json:"peak_bytes"`       // 历史最高存储占用（字节）
+}
+
+// StorageSnapshot 存储用量小时快照，用于按平均或峰值计费
+type StorageSnapshot struct {
+	Base
+	ApplicationId  int64 `gorm:"not null;index:idx_storage_snapshot_bucket,unique" json:"application_id"` // 组织应用ID
+	OrganizationId int64 `gorm:"not null;index" json:"organization_id"`                                   // 组织ID
+	BucketStart    int64 `gorm:"not null;index:idx_storage_snapshot_bucket,unique" json:"bucket_start"`   // 小时开始时间
+	MaxBytes       int64 `gorm:"not null;default:0" json:"max_bytes"`                                     // 该小时内的最高存储占用
+	LastBytes      int64 `gorm:"not null;default:0" json:"last_bytes"`                                    // 该小时内最后一次变更后的存储占用
+}
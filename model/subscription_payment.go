@@ -0,0 +1,23 @@
+package model
+
+// 订阅扣款状态
+const (
+	PaymentStatusPending   = "pending"   // 已创建，尚未扣款或扣款结果未知
+	PaymentStatusCharged   = "charged"   // 已扣款，套餐尚未生效
+	PaymentStatusCompleted = "completed" // 已扣款且套餐已生效
+	PaymentStatusFailed    = "failed"    // 扣款失败
+	PaymentStatusRefunded  = "refunded"  // 套餐生效失败，已退款
+)
+
+// SubscriptionPayment 订阅扣款记录，扣款前按幂等键创建，同一幂等键只扣款并生效一次
+type SubscriptionPayment struct {
+	Base
+	ApplicationId  int64  `gorm:"not null;index" json:"application_id"`                 // 组织应用ID
+	PlanId         int64  `gorm:"not null" json:"plan_id"`                              // 套餐ID
+	IdempotencyKey string `gorm:"size:200;not null;uniqueIndex" json:"idempotency_key"` // 幂等键
+	Amount         int64  `gorm:"not null" json:"amount"`                               // 金额（分）
+	Currency       string `gorm:"size:10" json:"currency"`                              // 币种
+	Attempts       int    `gorm:"not null;default:0" json:"attempts"`                   // 扣款次数，失败或退款后重新扣款时使用新的支付渠道幂等键
+	Status         string `gorm:"size:20;not null;default:'pending'" json:"status"`     // 状态：pending, charged, completed, failed, refunded
+	TransactionId  string `gorm:"size:100" json:"transaction_id"`                       // 支付渠道交易号
+}
@@ -0,0 +1,10 @@
+package model
+
+// UsageCounter 使用量计数器模型，按应用、使用类型和时间窗口累计使用量，用于配额的原子检查与累加
+type UsageCounter struct {
+	Base
+	ApplicationId int64  `gorm:"not null;index:idx_usage_counter_window,unique" json:"application_id"`     // 组织应用ID
+	UsageType     string `gorm:"size:50;not null;index:idx_usage_counter_window,unique" json:"usage_type"` // 使用类型：api_call, storage
+	WindowStart   int64  `gorm:"not null;index:idx_usage_counter_window,unique" json:"window_start"`       // 时间窗口开始时间，0表示不按窗口重置
+	Amount        int64  `gorm:"not null;default:0" json:"amount"`                                         // 窗口内累计使用量
+}
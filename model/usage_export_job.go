@@ -0,0 +1,30 @@
+package model
+
+// 使用记录导出任务状态
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+)
+
+// UsageExportJob 使用记录导出任务模型，导出文件写入文件存储后通过下载链接获取
+type UsageExportJob struct {
+	Base
+	OrganizationId int64  `gorm:"not null;index" json:"organization_id"`          // 组织ID
+	ApplicationId  int64  `gorm:"not null;default:0;index" json:"application_id"` // 组织应用ID，为0表示导出整个组织
+	Format         string `gorm:"size:10;not null" json:"format"`                 // 导出格式：csv, ndjson
+	UsageType      string `gorm:"size:50" json:"usage_type"`                      // 使用类型过滤条件
+	UserId         *int64 `json:"user_id"`                                        // 用户过滤条件
+	StartDate      int64  `gorm:"not null" json:"start_date"`                     // 开始时间（含）
+	EndDate        int64  `gorm:"not null" json:"end_date"`                       // 结束时间（不含）
+	Status         string `gorm:"size:20;default:'pending';index" json:"status"`  // 任务状态：pending, running, completed, failed
+	FileName       string `gorm:"size:255" json:"file_name"`                      // 导出文件名
+	FileSize       int64  `gorm:"default:0" json:"file_size"`                     // 导出文件大小（字节）
+	RowCount       int64  `gorm:"default:0" json:"row_count"`                     // 导出记录数
+	Error          string `gorm:"size:500" json:"error,omitempty"`                // 失败原因
+	Token          string `gorm:"size:64;not null" json:"-"`                      // 下载令牌
+	ClaimedAt      int64  `gorm:"not null;default:0" json:"claimed_at"`           // 最近一次被领取执行的时间
+	CompletedAt    int64  `gorm:"default:0" json:"completed_at"`                  // 完成时间
+	ExpiresAt      int64  `gorm:"default:0;index" json:"expires_at"`              // 导出文件过期时间
+}
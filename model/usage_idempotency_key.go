@@ -0,0 +1,11 @@
+package model
+
+// UsageIdempotencyKey 使用记录幂等键模型，在有效期内对客户端重复上报的使用事件去重
+type UsageIdempotencyKey struct {
+	Base
+	ApplicationId  int64  `gorm:"not null;index:idx_usage_idempotency_key,unique" json:"application_id"`           // 组织应用ID
+	IdempotencyKey string `gorm:"size:128;not null;index:idx_usage_idempotency_key,unique" json:"idempotency_key"` // 客户端提供的幂等键
+	UsageType      string `gorm:"size:50;not null" json:"usage_type"`                                              // 使用类型
+	UsageAmount    int64  `gorm:"not null" json:"usage_amount"`                                                    // 使用量
+	ExpiresAt      int64  `gorm:"not null;index" json:"expires_at"`                                                // 过期时间，过期后可重新使用
+}
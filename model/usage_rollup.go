@@ -0,0 +1,20 @@
+package model
+
+// 使用量汇总粒度
+const (
+	RollupGranularityHour = "hour"
+	RollupGranularityDay  = "day"
+)
+
+// UsageRollup 使用量汇总模型，按小时/天预聚合应用的使用记录
+type UsageRollup struct {
+	Base
+	ApplicationId  int64  `gorm:"not null;uniqueIndex:idx_usage_rollup_bucket" json:"application_id"`                   // 组织应用ID
+	OrganizationId int64  `gorm:"not null;index" json:"organization_id"`                                                // 组织ID
+	UsageType      string `gorm:"size:50;not null;uniqueIndex:idx_usage_rollup_bucket" json:"usage_type"`               // 使用类型
+	FeatureName    string `gorm:"size:100;not null;default:'';uniqueIndex:idx_usage_rollup_bucket" json:"feature_name"` // 功能名称，仅 feature 类型按功能分别汇总
+	Granularity    string `gorm:"size:10;not null;uniqueIndex:idx_usage_rollup_bucket" json:"granularity"`              // 汇总粒度：hour, day
+	BucketStart    int64  `gorm:"not null;uniqueIndex:idx_usage_rollup_bucket;index" json:"bucket_start"`               // 汇总区间开始时间
+	Amount         int64  `gorm:"not null;default:0" json:"amount"`                                                     // 累计使用量
+	Count          int64  `gorm:"not null;default:0" json:"count"`                                                      // 使用记录条数
+}
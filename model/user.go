@@ -9,4 +9,11 @@ type User struct {
 	Password string `gorm:"size:100;not null" json:"-"`            // 密码，不返回给前端
 	Avatar   string `gorm:"size:255" json:"avatar"`                // 头像URL
 	Status   string `gorm:"size:20;default:'active'" json:"status"` // 用户状态：active, inactive, suspended
+	Role     string `gorm:"size:20;default:'user'" json:"role"`     // 平台角色：user, admin，只能通过管理命令修改
 }
+
+// 平台角色，admin 可以访问平台管理接口
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
@@ -0,0 +1,37 @@
+package model
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryPending   = "pending"   // 等待投递或等待重试
+	WebhookDeliverySucceeded = "succeeded" // 投递成功
+	WebhookDeliveryFailed    = "failed"    // 超过最大重试次数，投递失败
+)
+
+// WebhookSubscription Webhook 订阅模型，组织ID为0表示平台级订阅，接收所有组织的事件
+type WebhookSubscription struct {
+	Base
+	OrganizationId int64  `gorm:"not null;index" json:"organization_id"` // 组织ID，0表示平台级订阅
+	URL            string `gorm:"size:500;not null" json:"url"`          // 接收事件的URL
+	EventTypes     string `gorm:"type:jsonb" json:"event_types"`         // 订阅的事件类型，JSON数组，["*"]表示全部
+	Secret         string `gorm:"size:100;not null" json:"-"`            // 签名密钥
+	Description    string `gorm:"size:255" json:"description"`           // 描述
+	Enabled        bool   `gorm:"not null" json:"enabled"`               // 是否启用
+}
+
+// WebhookDelivery Webhook 投递记录模型，记录一个事件向一个订阅的投递及最近一次尝试的结果
+type WebhookDelivery struct {
+	Base
+	SubscriptionId int64  `gorm:"not null;index" json:"subscription_id"`                          // 订阅ID
+	OrganizationId int64  `gorm:"not null;index" json:"organization_id"`                          // 订阅所属组织ID
+	EventId        string `gorm:"size:50;not null;index" json:"event_id"`                         // 事件ID
+	EventType      string `gorm:"size:100;not null" json:"event_type"`                            // 事件类型
+	Payload        string `gorm:"type:text;not null" json:"payload"`                              // 投递的请求体
+	Status         string `gorm:"size:20;not null;index:idx_webhook_delivery_due" json:"status"`  // 投递状态：pending, succeeded, failed
+	Attempts       int    `gorm:"not null;default:0" json:"attempts"`                             // 已尝试次数
+	NextAttemptAt  int64  `gorm:"not null;index:idx_webhook_delivery_due" json:"next_attempt_at"` // 下次尝试时间
+	LastAttemptAt  int64  `gorm:"default:0" json:"last_attempt_at"`                               // 最近一次尝试时间
+	ResponseStatus int    `gorm:"default:0" json:"response_status"`                               // 最近一次响应状态码
+	LastError      string `gorm:"size:500" json:"last_error"`                                     // 最近一次错误信息
+	DurationMs     int64  `gorm:"default:0" json:"duration_ms"`                                   // 最近一次请求耗时（毫秒）
+	RedeliveryOf   int64  `gorm:"default:0" json:"redelivery_of"`                                 // 手动重新投递时为原投递记录ID
+}
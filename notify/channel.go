@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"saas-account/config"
+	"sync"
+)
+
+// Channel 通知渠道，将通知发送到指定的接收地址
+type Channel interface {
+	Send(ctx context.Context, target string, n *Notification) error
+}
+
+var (
+	channels     map[string]Channel
+	channelsMu   sync.RWMutex
+	channelsOnce sync.Once
+)
+
+// initChannels 注册内置通知渠道，webhook 渠道由告警服务使用限制连接地址的客户端注册
+func initChannels() {
+	channelsOnce.Do(func() {
+		cfg := config.GetConfig()
+		channels = map[string]Channel{
+			"email": NewEmailChannel(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom),
+		}
+	})
+}
+
+// GetChannel 根据名称获取通知渠道
+func GetChannel(name string) (Channel, bool) {
+	initChannels()
+
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	ch, ok := channels[name]
+	return ch, ok
+}
+
+// RegisterChannel 注册通知渠道，名称已存在时替换
+func RegisterChannel(name string, ch Channel) {
+	initChannels()
+
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	channels[name] = ch
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"saas-account/logger"
+	"strings"
+)
+
+// EmailChannel 通过SMTP发送邮件通知的渠道，未配置SMTP服务器时只记录日志
+type EmailChannel struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// NewEmailChannel 创建邮件通知渠道
+func NewEmailChannel(host string, port int, username, password, from string) *EmailChannel {
+	return &EmailChannel{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send 将通知发送到 target 指定的邮箱，多个邮箱以逗号分隔
+func (c *EmailChannel) Send(ctx context.Context, target string, n *Notification) error {
+	recipients := strings.Split(target, ",")
+	for i := range recipients {
+		recipients[i] = strings.TrimSpace(recipients[i])
+	}
+
+	if c.host == "" {
+		logger.GetLogger().InfoWithContext(ctx, "邮件[%s] 收件人=%s: %s - %s",
+			n.Type, strings.Join(recipients, ","), n.Subject, n.Content)
+		return nil
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", c.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", n.Subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(n.Content)
+	msg.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if c.username != "" {
+		auth = smtp.PlainAuth("", c.username, c.password, c.host)
+	}
+	addr := fmt.Sprintf("%s:%d", c.host, c.port)
+	return smtp.SendMail(addr, auth, c.from, recipients, []byte(msg.String()))
+}
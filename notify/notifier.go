@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"saas-account/logger"
+	"sync"
+)
+
+// Notification 通知内容
+type Notification struct {
+	Type           string                 // 通知类型，如 subscription.expiring
+	OrganizationId int64                  // 组织ID
+	ApplicationId  int64                  // 组织应用ID
+	Subject        string                 // 标题
+	Content        string                 // 正文
+	Data           map[string]interface{} // 附加数据
+}
+
+// Notifier 通知发送接口
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// LogNotifier 将通知写入日志的通知器
+type LogNotifier struct{}
+
+// NewLogNotifier 创建日志通知器
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify 记录通知
+func (n *LogNotifier) Notify(ctx context.Context, notification *Notification) error {
+	logger.GetLogger().InfoWithContext(ctx, "通知[%s] 组织=%d 应用=%d: %s - %s",
+		notification.Type, notification.OrganizationId, notification.ApplicationId,
+		notification.Subject, notification.Content)
+	return nil
+}
+
+var (
+	notifier   Notifier = NewLogNotifier()
+	notifierMu sync.RWMutex
+)
+
+// GetNotifier 获取全局通知器
+func GetNotifier() Notifier {
+	notifierMu.RLock()
+	defer notifierMu.RUnlock()
+	return notifier
+}
+
+// SetNotifier 设置全局通知器
+func SetNotifier(n Notifier) {
+	notifierMu.Lock()
+	defer notifierMu.Unlock()
+	notifier = n
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookChannel 以 JSON POST 请求发送通知的渠道
+type WebhookChannel struct {
+	client *http.Client
+}
+
+// NewWebhookChannel 创建 Webhook 通知渠道，client 由调用方提供，需要限制可以连接的地址
+func NewWebhookChannel(client *http.Client) *WebhookChannel {
+	return &WebhookChannel{
+		client: client,
+	}
+}
+
+// Send 将通知发送到 target 指定的URL，响应状态码不是2xx时返回错误
+func (c *WebhookChannel) Send(ctx context.Context, target string, n *Notification) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"type":            n.Type,
+		"organization_id": n.OrganizationId,
+		"application_id":  n.ApplicationId,
+		"subject":         n.Subject,
+		"content":         n.Content,
+		"data":            n.Data,
+		"sent_at":         time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"saas-account/logger"
+)
+
+// Broker 消息队列生产者接口，可接入 Kafka、NATS 等，同一 key 的消息需要保持顺序
+type Broker interface {
+	Produce(ctx context.Context, topic, key string, value []byte, headers map[string]string) error
+}
+
+// BrokerSink 将事件发布到消息队列，按事件类型划分主题，以聚合作为消息 key 保证聚合内有序
+type BrokerSink struct {
+	broker      Broker
+	topicPrefix string
+}
+
+// NewBrokerSink 创建消息队列投递目标
+func NewBrokerSink(broker Broker, topicPrefix string) *BrokerSink {
+	return &BrokerSink{
+		broker:      broker,
+		topicPrefix: topicPrefix,
+	}
+}
+
+// Publish 发布事件
+func (s *BrokerSink) Publish(ctx context.Context, msg *Message) error {
+	value, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s:%d", msg.AggregateType, msg.AggregateId)
+	headers := map[string]string{
+		"event_id":   msg.ID,
+		"event_type": msg.Type,
+	}
+	return s.broker.Produce(ctx, s.topicPrefix+msg.Type, key, value, headers)
+}
+
+// LogBroker 只写日志的消息队列替身，未接入消息队列时使用
+type LogBroker struct{}
+
+// NewLogBroker 创建只写日志的消息队列替身
+func NewLogBroker() *LogBroker {
+	return &LogBroker{}
+}
+
+// Produce 写入日志
+func (b *LogBroker) Produce(ctx context.Context, topic, key string, value []byte, headers map[string]string) error {
+	logger.GetLogger().InfoWithContext(ctx, "消息[%s] key=%s 事件=%s: %s", topic, key, headers["event_id"], value)
+	return nil
+}
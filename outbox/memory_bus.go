@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+)
+
+// Handler 内存总线的事件处理函数
+type Handler func(ctx context.Context, msg *Message) error
+
+// MemoryBus 进程内事件总线，同步调用订阅者并保留已发布的事件，用于测试和进程内订阅
+type MemoryBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	messages []Message
+}
+
+// NewMemoryBus 创建进程内事件总线
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+var (
+	memoryBus     *MemoryBus
+	memoryBusOnce sync.Once
+)
+
+// GetMemoryBus 获取全局进程内事件总线
+func GetMemoryBus() *MemoryBus {
+	memoryBusOnce.Do(func() {
+		memoryBus = NewMemoryBus()
+	})
+	return memoryBus
+}
+
+// Subscribe 订阅事件类型，"*" 表示订阅全部事件
+func (b *MemoryBus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish 发布事件，任一订阅者返回错误时整条事件会被中继重试
+func (b *MemoryBus) Publish(ctx context.Context, msg *Message) error {
+	b.mu.Lock()
+	b.messages = append(b.messages, *msg)
+	handlers := append(append([]Handler{}, b.handlers[msg.Type]...), b.handlers["*"]...)
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Messages 获取已发布的事件
+func (b *MemoryBus) Messages() []Message {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]Message{}, b.messages...)
+}
+
+// Reset 清空已发布的事件
+func (b *MemoryBus) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = nil
+}
@@ -0,0 +1,38 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamBroker 基于 Redis Streams 的消息队列，每个主题一个 stream
+// 同一 stream 内按写入顺序消费，中继按聚合顺序发布，因此同一主题内同一 key 的消息保持顺序
+type RedisStreamBroker struct {
+	client redis.UniversalClient
+	maxLen int64
+}
+
+// NewRedisStreamBroker 创建 Redis Streams 消息队列，maxLen 为每个 stream 保留的大约消息数
+func NewRedisStreamBroker(client redis.UniversalClient, maxLen int64) *RedisStreamBroker {
+	return &RedisStreamBroker{
+		client: client,
+		maxLen: maxLen,
+	}
+}
+
+// Produce 将消息追加到主题对应的 stream，消息头作为额外字段写入
+func (b *RedisStreamBroker) Produce(ctx context.Context, topic, key string, value []byte, headers map[string]string) error {
+	values := make([]interface{}, 0, 4+2*len(headers))
+	values = append(values, "key", key, "value", value)
+	for name, header := range headers {
+		values = append(values, name, header)
+	}
+
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: topic,
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+}
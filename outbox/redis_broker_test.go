@@ -0,0 +1,45 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStreamBroker(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	sink := NewBrokerSink(NewRedisStreamBroker(client, 100), "saas-account.")
+	ctx := context.Background()
+	for _, id := range []string{"evt_1", "evt_2"} {
+		msg := &Message{ID: id, Type: "limit.updated", AggregateType: "application", AggregateId: 7}
+		if err := sink.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 同一主题的消息按发布顺序写入同一个 stream
+	entries, err := client.XRange(ctx, "saas-account.limit.updated", "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("消息数 = %d, 期望 2", len(entries))
+	}
+	for i, want := range []string{"evt_1", "evt_2"} {
+		values := entries[i].Values
+		if values["event_id"] != want || values["key"] != "application:7" || values["event_type"] != "limit.updated" {
+			t.Errorf("第%d条消息 = %v", i+1, values)
+		}
+	}
+
+	// Redis 不可用时返回错误，由中继重试
+	server.Close()
+	if err := sink.Publish(ctx, &Message{ID: "evt_3", Type: "limit.updated"}); err == nil {
+		t.Error("Redis不可用时没有返回错误")
+	}
+}
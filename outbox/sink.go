@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"saas-account/config"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Message 发件箱中继发布的领域事件
+type Message struct {
+	ID             string          `json:"id"`              // 事件ID，重复投递时保持不变，接收方可据此去重
+	Type           string          `json:"type"`            // 事件类型
+	AggregateType  string          `json:"aggregate_type"`  // 聚合类型
+	AggregateId    int64           `json:"aggregate_id"`    // 聚合ID，同一聚合的事件按写入顺序发布
+	OrganizationId int64           `json:"organization_id"` // 所属组织ID，0表示不属于任何组织
+	OccurredAt     int64           `json:"occurred_at"`     // 事件发生时间
+	Data           json.RawMessage `json:"data"`            // 事件数据
+}
+
+// Sink 事件投递目标，发布失败时中继会重试，实现需要能处理重复的事件
+type Sink interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+var (
+	sinks     map[string]Sink
+	sinksMu   sync.RWMutex
+	sinksOnce sync.Once
+)
+
+// initSinks 注册内置投递目标
+func initSinks() {
+	sinksOnce.Do(func() {
+		sinks = map[string]Sink{
+			"memory": GetMemoryBus(),
+			"broker": NewBrokerSink(newBroker(), "saas-account."),
+		}
+	})
+}
+
+// newBroker 根据配置创建 broker 投递目标使用的消息队列
+func newBroker() Broker {
+	cfg := config.GetConfig()
+	switch cfg.OutboxBroker {
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		return NewRedisStreamBroker(client, int64(cfg.OutboxStreamMaxLen))
+	case "log":
+		return NewLogBroker()
+	default:
+		log.Printf("未知的消息队列 %q，只写日志", cfg.OutboxBroker)
+		return NewLogBroker()
+	}
+}
+
+// GetSink 根据名称获取投递目标
+func GetSink(name string) (Sink, bool) {
+	initSinks()
+
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	sink, ok := sinks[name]
+	return sink, ok
+}
+
+// RegisterSink 注册投递目标，名称已存在时替换
+func RegisterSink(name string, sink Sink) {
+	initSinks()
+
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	sinks[name] = sink
+}
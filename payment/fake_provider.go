@@ -0,0 +1,77 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"saas-account/utils"
+	"sync"
+)
+
+// FakeProvider 模拟支付渠道，用于开发和测试环境
+type FakeProvider struct {
+	mu       sync.Mutex
+	failures map[int64]bool
+	charges  map[string]*ChargeResult
+	refunds  map[string]int64
+}
+
+// NewFakeProvider 创建模拟支付渠道
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		failures: make(map[int64]bool),
+		charges:  make(map[string]*ChargeResult),
+		refunds:  make(map[string]int64),
+	}
+}
+
+// SetFailure 设置指定应用的扣款是否失败
+func (p *FakeProvider) SetFailure(appID int64, fail bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures[appID] = fail
+}
+
+// Charge 模拟扣款
+func (p *FakeProvider) Charge(ctx context.Context, req *ChargeRequest) (*ChargeResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failures[req.ApplicationId] {
+		return nil, errors.New("模拟扣款失败")
+	}
+
+	// 幂等处理
+	if req.IdempotencyKey != "" {
+		if result, ok := p.charges[req.IdempotencyKey]; ok {
+			return result, nil
+		}
+	}
+
+	result := &ChargeResult{
+		TransactionId: "fake_" + utils.GenerateStringID(),
+		Amount:        req.Amount,
+	}
+	if req.IdempotencyKey != "" {
+		p.charges[req.IdempotencyKey] = result
+	}
+	return result, nil
+}
+
+// Refund 模拟退款，同一交易只能退款一次
+func (p *FakeProvider) Refund(ctx context.Context, req *RefundRequest) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.refunds[req.TransactionId]; ok {
+		return nil
+	}
+	p.refunds[req.TransactionId] = req.Amount
+	return nil
+}
+
+// Refunded 获取交易的退款金额，用于测试
+func (p *FakeProvider) Refunded(transactionID string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.refunds[transactionID]
+}
@@ -0,0 +1,56 @@
+package payment
+
+import (
+	"context"
+	"log"
+	"saas-account/config"
+	"sync"
+)
+
+// ChargeRequest 扣款请求
+type ChargeRequest struct {
+	ApplicationId  int64  // 组织应用ID
+	PlanId         int64  // 套餐ID
+	Amount         int64  // 金额（分）
+	Currency       string // 币种
+	Description    string // 扣款说明
+	IdempotencyKey string // 幂等键，同一键重复扣款只生效一次
+}
+
+// ChargeResult 扣款结果
+type ChargeResult struct {
+	TransactionId string // 支付渠道交易号
+	Amount        int64  // 实际扣款金额（分）
+}
+
+// RefundRequest 退款请求
+type RefundRequest struct {
+	TransactionId  string // 原扣款的支付渠道交易号
+	Amount         int64  // 退款金额（分）
+	IdempotencyKey string // 幂等键，同一键重复退款只生效一次
+}
+
+// Provider 支付渠道接口
+type Provider interface {
+	Charge(ctx context.Context, req *ChargeRequest) (*ChargeResult, error)
+	Refund(ctx context.Context, req *RefundRequest) error
+}
+
+var (
+	provider     Provider
+	providerOnce sync.Once
+)
+
+// GetProvider 获取全局支付渠道实例
+func GetProvider() Provider {
+	providerOnce.Do(func() {
+		switch config.GetConfig().PaymentProvider {
+		case "fake":
+			provider = NewFakeProvider()
+		default:
+			log.Printf("未知的支付渠道 %q，使用fake支付渠道", config.GetConfig().PaymentProvider)
+			provider = NewFakeProvider()
+		}
+	})
+	return provider
+}
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// take 取令牌时依次推进的时间和消耗
+type take struct {
+	after time.Duration // 距离开始的时间
+	cost  int64
+}
+
+// wantResult 取令牌后期望的结果
+type wantResult struct {
+	allowed    bool
+	remaining  int64
+	retryAfter time.Duration
+}
+
+// tokenBucketCases 内存存储和Redis存储共用的令牌桶用例，策略为容量3、每分钟补充60个
+var tokenBucketCases = []struct {
+	name  string
+	takes []take
+	want  []wantResult
+}{
+	{
+		name:  "新桶是满的",
+		takes: []take{{0, 1}},
+		want:  []wantResult{{allowed: true, remaining: 2}},
+	},
+	{
+		name:  "用完后拒绝",
+		takes: []take{{0, 1}, {0, 1}, {0, 1}, {0, 1}},
+		want: []wantResult{
+			{allowed: true, remaining: 2},
+			{allowed: true, remaining: 1},
+			{allowed: true, remaining: 0},
+			{allowed: false, remaining: 0, retryAfter: time.Second},
+		},
+	},
+	{
+		name:  "按时间补充",
+		takes: []take{{0, 3}, {500 * time.Millisecond, 1}, {2 * time.Second, 1}},
+		want: []wantResult{
+			{allowed: true, remaining: 0},
+			{allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond},
+			{allowed: true, remaining: 1},
+		},
+	},
+	{
+		name:  "补充不超过容量",
+		takes: []take{{0, 1}, {time.Hour, 1}},
+		want: []wantResult{
+			{allowed: true, remaining: 2},
+			{allowed: true, remaining: 2},
+		},
+	},
+	{
+		name:  "超过容量的消耗总是拒绝",
+		takes: []take{{0, 4}},
+		want:  []wantResult{{allowed: false, remaining: 3, retryAfter: time.Second}},
+	},
+	{
+		name:  "被拒绝的请求不消耗令牌",
+		takes: []take{{0, 2}, {0, 2}, {0, 1}},
+		want: []wantResult{
+			{allowed: true, remaining: 1},
+			{allowed: false, remaining: 1, retryAfter: time.Second},
+			{allowed: true, remaining: 0},
+		},
+	},
+	{
+		name:  "时钟回拨不补充令牌",
+		takes: []take{{time.Second, 3}, {0, 1}},
+		want: []wantResult{
+			{allowed: true, remaining: 0},
+			{allowed: false, remaining: 0, retryAfter: time.Second},
+		},
+	},
+}
+
+// runTokenBucketCases 对存储执行令牌桶用例，每个用例使用独立的键
+func runTokenBucketCases(t *testing.T, newStore func(t *testing.T) Store) {
+	policy := Policy{Burst: 3, PerMinute: 60}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range tokenBucketCases {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(t)
+			for i, tk := range tt.takes {
+				result, err := store.Take(context.Background(), tt.name, policy, tk.cost, start.Add(tk.after))
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := tt.want[i]
+				if result.Allowed != want.allowed || result.Remaining != want.remaining || result.RetryAfter != want.retryAfter {
+					t.Errorf("第%d次: 允许=%v 剩余=%d 重试=%v, 期望 允许=%v 剩余=%d 重试=%v",
+						i+1, result.Allowed, result.Remaining, result.RetryAfter, want.allowed, want.remaining, want.retryAfter)
+				}
+				if result.Limit != policy.Burst {
+					t.Errorf("第%d次: 容量 = %d, 期望 %d", i+1, result.Limit, policy.Burst)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	runTokenBucketCases(t, func(t *testing.T) Store {
+		return NewMemoryStore(10 * time.Minute)
+	})
+}
+
+// newMiniRedisStore 创建连接到本地替身Redis的令牌桶存储
+func newMiniRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(NewGoRedisClient(client), "ratelimit:"), server
+}
+
+func TestRedisStore(t *testing.T) {
+	runTokenBucketCases(t, func(t *testing.T) Store {
+		store, _ := newMiniRedisStore(t)
+		return store
+	})
+}
+
+func TestRedisStoreSharedAndExpiring(t *testing.T) {
+	store, server := newMiniRedisStore(t)
+	ctx := context.Background()
+	policy := Policy{Burst: 2, PerMinute: 60}
+	now := time.Now()
+
+	// 连接同一个Redis的实例共享令牌桶
+	other := NewRedisStore(NewGoRedisClient(redis.NewClient(&redis.Options{Addr: server.Addr()})), "ratelimit:")
+	if _, err := store.Take(ctx, "app:1", policy, 2, now); err != nil {
+		t.Fatal(err)
+	}
+	result, err := other.Take(ctx, "app:1", policy, 1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed {
+		t.Error("另一个实例取到了已用完的令牌")
+	}
+
+	// 令牌桶补满后过期，不会在Redis中永久保留
+	ttl := server.TTL("ratelimit:app:1")
+	if ttl <= 0 || ttl > 3*time.Second {
+		t.Errorf("过期时间 = %v, 期望补满时间加1秒", ttl)
+	}
+	server.FastForward(ttl)
+	if server.Exists("ratelimit:app:1") {
+		t.Error("令牌桶没有过期")
+	}
+}
+
+func TestRedisStoreUnavailable(t *testing.T) {
+	store, server := newMiniRedisStore(t)
+	server.Close()
+
+	if err := store.Ping(context.Background()); err == nil {
+		t.Error("Redis不可用时 Ping 没有返回错误")
+	}
+	if _, err := store.Take(context.Background(), "app:1", Policy{Burst: 1, PerMinute: 1}, 1, time.Now()); err == nil {
+		t.Error("Redis不可用时没有返回错误")
+	}
+}
+
+func TestSetStore(t *testing.T) {
+	previous := GetStore()
+	t.Cleanup(func() { SetStore(previous) })
+
+	store := NewMemoryStore(time.Minute)
+	SetStore(store)
+	if GetStore() != Store(store) {
+		t.Error("SetStore 之后 GetStore 没有返回新的存储")
+	}
+}
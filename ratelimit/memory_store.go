@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bucket 内存中的令牌桶
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryStore 内存令牌桶存储，适用于单节点部署
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	idleTTL time.Duration
+	sweptAt time.Time
+}
+
+// NewMemoryStore 创建内存令牌桶存储，超过 idleTTL 未使用的令牌桶会被清理
+func NewMemoryStore(idleTTL time.Duration) *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		idleTTL: idleTTL,
+		sweptAt: time.Now(),
+	}
+}
+
+// Ping 内存存储总是可用
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Take 从令牌桶中取出 cost 个令牌，新建的令牌桶是满的
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy, cost int64, now time.Time) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = refill(b.tokens, b.updatedAt, now, policy)
+	if now.After(b.updatedAt) {
+		b.updatedAt = now
+	}
+
+	allowed := b.tokens >= float64(cost)
+	if allowed {
+		b.tokens -= float64(cost)
+	}
+	return newResult(allowed, b.tokens, policy, cost), nil
+}
+
+// sweep 清理长时间未使用的令牌桶，删除的令牌桶下次使用时按满桶重建
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < s.idleTTL {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.updatedAt) >= s.idleTTL {
+			delete(s.buckets, key)
+		}
+	}
+	s.sweptAt = now
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisClient Redis兼容客户端接口，只需要支持 EVAL 和 PING 命令
+type RedisClient interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+	Ping(ctx context.Context) error
+}
+
+// goRedisClient 基于 go-redis 的客户端，优先使用 EVALSHA，脚本未缓存时回退到 EVAL
+type goRedisClient struct {
+	client redis.UniversalClient
+}
+
+// NewGoRedisClient 使用 go-redis 客户端创建 RedisClient
+func NewGoRedisClient(client redis.UniversalClient) RedisClient {
+	return &goRedisClient{client: client}
+}
+
+// Eval 执行Lua脚本
+func (c *goRedisClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return redis.NewScript(script).Run(ctx, c.client, keys, args...).Result()
+}
+
+// Ping 检查Redis是否可以连接
+func (c *goRedisClient) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+// tokenBucketScript 在Redis中原子执行的令牌桶脚本
+// KEYS[1] 令牌桶键，ARGV: 桶容量、每毫秒补充的令牌数、本次消耗、当前时间（毫秒）
+// 返回 {是否允许, 剩余令牌数}，令牌数以字符串返回以保留小数
+const tokenBucketScript = `
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+
+local allowed = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+if rate > 0 then
+	redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+end
+return {allowed, tostring(tokens)}
+`
+
+// RedisStore 基于Redis的令牌桶存储，适用于多节点部署
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore 创建Redis令牌桶存储，prefix 为键前缀
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Take 在Redis中原子地从令牌桶中取出 cost 个令牌
+func (s *RedisStore) Take(ctx context.Context, key string, policy Policy, cost int64, now time.Time) (*Result, error) {
+	ratePerMs := refillRate(policy) * float64(time.Millisecond)
+	reply, err := s.client.Eval(ctx, tokenBucketScript, []string{s.prefix + key},
+		policy.Burst, strconv.FormatFloat(ratePerMs, 'f', -1, 64), cost, now.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, tokens, err := parseTokenBucketReply(reply)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(allowed, tokens, policy, cost), nil
+}
+
+// Ping 检查Redis是否可用
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx)
+}
+
+// parseTokenBucketReply 解析令牌桶脚本的返回值
+func parseTokenBucketReply(reply interface{}) (bool, float64, error) {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("无效的令牌桶脚本返回值: %v", reply)
+	}
+
+	allowed, ok := values[0].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("无效的令牌桶脚本返回值: %v", reply)
+	}
+	tokensStr, ok := values[1].(string)
+	if !ok {
+		return false, 0, fmt.Errorf("无效的令牌桶脚本返回值: %v", reply)
+	}
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return allowed == 1, tokens, nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"saas-account/model"
+	"time"
+)
+
+// AlertRepository 用量告警仓库接口
+type AlertRepository interface {
+	CreateRule(ctx context.Context, rule *model.AlertRule) error
+	GetRule(ctx context.Context, id int64) (*model.AlertRule, error)
+	UpdateRule(ctx context.Context, rule *model.AlertRule) error
+	DeleteRule(ctx context.Context, id int64) error
+	GetRulesByApplication(ctx context.Context, appID int64) ([]model.AlertRule, error)
+	GetEnabledApplicationIDs(ctx context.Context) ([]int64, error)
+	CreateEvent(ctx context.Context, event *model.AlertEvent) (bool, error)
+	GetEventsByApplication(ctx context.Context, appID int64, page, pageSize int) ([]model.AlertEvent, int64, error)
+}
+
+// alertRepository 用量告警仓库实现
+type alertRepository struct{}
+
+// NewAlertRepository 创建用量告警仓库
+func NewAlertRepository() AlertRepository {
+	return &alertRepository{}
+}
+
+// CreateRule 创建告警规则
+func (r *alertRepository) CreateRule(ctx context.Context, rule *model.AlertRule) error {
+	return getDB(ctx).Create(rule).Error
+}
+
+// GetRule 根据ID获取告警规则
+func (r *alertRepository) GetRule(ctx context.Context, id int64) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	err := getDB(ctx).First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule 更新告警规则
+func (r *alertRepository) UpdateRule(ctx context.Context, rule *model.AlertRule) error {
+	return getDB(ctx).Save(rule).Error
+}
+
+// DeleteRule 删除告警规则（软删除）
+func (r *alertRepository) DeleteRule(ctx context.Context, id int64) error {
+	return getDB(ctx).Delete(&model.AlertRule{}, id).Error
+}
+
+// GetRulesByApplication 获取应用的所有告警规则
+func (r *alertRepository) GetRulesByApplication(ctx context.Context, appID int64) ([]model.AlertRule, error) {
+	var rules []model.AlertRule
+	err := getDB(ctx).
+		Where("application_id = ?", appID).
+		Order("id").
+		Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetEnabledApplicationIDs 获取配置了启用中告警规则的应用ID
+func (r *alertRepository) GetEnabledApplicationIDs(ctx context.Context) ([]int64, error) {
+	var ids []int64
+	err := getDB(ctx).Model(&model.AlertRule{}).
+		Where("enabled = ?", true).
+		Distinct().
+		Pluck("application_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// CreateEvent 创建告警记录，同一规则在同一统计周期内已有记录时不创建并返回false
+func (r *alertRepository) CreateEvent(ctx context.Context, event *model.AlertEvent) (bool, error) {
+	now := time.Now().Unix()
+
+	var ids []int64
+	err := getDB(ctx).Raw(`
+		INSERT INTO alert_events (rule_id, application_id, usage_type, period_start, used, quota, threshold, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (rule_id, period_start) DO NOTHING
+		RETURNING id`,
+		event.RuleId, event.ApplicationId, event.UsageType, event.PeriodStart, event.Used, event.Quota, event.Threshold, now, now).
+		Scan(&ids).Error
+	if err != nil {
+		return false, err
+	}
+
+	// 本周期已告警时不返回任何行
+	if len(ids) == 0 {
+		return false, nil
+	}
+	event.ID = ids[0]
+	event.CreatedAt = now
+	event.UpdatedAt = now
+
+	// 更新规则的最近告警时间
+	err = getDB(ctx).Model(&model.AlertRule{}).
+		Where("id = ?", event.RuleId).
+		Update("last_fired_at", now).Error
+	return true, err
+}
+
+// GetEventsByApplication 获取应用的告警记录
+func (r *alertRepository) GetEventsByApplication(ctx context.Context, appID int64, page, pageSize int) ([]model.AlertEvent, int64, error) {
+	var events []model.AlertEvent
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	// 获取总数
+	err := getReadDB(ctx).Model(&model.AlertEvent{}).Where("application_id = ?", appID).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err = getReadDB(ctx).Where("application_id = ?", appID).
+		Order("created_at DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
@@ -4,19 +4,33 @@ import (
 	"context"
 	"time"
 
-	"saas-account/config"
 	"saas-account/model"
+
+	"gorm.io/gorm"
 )
 
+// UsageFilter 使用记录过滤条件，时间区间为 [StartDate, EndDate)
+type UsageFilter struct {
+	ApplicationId  int64  // 组织应用ID，为0表示不限
+	OrganizationId int64  // 组织ID，为0表示不限
+	UsageType      string // 使用类型，为空表示不限
+	UserId         *int64 // 用户ID，为nil表示不限
+	StartDate      int64  // 开始时间（含）
+	EndDate        int64  // 结束时间（不含）
+}
+
 // ApplicationUsageRepository 应用使用记录仓库接口
 type ApplicationUsageRepository interface {
 	Create(ctx context.Context, usage *model.ApplicationUsage) error
+	CreateBatch(ctx context.Context, usages []*model.ApplicationUsage) error
 	GetByID(ctx context.Context, id int64) (*model.ApplicationUsage, error)
 	GetByApplication(ctx context.Context, appID int64, page, pageSize int) ([]model.ApplicationUsage, int64, error)
 	GetByApplicationAndDateRange(ctx context.Context, appID int64, startDate, endDate time.Time, page, pageSize int) ([]model.ApplicationUsage, int64, error)
 	GetByUser(ctx context.Context, userID int64, page, pageSize int) ([]model.ApplicationUsage, int64, error)
 	GetSummaryByApplication(ctx context.Context, appID int64, startDate, endDate time.Time) (map[string]int64, error)
 	Delete(ctx context.Context, id int64) error
+	DeleteBefore(ctx context.Context, before int64) (int64, error)
+	Stream(ctx context.Context, filter UsageFilter, fn func(usage *model.ApplicationUsage) error) error
 }
 
 // applicationUsageRepository 应用使用记录仓库实现
@@ -29,13 +43,18 @@ func NewApplicationUsageRepository() ApplicationUsageRepository {
 
 // Create 创建应用使用记录
 func (r *applicationUsageRepository) Create(ctx context.Context, usage *model.ApplicationUsage) error {
-	return config.DB.Model(&model.ApplicationUsage{}).WithContext(ctx).Create(usage).Error
+	return getDB(ctx).Model(&model.ApplicationUsage{}).Create(usage).Error
+}
+
+// CreateBatch 批量创建应用使用记录
+func (r *applicationUsageRepository) CreateBatch(ctx context.Context, usages []*model.ApplicationUsage) error {
+	return getDB(ctx).CreateInBatches(usages, 500).Error
 }
 
 // GetByID 根据ID获取应用使用记录
 func (r *applicationUsageRepository) GetByID(ctx context.Context, id int64) (*model.ApplicationUsage, error) {
 	var usage model.ApplicationUsage
-	err := config.DB.Model(&model.ApplicationUsage{}).WithContext(ctx).First(&usage, id).Error
+	err := getDB(ctx).Model(&model.ApplicationUsage{}).First(&usage, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -50,13 +69,13 @@ func (r *applicationUsageRepository) GetByApplication(ctx context.Context, appID
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.Model(&model.ApplicationUsage{}).WithContext(ctx).Where("application_id = ?", appID).Count(&total).Error
+	err := getReadDB(ctx).Model(&model.ApplicationUsage{}).Where("application_id = ?", appID).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.Model(&model.ApplicationUsage{}).WithContext(ctx).Where("application_id = ?", appID).
+	err = getReadDB(ctx).Model(&model.ApplicationUsage{}).Where("application_id = ?", appID).
 		Order("usage_date DESC").
 		Offset(offset).Limit(pageSize).
 		Find(&usages).Error
@@ -75,15 +94,15 @@ func (r *applicationUsageRepository) GetByApplicationAndDateRange(ctx context.Co
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.Model(&model.ApplicationUsage{}).WithContext(ctx).
-		Where("application_id = ? AND usage_date BETWEEN ? AND ?", appID, startDate, endDate).
+	err := getReadDB(ctx).Model(&model.ApplicationUsage{}).
+		Where("application_id = ? AND usage_date BETWEEN ? AND ?", appID, startDate.Unix(), endDate.Unix()).
 		Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.Model(&model.ApplicationUsage{}).WithContext(ctx).Where("application_id = ? AND usage_date BETWEEN ? AND ?", appID, startDate, endDate).
+	err = getReadDB(ctx).Model(&model.ApplicationUsage{}).Where("application_id = ? AND usage_date BETWEEN ? AND ?", appID, startDate.Unix(), endDate.Unix()).
 		Order("usage_date DESC").
 		Offset(offset).Limit(pageSize).
 		Find(&usages).Error
@@ -102,14 +121,14 @@ func (r *applicationUsageRepository) GetByUser(ctx context.Context, userID int64
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.Model(&model.ApplicationUsage{}).WithContext(ctx).Where("user_id = ?", userID).Count(&total).Error
+	err := getReadDB(ctx).Model(&model.ApplicationUsage{}).Where("user_id = ?", userID).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.Model(&model.ApplicationUsage{}).
-		WithContext(ctx).Where("user_id = ?", userID).
+	err = getReadDB(ctx).Model(&model.ApplicationUsage{}).
+		Where("user_id = ?", userID).
 		Order("usage_date DESC").
 		Offset(offset).Limit(pageSize).
 		Find(&usages).Error
@@ -128,9 +147,9 @@ func (r *applicationUsageRepository) GetSummaryByApplication(ctx context.Context
 	}
 
 	var results []Result
-	err := config.DB.Model(&model.ApplicationUsage{}).WithContext(ctx).
+	err := getReadDB(ctx).Model(&model.ApplicationUsage{}).
 		Select("usage_type, SUM(usage_amount) as total_amount").
-		Where("application_id = ? AND usage_date BETWEEN ? AND ?", appID, startDate, endDate).
+		Where("application_id = ? AND usage_date BETWEEN ? AND ?", appID, startDate.Unix(), endDate.Unix()).
 		Group("usage_type").
 		Scan(&results).Error
 
@@ -148,5 +167,41 @@ func (r *applicationUsageRepository) GetSummaryByApplication(ctx context.Context
 
 // Delete 删除应用使用记录（软删除）
 func (r *applicationUsageRepository) Delete(ctx context.Context, id int64) error {
-	return config.DB.Model(&model.ApplicationUsage{}).WithContext(ctx).Delete(&model.ApplicationUsage{}, id).Error
+	return getDB(ctx).Model(&model.ApplicationUsage{}).Delete(&model.ApplicationUsage{}, id).Error
+}
+
+// DeleteBefore 彻底删除早于指定时间的使用记录，返回删除条数
+func (r *applicationUsageRepository) DeleteBefore(ctx context.Context, before int64) (int64, error) {
+	result := getDB(ctx).Unscoped().
+		Where("usage_date < ?", before).
+		Delete(&model.ApplicationUsage{})
+	return result.RowsAffected, result.Error
+}
+
+// Stream 按ID顺序分批读取符合条件的使用记录，逐条回调，不会一次性加载全部记录
+func (r *applicationUsageRepository) Stream(ctx context.Context, filter UsageFilter, fn func(usage *model.ApplicationUsage) error) error {
+	query := getReadDB(ctx).Model(&model.ApplicationUsage{}).
+		Where("usage_date >= ? AND usage_date < ?", filter.StartDate, filter.EndDate)
+	if filter.ApplicationId != 0 {
+		query = query.Where("application_id = ?", filter.ApplicationId)
+	}
+	if filter.OrganizationId != 0 {
+		query = query.Where("organization_id = ?", filter.OrganizationId)
+	}
+	if filter.UsageType != "" {
+		query = query.Where("usage_type = ?", filter.UsageType)
+	}
+	if filter.UserId != nil {
+		query = query.Where("user_id = ?", *filter.UserId)
+	}
+
+	var batch []model.ApplicationUsage
+	return query.FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
 }
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"saas-account/model"
+)
+
+// AuditLogFilter 审计日志查询条件，零值表示不限
+type AuditLogFilter struct {
+	OrganizationId int64
+	ActorType      string
+	ActorId        string
+	Action         string
+	ResourceType   string
+	ResourceId     int64
+	From           int64 // 起始时间（含）
+	To             int64 // 结束时间（不含）
+}
+
+// AuditLogRepository 审计日志仓库接口，只提供追加和查询
+type AuditLogRepository interface {
+	LockChain(ctx context.Context, orgID int64, chain string) error
+	GetLast(ctx context.Context, orgID int64, chain string) (*model.AuditLog, error)
+	Create(ctx context.Context, log *model.AuditLog) error
+	Find(ctx context.Context, filter AuditLogFilter, page, pageSize int) ([]model.AuditLog, int64, error)
+	GetChains(ctx context.Context, orgID int64) ([]string, error)
+	GetChain(ctx context.Context, orgID int64, chain string, afterSequence int64, limit int) ([]model.AuditLog, error)
+}
+
+// auditLogRepository 审计日志仓库实现
+type auditLogRepository struct{}
+
+// NewAuditLogRepository 创建审计日志仓库
+func NewAuditLogRepository() AuditLogRepository {
+	return &auditLogRepository{}
+}
+
+// LockChain 锁定审计日志链直到事务结束，需在事务中调用，用于串行化同一日志链的追加
+func (r *auditLogRepository) LockChain(ctx context.Context, orgID int64, chain string) error {
+	key := fmt.Sprintf("audit_log:%d", orgID)
+	if chain != "" {
+		key += ":" + chain
+	}
+	return getDB(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+}
+
+// GetLast 获取日志链的最后一条审计日志，没有日志时返回nil
+func (r *auditLogRepository) GetLast(ctx context.Context, orgID int64, chain string) (*model.AuditLog, error) {
+	var logs []model.AuditLog
+	err := getDB(ctx).Where("organization_id = ? AND chain = ?", orgID, chain).Order("sequence DESC").Limit(1).Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	return &logs[0], nil
+}
+
+// Create 追加审计日志
+func (r *auditLogRepository) Create(ctx context.Context, log *model.AuditLog) error {
+	return getDB(ctx).Create(log).Error
+}
+
+// Find 按条件分页查询审计日志，按时间倒序
+func (r *auditLogRepository) Find(ctx context.Context, filter AuditLogFilter, page, pageSize int) ([]model.AuditLog, int64, error) {
+	var logs []model.AuditLog
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := getReadDB(ctx).Model(&model.AuditLog{}).Where("organization_id = ?", filter.OrganizationId)
+	if filter.ActorType != "" {
+		query = query.Where("actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorId != "" {
+		query = query.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceId != 0 {
+		query = query.Where("resource_id = ?", filter.ResourceId)
+	}
+	if filter.From != 0 {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if filter.To != 0 {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	// 平台级日志分布在多条日志链中，按时间而不是序号排序
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// GetChains 获取组织的所有审计日志链
+func (r *auditLogRepository) GetChains(ctx context.Context, orgID int64) ([]string, error) {
+	var chains []string
+	err := getDB(ctx).Model(&model.AuditLog{}).
+		Where("organization_id = ?", orgID).
+		Distinct("chain").Order("chain").
+		Pluck("chain", &chains).Error
+	if err != nil {
+		return nil, err
+	}
+	return chains, nil
+}
+
+// GetChain 按序号顺序获取日志链在 afterSequence 之后的审计日志，用于分批校验哈希链
+func (r *auditLogRepository) GetChain(ctx context.Context, orgID int64, chain string, afterSequence int64, limit int) ([]model.AuditLog, error) {
+	var logs []model.AuditLog
+	err := getDB(ctx).
+		Where("organization_id = ? AND chain = ? AND sequence > ?", orgID, chain, afterSequence).
+		Order("sequence").
+		Limit(limit).
+		Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
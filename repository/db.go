@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"saas-account/config"
+
+	"gorm.io/gorm"
+)
+
+// txKey 上下文中保存事务的键
+type txKey struct{}
+
+// primaryKey 上下文中标记只读查询也使用主库的键
+type primaryKey struct{}
+
+// getDB 获取主库连接，上下文中有事务时使用该事务，所有仓库都通过它访问数据库
+func getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return config.DB.WithContext(ctx)
+}
+
+// getReadDB 获取只读查询的连接，列表和统计等可以容忍副本延迟的查询使用
+// 上下文中有事务或通过 WithPrimary 要求读己之写时使用主库，否则使用只读副本
+func getReadDB(ctx context.Context) *gorm.DB {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return getDB(ctx)
+	}
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return getDB(ctx)
+	}
+	return config.ReadDB().WithContext(ctx)
+}
+
+// WithPrimary 返回只读查询也使用主库的上下文，用于需要读到刚写入数据（读己之写）的请求
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// TransactionManager 事务管理器，通过上下文传递事务，使多个仓库的操作在同一个事务中执行
+type TransactionManager interface {
+	// Transaction 在事务中执行 fn，fn 内使用传入的 ctx 调用的仓库操作都在该事务中执行
+	// 已处于事务中时复用外层事务，fn 返回错误或发生 panic 时回滚
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// transactionManager 基于 GORM 的事务管理器实现
+type transactionManager struct{}
+
+// NewTransactionManager 创建事务管理器
+func NewTransactionManager() TransactionManager {
+	return &transactionManager{}
+}
+
+// Transaction 在事务中执行 fn
+func (m *transactionManager) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	return config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"saas-account/model"
+
+	"gorm.io/gorm"
+)
+
+// InvoiceRepository 账单仓库接口
+type InvoiceRepository interface {
+	Create(ctx context.Context, invoice *model.Invoice) error
+	GetByID(ctx context.Context, id int64) (*model.Invoice, error)
+	GetByPeriod(ctx context.Context, orgID int64, periodStart int64) (*model.Invoice, error)
+	ListByOrganization(ctx context.Context, orgID int64, page, pageSize int) ([]model.Invoice, int64, error)
+	Update(ctx context.Context, invoice *model.Invoice) error
+	ReplaceLineItems(ctx context.Context, invoiceID int64, items []model.InvoiceLineItem) error
+}
+
+// invoiceRepository 账单仓库实现
+type invoiceRepository struct{}
+
+// NewInvoiceRepository 创建账单仓库
+func NewInvoiceRepository() InvoiceRepository {
+	return &invoiceRepository{}
+}
+
+// Create 创建账单，同时创建账单明细
+func (r *invoiceRepository) Create(ctx context.Context, invoice *model.Invoice) error {
+	return getDB(ctx).Create(invoice).Error
+}
+
+// GetByID 根据ID获取账单及其明细
+func (r *invoiceRepository) GetByID(ctx context.Context, id int64) (*model.Invoice, error) {
+	var invoice model.Invoice
+	err := getDB(ctx).
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&invoice, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetByPeriod 获取组织在指定计费周期的账单
+func (r *invoiceRepository) GetByPeriod(ctx context.Context, orgID int64, periodStart int64) (*model.Invoice, error) {
+	var invoice model.Invoice
+	err := getDB(ctx).
+		Where("organization_id = ? AND period_start = ?", orgID, periodStart).
+		First(&invoice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// ListByOrganization 获取组织的账单列表，不包含明细
+func (r *invoiceRepository) ListByOrganization(ctx context.Context, orgID int64, page, pageSize int) ([]model.Invoice, int64, error) {
+	var invoices []model.Invoice
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	// 获取总数
+	err := getReadDB(ctx).Model(&model.Invoice{}).Where("organization_id = ?", orgID).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err = getReadDB(ctx).Where("organization_id = ?", orgID).
+		Order("period_start DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&invoices).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return invoices, total, nil
+}
+
+// Update 更新账单，不更新明细
+func (r *invoiceRepository) Update(ctx context.Context, invoice *model.Invoice) error {
+	return getDB(ctx).Omit("LineItems").Save(invoice).Error
+}
+
+// ReplaceLineItems 彻底删除账单的原有明细并写入新明细
+func (r *invoiceRepository) ReplaceLineItems(ctx context.Context, invoiceID int64, items []model.InvoiceLineItem) error {
+	return getDB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("invoice_id = ?", invoiceID).Delete(&model.InvoiceLineItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		for i := range items {
+			items[i].InvoiceId = invoiceID
+		}
+		return tx.Create(&items).Error
+	})
+}
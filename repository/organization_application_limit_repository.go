@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"saas-account/config"
 	"saas-account/model"
 )
 
@@ -14,6 +13,8 @@ type OrganizationApplicationLimitRepository interface {
 	List(ctx context.Context, page, pageSize int) ([]model.OrganizationApplicationLimit, int64, error)
 	Update(ctx context.Context, limit *model.OrganizationApplicationLimit) error
 	Delete(ctx context.Context, id int64) error
+	GetExpiringUnnotified(ctx context.Context, from, to int64) ([]model.OrganizationApplicationLimit, error)
+	GetExpired(ctx context.Context, now int64) ([]model.OrganizationApplicationLimit, error)
 }
 
 // organizationApplicationLimitRepository 组织应用限制仓库实现
@@ -26,13 +27,13 @@ func NewOrganizationApplicationLimitRepository() OrganizationApplicationLimitRep
 
 // Create 创建组织应用限制
 func (r *organizationApplicationLimitRepository) Create(ctx context.Context, limit *model.OrganizationApplicationLimit) error {
-	return config.DB.WithContext(ctx).Create(limit).Error
+	return getDB(ctx).Create(limit).Error
 }
 
 // GetByID 根据ID获取组织应用限制
 func (r *organizationApplicationLimitRepository) GetByID(ctx context.Context, id int64) (*model.OrganizationApplicationLimit, error) {
 	var limit model.OrganizationApplicationLimit
-	err := config.DB.WithContext(ctx).First(&limit, id).Error
+	err := getDB(ctx).First(&limit, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +43,7 @@ func (r *organizationApplicationLimitRepository) GetByID(ctx context.Context, id
 // GetByApplicationID 根据应用ID获取组织应用限制
 func (r *organizationApplicationLimitRepository) GetByApplicationID(ctx context.Context, appID int64) (*model.OrganizationApplicationLimit, error) {
 	var limit model.OrganizationApplicationLimit
-	err := config.DB.WithContext(ctx).Where("application_id = ?", appID).First(&limit).Error
+	err := getDB(ctx).Where("organization_application_id = ?", appID).First(&limit).Error
 	if err != nil {
 		return nil, err
 	}
@@ -57,13 +58,13 @@ func (r *organizationApplicationLimitRepository) List(ctx context.Context, page,
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.WithContext(ctx).Model(&model.OrganizationApplicationLimit{}).Count(&total).Error
+	err := getReadDB(ctx).Model(&model.OrganizationApplicationLimit{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.WithContext(ctx).Offset(offset).Limit(pageSize).Find(&limits).Error
+	err = getReadDB(ctx).Offset(offset).Limit(pageSize).Find(&limits).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -73,10 +74,34 @@ func (r *organizationApplicationLimitRepository) List(ctx context.Context, page,
 
 // Update 更新组织应用限制
 func (r *organizationApplicationLimitRepository) Update(ctx context.Context, limit *model.OrganizationApplicationLimit) error {
-	return config.DB.WithContext(ctx).Save(limit).Error
+	return getDB(ctx).Save(limit).Error
 }
 
 // Delete 删除组织应用限制（软删除）
 func (r *organizationApplicationLimitRepository) Delete(ctx context.Context, id int64) error {
-	return config.DB.WithContext(ctx).Delete(&model.OrganizationApplicationLimit{}, id).Error
+	return getDB(ctx).Delete(&model.OrganizationApplicationLimit{}, id).Error
+}
+
+// GetExpiringUnnotified 获取在指定时间段内到期且尚未发送到期提醒的应用限制
+func (r *organizationApplicationLimitRepository) GetExpiringUnnotified(ctx context.Context, from, to int64) ([]model.OrganizationApplicationLimit, error) {
+	var limits []model.OrganizationApplicationLimit
+	err := getDB(ctx).
+		Where("expires_at > ? AND expires_at <= ? AND expiry_notified_at = 0", from, to).
+		Find(&limits).Error
+	if err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// GetExpired 获取已到期的应用限制（expires_at为0表示永不过期）
+func (r *organizationApplicationLimitRepository) GetExpired(ctx context.Context, now int64) ([]model.OrganizationApplicationLimit, error) {
+	var limits []model.OrganizationApplicationLimit
+	err := getDB(ctx).
+		Where("expires_at > 0 AND expires_at <= ?", now).
+		Find(&limits).Error
+	if err != nil {
+		return nil, err
+	}
+	return limits, nil
 }
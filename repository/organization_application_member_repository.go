@@ -2,20 +2,22 @@ package repository
 
 import (
 	"context"
-	"saas-account/config"
 	"saas-account/model"
+	"time"
 )
 
 // OrganizationApplicationMemberRepository 组织应用成员仓库接口
 type OrganizationApplicationMemberRepository interface {
 	Create(ctx context.Context, member *model.OrganizationApplicationMember) error
-	GetByID(ctx context.Context, id uint) (*model.OrganizationApplicationMember, error)
-	GetByApplicationAndUser(ctx context.Context, appID, userID uint) (*model.OrganizationApplicationMember, error)
-	GetByApplication(ctx context.Context, appID uint, page, pageSize int) ([]model.OrganizationApplicationMember, int64, error)
-	GetByUser(ctx context.Context, userID uint) ([]model.OrganizationApplicationMember, error)
+	GetByID(ctx context.Context, id int64) (*model.OrganizationApplicationMember, error)
+	GetByApplicationAndUser(ctx context.Context, appID, userID int64) (*model.OrganizationApplicationMember, error)
+	GetByApplication(ctx context.Context, appID int64, page, pageSize int) ([]model.OrganizationApplicationMember, int64, error)
+	GetByApplicationBetween(ctx context.Context, appID int64, start, end time.Time) ([]model.OrganizationApplicationMember, error)
+	GetByUser(ctx context.Context, userID int64) ([]model.OrganizationApplicationMember, error)
 	Update(ctx context.Context, member *model.OrganizationApplicationMember) error
-	Delete(ctx context.Context, id uint) error
-	DeleteByApplicationAndUser(ctx context.Context, appID, userID uint) error
+	Delete(ctx context.Context, id int64) error
+	DeleteByApplicationAndUser(ctx context.Context, appID, userID int64) error
+	PurgeByUsers(ctx context.Context, userIDs []int64) error
 }
 
 // organizationApplicationMemberRepository 组织应用成员仓库实现
@@ -28,13 +30,13 @@ func NewOrganizationApplicationMemberRepository() OrganizationApplicationMemberR
 
 // Create 创建组织应用成员
 func (r *organizationApplicationMemberRepository) Create(ctx context.Context, member *model.OrganizationApplicationMember) error {
-	return config.DB.WithContext(ctx).Create(member).Error
+	return getDB(ctx).Create(member).Error
 }
 
 // GetByID 根据ID获取组织应用成员
-func (r *organizationApplicationMemberRepository) GetByID(ctx context.Context, id uint) (*model.OrganizationApplicationMember, error) {
+func (r *organizationApplicationMemberRepository) GetByID(ctx context.Context, id int64) (*model.OrganizationApplicationMember, error) {
 	var member model.OrganizationApplicationMember
-	err := config.DB.WithContext(ctx).First(&member, id).Error
+	err := getDB(ctx).First(&member, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -42,9 +44,9 @@ func (r *organizationApplicationMemberRepository) GetByID(ctx context.Context, i
 }
 
 // GetByApplicationAndUser 根据应用ID和用户ID获取组织应用成员
-func (r *organizationApplicationMemberRepository) GetByApplicationAndUser(ctx context.Context, appID, userID uint) (*model.OrganizationApplicationMember, error) {
+func (r *organizationApplicationMemberRepository) GetByApplicationAndUser(ctx context.Context, appID, userID int64) (*model.OrganizationApplicationMember, error) {
 	var member model.OrganizationApplicationMember
-	err := config.DB.WithContext(ctx).Where("organization_application_id = ? AND user_id = ?", appID, userID).First(&member).Error
+	err := getDB(ctx).Where("application_id = ? AND member_id = ?", appID, userID).First(&member).Error
 	if err != nil {
 		return nil, err
 	}
@@ -52,20 +54,20 @@ func (r *organizationApplicationMemberRepository) GetByApplicationAndUser(ctx co
 }
 
 // GetByApplication 根据应用ID获取成员列表
-func (r *organizationApplicationMemberRepository) GetByApplication(ctx context.Context, appID uint, page, pageSize int) ([]model.OrganizationApplicationMember, int64, error) {
+func (r *organizationApplicationMemberRepository) GetByApplication(ctx context.Context, appID int64, page, pageSize int) ([]model.OrganizationApplicationMember, int64, error) {
 	var members []model.OrganizationApplicationMember
 	var total int64
 
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.WithContext(ctx).Model(&model.OrganizationApplicationMember{}).Where("organization_application_id = ?", appID).Count(&total).Error
+	err := getReadDB(ctx).Model(&model.OrganizationApplicationMember{}).Where("application_id = ?", appID).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.WithContext(ctx).Where("organization_application_id = ?", appID).
+	err = getReadDB(ctx).Where("application_id = ?", appID).
 		Offset(offset).Limit(pageSize).
 		Find(&members).Error
 	if err != nil {
@@ -75,10 +77,24 @@ func (r *organizationApplicationMemberRepository) GetByApplication(ctx context.C
 	return members, total, nil
 }
 
+// GetByApplicationBetween 获取在区间 [start, end) 内曾是应用成员的用户，包括已移除的成员
+func (r *organizationApplicationMemberRepository) GetByApplicationBetween(ctx context.Context, appID int64, start, end time.Time) ([]model.OrganizationApplicationMember, error) {
+	var members []model.OrganizationApplicationMember
+	err := getReadDB(ctx).Unscoped().
+		Where("application_id = ? AND created_at < ?", appID, end.Unix()).
+		Where("deleted_at IS NULL OR deleted_at >= ?", start).
+		Order("id").
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
 // GetByUser 根据用户ID获取所属应用列表
-func (r *organizationApplicationMemberRepository) GetByUser(ctx context.Context, userID uint) ([]model.OrganizationApplicationMember, error) {
+func (r *organizationApplicationMemberRepository) GetByUser(ctx context.Context, userID int64) ([]model.OrganizationApplicationMember, error) {
 	var members []model.OrganizationApplicationMember
-	err := config.DB.WithContext(ctx).Where("user_id = ?", userID).Find(&members).Error
+	err := getDB(ctx).Where("member_id = ?", userID).Find(&members).Error
 	if err != nil {
 		return nil, err
 	}
@@ -87,16 +103,25 @@ func (r *organizationApplicationMemberRepository) GetByUser(ctx context.Context,
 
 // Update 更新组织应用成员
 func (r *organizationApplicationMemberRepository) Update(ctx context.Context, member *model.OrganizationApplicationMember) error {
-	return config.DB.WithContext(ctx).Save(member).Error
+	return getDB(ctx).Save(member).Error
 }
 
 // Delete 删除组织应用成员（软删除）
-func (r *organizationApplicationMemberRepository) Delete(ctx context.Context, id uint) error {
-	return config.DB.WithContext(ctx).Delete(&model.OrganizationApplicationMember{}, id).Error
+func (r *organizationApplicationMemberRepository) Delete(ctx context.Context, id int64) error {
+	return getDB(ctx).Delete(&model.OrganizationApplicationMember{}, id).Error
 }
 
 // DeleteByApplicationAndUser 根据应用ID和用户ID删除组织应用成员
-func (r *organizationApplicationMemberRepository) DeleteByApplicationAndUser(ctx context.Context, appID, userID uint) error {
-	return config.DB.WithContext(ctx).Where("organization_application_id = ? AND user_id = ?", appID, userID).
+func (r *organizationApplicationMemberRepository) DeleteByApplicationAndUser(ctx context.Context, appID, userID int64) error {
+	return getDB(ctx).Where("application_id = ? AND member_id = ?", appID, userID).
+		Delete(&model.OrganizationApplicationMember{}).Error
+}
+
+// PurgeByUsers 彻底删除用户在所有应用中的成员身份
+func (r *organizationApplicationMemberRepository) PurgeByUsers(ctx context.Context, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return getDB(ctx).Unscoped().Where("member_id IN ?", userIDs).
 		Delete(&model.OrganizationApplicationMember{}).Error
 }
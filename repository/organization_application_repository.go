@@ -2,19 +2,51 @@ package repository
 
 import (
 	"context"
-	"saas-account/config"
+	"time"
+
 	"saas-account/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // OrganizationApplicationRepository 组织应用仓库接口
 type OrganizationApplicationRepository interface {
 	Create(ctx context.Context, app *model.OrganizationApplication) error
 	GetByID(ctx context.Context, id int64) (*model.OrganizationApplication, error)
+	GetByIDForUpdate(ctx context.Context, id int64) (*model.OrganizationApplication, error)
 	GetByAppKey(ctx context.Context, appKey string) (*model.OrganizationApplication, error)
 	GetByOrganization(ctx context.Context, orgID int64, page, pageSize int) ([]model.OrganizationApplication, int64, error)
+	GetBillableByOrganization(ctx context.Context, orgID int64, since time.Time) ([]model.OrganizationApplication, error)
 	List(ctx context.Context, page, pageSize int) ([]model.OrganizationApplication, int64, error)
 	Update(ctx context.Context, app *model.OrganizationApplication) error
 	Delete(ctx context.Context, id int64) error
+	DeleteByOrganization(ctx context.Context, orgID int64) error
+	ListDeleted(ctx context.Context, page, pageSize int) ([]model.OrganizationApplication, int64, error)
+	GetDeletedByID(ctx context.Context, id int64) (*model.OrganizationApplication, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, id int64) error
+	PurgeDeletedBefore(ctx context.Context, before time.Time) ([]int64, error)
+	PurgeByOrganizations(ctx context.Context, orgIDs []int64) ([]int64, error)
+	PurgeData(ctx context.Context, ids []int64) error
+}
+
+// applicationData 属于组织应用的数据表及其应用ID列，彻底删除应用时一起删除
+// 账单、套餐变更和支付记录需要保留，不在其中
+var applicationData = []struct {
+	model  interface{}
+	column string
+}{
+	{&model.OrganizationApplicationLimit{}, "organization_application_id"},
+	{&model.OrganizationApplicationMember{}, "application_id"},
+	{&model.ApplicationUsage{}, "application_id"},
+	{&model.UsageRollup{}, "application_id"},
+	{&model.UsageCounter{}, "application_id"},
+	{&model.UsageIdempotencyKey{}, "application_id"},
+	{&model.StorageGauge{}, "application_id"},
+	{&model.StorageSnapshot{}, "application_id"},
+	{&model.AlertRule{}, "application_id"},
+	{&model.AlertEvent{}, "application_id"},
 }
 
 // organizationApplicationRepository 组织应用仓库实现
@@ -27,13 +59,23 @@ func NewOrganizationApplicationRepository() OrganizationApplicationRepository {
 
 // Create 创建组织应用
 func (r *organizationApplicationRepository) Create(ctx context.Context, app *model.OrganizationApplication) error {
-	return config.DB.WithContext(ctx).Create(app).Error
+	return getDB(ctx).Create(app).Error
 }
 
 // GetByID 根据ID获取组织应用
 func (r *organizationApplicationRepository) GetByID(ctx context.Context, id int64) (*model.OrganizationApplication, error) {
 	var app model.OrganizationApplication
-	err := config.DB.WithContext(ctx).First(&app, id).Error
+	err := getDB(ctx).First(&app, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// GetByIDForUpdate 根据ID获取组织应用并加行锁，需在事务中调用，用于串行化同一应用的并发修改
+func (r *organizationApplicationRepository) GetByIDForUpdate(ctx context.Context, id int64) (*model.OrganizationApplication, error) {
+	var app model.OrganizationApplication
+	err := getDB(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&app, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +85,7 @@ func (r *organizationApplicationRepository) GetByID(ctx context.Context, id int6
 // GetByAppKey 根据AppKey获取组织应用
 func (r *organizationApplicationRepository) GetByAppKey(ctx context.Context, appKey string) (*model.OrganizationApplication, error) {
 	var app model.OrganizationApplication
-	err := config.DB.WithContext(ctx).Where("app_key = ?", appKey).First(&app).Error
+	err := getDB(ctx).Where("app_key = ?", appKey).First(&app).Error
 	if err != nil {
 		return nil, err
 	}
@@ -58,13 +100,13 @@ func (r *organizationApplicationRepository) GetByOrganization(ctx context.Contex
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.WithContext(ctx).Model(&model.OrganizationApplication{}).Where("organization_id = ?", orgID).Count(&total).Error
+	err := getReadDB(ctx).Model(&model.OrganizationApplication{}).Where("organization_id = ?", orgID).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.WithContext(ctx).Where("organization_id = ?", orgID).
+	err = getReadDB(ctx).Where("organization_id = ?", orgID).
 		Offset(offset).Limit(pageSize).
 		Find(&apps).Error
 	if err != nil {
@@ -74,6 +116,19 @@ func (r *organizationApplicationRepository) GetByOrganization(ctx context.Contex
 	return apps, total, nil
 }
 
+// GetBillableByOrganization 获取组织下需要计费的应用，包括在指定时间之后才删除的应用
+func (r *organizationApplicationRepository) GetBillableByOrganization(ctx context.Context, orgID int64, since time.Time) ([]model.OrganizationApplication, error) {
+	var apps []model.OrganizationApplication
+	err := getReadDB(ctx).Unscoped().
+		Where("organization_id = ? AND (deleted_at IS NULL OR deleted_at >= ?)", orgID, since).
+		Order("id").
+		Find(&apps).Error
+	if err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
 // List 获取应用列表
 func (r *organizationApplicationRepository) List(ctx context.Context, page, pageSize int) ([]model.OrganizationApplication, int64, error) {
 	var apps []model.OrganizationApplication
@@ -82,13 +137,13 @@ func (r *organizationApplicationRepository) List(ctx context.Context, page, page
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.WithContext(ctx).Model(&model.OrganizationApplication{}).Count(&total).Error
+	err := getReadDB(ctx).Model(&model.OrganizationApplication{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.WithContext(ctx).Offset(offset).Limit(pageSize).Find(&apps).Error
+	err = getReadDB(ctx).Offset(offset).Limit(pageSize).Find(&apps).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -98,10 +153,121 @@ func (r *organizationApplicationRepository) List(ctx context.Context, page, page
 
 // Update 更新组织应用
 func (r *organizationApplicationRepository) Update(ctx context.Context, app *model.OrganizationApplication) error {
-	return config.DB.WithContext(ctx).Save(app).Error
+	return getDB(ctx).Save(app).Error
 }
 
 // Delete 删除组织应用（软删除）
 func (r *organizationApplicationRepository) Delete(ctx context.Context, id int64) error {
-	return config.DB.WithContext(ctx).Delete(&model.OrganizationApplication{}, id).Error
+	return getDB(ctx).Delete(&model.OrganizationApplication{}, id).Error
+}
+
+// DeleteByOrganization 删除组织下的所有应用（软删除）
+func (r *organizationApplicationRepository) DeleteByOrganization(ctx context.Context, orgID int64) error {
+	return getDB(ctx).Where("organization_id = ?", orgID).Delete(&model.OrganizationApplication{}).Error
+}
+
+// ListDeleted 获取已软删除的组织应用列表
+func (r *organizationApplicationRepository) ListDeleted(ctx context.Context, page, pageSize int) ([]model.OrganizationApplication, int64, error) {
+	var apps []model.OrganizationApplication
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	// 获取总数
+	err := getReadDB(ctx).Unscoped().Model(&model.OrganizationApplication{}).Where("deleted_at IS NOT NULL").Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err = getReadDB(ctx).Unscoped().Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&apps).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return apps, total, nil
+}
+
+// GetDeletedByID 根据ID获取已软删除的组织应用
+func (r *organizationApplicationRepository) GetDeletedByID(ctx context.Context, id int64) (*model.OrganizationApplication, error) {
+	var app model.OrganizationApplication
+	err := getDB(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&app, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// Restore 恢复已软删除的组织应用
+func (r *organizationApplicationRepository) Restore(ctx context.Context, id int64) error {
+	result := getDB(ctx).Unscoped().Model(&model.OrganizationApplication{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge 彻底删除已软删除的组织应用
+func (r *organizationApplicationRepository) Purge(ctx context.Context, id int64) error {
+	result := getDB(ctx).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Delete(&model.OrganizationApplication{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedBefore 彻底删除在指定时间之前软删除的组织应用，返回删除的应用ID
+func (r *organizationApplicationRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	var apps []model.OrganizationApplication
+	err := getDB(ctx).Unscoped().Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Delete(&apps).Error
+	return applicationIDs(apps), err
+}
+
+// PurgeByOrganizations 彻底删除组织的全部应用（包括未删除的），返回删除的应用ID
+func (r *organizationApplicationRepository) PurgeByOrganizations(ctx context.Context, orgIDs []int64) ([]int64, error) {
+	if len(orgIDs) == 0 {
+		return nil, nil
+	}
+	var apps []model.OrganizationApplication
+	err := getDB(ctx).Unscoped().Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("organization_id IN ?", orgIDs).
+		Delete(&apps).Error
+	return applicationIDs(apps), err
+}
+
+// PurgeData 彻底删除属于组织应用的限制、成员、使用量、计数器、存储和告警数据
+func (r *organizationApplicationRepository) PurgeData(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, data := range applicationData {
+		if err := getDB(ctx).Unscoped().Where(data.column+" IN ?", ids).Delete(data.model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applicationIDs 获取应用ID列表
+func applicationIDs(apps []model.OrganizationApplication) []int64 {
+	ids := make([]int64, 0, len(apps))
+	for _, app := range apps {
+		ids = append(ids, app.ID)
+	}
+	return ids
 }
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"saas-account/config"
 	"saas-account/model"
 )
 
@@ -16,6 +15,8 @@ type OrganizationMemberRepository interface {
 	Update(ctx context.Context, member *model.OrganizationMember) error
 	Delete(ctx context.Context, id int64) error
 	DeleteByOrganizationAndUser(ctx context.Context, orgID, userID int64) error
+	PurgeByOrganizations(ctx context.Context, orgIDs []int64) error
+	PurgeByUsers(ctx context.Context, userIDs []int64) error
 }
 
 // organizationMemberRepository 组织成员仓库实现
@@ -28,13 +29,13 @@ func NewOrganizationMemberRepository() OrganizationMemberRepository {
 
 // Create 创建组织成员
 func (r *organizationMemberRepository) Create(ctx context.Context, member *model.OrganizationMember) error {
-	return config.DB.WithContext(ctx).Create(member).Error
+	return getDB(ctx).Create(member).Error
 }
 
 // GetByID 根据ID获取组织成员
 func (r *organizationMemberRepository) GetByID(ctx context.Context, id int64) (*model.OrganizationMember, error) {
 	var member model.OrganizationMember
-	err := config.DB.WithContext(ctx).First(&member, id).Error
+	err := getDB(ctx).First(&member, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +45,7 @@ func (r *organizationMemberRepository) GetByID(ctx context.Context, id int64) (*
 // GetByOrganizationAndUser 根据组织ID和用户ID获取组织成员
 func (r *organizationMemberRepository) GetByOrganizationAndUser(ctx context.Context, orgID, userID int64) (*model.OrganizationMember, error) {
 	var member model.OrganizationMember
-	err := config.DB.WithContext(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	err := getDB(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error
 	if err != nil {
 		return nil, err
 	}
@@ -59,13 +60,13 @@ func (r *organizationMemberRepository) GetByOrganization(ctx context.Context, or
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.WithContext(ctx).Model(&model.OrganizationMember{}).Where("organization_id = ?", orgID).Count(&total).Error
+	err := getReadDB(ctx).Model(&model.OrganizationMember{}).Where("organization_id = ?", orgID).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.WithContext(ctx).Where("organization_id = ?", orgID).
+	err = getReadDB(ctx).Where("organization_id = ?", orgID).
 		Offset(offset).Limit(pageSize).
 		Find(&members).Error
 	if err != nil {
@@ -78,7 +79,7 @@ func (r *organizationMemberRepository) GetByOrganization(ctx context.Context, or
 // GetByUser 根据用户ID获取所属组织列表
 func (r *organizationMemberRepository) GetByUser(ctx context.Context, userID int64) ([]model.OrganizationMember, error) {
 	var members []model.OrganizationMember
-	err := config.DB.WithContext(ctx).Where("user_id = ?", userID).Find(&members).Error
+	err := getDB(ctx).Where("user_id = ?", userID).Find(&members).Error
 	if err != nil {
 		return nil, err
 	}
@@ -87,16 +88,34 @@ func (r *organizationMemberRepository) GetByUser(ctx context.Context, userID int
 
 // Update 更新组织成员
 func (r *organizationMemberRepository) Update(ctx context.Context, member *model.OrganizationMember) error {
-	return config.DB.WithContext(ctx).Save(member).Error
+	return getDB(ctx).Save(member).Error
 }
 
 // Delete 删除组织成员（软删除）
 func (r *organizationMemberRepository) Delete(ctx context.Context, id int64) error {
-	return config.DB.WithContext(ctx).Delete(&model.OrganizationMember{}, id).Error
+	return getDB(ctx).Delete(&model.OrganizationMember{}, id).Error
 }
 
 // DeleteByOrganizationAndUser 根据组织ID和用户ID删除组织成员
 func (r *organizationMemberRepository) DeleteByOrganizationAndUser(ctx context.Context, orgID, userID int64) error {
-	return config.DB.WithContext(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).
+	return getDB(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).
+		Delete(&model.OrganizationMember{}).Error
+}
+
+// PurgeByOrganizations 彻底删除组织的全部成员
+func (r *organizationMemberRepository) PurgeByOrganizations(ctx context.Context, orgIDs []int64) error {
+	if len(orgIDs) == 0 {
+		return nil
+	}
+	return getDB(ctx).Unscoped().Where("organization_id IN ?", orgIDs).
+		Delete(&model.OrganizationMember{}).Error
+}
+
+// PurgeByUsers 彻底删除用户在所有组织中的成员身份
+func (r *organizationMemberRepository) PurgeByUsers(ctx context.Context, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return getDB(ctx).Unscoped().Where("user_id IN ?", userIDs).
 		Delete(&model.OrganizationMember{}).Error
 }
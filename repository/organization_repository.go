@@ -2,8 +2,12 @@ package repository
 
 import (
 	"context"
-	"saas-account/config"
+	"time"
+
 	"saas-account/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // OrganizationRepository 组织仓库接口
@@ -14,6 +18,12 @@ type OrganizationRepository interface {
 	List(ctx context.Context, page, pageSize int) ([]model.Organization, int64, error)
 	Update(ctx context.Context, org *model.Organization) error
 	Delete(ctx context.Context, id int64) error
+	ListDeleted(ctx context.Context, page, pageSize int) ([]model.Organization, int64, error)
+	GetDeletedByID(ctx context.Context, id int64) (*model.Organization, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, id int64) error
+	PurgeDeletedBefore(ctx context.Context, before time.Time) ([]int64, error)
+	PurgeByOwners(ctx context.Context, ownerIDs []int64) ([]int64, error)
 }
 
 // organizationRepository 组织仓库实现
@@ -26,13 +36,13 @@ func NewOrganizationRepository() OrganizationRepository {
 
 // Create 创建组织
 func (r *organizationRepository) Create(ctx context.Context, org *model.Organization) error {
-	return config.DB.WithContext(ctx).Create(org).Error
+	return getDB(ctx).Create(org).Error
 }
 
 // GetByID 根据ID获取组织
 func (r *organizationRepository) GetByID(ctx context.Context, id int64) (*model.Organization, error) {
 	var org model.Organization
-	err := config.DB.WithContext(ctx).First(&org, id).Error
+	err := getDB(ctx).First(&org, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +52,7 @@ func (r *organizationRepository) GetByID(ctx context.Context, id int64) (*model.
 // GetByOwnerID 根据拥有者ID获取组织列表
 func (r *organizationRepository) GetByOwnerID(ctx context.Context, ownerID int64) ([]model.Organization, error) {
 	var orgs []model.Organization
-	err := config.DB.WithContext(ctx).Where("owner_id = ?", ownerID).Find(&orgs).Error
+	err := getDB(ctx).Where("owner_id = ?", ownerID).Find(&orgs).Error
 	if err != nil {
 		return nil, err
 	}
@@ -57,13 +67,13 @@ func (r *organizationRepository) List(ctx context.Context, page, pageSize int) (
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.WithContext(ctx).Model(&model.Organization{}).Count(&total).Error
+	err := getReadDB(ctx).Model(&model.Organization{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.WithContext(ctx).Offset(offset).Limit(pageSize).Find(&orgs).Error
+	err = getReadDB(ctx).Offset(offset).Limit(pageSize).Find(&orgs).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -73,10 +83,105 @@ func (r *organizationRepository) List(ctx context.Context, page, pageSize int) (
 
 // Update 更新组织
 func (r *organizationRepository) Update(ctx context.Context, org *model.Organization) error {
-	return config.DB.WithContext(ctx).Save(org).Error
+	return getDB(ctx).Save(org).Error
 }
 
 // Delete 删除组织（软删除）
 func (r *organizationRepository) Delete(ctx context.Context, id int64) error {
-	return config.DB.WithContext(ctx).Delete(&model.Organization{}, id).Error
+	return getDB(ctx).Delete(&model.Organization{}, id).Error
+}
+
+// ListDeleted 获取已软删除的组织列表
+func (r *organizationRepository) ListDeleted(ctx context.Context, page, pageSize int) ([]model.Organization, int64, error) {
+	var orgs []model.Organization
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	// 获取总数
+	err := getReadDB(ctx).Unscoped().Model(&model.Organization{}).Where("deleted_at IS NOT NULL").Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err = getReadDB(ctx).Unscoped().Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&orgs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return orgs, total, nil
+}
+
+// GetDeletedByID 根据ID获取已软删除的组织
+func (r *organizationRepository) GetDeletedByID(ctx context.Context, id int64) (*model.Organization, error) {
+	var org model.Organization
+	err := getDB(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&org, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// Restore 恢复已软删除的组织
+func (r *organizationRepository) Restore(ctx context.Context, id int64) error {
+	result := getDB(ctx).Unscoped().Model(&model.Organization{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge 彻底删除已软删除的组织
+func (r *organizationRepository) Purge(ctx context.Context, id int64) error {
+	result := getDB(ctx).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Delete(&model.Organization{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedBefore 彻底删除在指定时间之前软删除的组织，返回删除的组织ID
+func (r *organizationRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	var orgs []model.Organization
+	err := getDB(ctx).Unscoped().Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Delete(&orgs).Error
+
+	ids := make([]int64, 0, len(orgs))
+	for _, org := range orgs {
+		ids = append(ids, org.ID)
+	}
+	return ids, err
+}
+
+// PurgeByOwners 彻底删除指定用户拥有的已软删除组织，返回删除的组织ID
+func (r *organizationRepository) PurgeByOwners(ctx context.Context, ownerIDs []int64) ([]int64, error) {
+	if len(ownerIDs) == 0 {
+		return nil, nil
+	}
+
+	var orgs []model.Organization
+	err := getDB(ctx).Unscoped().Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("deleted_at IS NOT NULL AND owner_id IN ?", ownerIDs).
+		Delete(&orgs).Error
+
+	ids := make([]int64, 0, len(orgs))
+	for _, org := range orgs {
+		ids = append(ids, org.ID)
+	}
+	return ids, err
 }
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"saas-account/model"
+
+	"gorm.io/gorm"
+)
+
+// OutboxRepository 发件箱事件仓库接口
+type OutboxRepository interface {
+	Create(ctx context.Context, event *model.OutboxEvent) error
+	GetPendingHeads(ctx context.Context, now int64, limit int) ([]model.OutboxEvent, error)
+	Claim(ctx context.Context, id, nextAttemptAt, leaseUntil int64) (bool, error)
+	Update(ctx context.Context, event *model.OutboxEvent) error
+}
+
+// outboxRepository 发件箱事件仓库实现
+type outboxRepository struct{}
+
+// NewOutboxRepository 创建发件箱事件仓库
+func NewOutboxRepository() OutboxRepository {
+	return &outboxRepository{}
+}
+
+// Create 写入发件箱事件并分配聚合内序号，上下文中有事务时与事务中的状态变更一起提交
+// 分配序号时锁定聚合的序号行直到事务结束，同一聚合后写入的事务要等前一个事务结束，因此序号顺序与提交顺序一致
+func (r *outboxRepository) Create(ctx context.Context, event *model.OutboxEvent) error {
+	return getDB(ctx).Transaction(func(tx *gorm.DB) error {
+		var sequence int64
+		err := tx.Raw(`
+			INSERT INTO outbox_sequences (aggregate_type, aggregate_id, last_sequence)
+			VALUES (?, ?, 1)
+			ON CONFLICT (aggregate_type, aggregate_id)
+			DO UPDATE SET last_sequence = outbox_sequences.last_sequence + 1
+			RETURNING last_sequence`,
+			event.AggregateType, event.AggregateId).
+			Scan(&sequence).Error
+		if err != nil {
+			return err
+		}
+
+		event.Sequence = sequence
+		return tx.Create(event).Error
+	})
+}
+
+// GetPendingHeads 获取每个聚合序号最小的一条未发布事件中已到发布时间的事件
+// 同一聚合的后续事件要等前一条发布或进入死信后才会被取出，以保证聚合内的发布顺序
+func (r *outboxRepository) GetPendingHeads(ctx context.Context, now int64, limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	heads := getDB(ctx).Model(&model.OutboxEvent{}).
+		Select("DISTINCT ON (aggregate_type, aggregate_id) id").
+		Where("status = ?", model.OutboxEventPending).
+		Order("aggregate_type, aggregate_id, sequence")
+	err := getDB(ctx).
+		Where("id IN (?) AND next_attempt_at <= ?", heads, now).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Claim 领取事件，将下次尝试时间推迟到租约结束，事件已被其他实例领取时返回false
+func (r *outboxRepository) Claim(ctx context.Context, id, nextAttemptAt, leaseUntil int64) (bool, error) {
+	result := getDB(ctx).Model(&model.OutboxEvent{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", id, model.OutboxEventPending, nextAttemptAt).
+		Update("next_attempt_at", leaseUntil)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Update 更新发件箱事件
+func (r *outboxRepository) Update(ctx context.Context, event *model.OutboxEvent) error {
+	return getDB(ctx).Save(event).Error
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+
+	"gorm.io/gorm"
+)
+
+// PlanChangeRepository 套餐变更记录仓库接口
+type PlanChangeRepository interface {
+	Create(ctx context.Context, change *model.PlanChange) error
+	GetByApplicationBetween(ctx context.Context, appID int64, start, end int64) ([]model.PlanChange, error)
+	GetLastBefore(ctx context.Context, appID int64, before int64) (*model.PlanChange, error)
+	GetLastFrom(ctx context.Context, appID int64, fromPlanID int64) (*model.PlanChange, error)
+}
+
+// planChangeRepository 套餐变更记录仓库实现
+type planChangeRepository struct{}
+
+// NewPlanChangeRepository 创建套餐变更记录仓库
+func NewPlanChangeRepository() PlanChangeRepository {
+	return &planChangeRepository{}
+}
+
+// Create 创建套餐变更记录
+func (r *planChangeRepository) Create(ctx context.Context, change *model.PlanChange) error {
+	return getDB(ctx).Create(change).Error
+}
+
+// GetByApplicationBetween 按变更时间顺序获取应用在区间 [start, end) 内的套餐变更记录
+func (r *planChangeRepository) GetByApplicationBetween(ctx context.Context, appID int64, start, end int64) ([]model.PlanChange, error) {
+	var changes []model.PlanChange
+	err := getDB(ctx).
+		Where("application_id = ? AND changed_at >= ? AND changed_at < ?", appID, start, end).
+		Order("changed_at, id").
+		Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetLastBefore 获取应用在指定时间之前的最后一次套餐变更，不存在时返回nil
+func (r *planChangeRepository) GetLastBefore(ctx context.Context, appID int64, before int64) (*model.PlanChange, error) {
+	var change model.PlanChange
+	err := getDB(ctx).
+		Where("application_id = ? AND changed_at < ?", appID, before).
+		Order("changed_at DESC, id DESC").
+		First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// GetLastFrom 获取应用最后一次从指定套餐变更出去的记录，不存在时返回nil
+func (r *planChangeRepository) GetLastFrom(ctx context.Context, appID int64, fromPlanID int64) (*model.PlanChange, error) {
+	var change model.PlanChange
+	err := getDB(ctx).
+		Where("application_id = ? AND from_plan_id = ?", appID, fromPlanID).
+		Order("changed_at DESC, id DESC").
+		First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
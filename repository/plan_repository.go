@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"saas-account/model"
+)
+
+// PlanRepository 套餐仓库接口
+type PlanRepository interface {
+	Create(ctx context.Context, plan *model.Plan) error
+	GetByID(ctx context.Context, id int64) (*model.Plan, error)
+	GetLatestByCode(ctx context.Context, code string) (*model.Plan, error)
+	GetDefault(ctx context.Context) (*model.Plan, error)
+	GetVersions(ctx context.Context, code string) ([]model.Plan, error)
+	List(ctx context.Context, status string, page, pageSize int) ([]model.Plan, int64, error)
+	Update(ctx context.Context, plan *model.Plan) error
+	ClearDefault(ctx context.Context) error
+	LockCode(ctx context.Context, code string) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// planRepository 套餐仓库实现
+type planRepository struct{}
+
+// NewPlanRepository 创建套餐仓库
+func NewPlanRepository() PlanRepository {
+	return &planRepository{}
+}
+
+// Create 创建套餐
+func (r *planRepository) Create(ctx context.Context, plan *model.Plan) error {
+	return getDB(ctx).Create(plan).Error
+}
+
+// GetByID 根据ID获取套餐
+func (r *planRepository) GetByID(ctx context.Context, id int64) (*model.Plan, error) {
+	var plan model.Plan
+	err := getDB(ctx).First(&plan, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetLatestByCode 根据套餐编码获取最新版本
+func (r *planRepository) GetLatestByCode(ctx context.Context, code string) (*model.Plan, error) {
+	var plan model.Plan
+	err := getDB(ctx).Where("code = ?", code).Order("version DESC").First(&plan).Error
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetDefault 获取默认套餐
+func (r *planRepository) GetDefault(ctx context.Context) (*model.Plan, error) {
+	var plan model.Plan
+	err := getDB(ctx).Where("is_default = ? AND status = ?", true, "active").
+		Order("version DESC").First(&plan).Error
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetVersions 获取套餐编码下的所有版本
+func (r *planRepository) GetVersions(ctx context.Context, code string) ([]model.Plan, error) {
+	var plans []model.Plan
+	err := getDB(ctx).Where("code = ?", code).Order("version DESC").Find(&plans).Error
+	if err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// List 获取套餐列表，status为空时返回所有状态
+func (r *planRepository) List(ctx context.Context, status string, page, pageSize int) ([]model.Plan, int64, error) {
+	var plans []model.Plan
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := getReadDB(ctx).Model(&model.Plan{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// 获取总数
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err = query.Order("level ASC, code ASC, version DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&plans).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return plans, total, nil
+}
+
+// Update 更新套餐
+func (r *planRepository) Update(ctx context.Context, plan *model.Plan) error {
+	return getDB(ctx).Save(plan).Error
+}
+
+// ClearDefault 取消所有套餐的默认标记
+func (r *planRepository) ClearDefault(ctx context.Context) error {
+	return getDB(ctx).Model(&model.Plan{}).Where("is_default = ?", true).
+		Update("is_default", false).Error
+}
+
+// LockCode 锁定套餐编码直到事务结束，需在事务中调用，用于串行化同一编码的新版本创建
+func (r *planRepository) LockCode(ctx context.Context, code string) error {
+	return getDB(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "plan:"+code).Error
+}
+
+// Delete 删除套餐（软删除）
+func (r *planRepository) Delete(ctx context.Context, id int64) error {
+	return getDB(ctx).Delete(&model.Plan{}, id).Error
+}
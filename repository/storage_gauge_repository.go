@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StorageGaugeRepository 存储用量仓库接口
+type StorageGaugeRepository interface {
+	Get(ctx context.Context, appID int64) (*model.StorageGauge, error)
+	Set(ctx context.Context, appID, orgID, bytes, bucketStart int64) (*model.StorageGauge, error)
+	Add(ctx context.Context, appID, orgID, delta, max, bucketStart int64) (*model.StorageGauge, bool, error)
+	GetSnapshots(ctx context.Context, appID int64, start, end int64) ([]model.StorageSnapshot, error)
+	GetLastSnapshotBefore(ctx context.Context, appID int64, before int64) (*model.StorageSnapshot, error)
+}
+
+// storageGaugeRepository 存储用量仓库实现
+type storageGaugeRepository struct{}
+
+// NewStorageGaugeRepository 创建存储用量仓库
+func NewStorageGaugeRepository() StorageGaugeRepository {
+	return &storageGaugeRepository{}
+}
+
+// Get 获取应用的存储用量，未记录过时返回零值
+func (r *storageGaugeRepository) Get(ctx context.Context, appID int64) (*model.StorageGauge, error) {
+	var gauge model.StorageGauge
+	err := getDB(ctx).Where("application_id = ?", appID).First(&gauge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.StorageGauge{ApplicationId: appID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &gauge, nil
+}
+
+// Set 设置应用的当前存储占用，并更新历史最高值和小时快照，调用方需要在事务中调用，保证用量和快照一起提交
+func (r *storageGaugeRepository) Set(ctx context.Context, appID, orgID, bytes, bucketStart int64) (*model.StorageGauge, error) {
+	now := time.Now().Unix()
+
+	var gauge model.StorageGauge
+	err := getDB(ctx).Raw(`
+		INSERT INTO storage_gauges (application_id, organization_id, current_bytes, peak_bytes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (application_id)
+		DO UPDATE SET current_bytes = EXCLUDED.current_bytes,
+			peak_bytes = GREATEST(storage_gauges.peak_bytes, EXCLUDED.current_bytes),
+			updated_at = EXCLUDED.updated_at
+		RETURNING *`,
+		appID, orgID, bytes, bytes, now, now).
+		Scan(&gauge).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.snapshot(ctx, &gauge, bucketStart); err != nil {
+		return nil, err
+	}
+	return &gauge, nil
+}
+
+// Add 原子增减应用的存储占用，结果不小于0，并更新小时快照，调用方需要在事务中调用
+// 增加后超过上限时不做任何修改，返回当前用量和false，max 为-1表示不限
+func (r *storageGaugeRepository) Add(ctx context.Context, appID, orgID, delta, max, bucketStart int64) (*model.StorageGauge, bool, error) {
+	now := time.Now().Unix()
+
+	initial := delta
+	if initial < 0 {
+		initial = 0
+	}
+	if max >= 0 && delta > 0 && initial > max {
+		gauge, err := r.Get(ctx, appID)
+		return gauge, false, err
+	}
+
+	var gauges []model.StorageGauge
+	err := getDB(ctx).Raw(`
+		INSERT INTO storage_gauges (application_id, organization_id, current_bytes, peak_bytes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (application_id)
+		DO UPDATE SET current_bytes = GREATEST(storage_gauges.current_bytes + ?, 0),
+			peak_bytes = GREATEST(storage_gauges.peak_bytes, storage_gauges.current_bytes + ?),
+			updated_at = EXCLUDED.updated_at
+		WHERE ? < 0 OR ? <= 0 OR storage_gauges.current_bytes + ? <= ?
+		RETURNING *`,
+		appID, orgID, initial, initial, now, now,
+		delta, delta,
+		max, delta, delta, max).
+		Scan(&gauges).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 冲突更新被上限条件拒绝时不返回任何行
+	if len(gauges) == 0 {
+		gauge, err := r.Get(ctx, appID)
+		return gauge, false, err
+	}
+
+	if err := r.snapshot(ctx, &gauges[0], bucketStart); err != nil {
+		return nil, false, err
+	}
+	return &gauges[0], true, nil
+}
+
+// snapshot 更新存储用量的小时快照
+// 新的一小时第一次变更前，占用还是上一个快照最后的值，所以新快照的最高值从两者中较大的开始
+func (r *storageGaugeRepository) snapshot(ctx context.Context, gauge *model.StorageGauge, bucketStart int64) error {
+	now := time.Now().Unix()
+
+	return getDB(ctx).Exec(`
+		INSERT INTO storage_snapshots (application_id, organization_id, bucket_start, max_bytes, last_bytes, created_at, updated_at)
+		VALUES (?, ?, ?, GREATEST(?, COALESCE((
+			SELECT previous.last_bytes FROM storage_snapshots previous
+			WHERE previous.application_id = ? AND previous.bucket_start < ?
+			ORDER BY previous.bucket_start DESC LIMIT 1), 0)), ?, ?, ?)
+		ON CONFLICT (application_id, bucket_start)
+		DO UPDATE SET max_bytes = GREATEST(storage_snapshots.max_bytes, EXCLUDED.last_bytes),
+			last_bytes = EXCLUDED.last_bytes, updated_at = EXCLUDED.updated_at`,
+		gauge.ApplicationId, gauge.OrganizationId, bucketStart,
+		gauge.CurrentBytes, gauge.ApplicationId, bucketStart,
+		gauge.CurrentBytes, now, now).Error
+}
+
+// GetSnapshots 获取应用在区间 [start, end) 内的小时快照
+func (r *storageGaugeRepository) GetSnapshots(ctx context.Context, appID int64, start, end int64) ([]model.StorageSnapshot, error) {
+	var snapshots []model.StorageSnapshot
+	err := getDB(ctx).
+		Where("application_id = ? AND bucket_start >= ? AND bucket_start < ?", appID, start, end).
+		Order("bucket_start").
+		Find(&snapshots).Error
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// GetLastSnapshotBefore 获取指定时间之前的最后一个小时快照，不存在时返回nil
+func (r *storageGaugeRepository) GetLastSnapshotBefore(ctx context.Context, appID int64, before int64) (*model.StorageSnapshot, error) {
+	var snapshot model.StorageSnapshot
+	err := getDB(ctx).
+		Where("application_id = ? AND bucket_start < ?", appID, before).
+		Order("bucket_start DESC").
+		First(&snapshot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"saas-account/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// SubscriptionPaymentRepository 订阅扣款记录仓库接口
+type SubscriptionPaymentRepository interface {
+	Begin(ctx context.Context, payment *model.SubscriptionPayment) (*model.SubscriptionPayment, error)
+	Update(ctx context.Context, payment *model.SubscriptionPayment) error
+}
+
+// subscriptionPaymentRepository 订阅扣款记录仓库实现
+type subscriptionPaymentRepository struct{}
+
+// NewSubscriptionPaymentRepository 创建订阅扣款记录仓库
+func NewSubscriptionPaymentRepository() SubscriptionPaymentRepository {
+	return &subscriptionPaymentRepository{}
+}
+
+// Begin 按幂等键创建扣款记录，幂等键已存在时返回已有的记录
+func (r *subscriptionPaymentRepository) Begin(ctx context.Context, payment *model.SubscriptionPayment) (*model.SubscriptionPayment, error) {
+	err := getDB(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "idempotency_key"}}, DoNothing: true}).
+		Create(payment).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var existing model.SubscriptionPayment
+	if err := getDB(ctx).Where("idempotency_key = ?", payment.IdempotencyKey).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Update 更新扣款记录
+func (r *subscriptionPaymentRepository) Update(ctx context.Context, payment *model.SubscriptionPayment) error {
+	return getDB(ctx).Save(payment).Error
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UsageCounterRepository 使用量计数器仓库接口
+type UsageCounterRepository interface {
+	Get(ctx context.Context, appID int64, usageType string, windowStart int64) (int64, error)
+	Increment(ctx context.Context, appID int64, usageType string, windowStart, amount int64) (int64, error)
+	IncrementWithinLimit(ctx context.Context, appID int64, usageType string, windowStart, amount, max int64) (int64, bool, error)
+}
+
+// usageCounterRepository 使用量计数器仓库实现
+type usageCounterRepository struct{}
+
+// NewUsageCounterRepository 创建使用量计数器仓库
+func NewUsageCounterRepository() UsageCounterRepository {
+	return &usageCounterRepository{}
+}
+
+// Get 获取时间窗口内的累计使用量，计数器不存在时返回0
+func (r *usageCounterRepository) Get(ctx context.Context, appID int64, usageType string, windowStart int64) (int64, error) {
+	var counter model.UsageCounter
+	err := getDB(ctx).
+		Where("application_id = ? AND usage_type = ? AND window_start = ?", appID, usageType, windowStart).
+		First(&counter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return counter.Amount, nil
+}
+
+// Increment 原子累加使用量，返回累加后的总量
+func (r *usageCounterRepository) Increment(ctx context.Context, appID int64, usageType string, windowStart, amount int64) (int64, error) {
+	now := time.Now().Unix()
+
+	var total int64
+	err := getDB(ctx).Raw(`
+		INSERT INTO usage_counters (application_id, usage_type, window_start, amount, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (application_id, usage_type, window_start)
+		DO UPDATE SET amount = usage_counters.amount + EXCLUDED.amount, updated_at = EXCLUDED.updated_at
+		RETURNING amount`,
+		appID, usageType, windowStart, amount, now, now).
+		Scan(&total).Error
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// IncrementWithinLimit 在不超过上限时原子累加使用量，返回累加后的总量和是否累加成功
+// 超过上限时不做任何修改，返回当前总量和false
+func (r *usageCounterRepository) IncrementWithinLimit(ctx context.Context, appID int64, usageType string, windowStart, amount, max int64) (int64, bool, error) {
+	if amount > max {
+		current, err := r.Get(ctx, appID, usageType, windowStart)
+		return current, false, err
+	}
+
+	now := time.Now().Unix()
+
+	var totals []int64
+	err := getDB(ctx).Raw(`
+		INSERT INTO usage_counters (application_id, usage_type, window_start, amount, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (application_id, usage_type, window_start)
+		DO UPDATE SET amount = usage_counters.amount + EXCLUDED.amount, updated_at = EXCLUDED.updated_at
+		WHERE usage_counters.amount + EXCLUDED.amount <= ?
+		RETURNING amount`,
+		appID, usageType, windowStart, amount, now, now, max).
+		Scan(&totals).Error
+	if err != nil {
+		return 0, false, err
+	}
+
+	// 冲突更新被条件拒绝时不返回任何行
+	if len(totals) == 0 {
+		current, err := r.Get(ctx, appID, usageType, windowStart)
+		return current, false, err
+	}
+	return totals[0], true, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"saas-account/model"
+)
+
+// UsageExportJobRepository 使用记录导出任务仓库接口
+type UsageExportJobRepository interface {
+	Create(ctx context.Context, job *model.UsageExportJob) error
+	GetByID(ctx context.Context, id int64) (*model.UsageExportJob, error)
+	Update(ctx context.Context, job *model.UsageExportJob) error
+	GetPending(ctx context.Context, staleBefore int64, limit int) ([]model.UsageExportJob, error)
+	Claim(ctx context.Context, id, claimedAt, staleBefore int64) (bool, error)
+	Finish(ctx context.Context, job *model.UsageExportJob) (bool, error)
+	GetExpired(ctx context.Context, before int64) ([]model.UsageExportJob, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// usageExportJobRepository 使用记录导出任务仓库实现
+type usageExportJobRepository struct{}
+
+// NewUsageExportJobRepository 创建使用记录导出任务仓库
+func NewUsageExportJobRepository() UsageExportJobRepository {
+	return &usageExportJobRepository{}
+}
+
+// Create 创建导出任务
+func (r *usageExportJobRepository) Create(ctx context.Context, job *model.UsageExportJob) error {
+	return getDB(ctx).Create(job).Error
+}
+
+// GetByID 根据ID获取导出任务
+func (r *usageExportJobRepository) GetByID(ctx context.Context, id int64) (*model.UsageExportJob, error) {
+	var job model.UsageExportJob
+	err := getDB(ctx).First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Update 更新导出任务
+func (r *usageExportJobRepository) Update(ctx context.Context, job *model.UsageExportJob) error {
+	return getDB(ctx).Save(job).Error
+}
+
+// GetPending 获取等待执行的导出任务，以及在 staleBefore 之前领取、租约已过期的执行中任务
+func (r *usageExportJobRepository) GetPending(ctx context.Context, staleBefore int64, limit int) ([]model.UsageExportJob, error) {
+	var jobs []model.UsageExportJob
+	err := getDB(ctx).
+		Where("status = ? OR (status = ? AND claimed_at < ?)", model.ExportStatusPending, model.ExportStatusRunning, staleBefore).
+		Order("id").
+		Limit(limit).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Claim 将等待中或租约已过期的导出任务标记为执行中并记录领取时间，任务已被其他实例领取时返回false
+func (r *usageExportJobRepository) Claim(ctx context.Context, id, claimedAt, staleBefore int64) (bool, error) {
+	result := getDB(ctx).Model(&model.UsageExportJob{}).
+		Where("id = ? AND (status = ? OR (status = ? AND claimed_at < ?))",
+			id, model.ExportStatusPending, model.ExportStatusRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"status":     model.ExportStatusRunning,
+			"claimed_at": claimedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Finish 保存导出任务的执行结果，任务在租约过期后已被其他实例重新领取时不保存并返回false
+func (r *usageExportJobRepository) Finish(ctx context.Context, job *model.UsageExportJob) (bool, error) {
+	result := getDB(ctx).Model(&model.UsageExportJob{}).
+		Where("id = ? AND status = ? AND claimed_at = ?", job.ID, model.ExportStatusRunning, job.ClaimedAt).
+		Updates(map[string]interface{}{
+			"status":       job.Status,
+			"file_name":    job.FileName,
+			"file_size":    job.FileSize,
+			"row_count":    job.RowCount,
+			"error":        job.Error,
+			"completed_at": job.CompletedAt,
+			"expires_at":   job.ExpiresAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetExpired 获取已过期的导出任务
+func (r *usageExportJobRepository) GetExpired(ctx context.Context, before int64) ([]model.UsageExportJob, error) {
+	var jobs []model.UsageExportJob
+	err := getDB(ctx).
+		Where("expires_at > 0 AND expires_at <= ?", before).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Delete 彻底删除导出任务
+func (r *usageExportJobRepository) Delete(ctx context.Context, id int64) error {
+	return getDB(ctx).Unscoped().Delete(&model.UsageExportJob{}, id).Error
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"saas-account/model"
+	"time"
+)
+
+// UsageIdempotencyRepository 使用记录幂等键仓库接口
+type UsageIdempotencyRepository interface {
+	Reserve(ctx context.Context, key *model.UsageIdempotencyKey) (bool, error)
+	Get(ctx context.Context, appID int64, idempotencyKey string) (*model.UsageIdempotencyKey, error)
+	DeleteExpired(ctx context.Context, before int64) (int64, error)
+}
+
+// usageIdempotencyRepository 使用记录幂等键仓库实现
+type usageIdempotencyRepository struct{}
+
+// NewUsageIdempotencyRepository 创建使用记录幂等键仓库
+func NewUsageIdempotencyRepository() UsageIdempotencyRepository {
+	return &usageIdempotencyRepository{}
+}
+
+// Reserve 原子占用幂等键，键已存在且未过期时返回false
+func (r *usageIdempotencyRepository) Reserve(ctx context.Context, key *model.UsageIdempotencyKey) (bool, error) {
+	now := time.Now().Unix()
+
+	var ids []int64
+	err := getDB(ctx).Raw(`
+		INSERT INTO usage_idempotency_keys (application_id, idempotency_key, usage_type, usage_amount, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (application_id, idempotency_key)
+		DO UPDATE SET usage_type = EXCLUDED.usage_type, usage_amount = EXCLUDED.usage_amount,
+			expires_at = EXCLUDED.expires_at, updated_at = EXCLUDED.updated_at
+		WHERE usage_idempotency_keys.expires_at <= ?
+		RETURNING id`,
+		key.ApplicationId, key.IdempotencyKey, key.UsageType, key.UsageAmount, key.ExpiresAt, now, now, now).
+		Scan(&ids).Error
+	if err != nil {
+		return false, err
+	}
+
+	// 键未过期时冲突更新被条件拒绝，不返回任何行
+	if len(ids) == 0 {
+		return false, nil
+	}
+	key.ID = ids[0]
+	return true, nil
+}
+
+// Get 获取未过期的幂等键
+func (r *usageIdempotencyRepository) Get(ctx context.Context, appID int64, idempotencyKey string) (*model.UsageIdempotencyKey, error) {
+	var key model.UsageIdempotencyKey
+	err := getDB(ctx).
+		Where("application_id = ? AND idempotency_key = ? AND expires_at > ?", appID, idempotencyKey, time.Now().Unix()).
+		First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// DeleteExpired 彻底删除已过期的幂等键，返回删除条数
+func (r *usageIdempotencyRepository) DeleteExpired(ctx context.Context, before int64) (int64, error) {
+	result := getDB(ctx).Unscoped().
+		Where("expires_at <= ?", before).
+		Delete(&model.UsageIdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
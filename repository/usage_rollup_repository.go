@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"saas-account/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UsageRollupRepository 使用量汇总仓库接口
+type UsageRollupRepository interface {
+	Add(ctx context.Context, usage *model.ApplicationUsage, loc *time.Location) error
+	Subtract(ctx context.Context, usage *model.ApplicationUsage, loc *time.Location) error
+	SumByApplication(ctx context.Context, appID int64, granularity string, start, end int64) (map[string]int64, error)
+	SumByOrganization(ctx context.Context, orgID int64, granularity string, start, end int64) (map[string]int64, error)
+	SumFeaturesByApplication(ctx context.Context, appID int64, granularity string, start, end int64) (map[string]int64, error)
+	SeriesByApplication(ctx context.Context, appID int64, granularity string, start, end int64) ([]model.UsageRollup, error)
+	SeriesByOrganization(ctx context.Context, orgID int64, granularity string, start, end int64) ([]model.UsageRollup, error)
+	ExistsByOrganization(ctx context.Context, orgID int64) (bool, error)
+	DeleteBefore(ctx context.Context, granularity string, before int64) (int64, error)
+}
+
+// usageRollupRepository 使用量汇总仓库实现
+type usageRollupRepository struct{}
+
+// NewUsageRollupRepository 创建使用量汇总仓库
+func NewUsageRollupRepository() UsageRollupRepository {
+	return &usageRollupRepository{}
+}
+
+// RollupBucket 计算事件时间在组织时区下所在的汇总区间开始时间
+func RollupBucket(granularity string, usageDate int64, loc *time.Location) int64 {
+	t := time.Unix(usageDate, 0).In(loc)
+	if granularity == model.RollupGranularityDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc).Unix()
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc).Unix()
+}
+
+// rollupFeatureName 获取功能使用记录的功能名称，其他类型的使用记录返回空
+func rollupFeatureName(usage *model.ApplicationUsage) string {
+	if usage.UsageType != "feature" || usage.Details == "" {
+		return ""
+	}
+
+	var details struct {
+		FeatureName string `json:"feature_name"`
+	}
+	if err := json.Unmarshal([]byte(usage.Details), &details); err != nil {
+		return ""
+	}
+	return details.FeatureName
+}
+
+// Add 将一条使用记录按组织时区累加到小时和天汇总中，功能使用按功能名称分别汇总
+func (r *usageRollupRepository) Add(ctx context.Context, usage *model.ApplicationUsage, loc *time.Location) error {
+	now := time.Now().Unix()
+	featureName := rollupFeatureName(usage)
+
+	for _, granularity := range []string{model.RollupGranularityHour, model.RollupGranularityDay} {
+		err := getDB(ctx).Exec(`
+			INSERT INTO usage_rollups (application_id, organization_id, usage_type, feature_name, granularity, bucket_start, amount, count, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?, ?)
+			ON CONFLICT (application_id, usage_type, feature_name, granularity, bucket_start)
+			DO UPDATE SET amount = usage_rollups.amount + EXCLUDED.amount, count = usage_rollups.count + 1, updated_at = EXCLUDED.updated_at`,
+			usage.ApplicationId, usage.OrganizationId, usage.UsageType, featureName, granularity,
+			RollupBucket(granularity, usage.UsageDate, loc), usage.UsageAmount, now, now).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subtract 从使用记录所在的小时和天汇总中减去该记录，用于删除使用记录
+// 汇总已按保留期清理时没有可更新的行
+func (r *usageRollupRepository) Subtract(ctx context.Context, usage *model.ApplicationUsage, loc *time.Location) error {
+	now := time.Now().Unix()
+	featureName := rollupFeatureName(usage)
+
+	for _, granularity := range []string{model.RollupGranularityHour, model.RollupGranularityDay} {
+		err := getDB(ctx).Model(&model.UsageRollup{}).
+			Where("application_id = ? AND usage_type = ? AND feature_name = ?", usage.ApplicationId, usage.UsageType, featureName).
+			Where("granularity = ? AND bucket_start = ?", granularity, RollupBucket(granularity, usage.UsageDate, loc)).
+			Updates(map[string]interface{}{
+				"amount":     gorm.Expr("GREATEST(amount - ?, 0)", usage.UsageAmount),
+				"count":      gorm.Expr("GREATEST(count - 1, 0)"),
+				"updated_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SumByApplication 按使用类型汇总应用在区间 [start, end) 内的使用量
+func (r *usageRollupRepository) SumByApplication(ctx context.Context, appID int64, granularity string, start, end int64) (map[string]int64, error) {
+	return r.sum(ctx, "application_id = ?", appID, granularity, start, end)
+}
+
+// SumByOrganization 按使用类型汇总组织在区间 [start, end) 内的使用量
+func (r *usageRollupRepository) SumByOrganization(ctx context.Context, orgID int64, granularity string, start, end int64) (map[string]int64, error) {
+	return r.sum(ctx, "organization_id = ?", orgID, granularity, start, end)
+}
+
+// SumFeaturesByApplication 按功能名称汇总应用在区间 [start, end) 内的功能使用量
+func (r *usageRollupRepository) SumFeaturesByApplication(ctx context.Context, appID int64, granularity string, start, end int64) (map[string]int64, error) {
+	type Result struct {
+		FeatureName string
+		TotalAmount int64
+	}
+
+	var results []Result
+	err := getReadDB(ctx).Model(&model.UsageRollup{}).
+		Select("feature_name, SUM(amount) as total_amount").
+		Where("application_id = ? AND usage_type = ?", appID, "feature").
+		Where("granularity = ? AND bucket_start >= ? AND bucket_start < ?", granularity, start, end).
+		Group("feature_name").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summary := make(map[string]int64)
+	for _, result := range results {
+		summary[result.FeatureName] = result.TotalAmount
+	}
+
+	return summary, nil
+}
+
+// sum 按使用类型汇总指定粒度的使用量
+func (r *usageRollupRepository) sum(ctx context.Context, scope string, id int64, granularity string, start, end int64) (map[string]int64, error) {
+	type Result struct {
+		UsageType   string
+		TotalAmount int64
+	}
+
+	var results []Result
+	err := getReadDB(ctx).Model(&model.UsageRollup{}).
+		Select("usage_type, SUM(amount) as total_amount").
+		Where(scope, id).
+		Where("granularity = ? AND bucket_start >= ? AND bucket_start < ?", granularity, start, end).
+		Group("usage_type").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summary := make(map[string]int64)
+	for _, result := range results {
+		summary[result.UsageType] = result.TotalAmount
+	}
+
+	return summary, nil
+}
+
+// SeriesByApplication 按汇总区间和使用类型获取应用在区间 [start, end) 内的使用量
+func (r *usageRollupRepository) SeriesByApplication(ctx context.Context, appID int64, granularity string, start, end int64) ([]model.UsageRollup, error) {
+	return r.series(ctx, "application_id = ?", appID, granularity, start, end)
+}
+
+// SeriesByOrganization 按汇总区间和使用类型获取组织在区间 [start, end) 内的使用量
+func (r *usageRollupRepository) SeriesByOrganization(ctx context.Context, orgID int64, granularity string, start, end int64) ([]model.UsageRollup, error) {
+	return r.series(ctx, "organization_id = ?", orgID, granularity, start, end)
+}
+
+// series 按汇总区间和使用类型汇总指定粒度的使用量
+func (r *usageRollupRepository) series(ctx context.Context, scope string, id int64, granularity string, start, end int64) ([]model.UsageRollup, error) {
+	var rollups []model.UsageRollup
+	err := getReadDB(ctx).Model(&model.UsageRollup{}).
+		Select("bucket_start, usage_type, SUM(amount) as amount, SUM(count) as count").
+		Where(scope, id).
+		Where("granularity = ? AND bucket_start >= ? AND bucket_start < ?", granularity, start, end).
+		Group("bucket_start, usage_type").
+		Order("bucket_start").
+		Scan(&rollups).Error
+	if err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+// ExistsByOrganization 组织是否已有使用量汇总
+func (r *usageRollupRepository) ExistsByOrganization(ctx context.Context, orgID int64) (bool, error) {
+	var count int64
+	err := getDB(ctx).Model(&model.UsageRollup{}).Where("organization_id = ?", orgID).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteBefore 删除指定粒度下早于指定时间的汇总，返回删除条数
+func (r *usageRollupRepository) DeleteBefore(ctx context.Context, granularity string, before int64) (int64, error) {
+	result := getDB(ctx).Unscoped().
+		Where("granularity = ? AND bucket_start < ?", granularity, before).
+		Delete(&model.UsageRollup{})
+	return result.RowsAffected, result.Error
+}
@@ -2,8 +2,12 @@ package repository
 
 import (
 	"context"
-	"saas-account/config"
+	"time"
+
 	"saas-account/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepository 用户仓库接口
@@ -15,6 +19,11 @@ type UserRepository interface {
 	List(ctx context.Context, page, pageSize int) ([]model.User, int64, error)
 	Update(ctx context.Context, user *model.User) error
 	Delete(ctx context.Context, id int64) error
+	ListDeleted(ctx context.Context, page, pageSize int) ([]model.User, int64, error)
+	GetDeletedByID(ctx context.Context, id int64) (*model.User, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, id int64) error
+	PurgeDeletedBefore(ctx context.Context, before time.Time) ([]int64, error)
 }
 
 // userRepository 用户仓库实现
@@ -27,13 +36,13 @@ func NewUserRepository() UserRepository {
 
 // Create 创建用户
 func (r *userRepository) Create(ctx context.Context, user *model.User) error {
-	return config.DB.WithContext(ctx).Create(user).Error
+	return getDB(ctx).Create(user).Error
 }
 
 // GetByID 根据ID获取用户
 func (r *userRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
 	var user model.User
-	err := config.DB.WithContext(ctx).First(&user, id).Error
+	err := getDB(ctx).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +52,7 @@ func (r *userRepository) GetByID(ctx context.Context, id int64) (*model.User, er
 // GetByEmail 根据邮箱获取用户
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := config.DB.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	err := getDB(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +62,7 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.U
 // GetByPhone 根据手机号获取用户
 func (r *userRepository) GetByPhone(ctx context.Context, phone string) (*model.User, error) {
 	var user model.User
-	err := config.DB.WithContext(ctx).Where("phone = ?", phone).First(&user).Error
+	err := getDB(ctx).Where("phone = ?", phone).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -68,13 +77,13 @@ func (r *userRepository) List(ctx context.Context, page, pageSize int) ([]model.
 	offset := (page - 1) * pageSize
 
 	// 获取总数
-	err := config.DB.WithContext(ctx).Model(&model.User{}).Count(&total).Error
+	err := getReadDB(ctx).Model(&model.User{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err = config.DB.WithContext(ctx).Offset(offset).Limit(pageSize).Find(&users).Error
+	err = getReadDB(ctx).Offset(offset).Limit(pageSize).Find(&users).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -84,10 +93,89 @@ func (r *userRepository) List(ctx context.Context, page, pageSize int) ([]model.
 
 // Update 更新用户
 func (r *userRepository) Update(ctx context.Context, user *model.User) error {
-	return config.DB.WithContext(ctx).Save(user).Error
+	return getDB(ctx).Save(user).Error
 }
 
 // Delete 删除用户（软删除）
 func (r *userRepository) Delete(ctx context.Context, id int64) error {
-	return config.DB.WithContext(ctx).Delete(&model.User{}, id).Error
+	return getDB(ctx).Delete(&model.User{}, id).Error
+}
+
+// ListDeleted 获取已软删除的用户列表
+func (r *userRepository) ListDeleted(ctx context.Context, page, pageSize int) ([]model.User, int64, error) {
+	var users []model.User
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	// 获取总数
+	err := getReadDB(ctx).Unscoped().Model(&model.User{}).Where("deleted_at IS NOT NULL").Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err = getReadDB(ctx).Unscoped().Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Offset(offset).Limit(pageSize).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// GetDeletedByID 根据ID获取已软删除的用户
+func (r *userRepository) GetDeletedByID(ctx context.Context, id int64) (*model.User, error) {
+	var user model.User
+	err := getDB(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Restore 恢复已软删除的用户
+func (r *userRepository) Restore(ctx context.Context, id int64) error {
+	result := getDB(ctx).Unscoped().Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge 彻底删除已软删除的用户
+func (r *userRepository) Purge(ctx context.Context, id int64) error {
+	result := getDB(ctx).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Delete(&model.User{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedBefore 彻底删除在指定时间之前软删除的用户，返回删除的用户ID
+// 仍是未删除组织拥有者的用户不删除，需要先转移或删除组织
+func (r *userRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	var users []model.User
+	err := getDB(ctx).Unscoped().Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM organizations o WHERE o.owner_id = users.id AND o.deleted_at IS NULL)").
+		Delete(&users).Error
+
+	ids := make([]int64, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, err
 }
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"saas-account/model"
+)
+
+// WebhookRepository Webhook 订阅与投递记录仓库接口
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id int64) (*model.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, sub *model.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int64) error
+	GetSubscriptionsByOrganization(ctx context.Context, orgID int64) ([]model.WebhookSubscription, error)
+	GetEnabledSubscriptions(ctx context.Context, orgID int64) ([]model.WebhookSubscription, error)
+	CreateDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error
+	HasDeliveries(ctx context.Context, eventID string) (bool, error)
+	GetDelivery(ctx context.Context, id int64) (*model.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, orgID, subID int64, status string, page, pageSize int) ([]model.WebhookDelivery, int64, error)
+	GetDueDeliveries(ctx context.Context, now int64, limit int) ([]model.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, id, nextAttemptAt, leaseUntil int64) (bool, error)
+	UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+}
+
+// webhookRepository Webhook 仓库实现
+type webhookRepository struct{}
+
+// NewWebhookRepository 创建 Webhook 仓库
+func NewWebhookRepository() WebhookRepository {
+	return &webhookRepository{}
+}
+
+// CreateSubscription 创建订阅
+func (r *webhookRepository) CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) error {
+	return getDB(ctx).Create(sub).Error
+}
+
+// GetSubscription 根据ID获取订阅
+func (r *webhookRepository) GetSubscription(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	err := getDB(ctx).First(&sub, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// UpdateSubscription 更新订阅
+func (r *webhookRepository) UpdateSubscription(ctx context.Context, sub *model.WebhookSubscription) error {
+	return getDB(ctx).Save(sub).Error
+}
+
+// DeleteSubscription 删除订阅（软删除）
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id int64) error {
+	return getDB(ctx).Delete(&model.WebhookSubscription{}, id).Error
+}
+
+// GetSubscriptionsByOrganization 获取组织的所有订阅
+func (r *webhookRepository) GetSubscriptionsByOrganization(ctx context.Context, orgID int64) ([]model.WebhookSubscription, error) {
+	var subs []model.WebhookSubscription
+	err := getDB(ctx).
+		Where("organization_id = ?", orgID).
+		Order("id").
+		Find(&subs).Error
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// GetEnabledSubscriptions 获取应接收组织事件的启用中订阅，包括组织的订阅和平台级订阅
+func (r *webhookRepository) GetEnabledSubscriptions(ctx context.Context, orgID int64) ([]model.WebhookSubscription, error) {
+	var subs []model.WebhookSubscription
+	err := getDB(ctx).
+		Where("enabled = ? AND organization_id IN ?", true, []int64{0, orgID}).
+		Order("id").
+		Find(&subs).Error
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// CreateDeliveries 批量创建投递记录
+func (r *webhookRepository) CreateDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return getDB(ctx).Create(&deliveries).Error
+}
+
+// HasDeliveries 判断事件是否已创建过投递记录，不包括手动重新投递的记录
+func (r *webhookRepository) HasDeliveries(ctx context.Context, eventID string) (bool, error) {
+	var count int64
+	err := getDB(ctx).Model(&model.WebhookDelivery{}).
+		Where("event_id = ? AND redelivery_of = 0", eventID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetDelivery 根据ID获取投递记录
+func (r *webhookRepository) GetDelivery(ctx context.Context, id int64) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := getDB(ctx).First(&delivery, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetDeliveries 获取组织的投递记录，subID 为0表示不限订阅，status 为空表示不限状态
+func (r *webhookRepository) GetDeliveries(ctx context.Context, orgID, subID int64, status string, page, pageSize int) ([]model.WebhookDelivery, int64, error) {
+	var deliveries []model.WebhookDelivery
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := getReadDB(ctx).Model(&model.WebhookDelivery{}).Where("organization_id = ?", orgID)
+	if subID != 0 {
+		query = query.Where("subscription_id = ?", subID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// GetDueDeliveries 获取已到投递时间的投递记录
+func (r *webhookRepository) GetDueDeliveries(ctx context.Context, now int64, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := getDB(ctx).
+		Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimDelivery 领取投递记录，将下次尝试时间推迟到租约结束，记录已被其他实例领取时返回false
+// 领取后实例异常退出时，租约结束后记录会被重新投递
+func (r *webhookRepository) ClaimDelivery(ctx context.Context, id, nextAttemptAt, leaseUntil int64) (bool, error) {
+	result := getDB(ctx).Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", id, model.WebhookDeliveryPending, nextAttemptAt).
+		Update("next_attempt_at", leaseUntil)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateDelivery 更新投递记录
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return getDB(ctx).Save(delivery).Error
+}
@@ -0,0 +1,53 @@
+package router
+
+import (
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/notify"
+	"saas-account/repository"
+	"saas-account/service"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerAlertRoutes 注册用量告警相关路由
+func registerAlertRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	appRepo := repository.NewOrganizationApplicationRepository()
+	memberRepo := repository.NewOrganizationMemberRepository()
+	alertService := service.NewAlertService(
+		repository.NewAlertRepository(),
+		appRepo,
+		repository.NewOrganizationRepository(),
+		repository.NewOrganizationApplicationLimitRepository(),
+		repository.NewUsageCounterRepository(),
+		repository.NewStorageGaugeRepository(),
+		notify.GetNotifier(),
+		service.NewOutboxPublisher(repository.NewOutboxRepository()),
+		repository.NewTransactionManager(),
+	)
+	alertHandler := handler.NewAlertHandler(alertService)
+
+	// 应用所属组织的成员可以查看，拥有者和管理员可以修改告警规则
+	appMember := middleware.OrgAccess(memberRepo, "app_id", applicationOrganization(appRepo))
+	appAdmin := middleware.OrgAccess(memberRepo, "app_id", applicationOrganization(appRepo), middleware.OrgRoleOwner, middleware.OrgRoleAdmin)
+	apps := group.Group("/applications/:app_id")
+
+	// 获取告警规则列表
+	apps.GET("/alert-rules", appMember, alertHandler.ListRules)
+
+	// 创建告警规则
+	apps.POST("/alert-rules", appAdmin, alertHandler.CreateRule)
+
+	// 获取告警规则
+	apps.GET("/alert-rules/:rule_id", appMember, alertHandler.GetRule)
+
+	// 更新告警规则
+	apps.PUT("/alert-rules/:rule_id", appAdmin, alertHandler.UpdateRule)
+
+	// 删除告警规则
+	apps.DELETE("/alert-rules/:rule_id", appAdmin, alertHandler.DeleteRule)
+
+	// 获取告警记录
+	apps.GET("/alert-events", appMember, alertHandler.ListEvents)
+}
@@ -1,9 +1,14 @@
 package router
 
 import (
+	"saas-account/config"
 	"saas-account/handler"
+	"saas-account/ingest"
+	"saas-account/middleware"
+	"saas-account/notify"
 	"saas-account/repository"
 	"saas-account/service"
+	"time"
 
 	"github.com/cloudwego/hertz/pkg/route"
 )
@@ -12,12 +17,31 @@ import (
 func registerApplicationUsageRoutes(group *route.RouterGroup) {
 	// 创建依赖
 	usageRepo := repository.NewApplicationUsageRepository()
+	rollupRepo := repository.NewUsageRollupRepository()
 	appRepo := repository.NewOrganizationApplicationRepository()
+	orgRepo := repository.NewOrganizationRepository()
 	limitRepo := repository.NewOrganizationApplicationLimitRepository()
-	usageService := service.NewApplicationUsageService(usageRepo, appRepo, limitRepo)
+	counterRepo := repository.NewUsageCounterRepository()
+	idempotencyRepo := repository.NewUsageIdempotencyRepository()
+	gaugeRepo := repository.NewStorageGaugeRepository()
+	alertService := service.NewAlertService(
+		repository.NewAlertRepository(), appRepo, orgRepo, limitRepo, counterRepo, gaugeRepo, notify.GetNotifier(),
+		service.NewOutboxPublisher(repository.NewOutboxRepository()), repository.NewTransactionManager(),
+	)
+	usageService := service.NewApplicationUsageService(
+		usageRepo, rollupRepo, appRepo, orgRepo, limitRepo, counterRepo,
+		notify.GetNotifier(), ingest.GetIngester(),
+		idempotencyRepo, time.Duration(config.GetConfig().UsageIdempotencyWindow)*time.Hour,
+		service.NewStorageService(gaugeRepo, appRepo, orgRepo, limitRepo, notify.GetNotifier(), alertService, repository.NewTransactionManager()),
+		alertService,
+		repository.NewTransactionManager(),
+	)
 	usageHandler := handler.NewApplicationUsageHandler(usageService)
+	memberRepo := repository.NewOrganizationMemberRepository()
+	appMember := middleware.OrgAccess(memberRepo, "app_id", applicationOrganization(appRepo))
 
-	apps := group.Group("/applications/:app_id")
+	// 仅应用所属组织的成员，认证后按套餐限流
+	apps := group.Group("/applications/:app_id", appMember, appRateLimit())
 
 	// 获取应用使用记录列表
 	apps.GET("/usages", usageHandler.List)
@@ -28,6 +52,9 @@ func registerApplicationUsageRoutes(group *route.RouterGroup) {
 	// 获取应用使用统计
 	apps.GET("/usages/summary", usageHandler.GetSummary)
 
+	// 批量记录使用事件
+	apps.POST("/usages/batch", usageHandler.RecordBatch)
+
 	// 记录API使用
 	apps.POST("/usages/api", usageHandler.RecordAPIUsage)
 
@@ -36,4 +63,10 @@ func registerApplicationUsageRoutes(group *route.RouterGroup) {
 
 	// 记录功能使用
 	apps.POST("/usages/feature", usageHandler.RecordFeatureUsage)
+
+	// 获取组织使用统计（仅组织成员）
+	group.GET("/organizations/:id/usages/summary", middleware.OrgAccess(memberRepo, "id", nil), usageHandler.GetOrganizationSummary)
+
+	// 获取使用记录异步写入指标（仅管理员）
+	group.GET("/admin/usages/ingester", middleware.AdminAuth(), usageHandler.GetIngesterStats)
 }
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/repository"
+	"saas-account/service"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerAuditRoutes 注册审计日志相关路由
+func registerAuditRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	auditService := service.NewAuditService(repository.NewAuditLogRepository(), repository.NewTransactionManager())
+	auditHandler := handler.NewAuditHandler(auditService)
+
+	// 组织级日志和平台级日志使用相同的处理器，平台级路由没有组织ID
+	// 组织级日志只允许组织的拥有者和管理员查询
+	orgAccess := middleware.OrgAccess(repository.NewOrganizationMemberRepository(), "id", nil, middleware.OrgRoleOwner, middleware.OrgRoleAdmin)
+	for _, prefix := range []*route.RouterGroup{
+		group.Group("/organizations/:id", orgAccess),
+		group.Group("/admin", middleware.AdminAuth()),
+	} {
+		// 查询审计日志
+		prefix.GET("/audit-logs", auditHandler.List)
+
+		// 校验审计日志哈希链
+		prefix.GET("/audit-logs/verify", auditHandler.Verify)
+	}
+}
@@ -0,0 +1,29 @@
+package router
+
+import (
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/repository"
+	"saas-account/service"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerEntitlementRoutes 注册功能权益相关路由
+func registerEntitlementRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	limitRepo := repository.NewOrganizationApplicationLimitRepository()
+	entitlementService := service.NewEntitlementService(limitRepo)
+	entitlementHandler := handler.NewEntitlementHandler(entitlementService)
+	appMember := middleware.OrgAccess(repository.NewOrganizationMemberRepository(), "app_id",
+		applicationOrganization(repository.NewOrganizationApplicationRepository()))
+
+	// 仅应用所属组织的成员，认证后按套餐限流
+	apps := group.Group("/applications/:app_id", appMember, appRateLimit())
+
+	// 批量检查功能权益
+	apps.GET("/entitlements", entitlementHandler.CheckAll)
+
+	// 检查单个功能权益
+	apps.GET("/entitlements/:feature", entitlementHandler.Check)
+}
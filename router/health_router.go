@@ -0,0 +1,40 @@
+package router
+
+import (
+	"saas-account/config"
+	"saas-account/filestore"
+	"saas-account/handler"
+	"saas-account/health"
+	"saas-account/middleware"
+	"saas-account/migration"
+	"saas-account/ratelimit"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerHealthRoutes 注册健康检查相关路由，不需要认证，也不记录请求日志
+func registerHealthRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	checker := health.NewChecker(
+		time.Duration(config.GetConfig().HealthCheckTimeout)*time.Millisecond,
+		health.Database(),
+		health.Migrations(migration.NewMigrator(config.DB)),
+		health.FileStore(filestore.GetStore()),
+		health.RateLimitStore(ratelimit.GetStore),
+		health.Shutdown(),
+	)
+	healthHandler := handler.NewHealthHandler(checker)
+
+	// 探针请求频繁，不记录请求日志
+	middleware.SkipRequestLog("/healthz", "/readyz", "/version")
+
+	// 存活检查
+	group.GET("/healthz", healthHandler.Live)
+
+	// 就绪检查
+	group.GET("/readyz", healthHandler.Ready)
+
+	// 构建信息
+	group.GET("/version", healthHandler.Version)
+}
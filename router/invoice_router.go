@@ -0,0 +1,55 @@
+package router
+
+import (
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/notify"
+	"saas-account/repository"
+	"saas-account/service"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerInvoiceRoutes 注册账单相关路由
+func registerInvoiceRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	appRepo := repository.NewOrganizationApplicationRepository()
+	orgRepo := repository.NewOrganizationRepository()
+	limitRepo := repository.NewOrganizationApplicationLimitRepository()
+	gaugeRepo := repository.NewStorageGaugeRepository()
+	alertService := service.NewAlertService(
+		repository.NewAlertRepository(), appRepo, orgRepo, limitRepo,
+		repository.NewUsageCounterRepository(), gaugeRepo, notify.GetNotifier(),
+		service.NewOutboxPublisher(repository.NewOutboxRepository()), repository.NewTransactionManager(),
+	)
+	storageService := service.NewStorageService(gaugeRepo, appRepo, orgRepo, limitRepo, notify.GetNotifier(), alertService, repository.NewTransactionManager())
+	invoiceRepo := repository.NewInvoiceRepository()
+	invoiceService := service.NewInvoiceService(
+		invoiceRepo,
+		orgRepo, appRepo,
+		repository.NewOrganizationApplicationMemberRepository(),
+		limitRepo,
+		repository.NewPlanRepository(),
+		repository.NewPlanChangeRepository(),
+		repository.NewUsageRollupRepository(),
+		storageService,
+		repository.NewTransactionManager(),
+	)
+	invoiceHandler := handler.NewInvoiceHandler(invoiceService)
+	memberRepo := repository.NewOrganizationMemberRepository()
+	orgAdmin := middleware.OrgAccess(memberRepo, "id", nil, middleware.OrgRoleOwner, middleware.OrgRoleAdmin)
+
+	// 获取组织的账单列表（组织拥有者或管理员）
+	group.GET("/organizations/:id/invoices", orgAdmin, invoiceHandler.ListByOrganization)
+
+	// 生成或重新生成计费周期的草稿账单（组织拥有者或管理员）
+	group.POST("/organizations/:id/invoices", orgAdmin, invoiceHandler.Generate)
+
+	// 获取账单详情（账单所属组织的拥有者或管理员）
+	group.GET("/invoices/:id",
+		middleware.OrgAccess(memberRepo, "id", invoiceOrganization(invoiceRepo), middleware.OrgRoleOwner, middleware.OrgRoleAdmin),
+		invoiceHandler.GetByID)
+
+	// 出账（仅管理员）
+	group.POST("/invoices/:id/issue", middleware.AdminAuth(), invoiceHandler.Issue)
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"saas-account/handler"
+	"saas-account/middleware"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerMetricsRoutes 注册Prometheus指标路由，配置了 metrics_token 时需要携带令牌，不记录请求日志
+func registerMetricsRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	metricsHandler := handler.NewMetricsHandler()
+
+	// 抓取请求频繁，不记录请求日志
+	middleware.SkipRequestLog("/metrics")
+
+	// Prometheus指标
+	group.GET("/metrics", middleware.MetricsAuth(), metricsHandler.Metrics)
+}
@@ -0,0 +1,40 @@
+package router
+
+import (
+	"context"
+	"saas-account/middleware"
+	"saas-account/repository"
+)
+
+// applicationOrganization 查询应用所属的组织，用于按应用ID检查组织成员身份
+func applicationOrganization(appRepo repository.OrganizationApplicationRepository) middleware.OrgLookupFunc {
+	return func(ctx context.Context, id int64) (int64, error) {
+		app, err := appRepo.GetByID(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		return app.OrganizationId, nil
+	}
+}
+
+// exportJobOrganization 查询导出任务所属的组织
+func exportJobOrganization(jobRepo repository.UsageExportJobRepository) middleware.OrgLookupFunc {
+	return func(ctx context.Context, id int64) (int64, error) {
+		job, err := jobRepo.GetByID(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		return job.OrganizationId, nil
+	}
+}
+
+// invoiceOrganization 查询账单所属的组织
+func invoiceOrganization(invoiceRepo repository.InvoiceRepository) middleware.OrgLookupFunc {
+	return func(ctx context.Context, id int64) (int64, error) {
+		invoice, err := invoiceRepo.GetByID(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		return invoice.OrganizationId, nil
+	}
+}
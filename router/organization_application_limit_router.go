@@ -2,6 +2,7 @@ package router
 
 import (
 	"saas-account/handler"
+	"saas-account/middleware"
 	"saas-account/repository"
 	"saas-account/service"
 
@@ -16,14 +17,26 @@ func registerOrganizationApplicationLimitRoutes(group *route.RouterGroup) {
 	appLimitRepo := repository.NewOrganizationApplicationLimitRepository()
 	orgRepo := repository.NewOrganizationRepository()
 	userRepo := repository.NewUserRepository()
-	appService := service.NewOrganizationApplicationService(appRepo, appMemberRepo, appLimitRepo, orgRepo, userRepo)
+	planRepo := repository.NewPlanRepository()
+	planChangeRepo := repository.NewPlanChangeRepository()
+	publisher := service.NewOutboxPublisher(repository.NewOutboxRepository())
+	txManager := repository.NewTransactionManager()
+	auditService := service.NewAuditService(repository.NewAuditLogRepository(), txManager)
+	appService := service.NewOrganizationApplicationService(appRepo, appMemberRepo, appLimitRepo, orgRepo, userRepo, planRepo, planChangeRepo, publisher, txManager, auditService, repository.NewStorageGaugeRepository())
 	appHandler := handler.NewOrganizationApplicationHandler(appService)
 
+	// 应用所属组织的成员可以查看，拥有者和管理员可以修改应用限制和套餐
+	memberRepo := repository.NewOrganizationMemberRepository()
+	appMember := middleware.OrgAccess(memberRepo, "app_id", applicationOrganization(appRepo))
+	appAdmin := middleware.OrgAccess(memberRepo, "app_id", applicationOrganization(appRepo), middleware.OrgRoleOwner, middleware.OrgRoleAdmin)
 	apps := group.Group("/applications/:app_id")
 
 	// 获取应用限制
-	apps.GET("/limits", appHandler.GetLimit)
+	apps.GET("/limits", appMember, appHandler.GetLimit)
 
 	// 创建或更新应用限制
-	apps.PUT("/limits", appHandler.SetLimit)
+	apps.PUT("/limits", appAdmin, appHandler.SetLimit)
+
+	// 变更应用套餐
+	apps.PUT("/plan", appAdmin, appHandler.ChangePlan)
 }
@@ -16,7 +16,12 @@ func registerOrganizationApplicationMemberRoutes(group *route.RouterGroup) {
 	appLimitRepo := repository.NewOrganizationApplicationLimitRepository()
 	orgRepo := repository.NewOrganizationRepository()
 	userRepo := repository.NewUserRepository()
-	appService := service.NewOrganizationApplicationService(appRepo, appMemberRepo, appLimitRepo, orgRepo, userRepo)
+	planRepo := repository.NewPlanRepository()
+	planChangeRepo := repository.NewPlanChangeRepository()
+	publisher := service.NewOutboxPublisher(repository.NewOutboxRepository())
+	txManager := repository.NewTransactionManager()
+	auditService := service.NewAuditService(repository.NewAuditLogRepository(), txManager)
+	appService := service.NewOrganizationApplicationService(appRepo, appMemberRepo, appLimitRepo, orgRepo, userRepo, planRepo, planChangeRepo, publisher, txManager, auditService, repository.NewStorageGaugeRepository())
 	appHandler := handler.NewOrganizationApplicationHandler(appService)
 
 	apps := group.Group("/applications/:app_id")
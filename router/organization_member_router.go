@@ -1,36 +0,0 @@
-package router
-
-import (
-	"github.com/cloudwego/hertz/pkg/app"
-	"github.com/cloudwego/hertz/pkg/route"
-)
-
-// registerOrganizationMemberRoutes 注册组织成员相关路由
-func registerOrganizationMemberRoutes(group *route.RouterGroup) {
-	orgs := group.Group("/organizations/:org_id")
-
-	// 获取组织成员列表
-	orgs.GET("/members", func(ctx *app.RequestContext) {
-		// TODO: 实现获取组织成员列表的处理函数
-	})
-
-	// 添加组织成员
-	orgs.POST("/members", func(ctx *app.RequestContext) {
-		// TODO: 实现添加组织成员的处理函数
-	})
-
-	// 获取单个组织成员
-	orgs.GET("/members/:id", func(ctx *app.RequestContext) {
-		// TODO: 实现获取单个组织成员的处理函数
-	})
-
-	// 更新组织成员
-	orgs.PUT("/members/:id", func(ctx *app.RequestContext) {
-		// TODO: 实现更新组织成员的处理函数
-	})
-
-	// 删除组织成员
-	orgs.DELETE("/members/:id", func(ctx *app.RequestContext) {
-		// TODO: 实现删除组织成员的处理函数
-	})
-}
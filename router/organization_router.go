@@ -14,7 +14,11 @@ func registerOrganizationRoutes(group *route.RouterGroup) {
 	orgRepo := repository.NewOrganizationRepository()
 	orgMemberRepo := repository.NewOrganizationMemberRepository()
 	userRepo := repository.NewUserRepository()
-	orgService := service.NewOrganizationService(orgRepo, orgMemberRepo, userRepo)
+	appRepo := repository.NewOrganizationApplicationRepository()
+	publisher := service.NewOutboxPublisher(repository.NewOutboxRepository())
+	txManager := repository.NewTransactionManager()
+	auditService := service.NewAuditService(repository.NewAuditLogRepository(), txManager)
+	orgService := service.NewOrganizationService(orgRepo, orgMemberRepo, userRepo, appRepo, publisher, txManager, auditService, repository.NewUsageRollupRepository())
 	orgHandler := handler.NewOrganizationHandler(orgService)
 
 	orgs := group.Group("/organizations")
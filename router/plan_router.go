@@ -0,0 +1,38 @@
+package router
+
+import (
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/repository"
+	"saas-account/service"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerPlanRoutes 注册套餐相关路由
+func registerPlanRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	planRepo := repository.NewPlanRepository()
+	planService := service.NewPlanService(planRepo, repository.NewTransactionManager())
+	planHandler := handler.NewPlanHandler(planService)
+
+	plans := group.Group("/plans")
+
+	// 获取套餐列表
+	plans.GET("", planHandler.List)
+
+	// 获取单个套餐
+	plans.GET("/:id", planHandler.GetByID)
+
+	// 获取套餐的所有版本
+	plans.GET("/codes/:code/versions", planHandler.GetVersions)
+
+	// 创建套餐或套餐新版本（仅管理员）
+	plans.POST("", middleware.AdminAuth(), planHandler.Create)
+
+	// 停售套餐（仅管理员）
+	plans.POST("/:id/deprecate", middleware.AdminAuth(), planHandler.Deprecate)
+
+	// 设置默认套餐（仅管理员）
+	plans.POST("/:id/default", middleware.AdminAuth(), planHandler.SetDefault)
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"saas-account/config"
+	"saas-account/middleware"
+	"saas-account/ratelimit"
+	"saas-account/repository"
+	"saas-account/service"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// appRateLimit 创建按应用套餐限流的中间件，每次请求使用当前的全局令牌桶存储
+// 需要放在认证之后，按用户限流时才能获取到用户ID
+func appRateLimit() app.HandlerFunc {
+	cfg := config.GetConfig()
+	rateLimitService := service.NewRateLimitService(repository.NewOrganizationApplicationLimitRepository())
+	return middleware.RateLimit(ratelimit.GetStore, rateLimitService.GetPolicy, cfg.RateLimitKeyBy,
+		middleware.RateLimitClientIP(cfg.TrustedProxyNets()), cfg.RateLimitFailOpen)
+}
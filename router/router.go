@@ -6,6 +6,12 @@ import (
 
 // RegisterRoutes 注册所有路由
 func RegisterRoutes(h *server.Hertz) {
+	// 注册健康检查相关路由，不在API版本前缀下
+	registerHealthRoutes(h.Group(""))
+
+	// 注册Prometheus指标路由，不在API版本前缀下
+	registerMetricsRoutes(h.Group(""))
+
 	// API版本前缀
 	api := h.Group("/api/v1")
 
@@ -15,9 +21,6 @@ func RegisterRoutes(h *server.Hertz) {
 	// 注册组织相关路由
 	registerOrganizationRoutes(api)
 
-	// 注册组织成员相关路由
-	registerOrganizationMemberRoutes(api)
-
 	// 注册组织应用相关路由
 	registerOrganizationApplicationRoutes(api)
 
@@ -29,4 +32,34 @@ func RegisterRoutes(h *server.Hertz) {
 
 	// 注册应用使用记录相关路由
 	registerApplicationUsageRoutes(api)
+
+	// 注册使用记录导出相关路由
+	registerUsageExportRoutes(api)
+
+	// 注册存储用量相关路由
+	registerStorageRoutes(api)
+
+	// 注册用量告警相关路由
+	registerAlertRoutes(api)
+
+	// 注册Webhook相关路由
+	registerWebhookRoutes(api)
+
+	// 注册审计日志相关路由
+	registerAuditRoutes(api)
+
+	// 注册套餐相关路由
+	registerPlanRoutes(api)
+
+	// 注册应用订阅相关路由
+	registerSubscriptionRoutes(api)
+
+	// 注册账单相关路由
+	registerInvoiceRoutes(api)
+
+	// 注册功能权益相关路由
+	registerEntitlementRoutes(api)
+
+	// 注册回收站相关路由
+	registerTrashRoutes(api)
 }
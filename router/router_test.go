@@ -0,0 +1,57 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// TestRegisterRoutes 注册全部路由，路径或参数名冲突时 Hertz 会在注册时 panic
+func TestRegisterRoutes(t *testing.T) {
+	h := server.Default()
+	RegisterRoutes(h)
+
+	if len(h.Routes()) == 0 {
+		t.Fatal("未注册任何路由")
+	}
+}
+
+// TestRoutesRequireAuth 组织和应用级的路由要求登录
+func TestRoutesRequireAuth(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	api := engine.Group("/api/v1")
+	registerAuditRoutes(api)
+	registerAlertRoutes(api)
+	registerSubscriptionRoutes(api)
+	registerOrganizationApplicationLimitRoutes(api)
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/api/v1/organizations/1/audit-logs"},
+		{"GET", "/api/v1/organizations/1/audit-logs/verify"},
+		{"GET", "/api/v1/applications/1/alert-rules"},
+		{"POST", "/api/v1/applications/1/alert-rules"},
+		{"PUT", "/api/v1/applications/1/alert-rules/2"},
+		{"DELETE", "/api/v1/applications/1/alert-rules/2"},
+		{"GET", "/api/v1/applications/1/alert-events"},
+		{"GET", "/api/v1/applications/1/subscription"},
+		{"POST", "/api/v1/applications/1/subscription"},
+		{"POST", "/api/v1/applications/1/subscription/trial"},
+		{"POST", "/api/v1/applications/1/subscription/renew"},
+		{"PUT", "/api/v1/applications/1/subscription/auto-renew"},
+		{"GET", "/api/v1/applications/1/limits"},
+		{"PUT", "/api/v1/applications/1/limits"},
+		{"PUT", "/api/v1/applications/1/plan"},
+	}
+	for _, tt := range tests {
+		if code := ut.PerformRequest(engine, tt.method, tt.path, nil).Result().StatusCode(); code != http.StatusUnauthorized {
+			t.Errorf("%s %s 状态码 = %d, 期望 401", tt.method, tt.path, code)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package router
+
+import (
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/notify"
+	"saas-account/repository"
+	"saas-account/service"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerStorageRoutes 注册存储用量相关路由
+func registerStorageRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	gaugeRepo := repository.NewStorageGaugeRepository()
+	appRepo := repository.NewOrganizationApplicationRepository()
+	orgRepo := repository.NewOrganizationRepository()
+	limitRepo := repository.NewOrganizationApplicationLimitRepository()
+	alertService := service.NewAlertService(
+		repository.NewAlertRepository(), appRepo, orgRepo, limitRepo,
+		repository.NewUsageCounterRepository(), gaugeRepo, notify.GetNotifier(),
+		service.NewOutboxPublisher(repository.NewOutboxRepository()), repository.NewTransactionManager(),
+	)
+	storageService := service.NewStorageService(gaugeRepo, appRepo, orgRepo, limitRepo, notify.GetNotifier(), alertService, repository.NewTransactionManager())
+	storageHandler := handler.NewStorageHandler(storageService)
+	appMember := middleware.OrgAccess(repository.NewOrganizationMemberRepository(), "app_id", applicationOrganization(appRepo))
+
+	// 仅应用所属组织的成员，认证后按套餐限流
+	storage := group.Group("/applications/:app_id/storage", appMember, appRateLimit())
+
+	// 获取当前存储用量
+	storage.GET("", storageHandler.GetStatus)
+
+	// 设置当前存储占用
+	storage.PUT("", storageHandler.SetUsage)
+
+	// 增减存储占用
+	storage.POST("/delta", storageHandler.AddUsage)
+
+	// 获取存储计费用量
+	storage.GET("/billing", storageHandler.GetBilling)
+}
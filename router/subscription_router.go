@@ -0,0 +1,60 @@
+package router
+
+import (
+	"saas-account/config"
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/notify"
+	"saas-account/payment"
+	"saas-account/repository"
+	"saas-account/service"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerSubscriptionRoutes 注册应用订阅相关路由
+func registerSubscriptionRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	appConfig := config.GetConfig()
+	appRepo := repository.NewOrganizationApplicationRepository()
+	appMemberRepo := repository.NewOrganizationApplicationMemberRepository()
+	appLimitRepo := repository.NewOrganizationApplicationLimitRepository()
+	planRepo := repository.NewPlanRepository()
+	planChangeRepo := repository.NewPlanChangeRepository()
+	memberRepo := repository.NewOrganizationMemberRepository()
+	txManager := repository.NewTransactionManager()
+	auditService := service.NewAuditService(repository.NewAuditLogRepository(), txManager)
+	subscriptionService := service.NewSubscriptionService(
+		appRepo, appMemberRepo, appLimitRepo, planRepo, planChangeRepo,
+		payment.GetProvider(), notify.GetNotifier(),
+		time.Duration(appConfig.SubscriptionGraceDays)*24*time.Hour,
+		time.Duration(appConfig.SubscriptionNotifyDays)*24*time.Hour,
+		service.NewOutboxPublisher(repository.NewOutboxRepository()),
+		txManager,
+		auditService,
+		repository.NewSubscriptionPaymentRepository(),
+		repository.NewStorageGaugeRepository(),
+	)
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionService)
+
+	// 应用所属组织的成员可以查看，拥有者和管理员可以变更订阅
+	appMember := middleware.OrgAccess(memberRepo, "app_id", applicationOrganization(appRepo))
+	appAdmin := middleware.OrgAccess(memberRepo, "app_id", applicationOrganization(appRepo), middleware.OrgRoleOwner, middleware.OrgRoleAdmin)
+	apps := group.Group("/applications/:app_id")
+
+	// 获取应用订阅信息
+	apps.GET("/subscription", appMember, subscriptionHandler.Get)
+
+	// 订阅套餐
+	apps.POST("/subscription", appAdmin, subscriptionHandler.Subscribe)
+
+	// 开始试用
+	apps.POST("/subscription/trial", appAdmin, subscriptionHandler.StartTrial)
+
+	// 手动续费
+	apps.POST("/subscription/renew", appAdmin, subscriptionHandler.Renew)
+
+	// 设置自动续费
+	apps.PUT("/subscription/auto-renew", appAdmin, subscriptionHandler.SetAutoRenew)
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/repository"
+	"saas-account/service"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerTrashRoutes 注册回收站相关路由（仅管理员）
+func registerTrashRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	userRepo := repository.NewUserRepository()
+	orgRepo := repository.NewOrganizationRepository()
+	appRepo := repository.NewOrganizationApplicationRepository()
+	trashService := service.NewTrashService(userRepo, orgRepo, appRepo, repository.NewOrganizationMemberRepository(), repository.NewTransactionManager(), repository.NewOrganizationApplicationMemberRepository())
+	trashHandler := handler.NewTrashHandler(trashService)
+
+	trash := group.Group("/admin/trash", middleware.AdminAuth())
+
+	// 获取已删除的用户列表
+	trash.GET("/users", trashHandler.ListUsers)
+
+	// 恢复已删除的用户
+	trash.POST("/users/:id/restore", trashHandler.RestoreUser)
+
+	// 彻底删除用户
+	trash.DELETE("/users/:id", trashHandler.PurgeUser)
+
+	// 获取已删除的组织列表
+	trash.GET("/organizations", trashHandler.ListOrganizations)
+
+	// 恢复已删除的组织
+	trash.POST("/organizations/:id/restore", trashHandler.RestoreOrganization)
+
+	// 彻底删除组织
+	trash.DELETE("/organizations/:id", trashHandler.PurgeOrganization)
+
+	// 获取已删除的应用列表
+	trash.GET("/applications", trashHandler.ListApplications)
+
+	// 恢复已删除的应用
+	trash.POST("/applications/:id/restore", trashHandler.RestoreApplication)
+
+	// 彻底删除应用
+	trash.DELETE("/applications/:id", trashHandler.PurgeApplication)
+}
@@ -0,0 +1,43 @@
+package router
+
+import (
+	"saas-account/config"
+	"saas-account/filestore"
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/repository"
+	"saas-account/service"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerUsageExportRoutes 注册使用记录导出相关路由
+func registerUsageExportRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	jobRepo := repository.NewUsageExportJobRepository()
+	appRepo := repository.NewOrganizationApplicationRepository()
+	memberRepo := repository.NewOrganizationMemberRepository()
+	exportService := service.NewUsageExportService(
+		repository.NewApplicationUsageRepository(),
+		jobRepo,
+		appRepo,
+		repository.NewOrganizationRepository(),
+		filestore.GetStore(),
+		time.Duration(config.GetConfig().ExportRetentionHours)*time.Hour,
+	)
+	exportHandler := handler.NewUsageExportHandler(exportService)
+
+	// 导出应用使用记录（仅应用所属组织的成员）
+	group.GET("/applications/:app_id/usages/export",
+		middleware.OrgAccess(memberRepo, "app_id", applicationOrganization(appRepo)), exportHandler.ExportApplication)
+
+	// 导出组织使用记录（仅组织成员）
+	group.GET("/organizations/:id/usages/export", middleware.OrgAccess(memberRepo, "id", nil), exportHandler.ExportOrganization)
+
+	// 获取导出任务（仅任务所属组织的成员）
+	group.GET("/exports/:id", middleware.OrgAccess(memberRepo, "id", exportJobOrganization(jobRepo)), exportHandler.GetJob)
+
+	// 下载导出文件，使用导出任务中的下载令牌验证
+	group.GET("/exports/:id/download", exportHandler.Download)
+}
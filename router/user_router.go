@@ -12,7 +12,10 @@ import (
 func registerUserRoutes(group *route.RouterGroup) {
 	// 创建依赖
 	userRepo := repository.NewUserRepository()
-	userService := service.NewUserService(userRepo)
+	publisher := service.NewOutboxPublisher(repository.NewOutboxRepository())
+	txManager := repository.NewTransactionManager()
+	auditService := service.NewAuditService(repository.NewAuditLogRepository(), txManager)
+	userService := service.NewUserService(userRepo, publisher, txManager, auditService)
 	userHandler := handler.NewUserHandler(userService)
 
 	users := group.Group("/users")
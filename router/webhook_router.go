@@ -0,0 +1,61 @@
+package router
+
+import (
+	"saas-account/config"
+	"saas-account/handler"
+	"saas-account/middleware"
+	"saas-account/repository"
+	"saas-account/service"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// registerWebhookRoutes 注册 Webhook 相关路由
+func registerWebhookRoutes(group *route.RouterGroup) {
+	// 创建依赖
+	appConfig := config.GetConfig()
+	webhookService := service.NewWebhookService(
+		repository.NewWebhookRepository(),
+		repository.NewOrganizationRepository(),
+		time.Duration(appConfig.WebhookTimeout)*time.Second,
+		appConfig.WebhookMaxAttempts,
+		time.Duration(appConfig.WebhookRetryBase)*time.Second,
+	)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+
+	// 组织级订阅和平台级订阅使用相同的处理器，平台级路由没有组织ID
+	// 组织级订阅只允许组织的拥有者和管理员管理
+	orgAccess := middleware.OrgAccess(repository.NewOrganizationMemberRepository(), "id", nil, middleware.OrgRoleOwner, middleware.OrgRoleAdmin)
+	for _, prefix := range []*route.RouterGroup{
+		group.Group("/organizations/:id", orgAccess),
+		group.Group("/admin", middleware.AdminAuth()),
+	} {
+		// 获取订阅列表
+		prefix.GET("/webhooks", webhookHandler.ListSubscriptions)
+
+		// 创建订阅
+		prefix.POST("/webhooks", webhookHandler.CreateSubscription)
+
+		// 获取订阅
+		prefix.GET("/webhooks/:webhook_id", webhookHandler.GetSubscription)
+
+		// 更新订阅
+		prefix.PUT("/webhooks/:webhook_id", webhookHandler.UpdateSubscription)
+
+		// 删除订阅
+		prefix.DELETE("/webhooks/:webhook_id", webhookHandler.DeleteSubscription)
+
+		// 轮换签名密钥
+		prefix.POST("/webhooks/:webhook_id/rotate-secret", webhookHandler.RotateSecret)
+
+		// 获取投递记录
+		prefix.GET("/webhook-deliveries", webhookHandler.ListDeliveries)
+
+		// 获取投递记录详情
+		prefix.GET("/webhook-deliveries/:delivery_id", webhookHandler.GetDelivery)
+
+		// 重新投递
+		prefix.POST("/webhook-deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+	}
+}
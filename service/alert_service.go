@@ -0,0 +1,547 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"saas-account/logger"
+	"saas-account/model"
+	"saas-account/notify"
+	"saas-account/repository"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// alertUsageTypes 支持告警的使用类型
+var alertUsageTypes = []string{"api_call", "storage"}
+
+// alertWebhookTimeout 告警 Webhook 的请求超时时间
+const alertWebhookTimeout = 10 * time.Second
+
+// registerAlertWebhookOnce 告警 Webhook 渠道只注册一次
+var registerAlertWebhookOnce sync.Once
+
+// registerAlertWebhookChannel 注册告警 Webhook 渠道，与 Webhook 投递使用同一个限制连接地址的客户端
+func registerAlertWebhookChannel() {
+	registerAlertWebhookOnce.Do(func() {
+		notify.RegisterChannel("webhook", notify.NewWebhookChannel(newWebhookClient(alertWebhookTimeout)))
+	})
+}
+
+// AlertService 用量告警服务接口
+type AlertService interface {
+	CreateRule(ctx context.Context, rule *model.AlertRule) error
+	GetRule(ctx context.Context, appID, ruleID int64) (*model.AlertRule, error)
+	ListRules(ctx context.Context, appID int64) ([]model.AlertRule, error)
+	UpdateRule(ctx context.Context, rule *model.AlertRule) error
+	DeleteRule(ctx context.Context, appID, ruleID int64) error
+	ListEvents(ctx context.Context, appID int64, page, pageSize int) ([]model.AlertEvent, int64, error)
+	Evaluate(ctx context.Context, app *model.OrganizationApplication, status *QuotaStatus, at time.Time)
+	EvaluateAll(ctx context.Context) (int, error)
+}
+
+// alertService 用量告警服务实现
+type alertService struct {
+	alertRepo   repository.AlertRepository
+	appRepo     repository.OrganizationApplicationRepository
+	orgRepo     repository.OrganizationRepository
+	limitRepo   repository.OrganizationApplicationLimitRepository
+	counterRepo repository.UsageCounterRepository
+	gaugeRepo   repository.StorageGaugeRepository
+	notifier    notify.Notifier
+	publisher   EventPublisher
+	txManager   repository.TransactionManager
+
+	// 已告警的规则及统计周期，避免同一周期内重复写入告警记录
+	firedMu sync.Mutex
+	fired   map[int64]int64
+}
+
+// NewAlertService 创建用量告警服务
+func NewAlertService(
+	alertRepo repository.AlertRepository,
+	appRepo repository.OrganizationApplicationRepository,
+	orgRepo repository.OrganizationRepository,
+	limitRepo repository.OrganizationApplicationLimitRepository,
+	counterRepo repository.UsageCounterRepository,
+	gaugeRepo repository.StorageGaugeRepository,
+	notifier notify.Notifier,
+	publisher EventPublisher,
+	txManager repository.TransactionManager,
+) AlertService {
+	registerAlertWebhookChannel()
+	return &alertService{
+		alertRepo:   alertRepo,
+		appRepo:     appRepo,
+		orgRepo:     orgRepo,
+		limitRepo:   limitRepo,
+		counterRepo: counterRepo,
+		gaugeRepo:   gaugeRepo,
+		notifier:    notifier,
+		publisher:   publisher,
+		txManager:   txManager,
+		fired:       make(map[int64]int64),
+	}
+}
+
+// alertRuleCacheEntry 告警规则缓存条目
+type alertRuleCacheEntry struct {
+	rules     []model.AlertRule
+	expiresAt time.Time
+}
+
+// alertRuleCache 应用告警规则缓存，减少记录使用量时的数据库查询
+type alertRuleCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[int64]alertRuleCacheEntry
+}
+
+// appAlertRuleCache 全局应用告警规则缓存，告警规则变更时失效
+var appAlertRuleCache = &alertRuleCache{
+	ttl:     30 * time.Second,
+	entries: make(map[int64]alertRuleCacheEntry),
+}
+
+// get 获取缓存的启用中告警规则
+func (c *alertRuleCache) get(appID int64) ([]model.AlertRule, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[appID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.rules, true
+}
+
+// set 缓存启用中告警规则
+func (c *alertRuleCache) set(appID int64, rules []model.AlertRule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[appID] = alertRuleCacheEntry{
+		rules:     rules,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
+
+// invalidate 使应用告警规则缓存失效
+func (c *alertRuleCache) invalidate(appID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, appID)
+}
+
+// parseAlertChannels 解析并校验告警规则的发送渠道
+func parseAlertChannels(raw string) ([]model.AlertChannel, error) {
+	var channels []model.AlertChannel
+	if raw == "" {
+		return channels, nil
+	}
+
+	if err := json.Unmarshal([]byte(raw), &channels); err != nil {
+		return nil, errors.New("发送渠道必须是有效的JSON数组")
+	}
+
+	for _, c := range channels {
+		if _, ok := notify.GetChannel(c.Type); !ok {
+			return nil, fmt.Errorf("不支持的发送渠道: %s", c.Type)
+		}
+		switch c.Type {
+		case "webhook":
+			u, err := url.Parse(c.Target)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+				return nil, fmt.Errorf("无效的webhook地址: %s", c.Target)
+			}
+		case "email":
+			for _, address := range strings.Split(c.Target, ",") {
+				if !strings.Contains(address, "@") {
+					return nil, fmt.Errorf("无效的邮箱地址: %s", address)
+				}
+			}
+		default:
+			if c.Target == "" {
+				return nil, fmt.Errorf("发送渠道 %s 缺少接收地址", c.Type)
+			}
+		}
+	}
+
+	return channels, nil
+}
+
+// validateAlertRule 校验告警规则，webhook 地址解析到内网或元数据地址时拒绝
+func validateAlertRule(ctx context.Context, rule *model.AlertRule) error {
+	supported := false
+	for _, usageType := range alertUsageTypes {
+		if rule.UsageType == usageType {
+			supported = true
+		}
+	}
+	if !supported {
+		return errors.New("无效的使用类型，可选值为api_call, storage")
+	}
+
+	switch rule.ThresholdType {
+	case model.AlertThresholdPercent, model.AlertThresholdAbsolute:
+	default:
+		return errors.New("无效的阈值类型，可选值为percent, absolute")
+	}
+	if rule.Threshold <= 0 {
+		return errors.New("告警阈值必须大于0")
+	}
+
+	if rule.Channels == "" {
+		rule.Channels = "[]"
+	}
+	channels, err := parseAlertChannels(rule.Channels)
+	if err != nil {
+		return err
+	}
+	for _, c := range channels {
+		if c.Type != "webhook" {
+			continue
+		}
+		u, _ := url.Parse(c.Target)
+		if err := checkWebhookHost(ctx, u.Hostname()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alertThreshold 计算告警规则对应的使用量阈值，配额不限时百分比规则不生效
+func alertThreshold(rule *model.AlertRule, quota int64) (int64, bool) {
+	if rule.ThresholdType == model.AlertThresholdAbsolute {
+		return rule.Threshold, true
+	}
+	if quota < 0 {
+		return 0, false
+	}
+	return (quota*rule.Threshold + 99) / 100, true
+}
+
+// alertPeriod 获取告警去重的统计周期，api_call 与配额窗口一致按自然日，其他类型按自然月
+func alertPeriod(usageType string, at time.Time, loc *time.Location) int64 {
+	if windowStart, _ := quotaWindow(usageType, at, loc); windowStart != 0 {
+		return windowStart
+	}
+	return periodStart(UsageGranularityMonth, at, loc).Unix()
+}
+
+// CreateRule 创建告警规则
+func (s *alertService) CreateRule(ctx context.Context, rule *model.AlertRule) error {
+	// 检查应用是否存在
+	if _, err := s.appRepo.GetByID(ctx, rule.ApplicationId); err != nil {
+		return err
+	}
+
+	if err := validateAlertRule(ctx, rule); err != nil {
+		return err
+	}
+
+	rule.LastFiredAt = 0
+	if err := s.alertRepo.CreateRule(ctx, rule); err != nil {
+		return err
+	}
+
+	appAlertRuleCache.invalidate(rule.ApplicationId)
+	return nil
+}
+
+// GetRule 获取应用的告警规则
+func (s *alertService) GetRule(ctx context.Context, appID, ruleID int64) (*model.AlertRule, error) {
+	rule, err := s.alertRepo.GetRule(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if rule.ApplicationId != appID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return rule, nil
+}
+
+// ListRules 获取应用的所有告警规则
+func (s *alertService) ListRules(ctx context.Context, appID int64) ([]model.AlertRule, error) {
+	// 检查应用是否存在
+	if _, err := s.appRepo.GetByID(ctx, appID); err != nil {
+		return nil, err
+	}
+
+	return s.alertRepo.GetRulesByApplication(ctx, appID)
+}
+
+// UpdateRule 更新告警规则，规则所属应用和最近告警时间不会被修改
+func (s *alertService) UpdateRule(ctx context.Context, rule *model.AlertRule) error {
+	existing, err := s.GetRule(ctx, rule.ApplicationId, rule.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := validateAlertRule(ctx, rule); err != nil {
+		return err
+	}
+
+	rule.CreatedAt = existing.CreatedAt
+	rule.LastFiredAt = existing.LastFiredAt
+	if err := s.alertRepo.UpdateRule(ctx, rule); err != nil {
+		return err
+	}
+
+	appAlertRuleCache.invalidate(rule.ApplicationId)
+	return nil
+}
+
+// DeleteRule 删除应用的告警规则
+func (s *alertService) DeleteRule(ctx context.Context, appID, ruleID int64) error {
+	if _, err := s.GetRule(ctx, appID, ruleID); err != nil {
+		return err
+	}
+
+	if err := s.alertRepo.DeleteRule(ctx, ruleID); err != nil {
+		return err
+	}
+
+	appAlertRuleCache.invalidate(appID)
+	return nil
+}
+
+// ListEvents 获取应用的告警记录
+func (s *alertService) ListEvents(ctx context.Context, appID int64, page, pageSize int) ([]model.AlertEvent, int64, error) {
+	return s.alertRepo.GetEventsByApplication(ctx, appID, page, pageSize)
+}
+
+// Evaluate 根据记录使用量后的配额状态检查告警规则，达到阈值且本周期未告警时发送告警，失败只记录日志
+func (s *alertService) Evaluate(ctx context.Context, app *model.OrganizationApplication, status *QuotaStatus, at time.Time) {
+	if _, err := s.evaluate(ctx, app, status, at); err != nil {
+		logger.GetLogger().ErrorWithContext(ctx, "检查用量告警失败: 应用=%d, 错误: %v", app.ID, err)
+	}
+}
+
+// EvaluateAll 检查所有配置了告警规则的应用的当前用量，返回发送的告警数量
+func (s *alertService) EvaluateAll(ctx context.Context) (int, error) {
+	appIDs, err := s.alertRepo.GetEnabledApplicationIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	total := 0
+	for _, appID := range appIDs {
+		app, err := s.appRepo.GetByID(ctx, appID)
+		if err != nil {
+			// 应用已删除
+			continue
+		}
+
+		for _, usageType := range alertUsageTypes {
+			status, err := s.currentStatus(ctx, app, usageType, now)
+			if err == nil {
+				var fired int
+				fired, err = s.evaluate(ctx, app, status, now)
+				total += fired
+			}
+			if err != nil {
+				logger.GetLogger().ErrorWithContext(ctx, "检查用量告警失败: 应用=%d, 类型=%s, 错误: %v", appID, usageType, err)
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// currentStatus 获取应用当前的配额状态
+func (s *alertService) currentStatus(ctx context.Context, app *model.OrganizationApplication, usageType string, at time.Time) (*QuotaStatus, error) {
+	mode, quota := "", int64(-1)
+	limit, err := s.limitRepo.GetByApplicationID(ctx, app.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if limit != nil {
+		mode, quota = limit.EnforcementMode, quotaLimit(limit, usageType)
+	}
+
+	if usageType == "storage" {
+		gauge, err := s.gaugeRepo.Get(ctx, app.ID)
+		if err != nil {
+			return nil, err
+		}
+		return newQuotaStatus(usageType, mode, quota, gauge.CurrentBytes, 0), nil
+	}
+
+	loc, err := loadOrgLocation(ctx, s.orgRepo, app.OrganizationId)
+	if err != nil {
+		return nil, err
+	}
+	windowStart, resetAt := quotaWindow(usageType, at, loc)
+	used, err := s.counterRepo.Get(ctx, app.ID, usageType, windowStart)
+	if err != nil {
+		return nil, err
+	}
+	return newQuotaStatus(usageType, mode, quota, used, resetAt), nil
+}
+
+// evaluate 检查告警规则并发送达到阈值的告警，返回发送的告警数量
+func (s *alertService) evaluate(ctx context.Context, app *model.OrganizationApplication, status *QuotaStatus, at time.Time) (int, error) {
+	if status == nil || status.Used <= 0 {
+		return 0, nil
+	}
+
+	rules, err := s.loadRules(ctx, app.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	var loc *time.Location
+	fired := 0
+	for i := range rules {
+		rule := &rules[i]
+		if rule.UsageType != status.UsageType {
+			continue
+		}
+		threshold, ok := alertThreshold(rule, status.Limit)
+		if !ok || status.Used < threshold {
+			continue
+		}
+
+		if loc == nil {
+			if loc, err = loadOrgLocation(ctx, s.orgRepo, app.OrganizationId); err != nil {
+				return fired, err
+			}
+		}
+		period := alertPeriod(status.UsageType, at, loc)
+		if s.firedIn(rule.ID, period) {
+			continue
+		}
+
+		event := &model.AlertEvent{
+			RuleId:        rule.ID,
+			ApplicationId: app.ID,
+			UsageType:     status.UsageType,
+			PeriodStart:   period,
+			Used:          status.Used,
+			Quota:         status.Limit,
+			Threshold:     threshold,
+		}
+		// 告警记录和阈值事件在同一事务中写入，本周期已有告警记录时不写入事件
+		var created bool
+		err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+			var err error
+			created, err = s.alertRepo.CreateEvent(ctx, event)
+			if err != nil || !created {
+				return err
+			}
+			return s.publisher.Publish(ctx, Event{
+				Type:           EventUsageThresholdReached,
+				AggregateType:  AggregateApplication,
+				AggregateId:    app.ID,
+				OrganizationId: app.OrganizationId,
+				Data:           alertEventData(app, rule, event),
+			})
+		})
+		if err != nil {
+			return fired, err
+		}
+		s.markFired(rule.ID, period)
+		if !created {
+			// 本周期已由其他请求或实例告警
+			continue
+		}
+
+		s.deliver(ctx, app, rule, event)
+		fired++
+	}
+
+	return fired, nil
+}
+
+// loadRules 获取应用启用中的告警规则，优先读取缓存
+func (s *alertService) loadRules(ctx context.Context, appID int64) ([]model.AlertRule, error) {
+	if rules, ok := appAlertRuleCache.get(appID); ok {
+		return rules, nil
+	}
+
+	all, err := s.alertRepo.GetRulesByApplication(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]model.AlertRule, 0, len(all))
+	for _, rule := range all {
+		if rule.Enabled {
+			rules = append(rules, rule)
+		}
+	}
+
+	appAlertRuleCache.set(appID, rules)
+	return rules, nil
+}
+
+// firedIn 判断规则在统计周期内是否已告警
+func (s *alertService) firedIn(ruleID, period int64) bool {
+	s.firedMu.Lock()
+	defer s.firedMu.Unlock()
+
+	return s.fired[ruleID] == period
+}
+
+// markFired 记录规则已在统计周期内告警
+func (s *alertService) markFired(ruleID, period int64) {
+	s.firedMu.Lock()
+	defer s.firedMu.Unlock()
+
+	s.fired[ruleID] = period
+}
+
+// alertEventData 告警通知和阈值事件的数据
+func alertEventData(app *model.OrganizationApplication, rule *model.AlertRule, event *model.AlertEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"application_id": app.ID,
+		"rule_id":        rule.ID,
+		"rule_name":      rule.Name,
+		"usage_type":     event.UsageType,
+		"used":           event.Used,
+		"quota":          event.Quota,
+		"threshold":      event.Threshold,
+		"threshold_type": rule.ThresholdType,
+		"period_start":   event.PeriodStart,
+	}
+}
+
+// deliver 通过全局通知器和规则配置的渠道发送告警，发送失败只记录日志
+func (s *alertService) deliver(ctx context.Context, app *model.OrganizationApplication, rule *model.AlertRule, event *model.AlertEvent) {
+	notification := &notify.Notification{
+		Type:           "usage.alert",
+		OrganizationId: app.OrganizationId,
+		ApplicationId:  app.ID,
+		Subject:        "用量告警",
+		Content: fmt.Sprintf("应用 %s 的 %s 使用量 %d 已达到告警阈值 %d（配额 %d）",
+			app.Name, event.UsageType, event.Used, event.Threshold, event.Quota),
+		Data: alertEventData(app, rule, event),
+	}
+
+	if err := s.notifier.Notify(ctx, notification); err != nil {
+		logger.GetLogger().ErrorWithContext(ctx, "发送用量告警失败: 规则=%d, 错误: %v", rule.ID, err)
+	}
+
+	channels, err := parseAlertChannels(rule.Channels)
+	if err != nil {
+		logger.GetLogger().ErrorWithContext(ctx, "解析告警渠道失败: 规则=%d, 错误: %v", rule.ID, err)
+		return
+	}
+	for _, c := range channels {
+		ch, ok := notify.GetChannel(c.Type)
+		if !ok {
+			continue
+		}
+		if err := ch.Send(ctx, c.Target, notification); err != nil {
+			logger.GetLogger().ErrorWithContext(ctx, "发送用量告警失败: 规则=%d, 渠道=%s, 错误: %v", rule.ID, c.Type, err)
+		}
+	}
+}
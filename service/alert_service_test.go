@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"saas-account/model"
+	"saas-account/repository"
+	"testing"
+	"time"
+)
+
+// fakeAlertRepo 内存中的告警仓库，created 为写入告警记录的结果
+type fakeAlertRepo struct {
+	repository.AlertRepository
+	rules   []model.AlertRule
+	created bool
+}
+
+func (r *fakeAlertRepo) GetRulesByApplication(ctx context.Context, appID int64) ([]model.AlertRule, error) {
+	return r.rules, nil
+}
+
+func (r *fakeAlertRepo) CreateEvent(ctx context.Context, event *model.AlertEvent) (bool, error) {
+	return r.created, nil
+}
+
+// recordingPublisher 记录发布的事件及是否在事务中发布
+type recordingPublisher struct {
+	events    []Event
+	outsideTx int
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event Event) error {
+	if ctx.Value(fakeTxKey{}) == nil {
+		p.outsideTx++
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestAlertThresholdEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		created    bool
+		wantEvents int
+	}{
+		{name: "写入告警记录时发布事件", created: true, wantEvents: 1},
+		{name: "本周期已告警时不发布事件", created: false, wantEvents: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fa := newFixtureApp("UTC", nil)
+			app, id := fa.app, fa.app.ID
+			alertRepo := &fakeAlertRepo{created: tt.created, rules: []model.AlertRule{{
+				Base: model.Base{ID: id}, ApplicationId: id, UsageType: "storage",
+				ThresholdType: model.AlertThresholdAbsolute, Threshold: 10, Channels: "[]", Enabled: true,
+			}}}
+			publisher := &recordingPublisher{}
+			svc := NewAlertService(alertRepo, nil, fa.orgs, nil, nil, nil, &recordingNotifier{}, publisher, newFakeTxManager()).(*alertService)
+
+			status := newQuotaStatus("storage", model.EnforcementModeHard, 100, 20, 0)
+			if _, err := svc.evaluate(context.Background(), app, status, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			if len(publisher.events) != tt.wantEvents || publisher.outsideTx != 0 {
+				t.Errorf("事件数 = %d, 事务外发布 %d 次, 期望 %d 个事件且都在事务中", len(publisher.events), publisher.outsideTx, tt.wantEvents)
+			}
+			if tt.wantEvents > 0 && publisher.events[0].Type != EventUsageThresholdReached {
+				t.Errorf("事件类型 = %s", publisher.events[0].Type)
+			}
+		})
+	}
+}
+
+func TestValidateAlertRuleWebhook(t *testing.T) {
+	tests := []struct {
+		target  string
+		wantErr error
+	}{
+		{target: "https://93.184.216.34/hooks/alert"},
+		{target: "http://169.254.169.254/latest/meta-data", wantErr: errWebhookAddressBlocked},
+		{target: "http://10.0.0.5:8080/alert", wantErr: errWebhookAddressBlocked},
+		{target: "http://[::1]/alert", wantErr: errWebhookAddressBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rule := &model.AlertRule{
+				UsageType: "api_call", ThresholdType: model.AlertThresholdAbsolute, Threshold: 10,
+				Channels: `[{"type":"webhook","target":"` + tt.target + `"}]`,
+			}
+			registerAlertWebhookChannel()
+			if err := validateAlertRule(context.Background(), rule); err != tt.wantErr {
+				t.Errorf("错误 = %v, 期望 %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,9 +2,17 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"saas-account/ingest"
+	"saas-account/logger"
+	"saas-account/metrics"
 	"saas-account/model"
+	"saas-account/notify"
 	"saas-account/repository"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // ApplicationUsageService 应用使用记录服务接口
@@ -15,48 +23,133 @@ type ApplicationUsageService interface {
 	GetByApplicationAndDateRange(ctx context.Context, appID int64, startDate, endDate time.Time, page, pageSize int) ([]model.ApplicationUsage, int64, error)
 	GetByUser(ctx context.Context, userID int64, page, pageSize int) ([]model.ApplicationUsage, int64, error)
 	GetSummaryByApplication(ctx context.Context, appID int64, startDate, endDate time.Time) (map[string]int64, error)
+	GetSummaryByOrganization(ctx context.Context, orgID int64, startDate, endDate time.Time) (map[string]int64, error)
+	GetSeriesByApplication(ctx context.Context, appID int64, granularity string, startDate, endDate time.Time) (*UsageSeries, error)
+	GetSeriesByOrganization(ctx context.Context, orgID int64, granularity string, startDate, endDate time.Time) (*UsageSeries, error)
 	Delete(ctx context.Context, id int64) error
 	RecordAPIUsage(ctx context.Context, appID int64, userID *int64, amount int64) error
 	RecordStorageUsage(ctx context.Context, appID int64, userID *int64, amount int64) error
 	RecordFeatureUsage(ctx context.Context, appID int64, userID *int64, featureName string, amount int64) error
 	CheckAPILimit(ctx context.Context, appID int64) (bool, error)
 	CheckStorageLimit(ctx context.Context, appID int64, additionalAmount int64) (bool, error)
+	GetQuotaStatus(ctx context.Context, appID int64, usageType string) (*QuotaStatus, error)
+	PurgeExpired(ctx context.Context, rawRetention, hourlyRetention time.Duration) (int64, error)
+	RecordBatch(ctx context.Context, appID int64, events []UsageEvent) ([]UsageEventResult, error)
 }
 
+// ErrFeatureNotIncluded 套餐未包含该功能
+var ErrFeatureNotIncluded = errors.New("套餐未包含该功能")
+
 // applicationUsageService 应用使用记录服务实现
 type applicationUsageService struct {
-	usageRepo repository.ApplicationUsageRepository
-	appRepo   repository.OrganizationApplicationRepository
-	limitRepo repository.OrganizationApplicationLimitRepository
+	usageRepo   repository.ApplicationUsageRepository
+	rollupRepo  repository.UsageRollupRepository
+	appRepo     repository.OrganizationApplicationRepository
+	orgRepo     repository.OrganizationRepository
+	limitRepo   repository.OrganizationApplicationLimitRepository
+	counterRepo repository.UsageCounterRepository
+	notifier    notify.Notifier
+	ingester    *ingest.Ingester
+
+	idempotencyRepo   repository.UsageIdempotencyRepository
+	idempotencyWindow time.Duration
+	storageService    StorageService
+	alertService      AlertService
+	txManager         repository.TransactionManager
 }
 
 // NewApplicationUsageService 创建应用使用记录服务
 func NewApplicationUsageService(
 	usageRepo repository.ApplicationUsageRepository,
+	rollupRepo repository.UsageRollupRepository,
 	appRepo repository.OrganizationApplicationRepository,
+	orgRepo repository.OrganizationRepository,
 	limitRepo repository.OrganizationApplicationLimitRepository,
+	counterRepo repository.UsageCounterRepository,
+	notifier notify.Notifier,
+	ingester *ingest.Ingester,
+	idempotencyRepo repository.UsageIdempotencyRepository,
+	idempotencyWindow time.Duration,
+	storageService StorageService,
+	alertService AlertService,
+	txManager repository.TransactionManager,
 ) ApplicationUsageService {
 	return &applicationUsageService{
-		usageRepo: usageRepo,
-		appRepo:   appRepo,
-		limitRepo: limitRepo,
+		usageRepo:   usageRepo,
+		rollupRepo:  rollupRepo,
+		appRepo:     appRepo,
+		orgRepo:     orgRepo,
+		limitRepo:   limitRepo,
+		counterRepo: counterRepo,
+		notifier:    notifier,
+		ingester:    ingester,
+
+		idempotencyRepo:   idempotencyRepo,
+		idempotencyWindow: idempotencyWindow,
+		storageService:    storageService,
+		alertService:      alertService,
+		txManager:         txManager,
 	}
 }
 
 // Create 创建应用使用记录
 func (s *applicationUsageService) Create(ctx context.Context, usage *model.ApplicationUsage) error {
 	// 检查应用是否存在
-	_, err := s.appRepo.GetByID(ctx, usage.ApplicationId)
+	app, err := s.appRepo.GetByID(ctx, usage.ApplicationId)
 	if err != nil {
 		return err
 	}
 
-	// 设置使用日期
-
-	usage.UsageDate = time.Now().Unix()
+	// 未指定事件时间时使用当前时间
+	if usage.UsageDate == 0 {
+		usage.UsageDate = time.Now().Unix()
+	}
+	usage.OrganizationId = app.OrganizationId
 
 	// 创建使用记录
-	return s.usageRepo.Create(ctx, usage)
+	return s.createUsage(ctx, usage)
+}
+
+// createUsage 创建使用记录并按组织时区累加到小时和天汇总，记录和汇总在同一个事务中写入
+func (s *applicationUsageService) createUsage(ctx context.Context, usage *model.ApplicationUsage) error {
+	loc, err := loadOrgLocation(ctx, s.orgRepo, usage.OrganizationId)
+	if err != nil {
+		return err
+	}
+
+	usage.IngestedAt = time.Now().Unix()
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.usageRepo.Create(ctx, usage); err != nil {
+			return err
+		}
+		return s.rollupRepo.Add(ctx, usage, loc)
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.UsageEventsRecorded.WithLabelValues(usage.UsageType).Inc()
+	return nil
+}
+
+// ingestUsage 写入使用记录，开启异步写入时交给缓冲写入器批量写入
+func (s *applicationUsageService) ingestUsage(ctx context.Context, usage *model.ApplicationUsage) error {
+	if s.ingester == nil {
+		return s.createUsage(ctx, usage)
+	}
+
+	loc, err := loadOrgLocation(ctx, s.orgRepo, usage.OrganizationId)
+	if err != nil {
+		return err
+	}
+
+	usage.IngestedAt = time.Now().Unix()
+	if err := s.ingester.Submit(ctx, usage, loc); err != nil {
+		return err
+	}
+
+	metrics.UsageEventsRecorded.WithLabelValues(usage.UsageType).Inc()
+	return nil
 }
 
 // GetByID 根据ID获取应用使用记录
@@ -91,121 +184,322 @@ func (s *applicationUsageService) GetByUser(ctx context.Context, userID int64, p
 	return s.usageRepo.GetByUser(ctx, userID, page, pageSize)
 }
 
-// GetSummaryByApplication 获取应用使用统计摘要
+// GetSummaryByApplication 获取应用使用统计摘要，日期部分按组织时区解释，包含结束日期当天
 func (s *applicationUsageService) GetSummaryByApplication(ctx context.Context, appID int64, startDate, endDate time.Time) (map[string]int64, error) {
 	// 检查应用是否存在
-	_, err := s.appRepo.GetByID(ctx, appID)
+	app, err := s.appRepo.GetByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := loadOrgLocation(ctx, s.orgRepo, app.OrganizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := localDateRange(startDate, endDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rollupRepo.SumByApplication(ctx, appID, model.RollupGranularityDay, start.Unix(), end.Unix())
+}
+
+// GetSummaryByOrganization 获取组织下所有应用的使用统计摘要，日期部分按组织时区解释
+func (s *applicationUsageService) GetSummaryByOrganization(ctx context.Context, orgID int64, startDate, endDate time.Time) (map[string]int64, error) {
+	// 检查组织是否存在
+	loc, err := loadOrgLocation(ctx, s.orgRepo, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := localDateRange(startDate, endDate, loc)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.usageRepo.GetSummaryByApplication(ctx, appID, startDate, endDate)
+	return s.rollupRepo.SumByOrganization(ctx, orgID, model.RollupGranularityDay, start.Unix(), end.Unix())
 }
 
-// Delete 删除应用使用记录
+// PurgeExpired 彻底删除超过保留期限的原始使用记录和小时汇总，以及已过期的幂等键，保留期限为0表示永久保留
+func (s *applicationUsageService) PurgeExpired(ctx context.Context, rawRetention, hourlyRetention time.Duration) (int64, error) {
+	purged, err := s.idempotencyRepo.DeleteExpired(ctx, time.Now().Unix())
+	if err != nil {
+		return purged, err
+	}
+
+	if rawRetention > 0 {
+		n, err := s.usageRepo.DeleteBefore(ctx, time.Now().Add(-rawRetention).Unix())
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+
+	if hourlyRetention > 0 {
+		n, err := s.rollupRepo.DeleteBefore(ctx, model.RollupGranularityHour, time.Now().Add(-hourlyRetention).Unix())
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+
+	return purged, nil
+}
+
+// Delete 删除应用使用记录，并在同一个事务中从所在的汇总中减去
 func (s *applicationUsageService) Delete(ctx context.Context, id int64) error {
-	return s.usageRepo.Delete(ctx, id)
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		usage, err := s.usageRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		loc, err := loadOrgLocation(ctx, s.orgRepo, usage.OrganizationId)
+		if err != nil {
+			return err
+		}
+
+		if err := s.usageRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.rollupRepo.Subtract(ctx, usage, loc)
+	})
 }
 
-// RecordAPIUsage 记录API使用
+// RecordAPIUsage 记录API使用，按配额执行模式检查并累加当日配额
 func (s *applicationUsageService) RecordAPIUsage(ctx context.Context, appID int64, userID *int64, amount int64) error {
+	return s.recordQuotaUsage(ctx, appID, userID, "api_call", amount)
+}
+
+// RecordStorageUsage 记录存储使用，作为当前存储占用的增量，按配额执行模式检查当前占用
+func (s *applicationUsageService) RecordStorageUsage(ctx context.Context, appID int64, userID *int64, amount int64) error {
+	_, err := s.storageService.AddUsage(ctx, appID, amount)
+	return err
+}
+
+// recordQuotaUsage 原子检查并累加配额后创建使用记录，配额计数和使用记录在同一个事务中提交，
+// 写入使用记录失败时配额计数一起回滚。开启异步写入时在事务提交后交给缓冲写入器，提交失败时退回本次累加的配额
+func (s *applicationUsageService) recordQuotaUsage(ctx context.Context, appID int64, userID *int64, usageType string, amount int64) error {
 	// 检查应用是否存在
-	_, err := s.appRepo.GetByID(ctx, appID)
+	app, err := s.appRepo.GetByID(ctx, appID)
 	if err != nil {
 		return err
 	}
 
-	// 创建使用记录
-	usage := &model.ApplicationUsage{
-		ApplicationId: appID,
-		UserId:        userID,
-		UsageType:     "api_call",
-		UsageAmount:   amount,
-		UsageDate:     time.Now().Unix(),
-		Details:       "{}",
+	now := time.Now()
+	var status *QuotaStatus
+	var usage *model.ApplicationUsage
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 检查并累加配额
+		var err error
+		status, err = s.consumeQuota(ctx, app, usageType, amount, now)
+		if err != nil {
+			return err
+		}
+
+		// 超额部分单独记录，便于计费
+		details := make(map[string]interface{})
+		if status.Limit >= 0 && status.Used > status.Limit {
+			overage := status.Used - status.Limit
+			if overage > amount {
+				overage = amount
+			}
+			details["overage"] = overage
+		}
+		detailsJSON, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+
+		// 创建使用记录
+		usage = &model.ApplicationUsage{
+			ApplicationId:  appID,
+			UserId:         userID,
+			UsageType:      usageType,
+			UsageAmount:    amount,
+			UsageDate:      now.Unix(),
+			Details:        string(detailsJSON),
+			OrganizationId: app.OrganizationId,
+		}
+		if s.ingester == nil {
+			return s.createUsage(ctx, usage)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.ingester != nil {
+		if err := s.ingestUsage(ctx, usage); err != nil {
+			s.releaseQuota(ctx, app, usageType, amount, now)
+			return err
+		}
 	}
 
-	return s.usageRepo.Create(ctx, usage)
+	s.quotaConsumed(ctx, app, status, amount, now)
+	return nil
 }
 
-// RecordStorageUsage 记录存储使用
-func (s *applicationUsageService) RecordStorageUsage(ctx context.Context, appID int64, userID *int64, amount int64) error {
-	// 检查应用是否存在
-	_, err := s.appRepo.GetByID(ctx, appID)
+// consumeQuota 按配额执行模式原子检查并累加事件时间所在窗口的配额，通知和告警由调用方在事务提交后通过 quotaConsumed 发送
+func (s *applicationUsageService) consumeQuota(ctx context.Context, app *model.OrganizationApplication, usageType string, amount int64, at time.Time) (*QuotaStatus, error) {
+	loc, err := loadOrgLocation(ctx, s.orgRepo, app.OrganizationId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	windowStart, resetAt := quotaWindow(usageType, at, loc)
+
+	limit, err := s.limitRepo.GetByApplicationID(ctx, app.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 未配置应用限制，不做限制
+		used, err := s.counterRepo.Increment(ctx, app.ID, usageType, windowStart, amount)
+		if err != nil {
+			return nil, err
+		}
+		return newQuotaStatus(usageType, "", -1, used, resetAt), nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// 创建使用记录
-	usage := &model.ApplicationUsage{
-		ApplicationId: appID,
-		UserId:        userID,
-		UsageType:     "storage",
-		UsageAmount:   amount,
-		UsageDate:     time.Now().Unix(),
-		Details:       "{}",
+	quota := quotaLimit(limit, usageType)
+
+	var used int64
+	allowed := true
+	switch {
+	case quota < 0 || limit.EnforcementMode == model.EnforcementModeNotify:
+		used, err = s.counterRepo.Increment(ctx, app.ID, usageType, windowStart, amount)
+	case limit.EnforcementMode == model.EnforcementModeSoft && limit.OveragePercent == 0:
+		used, err = s.counterRepo.Increment(ctx, app.ID, usageType, windowStart, amount)
+	case limit.EnforcementMode == model.EnforcementModeSoft:
+		ceiling := quota + quota*int64(limit.OveragePercent)/100
+		used, allowed, err = s.counterRepo.IncrementWithinLimit(ctx, app.ID, usageType, windowStart, amount, ceiling)
+	default:
+		used, allowed, err = s.counterRepo.IncrementWithinLimit(ctx, app.ID, usageType, windowStart, amount, quota)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := newQuotaStatus(usageType, limit.EnforcementMode, quota, used, resetAt)
+	if !allowed {
+		metrics.QuotaRejections.WithLabelValues(usageType).Inc()
+		return nil, &QuotaExceededError{Status: status}
 	}
+	return status, nil
+}
 
-	return s.usageRepo.Create(ctx, usage)
+// quotaConsumed 配额累加提交后，本次使用首次超出配额时发送通知，并检查用量告警
+func (s *applicationUsageService) quotaConsumed(ctx context.Context, app *model.OrganizationApplication, status *QuotaStatus, amount int64, at time.Time) {
+	if status.Limit >= 0 && status.Used > status.Limit && status.Used-amount <= status.Limit {
+		notifyQuotaExceeded(ctx, s.notifier, app, status)
+	}
+	s.alertService.Evaluate(ctx, app, status, at)
+}
+
+// releaseQuota 退回已提交的配额累加，用于使用记录在事务提交后写入失败时
+func (s *applicationUsageService) releaseQuota(ctx context.Context, app *model.OrganizationApplication, usageType string, amount int64, at time.Time) {
+	loc, err := loadOrgLocation(ctx, s.orgRepo, app.OrganizationId)
+	if err == nil {
+		windowStart, _ := quotaWindow(usageType, at, loc)
+		_, err = s.counterRepo.Increment(ctx, app.ID, usageType, windowStart, -amount)
+	}
+	if err != nil {
+		logger.GetLogger().ErrorWithContext(ctx, "退回配额失败: 应用=%d, 类型=%s, 数量=%d, 错误: %v", app.ID, usageType, amount, err)
+	}
 }
 
 // RecordFeatureUsage 记录功能使用
 func (s *applicationUsageService) RecordFeatureUsage(ctx context.Context, appID int64, userID *int64, featureName string, amount int64) error {
 	// 检查应用是否存在
-	_, err := s.appRepo.GetByID(ctx, appID)
+	app, err := s.appRepo.GetByID(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	// 检查套餐是否包含该功能
+	entitlements, err := loadEntitlements(ctx, s.limitRepo, appID)
+	if err != nil {
+		return err
+	}
+	if e, ok := entitlements[featureName]; !ok || !entitlementIncluded(e) {
+		return ErrFeatureNotIncluded
+	}
+
+	details, err := json.Marshal(map[string]interface{}{"feature_name": featureName})
 	if err != nil {
 		return err
 	}
 
 	// 创建使用记录
 	usage := &model.ApplicationUsage{
-		ApplicationId: appID,
-		UserId:        userID,
-		UsageType:     "feature",
-		UsageAmount:   amount,
-		UsageDate:     time.Now().Unix(),
-		Details:       "{\"feature_name\":\"" + featureName + "\"}",
+		ApplicationId:  appID,
+		UserId:         userID,
+		UsageType:      "feature",
+		UsageAmount:    amount,
+		UsageDate:      time.Now().Unix(),
+		Details:        string(details),
+		OrganizationId: app.OrganizationId,
 	}
 
-	return s.usageRepo.Create(ctx, usage)
+	return s.ingestUsage(ctx, usage)
 }
 
-// CheckAPILimit 检查API限制
+// CheckAPILimit 检查API限制，返回当日是否还有剩余配额
 func (s *applicationUsageService) CheckAPILimit(ctx context.Context, appID int64) (bool, error) {
-	// 获取应用限制
-	limit, err := s.limitRepo.GetByApplicationID(ctx, appID)
+	status, err := s.GetQuotaStatus(ctx, appID, "api_call")
 	if err != nil {
 		return false, err
 	}
 
-	// 获取今日API使用量
-	today := time.Now().Truncate(24 * time.Hour)
-	tomorrow := today.Add(24 * time.Hour)
-	summary, err := s.usageRepo.GetSummaryByApplication(ctx, appID, today, tomorrow)
+	return status.Limit < 0 || status.Used < status.Limit, nil
+}
+
+// CheckStorageLimit 检查存储限制，按当前存储占用计算
+func (s *applicationUsageService) CheckStorageLimit(ctx context.Context, appID int64, additionalAmount int64) (bool, error) {
+	status, err := s.storageService.GetStatus(ctx, appID)
 	if err != nil {
 		return false, err
 	}
 
-	// 检查是否超过限制
-	apiUsage := summary["api_call"]
-	return apiUsage < int64(limit.MaxRequests), nil
+	return status.Quota.Limit < 0 || status.CurrentBytes+additionalAmount <= status.Quota.Limit, nil
 }
 
-// CheckStorageLimit 检查存储限制
-func (s *applicationUsageService) CheckStorageLimit(ctx context.Context, appID int64, additionalAmount int64) (bool, error) {
-	// 获取应用限制
+// GetQuotaStatus 获取应用当前窗口的配额状态，存储按当前占用计算
+func (s *applicationUsageService) GetQuotaStatus(ctx context.Context, appID int64, usageType string) (*QuotaStatus, error) {
+	if usageType == "storage" {
+		status, err := s.storageService.GetStatus(ctx, appID)
+		if err != nil {
+			return nil, err
+		}
+		return status.Quota, nil
+	}
+
+	// 获取应用限制，未配置时不做限制
 	limit, err := s.limitRepo.GetByApplicationID(ctx, appID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		limit = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	app, err := s.appRepo.GetByID(ctx, appID)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	loc, err := loadOrgLocation(ctx, s.orgRepo, app.OrganizationId)
+	if err != nil {
+		return nil, err
 	}
 
-	// 获取存储使用量
-	summary, err := s.usageRepo.GetSummaryByApplication(ctx, appID, time.Time{}, time.Now())
+	windowStart, resetAt := quotaWindow(usageType, time.Now(), loc)
+	used, err := s.counterRepo.Get(ctx, appID, usageType, windowStart)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	// 检查是否超过限制
-	storageUsage := summary["storage"]
-	return storageUsage+additionalAmount <= limit.MaxStorage, nil
+	if limit == nil {
+		return newQuotaStatus(usageType, "", -1, used, resetAt), nil
+	}
+	return newQuotaStatus(usageType, limit.EnforcementMode, quotaLimit(limit, usageType), used, resetAt), nil
 }
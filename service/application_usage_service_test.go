@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestCreateAndDeleteUsage(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("缺少时区数据:", err)
+	}
+	f := newUsageFixture(t, "Asia/Kolkata", nil)
+	ctx := context.Background()
+	date := time.Date(2026, 1, 1, 10, 40, 0, 0, time.UTC).Unix()
+
+	usage := &model.ApplicationUsage{ApplicationId: f.app.ID, UsageType: "export", UsageAmount: 5, UsageDate: date}
+	if err := f.service.Create(ctx, usage); err != nil {
+		t.Fatal(err)
+	}
+	key := rollupKey(usage, loc)
+	if got := f.store.state.rollups[key]; got != 5 || f.store.usageCount() != 1 {
+		t.Fatalf("创建后汇总 = %d, 记录数 = %d", got, f.store.usageCount())
+	}
+
+	// 删除使用记录时从所在的汇总中减去
+	if err := f.service.Delete(ctx, usage.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.store.state.rollups[key]; got != 0 || f.store.usageCount() != 0 {
+		t.Errorf("删除后汇总 = %d, 记录数 = %d", got, f.store.usageCount())
+	}
+	if err := f.service.Delete(ctx, usage.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("重复删除错误 = %v, 期望记录不存在", err)
+	}
+
+	// 记录和汇总在同一个事务中写入
+	if len(f.store.outsideTx) != 0 {
+		t.Errorf("事务外的写操作: %v", f.store.outsideTx)
+	}
+}
+
+func TestRecordAPIUsageNotifiesAfterCommit(t *testing.T) {
+	f := newUsageFixture(t, "", &model.OrganizationApplicationLimit{
+		MaxRequests:     5,
+		EnforcementMode: model.EnforcementModeNotify,
+	})
+	ctx := context.Background()
+
+	// 首次超出配额时在事务提交后发送一次通知
+	for _, amount := range []int64{4, 3, 2} {
+		if err := f.service.RecordAPIUsage(ctx, f.app.ID, nil, amount); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if f.notifier.count() != 1 || f.notifier.insideTx != 0 {
+		t.Errorf("通知数 = %d, 事务中发送 %d 条, 期望事务外发送1条", f.notifier.count(), f.notifier.insideTx)
+	}
+	if f.store.usageCount() != 3 || len(f.store.outsideTx) != 0 {
+		t.Errorf("记录数 = %d, 事务外的写操作: %v", f.store.usageCount(), f.store.outsideTx)
+	}
+}
+
+func TestGetQuotaStatusWithoutLimit(t *testing.T) {
+	f := newUsageFixture(t, "", nil)
+	ctx := context.Background()
+
+	if err := f.service.RecordAPIUsage(ctx, f.app.ID, nil, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未配置应用限制时不做限制
+	status, err := f.service.GetQuotaStatus(ctx, f.app.ID, "api_call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Limit != -1 || status.Used != 3 {
+		t.Errorf("配额状态 = %+v, 期望不限制且已使用3", status)
+	}
+	if ok, err := f.service.CheckAPILimit(ctx, f.app.ID); err != nil || !ok {
+		t.Errorf("检查API限制 = %v, %v, 期望允许", ok, err)
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"saas-account/audit"
+	"saas-account/model"
+	"saas-account/repository"
+	"time"
+)
+
+// 审计资源类型
+const (
+	AuditResourceUser               = "user"
+	AuditResourceOrganization       = "organization"
+	AuditResourceOrganizationMember = "organization_member"
+	AuditResourceApplication        = "application"
+	AuditResourceApplicationMember  = "application_member"
+	AuditResourceApplicationLimit   = "application_limit"
+)
+
+// auditVerifyBatchSize 校验哈希链时每批读取的日志数
+const auditVerifyBatchSize = 1000
+
+// AuditEntry 待记录的审计事件，Before 为空表示创建，After 为空表示删除
+type AuditEntry struct {
+	OrganizationId int64       // 组织ID，0表示平台级操作
+	Action         string      // 操作，如 organization.update
+	ResourceType   string      // 资源类型
+	ResourceId     int64       // 资源ID
+	Before         interface{} // 变更前的资源
+	After          interface{} // 变更后的资源
+}
+
+// AuditVerification 哈希链校验结果
+type AuditVerification struct {
+	OrganizationId int64  `json:"organization_id"`
+	Valid          bool   `json:"valid"`               // 哈希链是否完整
+	Checked        int64  `json:"checked"`             // 已校验的日志数
+	Chain          string `json:"chain,omitempty"`     // 校验失败的日志链
+	BrokenAt       int64  `json:"broken_at,omitempty"` // 校验失败的日志序号
+	Reason         string `json:"reason,omitempty"`    // 校验失败原因
+}
+
+// AuditService 审计日志服务接口
+type AuditService interface {
+	Record(ctx context.Context, entry AuditEntry) error
+	List(ctx context.Context, filter repository.AuditLogFilter, page, pageSize int) ([]model.AuditLog, int64, error)
+	Verify(ctx context.Context, orgID int64) (*AuditVerification, error)
+}
+
+// auditService 审计日志服务实现
+type auditService struct {
+	auditRepo repository.AuditLogRepository
+	txManager repository.TransactionManager
+}
+
+// NewAuditService 创建审计日志服务
+func NewAuditService(auditRepo repository.AuditLogRepository, txManager repository.TransactionManager) AuditService {
+	return &auditService{
+		auditRepo: auditRepo,
+		txManager: txManager,
+	}
+}
+
+// auditHashContent 参与哈希计算的日志内容，字段顺序固定
+type auditHashContent struct {
+	PrevHash       string `json:"prev_hash"`
+	OrganizationId int64  `json:"organization_id"`
+	Chain          string `json:"chain,omitempty"` // 组织级日志为空，不改变已有日志的哈希
+	Sequence       int64  `json:"sequence"`
+	ActorType      string `json:"actor_type"`
+	ActorId        string `json:"actor_id"`
+	Action         string `json:"action"`
+	ResourceType   string `json:"resource_type"`
+	ResourceId     int64  `json:"resource_id"`
+	Before         string `json:"before"`
+	After          string `json:"after"`
+	Changes        string `json:"changes"`
+	RequestId      string `json:"request_id"`
+	TraceId        string `json:"trace_id"`
+	IP             string `json:"ip"`
+	UserAgent      string `json:"user_agent"`
+	CreatedAt      int64  `json:"created_at"`
+}
+
+// hashAuditLog 计算审计日志的哈希：SHA-256(上一条哈希和本条内容)
+func hashAuditLog(log *model.AuditLog) string {
+	content, _ := json.Marshal(auditHashContent{
+		PrevHash:       log.PrevHash,
+		OrganizationId: log.OrganizationId,
+		Chain:          log.Chain,
+		Sequence:       log.Sequence,
+		ActorType:      log.ActorType,
+		ActorId:        log.ActorId,
+		Action:         log.Action,
+		ResourceType:   log.ResourceType,
+		ResourceId:     log.ResourceId,
+		Before:         log.Before,
+		After:          log.After,
+		Changes:        log.Changes,
+		RequestId:      log.RequestId,
+		TraceId:        log.TraceId,
+		IP:             log.IP,
+		UserAgent:      log.UserAgent,
+		CreatedAt:      log.CreatedAt,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditSnapshot 将资源序列化为JSON，并解析为字段映射用于比较，资源为空时返回空
+// 序列化遵循模型的 json 标签，密码、应用密钥等不返回给前端的字段不会被记录
+func auditSnapshot(v interface{}) (string, map[string]interface{}, error) {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return "", nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return "", nil, err
+	}
+	return string(data), fields, nil
+}
+
+// auditChanges 比较变更前后的字段，返回变更的字段，不比较更新时间
+func auditChanges(before, after map[string]interface{}) (string, error) {
+	changes := make(map[string]map[string]interface{})
+	for key, to := range after {
+		if from, ok := before[key]; !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = map[string]interface{}{"from": before[key], "to": to}
+		}
+	}
+	for key, from := range before {
+		if _, ok := after[key]; !ok {
+			changes[key] = map[string]interface{}{"from": from, "to": nil}
+		}
+	}
+	delete(changes, "updated_at")
+
+	if len(changes) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(changes)
+	return string(data), err
+}
+
+// auditChain 日志所在的日志链，组织级日志每个组织一条链；平台级日志按资源分链，
+// 避免所有用户的操作争用同一把日志链锁
+func auditChain(entry AuditEntry) string {
+	if entry.OrganizationId != 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", entry.ResourceType, entry.ResourceId)
+}
+
+// Record 追加审计日志，在调用方的事务中调用时与变更一起提交
+func (s *auditService) Record(ctx context.Context, entry AuditEntry) error {
+	before, beforeFields, err := auditSnapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, afterFields, err := auditSnapshot(entry.After)
+	if err != nil {
+		return err
+	}
+	changes, err := auditChanges(beforeFields, afterFields)
+	if err != nil {
+		return err
+	}
+
+	metadata := audit.FromContext(ctx)
+	log := &model.AuditLog{
+		OrganizationId: entry.OrganizationId,
+		Chain:          auditChain(entry),
+		ActorType:      metadata.ActorType,
+		ActorId:        metadata.ActorId,
+		Action:         entry.Action,
+		ResourceType:   entry.ResourceType,
+		ResourceId:     entry.ResourceId,
+		Before:         before,
+		After:          after,
+		Changes:        changes,
+		RequestId:      metadata.RequestId,
+		TraceId:        metadata.TraceId,
+		IP:             metadata.IP,
+		UserAgent:      metadata.UserAgent,
+	}
+
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 锁定日志链，保证序号连续且每条日志链接到真正的上一条
+		if err := s.auditRepo.LockChain(ctx, log.OrganizationId, log.Chain); err != nil {
+			return err
+		}
+
+		last, err := s.auditRepo.GetLast(ctx, log.OrganizationId, log.Chain)
+		if err != nil {
+			return err
+		}
+
+		log.Sequence = 1
+		log.PrevHash = ""
+		if last != nil {
+			log.Sequence = last.Sequence + 1
+			log.PrevHash = last.Hash
+		}
+		log.CreatedAt = time.Now().Unix()
+		log.Hash = hashAuditLog(log)
+
+		return s.auditRepo.Create(ctx, log)
+	})
+}
+
+// List 按条件分页查询组织的审计日志
+func (s *auditService) List(ctx context.Context, filter repository.AuditLogFilter, page, pageSize int) ([]model.AuditLog, int64, error) {
+	return s.auditRepo.Find(ctx, filter, page, pageSize)
+}
+
+// Verify 按序号顺序重新计算组织每条日志链的哈希，检查序号是否连续、每条日志是否链接到上一条且内容未被修改
+func (s *auditService) Verify(ctx context.Context, orgID int64) (*AuditVerification, error) {
+	result := &AuditVerification{OrganizationId: orgID, Valid: true}
+
+	chains, err := s.auditRepo.GetChains(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, chain := range chains {
+		if err := s.verifyChain(ctx, orgID, chain, result); err != nil {
+			return nil, err
+		}
+		if !result.Valid {
+			result.Chain = chain
+			return result, nil
+		}
+	}
+	return result, nil
+}
+
+// verifyChain 校验一条日志链，校验失败时在 result 中记录失败的序号和原因
+func (s *auditService) verifyChain(ctx context.Context, orgID int64, chain string, result *AuditVerification) error {
+	var sequence int64
+	prevHash := ""
+	for {
+		logs, err := s.auditRepo.GetChain(ctx, orgID, chain, sequence, auditVerifyBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range logs {
+			log := &logs[i]
+			switch {
+			case log.Sequence != sequence+1:
+				result.Reason = "日志序号不连续，可能有日志被删除"
+			case log.PrevHash != prevHash:
+				result.Reason = "日志未链接到上一条日志"
+			case hashAuditLog(log) != log.Hash:
+				result.Reason = "日志内容与哈希不一致，可能被修改"
+			}
+			if result.Reason != "" {
+				result.Valid = false
+				result.BrokenAt = log.Sequence
+				return nil
+			}
+
+			sequence = log.Sequence
+			prevHash = log.Hash
+			result.Checked++
+		}
+
+		if len(logs) < auditVerifyBatchSize {
+			return nil
+		}
+	}
+}
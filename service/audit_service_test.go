@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"saas-account/audit"
+	"saas-account/model"
+	"saas-account/repository"
+	"testing"
+)
+
+// fakeAuditLogRepo 内存中的审计日志仓库，按组织保存日志，locked 为锁定过的日志链
+type fakeAuditLogRepo struct {
+	repository.AuditLogRepository
+	logs   map[int64][]model.AuditLog
+	locked []string
+}
+
+func (r *fakeAuditLogRepo) LockChain(ctx context.Context, orgID int64, chain string) error {
+	r.locked = append(r.locked, chain)
+	return nil
+}
+
+func (r *fakeAuditLogRepo) GetLast(ctx context.Context, orgID int64, chain string) (*model.AuditLog, error) {
+	var last *model.AuditLog
+	for i, log := range r.logs[orgID] {
+		if log.Chain == chain {
+			last = &r.logs[orgID][i]
+		}
+	}
+	return last, nil
+}
+
+func (r *fakeAuditLogRepo) Create(ctx context.Context, log *model.AuditLog) error {
+	log.ID = nextFixtureID()
+	r.logs[log.OrganizationId] = append(r.logs[log.OrganizationId], *log)
+	return nil
+}
+
+func (r *fakeAuditLogRepo) GetChains(ctx context.Context, orgID int64) ([]string, error) {
+	var chains []string
+	seen := map[string]bool{}
+	for _, log := range r.logs[orgID] {
+		if !seen[log.Chain] {
+			seen[log.Chain] = true
+			chains = append(chains, log.Chain)
+		}
+	}
+	return chains, nil
+}
+
+func (r *fakeAuditLogRepo) GetChain(ctx context.Context, orgID int64, chain string, afterSequence int64, limit int) ([]model.AuditLog, error) {
+	var logs []model.AuditLog
+	for _, log := range r.logs[orgID] {
+		if log.Chain == chain && log.Sequence > afterSequence && len(logs) < limit {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// newAuditFixture 创建记录了3条组织日志的审计服务
+func newAuditFixture(t *testing.T, orgID int64) (AuditService, *fakeAuditLogRepo) {
+	t.Helper()
+	repo := &fakeAuditLogRepo{logs: map[int64][]model.AuditLog{}}
+	svc := NewAuditService(repo, newFakeTxManager())
+
+	ctx := audit.WithMetadata(context.Background(), audit.Metadata{ActorType: "user", ActorId: "1"})
+	entries := []AuditEntry{
+		{Action: "organization.create", After: map[string]string{"name": "a"}},
+		{Action: "organization.update", Before: map[string]string{"name": "a"}, After: map[string]string{"name": "b"}},
+		{Action: "organization.delete", Before: map[string]string{"name": "b"}},
+	}
+	for _, entry := range entries {
+		entry.OrganizationId = orgID
+		entry.ResourceType = AuditResourceOrganization
+		entry.ResourceId = orgID
+		if err := svc.Record(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return svc, repo
+}
+
+func TestAuditVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamper     func(chain []model.AuditLog) []model.AuditLog
+		wantBroken int64
+	}{
+		{name: "完整的哈希链", tamper: func(chain []model.AuditLog) []model.AuditLog { return chain }},
+		{name: "修改日志内容", wantBroken: 2, tamper: func(chain []model.AuditLog) []model.AuditLog {
+			chain[1].After = `{"name":"c"}`
+			return chain
+		}},
+		{name: "修改内容并重新计算哈希", wantBroken: 3, tamper: func(chain []model.AuditLog) []model.AuditLog {
+			chain[1].After = `{"name":"c"}`
+			chain[1].Hash = hashAuditLog(&chain[1])
+			return chain
+		}},
+		{name: "删除中间的日志", wantBroken: 3, tamper: func(chain []model.AuditLog) []model.AuditLog {
+			return append(chain[:1], chain[2:]...)
+		}},
+		{name: "删除第一条日志", wantBroken: 2, tamper: func(chain []model.AuditLog) []model.AuditLog {
+			return chain[1:]
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgID := nextFixtureID()
+			svc, repo := newAuditFixture(t, orgID)
+			repo.logs[orgID] = tt.tamper(repo.logs[orgID])
+
+			result, err := svc.Verify(context.Background(), orgID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid != (tt.wantBroken == 0) || result.BrokenAt != tt.wantBroken {
+				t.Errorf("校验结果 = %+v, 期望在序号 %d 处失败", result, tt.wantBroken)
+			}
+		})
+	}
+}
+
+func TestAuditRecordChain(t *testing.T) {
+	orgID := nextFixtureID()
+	_, repo := newAuditFixture(t, orgID)
+
+	// 每条日志的序号连续，并链接到上一条日志的哈希
+	chain := repo.logs[orgID]
+	for i, log := range chain {
+		prevHash := ""
+		if i > 0 {
+			prevHash = chain[i-1].Hash
+		}
+		if log.Sequence != int64(i+1) || log.PrevHash != prevHash || log.Hash != hashAuditLog(&log) {
+			t.Errorf("第%d条日志 = %+v", i+1, log)
+		}
+	}
+	if want := `{"name":{"from":"a","to":"b"}}`; chain[1].Changes != want {
+		t.Errorf("变更字段 = %s, 期望 %s", chain[1].Changes, want)
+	}
+}
+
+func TestAuditPlatformChains(t *testing.T) {
+	repo := &fakeAuditLogRepo{logs: map[int64][]model.AuditLog{}}
+	svc := NewAuditService(repo, newFakeTxManager())
+	ctx := audit.WithMetadata(context.Background(), audit.Metadata{ActorType: "user", ActorId: "1"})
+
+	// 平台级日志按资源分链，不同用户的操作使用不同的日志链锁
+	for _, userID := range []int64{1, 2, 1} {
+		entry := AuditEntry{Action: "user.update", ResourceType: AuditResourceUser, ResourceId: userID}
+		if err := svc.Record(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"user:1", "user:2", "user:1"}
+	for i, log := range repo.logs[0] {
+		if log.Chain != want[i] || log.Sequence != int64(i/2+1) {
+			t.Errorf("第%d条日志 = 链 %s 序号 %d", i+1, log.Chain, log.Sequence)
+		}
+	}
+	if len(repo.locked) != 3 || repo.locked[0] != "user:1" || repo.locked[1] != "user:2" {
+		t.Errorf("锁定的日志链 = %v", repo.locked)
+	}
+
+	result, err := svc.Verify(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Checked != 3 {
+		t.Errorf("校验结果 = %+v", result)
+	}
+
+	// 篡改其中一条日志链时报告所在的链
+	repo.logs[0][2].After = `{"name":"c"}`
+	result, err = svc.Verify(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.Chain != "user:1" || result.BrokenAt != 2 {
+		t.Errorf("校验结果 = %+v, 期望在 user:1 的序号2处失败", result)
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"saas-account/model"
+	"saas-account/repository"
+	"sync"
+	"time"
+)
+
+// parseEntitlements 解析并校验 Features 字段中的功能权益
+func parseEntitlements(features string) (model.Entitlements, error) {
+	entitlements := make(model.Entitlements)
+	if features == "" {
+		return entitlements, nil
+	}
+
+	if err := json.Unmarshal([]byte(features), &entitlements); err != nil {
+		return nil, errors.New("功能特性必须是有效的权益JSON")
+	}
+
+	for name, e := range entitlements {
+		if name == "" {
+			return nil, errors.New("功能名称不能为空")
+		}
+		switch e.Type {
+		case model.EntitlementTypeBoolean:
+		case model.EntitlementTypeNumeric:
+			if e.Limit < -1 {
+				return nil, fmt.Errorf("功能 %s 的上限无效", name)
+			}
+		case model.EntitlementTypeEnum:
+			if len(e.Options) == 0 {
+				return nil, fmt.Errorf("功能 %s 缺少可选等级", name)
+			}
+			if e.Value != "" && tierIndex(e.Options, e.Value) < 0 {
+				return nil, fmt.Errorf("功能 %s 的等级 %s 不在可选等级中", name, e.Value)
+			}
+		default:
+			return nil, fmt.Errorf("功能 %s 的权益类型无效", name)
+		}
+	}
+
+	return entitlements, nil
+}
+
+// tierIndex 获取等级在可选等级中的位置，不存在时返回-1
+func tierIndex(options []string, tier string) int {
+	for i, option := range options {
+		if option == tier {
+			return i
+		}
+	}
+	return -1
+}
+
+// entitlementIncluded 判断功能权益是否包含在套餐中
+func entitlementIncluded(e model.Entitlement) bool {
+	switch e.Type {
+	case model.EntitlementTypeBoolean:
+		return e.Enabled
+	case model.EntitlementTypeNumeric:
+		return e.Limit != 0
+	case model.EntitlementTypeEnum:
+		return e.Value != ""
+	}
+	return false
+}
+
+// entitlementCacheEntry 权益缓存条目
+type entitlementCacheEntry struct {
+	entitlements model.Entitlements
+	expiresAt    time.Time
+}
+
+// entitlementCache 应用权益缓存，减少权益检查时的数据库查询
+type entitlementCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[int64]entitlementCacheEntry
+}
+
+// appEntitlementCache 全局应用权益缓存，应用限制变更时失效
+var appEntitlementCache = &entitlementCache{
+	ttl:     30 * time.Second,
+	entries: make(map[int64]entitlementCacheEntry),
+}
+
+// get 获取缓存的应用权益
+func (c *entitlementCache) get(appID int64) (model.Entitlements, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[appID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.entitlements, true
+}
+
+// set 缓存应用权益
+func (c *entitlementCache) set(appID int64, entitlements model.Entitlements) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[appID] = entitlementCacheEntry{
+		entitlements: entitlements,
+		expiresAt:    time.Now().Add(c.ttl),
+	}
+}
+
+// invalidate 使应用权益缓存失效
+func (c *entitlementCache) invalidate(appID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, appID)
+}
+
+// loadEntitlements 获取应用的功能权益，优先读取缓存
+func loadEntitlements(ctx context.Context, limitRepo repository.OrganizationApplicationLimitRepository, appID int64) (model.Entitlements, error) {
+	if entitlements, ok := appEntitlementCache.get(appID); ok {
+		return entitlements, nil
+	}
+
+	limit, err := limitRepo.GetByApplicationID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	entitlements, err := parseEntitlements(limit.Features)
+	if err != nil {
+		return nil, err
+	}
+
+	appEntitlementCache.set(appID, entitlements)
+	return entitlements, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"saas-account/model"
+	"saas-account/repository"
+)
+
+// EntitlementCheck 功能权益检查结果
+type EntitlementCheck struct {
+	Feature string `json:"feature"`          // 功能名称
+	Allowed bool   `json:"allowed"`          // 是否允许使用
+	Type    string `json:"type,omitempty"`   // 权益类型
+	Limit   int64  `json:"limit,omitempty"`  // 数值上限（numeric）
+	Value   string `json:"value,omitempty"`  // 当前等级（enum）
+	Reason  string `json:"reason,omitempty"` // 不允许使用的原因
+}
+
+// EntitlementService 功能权益服务接口，供租户应用判断功能是否可用
+type EntitlementService interface {
+	GetEntitlements(ctx context.Context, appID int64) (model.Entitlements, error)
+	Check(ctx context.Context, appID int64, feature string, quantity int64, tier string) (*EntitlementCheck, error)
+	CheckAll(ctx context.Context, appID int64, features []string) ([]EntitlementCheck, error)
+}
+
+// entitlementService 功能权益服务实现
+type entitlementService struct {
+	limitRepo repository.OrganizationApplicationLimitRepository
+}
+
+// NewEntitlementService 创建功能权益服务
+func NewEntitlementService(limitRepo repository.OrganizationApplicationLimitRepository) EntitlementService {
+	return &entitlementService{
+		limitRepo: limitRepo,
+	}
+}
+
+// GetEntitlements 获取应用的所有功能权益
+func (s *entitlementService) GetEntitlements(ctx context.Context, appID int64) (model.Entitlements, error) {
+	return loadEntitlements(ctx, s.limitRepo, appID)
+}
+
+// Check 检查单个功能，quantity 为数值型功能的需求数量，tier 为等级型功能的最低等级
+func (s *entitlementService) Check(ctx context.Context, appID int64, feature string, quantity int64, tier string) (*EntitlementCheck, error) {
+	entitlements, err := loadEntitlements(ctx, s.limitRepo, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := checkEntitlement(entitlements, feature, quantity, tier)
+	return &check, nil
+}
+
+// CheckAll 批量检查功能，features 为空时返回所有功能
+func (s *entitlementService) CheckAll(ctx context.Context, appID int64, features []string) ([]EntitlementCheck, error) {
+	entitlements, err := loadEntitlements(ctx, s.limitRepo, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(features) == 0 {
+		for name := range entitlements {
+			features = append(features, name)
+		}
+	}
+
+	checks := make([]EntitlementCheck, 0, len(features))
+	for _, feature := range features {
+		checks = append(checks, checkEntitlement(entitlements, feature, 0, ""))
+	}
+	return checks, nil
+}
+
+// checkEntitlement 根据权益判断功能是否可用
+func checkEntitlement(entitlements model.Entitlements, feature string, quantity int64, tier string) EntitlementCheck {
+	e, ok := entitlements[feature]
+	if !ok {
+		return EntitlementCheck{Feature: feature, Reason: "套餐未包含该功能"}
+	}
+
+	check := EntitlementCheck{
+		Feature: feature,
+		Type:    e.Type,
+		Limit:   e.Limit,
+		Value:   e.Value,
+		Allowed: entitlementIncluded(e),
+	}
+	if !check.Allowed {
+		check.Reason = "套餐未包含该功能"
+		return check
+	}
+
+	switch e.Type {
+	case model.EntitlementTypeNumeric:
+		if e.Limit >= 0 && quantity > e.Limit {
+			check.Allowed = false
+			check.Reason = "超过功能数量上限"
+		}
+	case model.EntitlementTypeEnum:
+		if tier != "" {
+			required := tierIndex(e.Options, tier)
+			if required < 0 {
+				check.Allowed = false
+				check.Reason = "无效的功能等级"
+			} else if tierIndex(e.Options, e.Value) < required {
+				check.Allowed = false
+				check.Reason = "功能等级不足"
+			}
+		}
+	}
+
+	return check
+}
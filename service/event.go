@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"saas-account/model"
+	"saas-account/repository"
+)
+
+// 领域事件类型
+const (
+	EventUserCreated              = "user.created"
+	EventOrganizationCreated      = "organization.created"
+	EventOrganizationMemberAdded  = "organization.member_added"
+	EventApplicationCreated       = "application.created"
+	EventApplicationSecretRotated = "application.secret_rotated"
+	EventLimitUpdated             = "limit.updated"
+	EventUsageThresholdReached    = "usage.threshold_reached"
+)
+
+// eventTypes 所有可订阅的事件类型
+var eventTypes = []string{
+	EventUserCreated,
+	EventOrganizationCreated,
+	EventOrganizationMemberAdded,
+	EventApplicationCreated,
+	EventApplicationSecretRotated,
+	EventLimitUpdated,
+	EventUsageThresholdReached,
+}
+
+// 聚合类型，同一聚合的事件按写入顺序发布
+const (
+	AggregateUser         = "user"
+	AggregateOrganization = "organization"
+	AggregateApplication  = "application"
+)
+
+// Event 领域事件
+type Event struct {
+	Type           string      // 事件类型
+	AggregateType  string      // 聚合类型
+	AggregateId    int64       // 聚合ID
+	OrganizationId int64       // 所属组织ID，0表示不属于任何组织
+	Data           interface{} // 事件数据
+}
+
+// EventPublisher 领域事件发布接口
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// outboxPublisher 将领域事件写入发件箱，由发件箱中继异步发布
+type outboxPublisher struct {
+	outboxRepo repository.OutboxRepository
+}
+
+// NewOutboxPublisher 创建发件箱事件发布者，在事务中调用时事件随事务一起提交
+func NewOutboxPublisher(outboxRepo repository.OutboxRepository) EventPublisher {
+	return &outboxPublisher{
+		outboxRepo: outboxRepo,
+	}
+}
+
+// Publish 写入发件箱
+func (p *outboxPublisher) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	return p.outboxRepo.Create(ctx, &model.OutboxEvent{
+		AggregateType:  event.AggregateType,
+		AggregateId:    event.AggregateId,
+		OrganizationId: event.OrganizationId,
+		EventType:      event.Type,
+		Payload:        string(payload),
+		Status:         model.OutboxEventPending,
+	})
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"saas-account/repository"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// errInjected 测试中注入的写入错误
+var errInjected = errors.New("injected failure")
+
+// fakeTxKey 上下文中标记处于假事务中的键
+type fakeTxKey struct{}
+
+// fakeTxState 参与假事务的内存数据
+type fakeTxState interface {
+	// snapshot 保存当前数据，返回恢复函数
+	snapshot() func()
+}
+
+// fakeTxManager 假事务管理器，fn 返回错误时恢复所有数据的快照，嵌套调用复用外层事务
+type fakeTxManager struct {
+	states []fakeTxState
+}
+
+// newFakeTxManager 创建假事务管理器
+func newFakeTxManager(states ...fakeTxState) *fakeTxManager {
+	return &fakeTxManager{states: states}
+}
+
+func (t *fakeTxManager) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(fakeTxKey{}) != nil {
+		return fn(ctx)
+	}
+
+	restores := make([]func(), 0, len(t.states))
+	for _, state := range t.states {
+		restores = append(restores, state.snapshot())
+	}
+
+	if err := fn(context.WithValue(ctx, fakeTxKey{}, true)); err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+		return err
+	}
+	return nil
+}
+
+var (
+	fixtureMu     sync.Mutex
+	fixtureNextID int64 = 1000
+)
+
+// nextFixtureID 获取测试间不重复的ID
+func nextFixtureID() int64 {
+	fixtureMu.Lock()
+	defer fixtureMu.Unlock()
+	fixtureNextID++
+	return fixtureNextID
+}
+
+// fixtureApp 测试应用及其组织、应用限制的内存仓库，组织ID和应用ID在测试间不重复，避免组织时区缓存互相影响
+type fixtureApp struct {
+	app    *model.OrganizationApplication
+	apps   *fakeAppRepo
+	orgs   *fakeOrgRepo
+	limits *fakeLimitRepo
+}
+
+// newFixtureApp 创建测试应用，limit 为nil表示应用未配置限制
+func newFixtureApp(timeZone string, limit *model.OrganizationApplicationLimit) *fixtureApp {
+	id := nextFixtureID()
+	app := &model.OrganizationApplication{Base: model.Base{ID: id}, OrganizationId: id, Name: "测试应用"}
+	limits := map[int64]*model.OrganizationApplicationLimit{}
+	if limit != nil {
+		limit.OrganizationApplicationId = id
+		limits[id] = limit
+	}
+
+	return &fixtureApp{
+		app:    app,
+		apps:   &fakeAppRepo{apps: map[int64]*model.OrganizationApplication{id: app}},
+		orgs:   &fakeOrgRepo{orgs: map[int64]*model.Organization{id: {Base: model.Base{ID: id}, TimeZone: timeZone}}},
+		limits: &fakeLimitRepo{limits: limits},
+	}
+}
+
+// fakeAppRepo 内存中的组织应用仓库
+type fakeAppRepo struct {
+	repository.OrganizationApplicationRepository
+	apps map[int64]*model.OrganizationApplication
+}
+
+func (r *fakeAppRepo) GetByID(ctx context.Context, id int64) (*model.OrganizationApplication, error) {
+	app, ok := r.apps[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return app, nil
+}
+
+func (r *fakeAppRepo) GetByIDForUpdate(ctx context.Context, id int64) (*model.OrganizationApplication, error) {
+	return r.GetByID(ctx, id)
+}
+
+// fakeOrgRepo 内存中的组织仓库
+type fakeOrgRepo struct {
+	repository.OrganizationRepository
+	orgs map[int64]*model.Organization
+}
+
+func (r *fakeOrgRepo) GetByID(ctx context.Context, id int64) (*model.Organization, error) {
+	org, ok := r.orgs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return org, nil
+}
+
+// fakeLimitRepo 内存中的应用限制仓库
+type fakeLimitRepo struct {
+	repository.OrganizationApplicationLimitRepository
+	limits map[int64]*model.OrganizationApplicationLimit
+}
+
+func (r *fakeLimitRepo) GetByApplicationID(ctx context.Context, appID int64) (*model.OrganizationApplicationLimit, error) {
+	limit, ok := r.limits[appID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return limit, nil
+}
@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"saas-account/logger"
+	"saas-account/model"
+	"saas-account/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvoiceIssued 账单已出账，不能重新生成
+var ErrInvoiceIssued = errors.New("账单已出账，不能重新生成")
+
+// InvoiceService 账单服务接口，按组织时区的自然月生成账单
+type InvoiceService interface {
+	Generate(ctx context.Context, orgID int64, period string) (*model.Invoice, error)
+	Issue(ctx context.Context, id int64) (*model.Invoice, error)
+	GetByID(ctx context.Context, id int64) (*model.Invoice, error)
+	ListByOrganization(ctx context.Context, orgID int64, page, pageSize int) ([]model.Invoice, int64, error)
+	ProcessPreviousPeriod(ctx context.Context) (int, error)
+}
+
+// invoiceService 账单服务实现
+type invoiceService struct {
+	invoiceRepo    repository.InvoiceRepository
+	orgRepo        repository.OrganizationRepository
+	appRepo        repository.OrganizationApplicationRepository
+	appMemberRepo  repository.OrganizationApplicationMemberRepository
+	limitRepo      repository.OrganizationApplicationLimitRepository
+	planRepo       repository.PlanRepository
+	planChangeRepo repository.PlanChangeRepository
+	rollupRepo     repository.UsageRollupRepository
+	storageService StorageService
+	txManager      repository.TransactionManager
+}
+
+// NewInvoiceService 创建账单服务
+func NewInvoiceService(
+	invoiceRepo repository.InvoiceRepository,
+	orgRepo repository.OrganizationRepository,
+	appRepo repository.OrganizationApplicationRepository,
+	appMemberRepo repository.OrganizationApplicationMemberRepository,
+	limitRepo repository.OrganizationApplicationLimitRepository,
+	planRepo repository.PlanRepository,
+	planChangeRepo repository.PlanChangeRepository,
+	rollupRepo repository.UsageRollupRepository,
+	storageService StorageService,
+	txManager repository.TransactionManager,
+) InvoiceService {
+	return &invoiceService{
+		invoiceRepo:    invoiceRepo,
+		orgRepo:        orgRepo,
+		appRepo:        appRepo,
+		appMemberRepo:  appMemberRepo,
+		limitRepo:      limitRepo,
+		planRepo:       planRepo,
+		planChangeRepo: planChangeRepo,
+		rollupRepo:     rollupRepo,
+		storageService: storageService,
+		txManager:      txManager,
+	}
+}
+
+// planSegment 应用在计费周期内使用同一套餐的一段时间 [start, end)
+type planSegment struct {
+	planID int64
+	start  time.Time
+	end    time.Time
+}
+
+// Generate 生成组织在计费周期（YYYY-MM）的账单，已存在的草稿账单会按最新用量重新生成
+func (s *invoiceService) Generate(ctx context.Context, orgID int64, period string) (*model.Invoice, error) {
+	loc, err := loadOrgLocation(ctx, s.orgRepo, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := time.ParseInLocation("2006-01", period, loc)
+	if err != nil {
+		return nil, errors.New("无效的计费周期，格式为YYYY-MM")
+	}
+	end := nextPeriod(UsageGranularityMonth, start)
+	if start.After(time.Now()) {
+		return nil, errors.New("计费周期尚未开始")
+	}
+
+	invoice, err := s.invoiceRepo.GetByPeriod(ctx, orgID, start.Unix())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		invoice = &model.Invoice{
+			OrganizationId: orgID,
+			Number:         fmt.Sprintf("INV-%d-%s", orgID, start.Format("200601")),
+			PeriodStart:    start.Unix(),
+			PeriodEnd:      end.Unix(),
+			Status:         model.InvoiceStatusDraft,
+		}
+	case err != nil:
+		return nil, err
+	case invoice.Status == model.InvoiceStatusIssued:
+		return nil, ErrInvoiceIssued
+	}
+
+	items, currency, err := s.buildLineItems(ctx, orgID, start, end, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice.Currency = currency
+	invoice.Total = 0
+	for _, item := range items {
+		invoice.Total += item.Amount
+	}
+
+	// 账单和明细一起提交，避免重新生成失败时留下与明细不一致的总额
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if invoice.ID == 0 {
+			invoice.LineItems = items
+			return s.invoiceRepo.Create(ctx, invoice)
+		}
+		if err := s.invoiceRepo.Update(ctx, invoice); err != nil {
+			return err
+		}
+		return s.invoiceRepo.ReplaceLineItems(ctx, invoice.ID, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.invoiceRepo.GetByID(ctx, invoice.ID)
+}
+
+// Issue 出账，出账后账单不能再重新生成，计费周期结束前不能出账
+func (s *invoiceService) Issue(ctx context.Context, id int64) (*model.Invoice, error) {
+	invoice, err := s.invoiceRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if invoice.Status == model.InvoiceStatusIssued {
+		return invoice, nil
+	}
+	if now := time.Now().Unix(); invoice.PeriodEnd > now {
+		return nil, errors.New("计费周期尚未结束，不能出账")
+	}
+
+	invoice.Status = model.InvoiceStatusIssued
+	invoice.IssuedAt = time.Now().Unix()
+	if err := s.invoiceRepo.Update(ctx, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// GetByID 根据ID获取账单及其明细
+func (s *invoiceService) GetByID(ctx context.Context, id int64) (*model.Invoice, error) {
+	return s.invoiceRepo.GetByID(ctx, id)
+}
+
+// ListByOrganization 获取组织的账单列表
+func (s *invoiceService) ListByOrganization(ctx context.Context, orgID int64, page, pageSize int) ([]model.Invoice, int64, error) {
+	// 检查组织是否存在
+	if _, err := s.orgRepo.GetByID(ctx, orgID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.invoiceRepo.ListByOrganization(ctx, orgID, page, pageSize)
+}
+
+// ProcessPreviousPeriod 为所有组织生成并出账上一个计费周期的账单，返回出账数量
+func (s *invoiceService) ProcessPreviousPeriod(ctx context.Context) (int, error) {
+	const pageSize = 100
+
+	issued := 0
+	for page := 1; ; page++ {
+		orgs, _, err := s.orgRepo.List(ctx, page, pageSize)
+		if err != nil {
+			return issued, err
+		}
+
+		for i := range orgs {
+			ok, err := s.closePreviousPeriod(ctx, orgs[i].ID)
+			if err != nil {
+				logger.GetLogger().ErrorWithContext(ctx, "生成账单失败: 组织=%d, 错误: %v", orgs[i].ID, err)
+				continue
+			}
+			if ok {
+				issued++
+			}
+		}
+
+		if len(orgs) < pageSize {
+			return issued, nil
+		}
+	}
+}
+
+// closePreviousPeriod 生成并出账组织上一个计费周期的账单，已出账时返回false
+func (s *invoiceService) closePreviousPeriod(ctx context.Context, orgID int64) (bool, error) {
+	loc, err := loadOrgLocation(ctx, s.orgRepo, orgID)
+	if err != nil {
+		return false, err
+	}
+
+	start := periodStart(UsageGranularityMonth, time.Now(), loc).AddDate(0, -1, 0)
+	invoice, err := s.invoiceRepo.GetByPeriod(ctx, orgID, start.Unix())
+	if err == nil && invoice.Status == model.InvoiceStatusIssued {
+		return false, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	invoice, err = s.Generate(ctx, orgID, start.Format("2006-01"))
+	if err != nil {
+		return false, err
+	}
+	if _, err := s.Issue(ctx, invoice.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// buildLineItems 计算组织下所有应用在计费周期内的账单明细，返回明细和币种
+// 周期内删除的应用同样计费，计到删除当天
+func (s *invoiceService) buildLineItems(ctx context.Context, orgID int64, start, end time.Time, loc *time.Location) ([]model.InvoiceLineItem, string, error) {
+	// 账单金额必须基于最新的应用和用量数据，不读只读副本
+	ctx = repository.WithPrimary(ctx)
+
+	apps, err := s.appRepo.GetBillableByOrganization(ctx, orgID, start)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]model.InvoiceLineItem, 0)
+	plans := make(map[int64]*model.Plan)
+	currency := ""
+	for i := range apps {
+		segments, err := s.planSegments(ctx, &apps[i], start, end, loc)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, segment := range segments {
+			// 自定义限制没有价格组成，不计费
+			if segment.planID == 0 {
+				continue
+			}
+
+			plan, ok := plans[segment.planID]
+			if !ok {
+				plan, err = s.planRepo.GetByID(ctx, segment.planID)
+				if err != nil {
+					return nil, "", err
+				}
+				plans[segment.planID] = plan
+			}
+
+			segmentItems, err := s.segmentLineItems(ctx, &apps[i], plan, segment, end.Sub(start))
+			if err != nil {
+				return nil, "", err
+			}
+			if len(segmentItems) == 0 {
+				continue
+			}
+
+			if currency == "" {
+				currency = plan.Currency
+			} else if currency != plan.Currency {
+				return nil, "", errors.New("账单中的套餐币种不一致")
+			}
+			items = append(items, segmentItems...)
+		}
+	}
+
+	if currency == "" {
+		currency = "CNY"
+	}
+	return items, currency, nil
+}
+
+// planSegments 按套餐变更记录将应用在计费周期内的使用时间拆分为多段，变更当天起按新套餐计费
+func (s *invoiceService) planSegments(ctx context.Context, app *model.OrganizationApplication, start, end time.Time, loc *time.Location) ([]planSegment, error) {
+	// 周期内创建的应用从创建当天开始计费
+	from := start
+	if created := periodStart(UsageGranularityDay, time.Unix(app.CreatedAt, 0), loc); created.After(from) {
+		from = created
+	}
+	// 周期内删除的应用计费到删除当天
+	if app.DeletedAt.Valid {
+		if deleted := periodStart(UsageGranularityDay, app.DeletedAt.Time, loc).AddDate(0, 0, 1); deleted.Before(end) {
+			end = deleted
+		}
+	}
+	if !from.Before(end) {
+		return nil, nil
+	}
+
+	changes, err := s.planChangeRepo.GetByApplicationBetween(ctx, app.ID, from.Unix(), end.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	// 周期开始时使用的套餐
+	var planID int64
+	last, err := s.planChangeRepo.GetLastBefore(ctx, app.ID, from.Unix())
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case last != nil:
+		planID = last.ToPlanId
+	case len(changes) > 0:
+		planID = changes[0].FromPlanId
+	default:
+		// 没有变更记录时使用当前套餐
+		limit, err := s.limitRepo.GetByApplicationID(ctx, app.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if limit != nil {
+			planID = limit.PlanId
+		}
+	}
+
+	var segments []planSegment
+	segmentStart := from
+	for _, change := range changes {
+		at := periodStart(UsageGranularityDay, time.Unix(change.ChangedAt, 0), loc)
+		if at.After(segmentStart) {
+			segments = append(segments, planSegment{planID: planID, start: segmentStart, end: at})
+			segmentStart = at
+		}
+		planID = change.ToPlanId
+	}
+	segments = append(segments, planSegment{planID: planID, start: segmentStart, end: end})
+
+	return segments, nil
+}
+
+// segmentLineItems 按套餐的价格组成计算应用在一段时间内的账单明细，固定费用和按成员计费按天折算
+func (s *invoiceService) segmentLineItems(ctx context.Context, app *model.OrganizationApplication, plan *model.Plan, segment planSegment, period time.Duration) ([]model.InvoiceLineItem, error) {
+	components, err := parsePriceComponents(plan.PriceComponents)
+	if err != nil {
+		return nil, err
+	}
+
+	used := int64(segment.end.Sub(segment.start) / time.Second)
+	total := int64(period / time.Second)
+	dates := fmt.Sprintf("%s ~ %s", segment.start.Format("2006-01-02"), segment.end.AddDate(0, 0, -1).Format("2006-01-02"))
+
+	var items []model.InvoiceLineItem
+	for _, c := range components {
+		item := model.InvoiceLineItem{
+			ApplicationId: app.ID,
+			PlanId:        plan.ID,
+			PlanVersion:   plan.Version,
+			Type:          c.Type,
+			Description:   fmt.Sprintf("%s %s（%s v%d，%s）", app.Name, c.Name, plan.Code, plan.Version, dates),
+			Metric:        c.Metric,
+			PeriodStart:   segment.start.Unix(),
+			PeriodEnd:     segment.end.Unix(),
+		}
+
+		switch c.Type {
+		case model.PriceTypeFlat:
+			item.Quantity = 1
+			item.Amount = prorate(c.UnitAmount, used, total)
+		case model.PriceTypePerSeat:
+			item.Quantity, item.Amount, err = s.seats(ctx, app.ID, c.UnitAmount, segment, total)
+			if err != nil {
+				return nil, err
+			}
+		case model.PriceTypePerUnit:
+			item.Quantity, err = s.meter(ctx, app.ID, c.Metric, segment)
+			if err != nil {
+				return nil, err
+			}
+			included := prorate(c.Included, used, total)
+			item.Amount = unitPrice(billableUnits(item.Quantity, c, included), c)
+		}
+
+		if item.Quantity == 0 && item.Amount == 0 {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// meter 获取应用在一段时间内某个计量项的用量，storage 按GB月向上取整
+func (s *invoiceService) meter(ctx context.Context, appID int64, metric string, segment planSegment) (int64, error) {
+	switch metric {
+	case "api_call":
+		sums, err := s.rollupRepo.SumByApplication(ctx, appID, model.RollupGranularityDay, segment.start.Unix(), segment.end.Unix())
+		if err != nil {
+			return 0, err
+		}
+		return sums["api_call"], nil
+	case "storage":
+		billing, err := s.storageService.GetBilling(ctx, appID, segment.start, segment.end.AddDate(0, 0, -1))
+		if err != nil {
+			return 0, err
+		}
+		return int64(math.Ceil(billing.GBMonths)), nil
+	}
+
+	// 其他计量项为功能名称，原始使用记录会被定期清理，按汇总计量
+	sums, err := s.rollupRepo.SumFeaturesByApplication(ctx, appID, model.RollupGranularityDay, segment.start.Unix(), segment.end.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return sums[metric], nil
+}
+
+// seats 按成员在一段时间内的实际在册天数计算按成员计费的数量和金额，返回期间内的成员数和金额
+// 成员从加入当天起计费，计到移除当天，period 为整个计费周期的秒数
+func (s *invoiceService) seats(ctx context.Context, appID, unitAmount int64, segment planSegment, period int64) (int64, int64, error) {
+	members, err := s.appMemberRepo.GetByApplicationBetween(ctx, appID, segment.start, segment.end)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	loc := segment.start.Location()
+	var quantity, amount int64
+	for _, member := range members {
+		from := segment.start
+		if joined := periodStart(UsageGranularityDay, time.Unix(member.CreatedAt, 0), loc); joined.After(from) {
+			from = joined
+		}
+		to := segment.end
+		if member.DeletedAt.Valid {
+			if removed := periodStart(UsageGranularityDay, member.DeletedAt.Time, loc).AddDate(0, 0, 1); removed.Before(to) {
+				to = removed
+			}
+		}
+		if !from.Before(to) {
+			continue
+		}
+
+		quantity++
+		amount += prorate(unitAmount, int64(to.Sub(from)/time.Second), period)
+	}
+	return quantity, amount, nil
+}
@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"saas-account/model"
+	"saas-account/repository"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeInvoiceRepo 内存中的账单仓库
+type fakeInvoiceRepo struct {
+	repository.InvoiceRepository
+	invoices  map[int64]*model.Invoice
+	outsideTx []string // 在事务外执行的写操作
+}
+
+func (r *fakeInvoiceRepo) write(ctx context.Context, op string) {
+	if ctx.Value(fakeTxKey{}) == nil {
+		r.outsideTx = append(r.outsideTx, op)
+	}
+}
+
+func (r *fakeInvoiceRepo) Create(ctx context.Context, invoice *model.Invoice) error {
+	r.write(ctx, "create")
+	invoice.ID = int64(len(r.invoices) + 1)
+	r.invoices[invoice.ID] = invoice
+	return nil
+}
+
+func (r *fakeInvoiceRepo) GetByID(ctx context.Context, id int64) (*model.Invoice, error) {
+	invoice, ok := r.invoices[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return invoice, nil
+}
+
+func (r *fakeInvoiceRepo) GetByPeriod(ctx context.Context, orgID int64, periodStart int64) (*model.Invoice, error) {
+	for _, invoice := range r.invoices {
+		if invoice.OrganizationId == orgID && invoice.PeriodStart == periodStart {
+			return invoice, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeInvoiceRepo) Update(ctx context.Context, invoice *model.Invoice) error {
+	r.write(ctx, "update")
+	r.invoices[invoice.ID] = invoice
+	return nil
+}
+
+func (r *fakeInvoiceRepo) ReplaceLineItems(ctx context.Context, invoiceID int64, items []model.InvoiceLineItem) error {
+	r.write(ctx, "replace")
+	r.invoices[invoiceID].LineItems = items
+	return nil
+}
+
+// fakeBillingAppRepo 内存中的组织应用仓库，按删除时间筛选需要计费的应用
+type fakeBillingAppRepo struct {
+	repository.OrganizationApplicationRepository
+	apps []model.OrganizationApplication
+}
+
+func (r *fakeBillingAppRepo) GetBillableByOrganization(ctx context.Context, orgID int64, since time.Time) ([]model.OrganizationApplication, error) {
+	var apps []model.OrganizationApplication
+	for _, app := range r.apps {
+		if app.OrganizationId == orgID && (!app.DeletedAt.Valid || !app.DeletedAt.Time.Before(since)) {
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
+}
+
+// fakeAppMemberRepo 内存中的应用成员仓库，包括已移除的成员
+type fakeAppMemberRepo struct {
+	repository.OrganizationApplicationMemberRepository
+	members []model.OrganizationApplicationMember
+}
+
+func (r *fakeAppMemberRepo) GetByApplicationBetween(ctx context.Context, appID int64, start, end time.Time) ([]model.OrganizationApplicationMember, error) {
+	var members []model.OrganizationApplicationMember
+	for _, member := range r.members {
+		if member.ApplicationId == appID && member.CreatedAt < end.Unix() &&
+			(!member.DeletedAt.Valid || !member.DeletedAt.Time.Before(start)) {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+// fakePlanRepo 内存中的套餐仓库
+type fakePlanRepo struct {
+	repository.PlanRepository
+	plans map[int64]*model.Plan
+}
+
+func (r *fakePlanRepo) GetByID(ctx context.Context, id int64) (*model.Plan, error) {
+	plan, ok := r.plans[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return plan, nil
+}
+
+// fakePlanChangeRepo 内存中的套餐变更记录仓库
+type fakePlanChangeRepo struct {
+	repository.PlanChangeRepository
+	changes []model.PlanChange
+}
+
+func (r *fakePlanChangeRepo) GetByApplicationBetween(ctx context.Context, appID int64, start, end int64) ([]model.PlanChange, error) {
+	var changes []model.PlanChange
+	for _, change := range r.changes {
+		if change.ApplicationId == appID && change.ChangedAt >= start && change.ChangedAt < end {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (r *fakePlanChangeRepo) GetLastBefore(ctx context.Context, appID int64, before int64) (*model.PlanChange, error) {
+	var last *model.PlanChange
+	for i := range r.changes {
+		if r.changes[i].ApplicationId == appID && r.changes[i].ChangedAt < before {
+			last = &r.changes[i]
+		}
+	}
+	return last, nil
+}
+
+func (r *fakePlanChangeRepo) GetLastFrom(ctx context.Context, appID int64, fromPlanID int64) (*model.PlanChange, error) {
+	var last *model.PlanChange
+	for i := range r.changes {
+		if r.changes[i].ApplicationId == appID && r.changes[i].FromPlanId == fromPlanID {
+			last = &r.changes[i]
+		}
+	}
+	return last, nil
+}
+
+// fakeBillingRollupRepo 内存中的功能使用量汇总，键为 应用ID, 功能名称
+type fakeBillingRollupRepo struct {
+	repository.UsageRollupRepository
+	features map[int64]map[string]int64
+}
+
+func (r *fakeBillingRollupRepo) SumByApplication(ctx context.Context, appID int64, granularity string, start, end int64) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+func (r *fakeBillingRollupRepo) SumFeaturesByApplication(ctx context.Context, appID int64, granularity string, start, end int64) (map[string]int64, error) {
+	if r.features[appID] == nil {
+		return map[string]int64{}, nil
+	}
+	return r.features[appID], nil
+}
+
+// invoiceFixture 账单服务的测试环境，组织时区为UTC
+type invoiceFixture struct {
+	service     *invoiceService
+	orgID       int64
+	invoiceRepo *fakeInvoiceRepo
+	appRepo     *fakeBillingAppRepo
+	memberRepo  *fakeAppMemberRepo
+	planRepo    *fakePlanRepo
+	changeRepo  *fakePlanChangeRepo
+	limitRepo   *fakeLimitRepo
+	rollupRepo  *fakeBillingRollupRepo
+}
+
+func newInvoiceFixture(t *testing.T) *invoiceFixture {
+	t.Helper()
+
+	orgID := nextFixtureID()
+	f := &invoiceFixture{
+		orgID:       orgID,
+		invoiceRepo: &fakeInvoiceRepo{invoices: map[int64]*model.Invoice{}},
+		appRepo:     &fakeBillingAppRepo{},
+		memberRepo:  &fakeAppMemberRepo{},
+		planRepo:    &fakePlanRepo{plans: map[int64]*model.Plan{}},
+		changeRepo:  &fakePlanChangeRepo{},
+		limitRepo:   &fakeLimitRepo{limits: map[int64]*model.OrganizationApplicationLimit{}},
+		rollupRepo:  &fakeBillingRollupRepo{features: map[int64]map[string]int64{}},
+	}
+	f.service = NewInvoiceService(
+		f.invoiceRepo,
+		&fakeOrgRepo{orgs: map[int64]*model.Organization{orgID: {Base: model.Base{ID: orgID}, TimeZone: "UTC"}}},
+		f.appRepo,
+		f.memberRepo,
+		f.limitRepo,
+		f.planRepo,
+		f.changeRepo,
+		f.rollupRepo,
+		nil,
+		newFakeTxManager(),
+	).(*invoiceService)
+	return f
+}
+
+// addPlan 添加套餐
+func (f *invoiceFixture) addPlan(t *testing.T, id int64, components []model.PriceComponent) {
+	t.Helper()
+	raw, err := json.Marshal(components)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.planRepo.plans[id] = &model.Plan{Base: model.Base{ID: id}, Code: "plan", Version: 1, Currency: "CNY", PriceComponents: string(raw)}
+}
+
+// addApp 添加使用指定套餐的应用，deleted 为零值表示未删除
+func (f *invoiceFixture) addApp(id, planID int64, created, deleted time.Time) {
+	app := model.OrganizationApplication{Base: model.Base{ID: id, CreatedAt: created.Unix()}, OrganizationId: f.orgID, Name: "应用"}
+	if !deleted.IsZero() {
+		app.DeletedAt = gorm.DeletedAt{Time: deleted, Valid: true}
+	}
+	f.appRepo.apps = append(f.appRepo.apps, app)
+	f.limitRepo.limits[id] = &model.OrganizationApplicationLimit{OrganizationApplicationId: id, PlanId: planID}
+}
+
+// addMember 添加应用成员，removed 为零值表示仍在应用中
+func (f *invoiceFixture) addMember(appID, userID int64, joined, removed time.Time) {
+	member := model.OrganizationApplicationMember{Base: model.Base{CreatedAt: joined.Unix()}, ApplicationId: appID, MemberId: userID}
+	if !removed.IsZero() {
+		member.DeletedAt = gorm.DeletedAt{Time: removed, Valid: true}
+	}
+	f.memberRepo.members = append(f.memberRepo.members, member)
+}
+
+// lineItem 账单明细中用于比较的字段
+type lineItem struct {
+	appID    int64
+	typ      string
+	metric   string
+	quantity int64
+	amount   int64
+	end      int64
+}
+
+func date(month time.Month, day, hour int) time.Time {
+	return time.Date(2025, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestInvoiceGenerate(t *testing.T) {
+	f := newInvoiceFixture(t)
+	f.addPlan(t, 1, []model.PriceComponent{
+		{Type: model.PriceTypeFlat, Name: "基础费", UnitAmount: 3000},
+		{Type: model.PriceTypePerSeat, Name: "成员费", UnitAmount: 1000},
+		{Type: model.PriceTypePerUnit, Name: "导出", Metric: "export", UnitAmount: 10, Included: 100},
+		{Type: model.PriceTypePerUnit, Name: "调用", Metric: "api_call", UnitAmount: 1},
+	})
+
+	// 应用 A 整月使用；应用 B 在4月10日删除，计费到删除当天；应用 C 在计费周期前删除，不计费
+	f.addApp(1, 1, date(1, 1, 0), time.Time{})
+	f.addApp(2, 1, date(1, 1, 0), date(4, 10, 12))
+	f.addApp(3, 1, date(1, 1, 0), date(3, 20, 0))
+
+	// 成员按在册天数计费：整月、4月11日加入、4月20日移除、计费周期前已移除
+	f.addMember(1, 101, date(1, 1, 0), time.Time{})
+	f.addMember(1, 102, date(4, 11, 10), time.Time{})
+	f.addMember(1, 103, date(2, 1, 0), date(4, 20, 15))
+	f.addMember(1, 104, date(2, 1, 0), date(3, 15, 0))
+
+	// 功能用量来自汇总
+	f.rollupRepo.features[1] = map[string]int64{"export": 250}
+
+	invoice, err := f.service.Generate(context.Background(), f.orgID, "2025-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	periodEnd := date(5, 1, 0).Unix()
+	want := []lineItem{
+		{appID: 1, typ: model.PriceTypeFlat, quantity: 1, amount: 3000, end: periodEnd},
+		{appID: 1, typ: model.PriceTypePerSeat, quantity: 3, amount: 1000 + 666 + 666, end: periodEnd},
+		{appID: 1, typ: model.PriceTypePerUnit, metric: "export", quantity: 250, amount: 1500, end: periodEnd},
+		{appID: 2, typ: model.PriceTypeFlat, quantity: 1, amount: 1000, end: date(4, 11, 0).Unix()},
+	}
+	assertLineItems(t, invoice.LineItems, want)
+
+	if invoice.Total != 3000+2332+1500+1000 {
+		t.Errorf("总额 = %d, 期望 %d", invoice.Total, 3000+2332+1500+1000)
+	}
+	if invoice.PeriodStart != date(4, 1, 0).Unix() || invoice.PeriodEnd != periodEnd || invoice.Status != model.InvoiceStatusDraft {
+		t.Errorf("账单 = %+v", invoice)
+	}
+
+	// 重新生成草稿账单时，账单和明细在同一个事务中更新
+	f.rollupRepo.features[1] = map[string]int64{"export": 300}
+	invoice, err = f.service.Generate(context.Background(), f.orgID, "2025-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoice.Total != 3000+2332+2000+1000 {
+		t.Errorf("重新生成后总额 = %d, 期望 %d", invoice.Total, 3000+2332+2000+1000)
+	}
+	if len(f.invoiceRepo.outsideTx) != 0 {
+		t.Errorf("事务外的写操作: %v", f.invoiceRepo.outsideTx)
+	}
+
+	// 已出账的账单不能重新生成
+	invoice.Status = model.InvoiceStatusIssued
+	if _, err := f.service.Generate(context.Background(), f.orgID, "2025-04"); err != ErrInvoiceIssued {
+		t.Errorf("错误 = %v, 期望 %v", err, ErrInvoiceIssued)
+	}
+}
+
+func TestInvoicePlanChangeProration(t *testing.T) {
+	f := newInvoiceFixture(t)
+	f.addPlan(t, 1, []model.PriceComponent{{Type: model.PriceTypeFlat, Name: "基础费", UnitAmount: 3000}})
+	f.addPlan(t, 2, []model.PriceComponent{{Type: model.PriceTypeFlat, Name: "基础费", UnitAmount: 6000}})
+
+	// 4月16日下午升级，变更当天起按新套餐计费
+	f.addApp(1, 2, date(1, 1, 0), time.Time{})
+	f.changeRepo.changes = []model.PlanChange{
+		{ApplicationId: 1, FromPlanId: 0, ToPlanId: 1, ChangedAt: date(1, 1, 0).Unix()},
+		{ApplicationId: 1, FromPlanId: 1, ToPlanId: 2, ChangedAt: date(4, 16, 15).Unix()},
+	}
+
+	// 应用在4月21日创建，从创建当天计费
+	f.addApp(2, 1, date(4, 21, 9), time.Time{})
+
+	invoice, err := f.service.Generate(context.Background(), f.orgID, "2025-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertLineItems(t, invoice.LineItems, []lineItem{
+		{appID: 1, typ: model.PriceTypeFlat, quantity: 1, amount: 1500, end: date(4, 16, 0).Unix()},
+		{appID: 1, typ: model.PriceTypeFlat, quantity: 1, amount: 3000, end: date(5, 1, 0).Unix()},
+		{appID: 2, typ: model.PriceTypeFlat, quantity: 1, amount: 1000, end: date(5, 1, 0).Unix()},
+	})
+}
+
+// assertLineItems 按顺序比较账单明细
+func assertLineItems(t *testing.T, items []model.InvoiceLineItem, want []lineItem) {
+	t.Helper()
+
+	if len(items) != len(want) {
+		for _, item := range items {
+			t.Logf("明细: %+v", item)
+		}
+		t.Fatalf("明细数 = %d, 期望 %d", len(items), len(want))
+	}
+	for i, item := range items {
+		got := lineItem{
+			appID:    item.ApplicationId,
+			typ:      item.Type,
+			metric:   item.Metric,
+			quantity: item.Quantity,
+			amount:   item.Amount,
+			end:      item.PeriodEnd,
+		}
+		if got != want[i] {
+			t.Errorf("明细 %d = %+v, 期望 %+v", i, got, want[i])
+		}
+	}
+}
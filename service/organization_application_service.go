@@ -5,8 +5,12 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"saas-account/logger"
 	"saas-account/model"
 	"saas-account/repository"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // OrganizationApplicationService 组织应用服务接口
@@ -25,15 +29,22 @@ type OrganizationApplicationService interface {
 	UpdateMember(ctx context.Context, appID, userID int64, role string, permissions string) error
 	SetLimit(ctx context.Context, limit *model.OrganizationApplicationLimit) error
 	GetLimit(ctx context.Context, appID int64) (*model.OrganizationApplicationLimit, error)
+	ChangePlan(ctx context.Context, appID, planID int64) (*model.OrganizationApplicationLimit, error)
 }
 
 // organizationApplicationService 组织应用服务实现
 type organizationApplicationService struct {
-	appRepo       repository.OrganizationApplicationRepository
-	appMemberRepo repository.OrganizationApplicationMemberRepository
-	appLimitRepo  repository.OrganizationApplicationLimitRepository
-	orgRepo       repository.OrganizationRepository
-	userRepo      repository.UserRepository
+	appRepo        repository.OrganizationApplicationRepository
+	appMemberRepo  repository.OrganizationApplicationMemberRepository
+	appLimitRepo   repository.OrganizationApplicationLimitRepository
+	orgRepo        repository.OrganizationRepository
+	userRepo       repository.UserRepository
+	planRepo       repository.PlanRepository
+	planChangeRepo repository.PlanChangeRepository
+	publisher      EventPublisher
+	txManager      repository.TransactionManager
+	auditService   AuditService
+	gaugeRepo      repository.StorageGaugeRepository
 }
 
 // NewOrganizationApplicationService 创建组织应用服务
@@ -43,14 +54,139 @@ func NewOrganizationApplicationService(
 	appLimitRepo repository.OrganizationApplicationLimitRepository,
 	orgRepo repository.OrganizationRepository,
 	userRepo repository.UserRepository,
+	planRepo repository.PlanRepository,
+	planChangeRepo repository.PlanChangeRepository,
+	publisher EventPublisher,
+	txManager repository.TransactionManager,
+	auditService AuditService,
+	gaugeRepo repository.StorageGaugeRepository,
 ) OrganizationApplicationService {
 	return &organizationApplicationService{
-		appRepo:       appRepo,
-		appMemberRepo: appMemberRepo,
-		appLimitRepo:  appLimitRepo,
-		orgRepo:       orgRepo,
-		userRepo:      userRepo,
+		appRepo:        appRepo,
+		appMemberRepo:  appMemberRepo,
+		appLimitRepo:   appLimitRepo,
+		orgRepo:        orgRepo,
+		userRepo:       userRepo,
+		planRepo:       planRepo,
+		planChangeRepo: planChangeRepo,
+		publisher:      publisher,
+		txManager:      txManager,
+		auditService:   auditService,
+		gaugeRepo:      gaugeRepo,
+	}
+}
+
+// builtinFreePlan 内置免费套餐，套餐目录中未配置默认套餐时使用
+var builtinFreePlan = model.Plan{
+	Code:            "free",
+	Name:            "Free",
+	MaxUsers:        5,
+	MaxStorage:      1073741824, // 1GB
+	MaxRequests:     10000,      // 每天10000请求
+	EnforcementMode: model.EnforcementModeHard,
+	Features:        "{}", // 空特性
+}
+
+// getDefaultPlan 获取新应用的默认套餐，未配置时使用内置免费套餐
+func getDefaultPlan(ctx context.Context, planRepo repository.PlanRepository) *model.Plan {
+	plan, err := planRepo.GetDefault(ctx)
+	if err != nil {
+		freePlan := builtinFreePlan
+		return &freePlan
+	}
+	return plan
+}
+
+// applyPlan 将套餐权益复制到应用限制
+func applyPlan(limit *model.OrganizationApplicationLimit, plan *model.Plan) {
+	limit.PlanId = plan.ID
+	limit.PlanName = plan.Code
+	limit.PlanVersion = plan.Version
+	limit.MaxUsers = plan.MaxUsers
+	limit.MaxStorage = plan.MaxStorage
+	limit.MaxRequests = plan.MaxRequests
+	limit.EnforcementMode = plan.EnforcementMode
+	limit.OveragePercent = plan.OveragePercent
+	limit.RateLimitBurst = plan.RateLimitBurst
+	limit.RateLimitPerMin = plan.RateLimitPerMin
+	limit.Features = plan.Features
+}
+
+// recordPlanChange 记录应用套餐变更，供账单按变更时间折算，套餐未变化时不记录，记录失败只写日志
+func recordPlanChange(ctx context.Context, planChangeRepo repository.PlanChangeRepository, appID, fromPlanID, toPlanID int64) {
+	if fromPlanID == toPlanID {
+		return
+	}
+
+	err := planChangeRepo.Create(ctx, &model.PlanChange{
+		ApplicationId: appID,
+		FromPlanId:    fromPlanID,
+		ToPlanId:      toPlanID,
+		ChangedAt:     time.Now().Unix(),
+	})
+	if err != nil {
+		logger.GetLogger().ErrorWithContext(ctx, "记录套餐变更失败: 应用=%d, 错误: %v", appID, err)
+	}
+}
+
+// newLimitUpdatedEvent 创建应用限制变更事件
+func newLimitUpdatedEvent(orgID int64, limit *model.OrganizationApplicationLimit) Event {
+	return Event{
+		Type:           EventLimitUpdated,
+		AggregateType:  AggregateApplication,
+		AggregateId:    limit.OrganizationApplicationId,
+		OrganizationId: orgID,
+		Data:           limit,
+	}
+}
+
+// recordLimitAudit 记录应用限制变更的审计日志，before 为空表示新建限制
+func recordLimitAudit(ctx context.Context, auditService AuditService, orgID int64, action string, before, after *model.OrganizationApplicationLimit) error {
+	entry := AuditEntry{
+		OrganizationId: orgID,
+		Action:         action,
+		ResourceType:   AuditResourceApplicationLimit,
+		ResourceId:     after.OrganizationApplicationId,
+		After:          after,
+	}
+	if before != nil && before.ID != 0 {
+		entry.Before = before
+	}
+	return auditService.Record(ctx, entry)
+}
+
+// countApplicationMembers 获取应用成员数量，用于套餐限额校验，读主库以免副本延迟导致超限
+func countApplicationMembers(ctx context.Context, appMemberRepo repository.OrganizationApplicationMemberRepository, appID int64) (int64, error) {
+	_, total, err := appMemberRepo.GetByApplication(repository.WithPrimary(ctx), appID, 1, 1)
+	return total, err
+}
+
+// validatePlanChange 校验应用能否变更到目标套餐
+func validatePlanChange(ctx context.Context, appMemberRepo repository.OrganizationApplicationMemberRepository, gaugeRepo repository.StorageGaugeRepository, limit *model.OrganizationApplicationLimit, plan *model.Plan) error {
+	// 已停售的套餐只对已订阅的应用保留
+	if plan.Status != "active" && limit.PlanId != plan.ID {
+		return errors.New("套餐已停售")
+	}
+
+	// 降级时成员数不能超过目标套餐的最大用户数
+	memberCount, err := countApplicationMembers(ctx, appMemberRepo, limit.OrganizationApplicationId)
+	if err != nil {
+		return err
+	}
+	if memberCount > int64(plan.MaxUsers) {
+		return errors.New("当前成员数超过目标套餐的最大用户数，无法降级")
+	}
+
+	// 降级时当前存储占用不能超过目标套餐的存储上限
+	gauge, err := gaugeRepo.Get(ctx, limit.OrganizationApplicationId)
+	if err != nil {
+		return err
 	}
+	if gauge.CurrentBytes > plan.MaxStorage {
+		return errors.New("当前存储占用超过目标套餐的存储上限，无法降级")
+	}
+
+	return nil
 }
 
 // generateAppKey 生成应用密钥
@@ -98,24 +234,51 @@ func (s *organizationApplicationService) Create(ctx context.Context, app *model.
 		app.Status = "active"
 	}
 
-	// 创建应用
-	if err := s.appRepo.Create(ctx, app); err != nil {
-		return err
-	}
+	plan := getDefaultPlan(ctx, s.planRepo)
 
-	// 创建默认应用限制
-	limit := &model.OrganizationApplicationLimit{
-		OrganizationApplicationId: app.ID,
-		PlanName:                  "free",
-		MaxUsers:                  5,
-		MaxStorage:                1073741824, // 1GB
-		MaxRequests:               10000,      // 每天10000请求
-		Features:                  "{}",       // 空特性
-		ExpiresAt:                 0,          // 永不过期
-		AutoRenew:                 false,
-	}
+	// 应用、默认限制和事件在同一事务中写入
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 创建应用
+		if err := s.appRepo.Create(ctx, app); err != nil {
+			return err
+		}
 
-	return s.appLimitRepo.Create(ctx, limit)
+		// 按默认套餐创建应用限制
+		limit := &model.OrganizationApplicationLimit{
+			OrganizationApplicationId: app.ID,
+			ExpiresAt:                 0, // 永不过期
+			AutoRenew:                 false,
+		}
+		applyPlan(limit, plan)
+
+		if err := s.appLimitRepo.Create(ctx, limit); err != nil {
+			return err
+		}
+		recordPlanChange(ctx, s.planChangeRepo, app.ID, 0, limit.PlanId)
+
+		if err := s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: app.OrganizationId,
+			Action:         "application.create",
+			ResourceType:   AuditResourceApplication,
+			ResourceId:     app.ID,
+			After:          app,
+		}); err != nil {
+			return err
+		}
+
+		return s.publisher.Publish(ctx, Event{
+			Type:           EventApplicationCreated,
+			AggregateType:  AggregateApplication,
+			AggregateId:    app.ID,
+			OrganizationId: app.OrganizationId,
+			Data: map[string]interface{}{
+				"application_id": app.ID,
+				"name":           app.Name,
+				"app_key":        app.AppKey,
+				"plan_id":        limit.PlanId,
+			},
+		})
+	})
 }
 
 // GetByID 根据ID获取组织应用
@@ -158,12 +321,47 @@ func (s *organizationApplicationService) Update(ctx context.Context, app *model.
 	app.AppSecret = existingApp.AppSecret
 
 	// 更新应用
-	return s.appRepo.Update(ctx, app)
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.appRepo.Update(ctx, app); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: app.OrganizationId,
+			Action:         "application.update",
+			ResourceType:   AuditResourceApplication,
+			ResourceId:     app.ID,
+			Before:         existingApp,
+			After:          app,
+		})
+	})
 }
 
 // Delete 删除组织应用
 func (s *organizationApplicationService) Delete(ctx context.Context, id int64) error {
-	return s.appRepo.Delete(ctx, id)
+	err := s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 锁定应用，避免与并发的成员和限制修改交错
+		app, err := s.appRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.appRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: app.OrganizationId,
+			Action:         "application.delete",
+			ResourceType:   AuditResourceApplication,
+			ResourceId:     id,
+			Before:         app,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	appEntitlementCache.invalidate(id)
+	appRateLimitCache.invalidate(id)
+	return nil
 }
 
 // RegenerateAppSecret 重新生成应用密钥
@@ -181,8 +379,34 @@ func (s *organizationApplicationService) RegenerateAppSecret(ctx context.Context
 	}
 	app.AppSecret = appSecret
 
-	// 更新应用
-	if err := s.appRepo.Update(ctx, app); err != nil {
+	// 更新应用，事件中不包含新密钥
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.appRepo.Update(ctx, app); err != nil {
+			return err
+		}
+
+		// 密钥不记录在审计日志中，只记录轮换动作
+		if err := s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: app.OrganizationId,
+			Action:         "application.rotate_secret",
+			ResourceType:   AuditResourceApplication,
+			ResourceId:     app.ID,
+		}); err != nil {
+			return err
+		}
+
+		return s.publisher.Publish(ctx, Event{
+			Type:           EventApplicationSecretRotated,
+			AggregateType:  AggregateApplication,
+			AggregateId:    app.ID,
+			OrganizationId: app.OrganizationId,
+			Data: map[string]interface{}{
+				"application_id": app.ID,
+				"app_key":        app.AppKey,
+			},
+		})
+	})
+	if err != nil {
 		return "", err
 	}
 
@@ -191,60 +415,92 @@ func (s *organizationApplicationService) RegenerateAppSecret(ctx context.Context
 
 // AddMember 添加应用成员
 func (s *organizationApplicationService) AddMember(ctx context.Context, appID, userID int64, role string, permissions string) error {
-	// 检查应用是否存在
-	app, err := s.appRepo.GetByID(ctx, appID)
-	if err != nil {
-		return err
-	}
-
 	// 检查用户是否存在
-	_, err = s.userRepo.GetByID(ctx, userID)
+	_, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return err
 	}
 
-	// 检查用户是否已经是应用成员
-	existingMember, err := s.appMemberRepo.GetByApplicationAndUser(ctx, appID, userID)
-	if err == nil && existingMember != nil {
-		return errors.New("用户已经是应用成员")
-	}
-
 	// 验证角色
 	if role != "admin" && role != "user" && role != "guest" {
 		return errors.New("无效的角色")
 	}
 
-	// 检查应用成员数量限制
-	limit, err := s.appLimitRepo.GetByApplicationID(ctx, appID)
-	if err == nil && limit != nil {
-		members, total, err := s.appMemberRepo.GetByApplication(ctx, appID, 1, 0)
-		if err == nil && total >= int64(limit.MaxUsers) {
-			return errors.New("已达到应用成员数量限制")
+	// 成员数检查和写入在同一事务中，并锁定应用，避免并发添加时超出成员数量限制
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 检查应用是否存在
+		app, err := s.appRepo.GetByIDForUpdate(ctx, appID)
+		if err != nil {
+			return err
 		}
-	}
 
-	// 添加应用成员
-	member := &model.OrganizationApplicationMember{
-		ApplicationId: appID,
-		MemberId:      userID,
-		Role:          role,
-		Status:        "active",
-		Permissions:   permissions,
-	}
+		// 检查用户是否已经是应用成员
+		existingMember, err := s.appMemberRepo.GetByApplicationAndUser(ctx, appID, userID)
+		if err == nil && existingMember != nil {
+			return errors.New("用户已经是应用成员")
+		}
+
+		// 检查应用成员数量限制
+		limit, err := s.appLimitRepo.GetByApplicationID(ctx, appID)
+		if err == nil && limit != nil {
+			total, err := countApplicationMembers(ctx, s.appMemberRepo, appID)
+			if err != nil {
+				return err
+			}
+			if total >= int64(limit.MaxUsers) {
+				return errors.New("已达到应用成员数量限制")
+			}
+		}
+
+		// 添加应用成员
+		member := &model.OrganizationApplicationMember{
+			ApplicationId: appID,
+			MemberId:      userID,
+			Role:          role,
+			Status:        "active",
+			Permissions:   permissions,
+		}
 
-	return s.appMemberRepo.Create(ctx, member)
+		if err := s.appMemberRepo.Create(ctx, member); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: app.OrganizationId,
+			Action:         "application_member.add",
+			ResourceType:   AuditResourceApplicationMember,
+			ResourceId:     member.ID,
+			After:          member,
+		})
+	})
 }
 
 // RemoveMember 移除应用成员
 func (s *organizationApplicationService) RemoveMember(ctx context.Context, appID, userID int64) error {
 	// 检查应用是否存在
-	_, err := s.appRepo.GetByID(ctx, appID)
+	app, err := s.appRepo.GetByID(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	// 检查成员是否存在
+	member, err := s.appMemberRepo.GetByApplicationAndUser(ctx, appID, userID)
 	if err != nil {
 		return err
 	}
 
 	// 移除应用成员
-	return s.appMemberRepo.DeleteByApplicationAndUser(ctx, appID, userID)
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.appMemberRepo.DeleteByApplicationAndUser(ctx, appID, userID); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: app.OrganizationId,
+			Action:         "application_member.remove",
+			ResourceType:   AuditResourceApplicationMember,
+			ResourceId:     member.ID,
+			Before:         member,
+		})
+	})
 }
 
 // GetMembers 获取应用成员列表
@@ -261,7 +517,7 @@ func (s *organizationApplicationService) GetMembers(ctx context.Context, appID i
 // UpdateMember 更新应用成员
 func (s *organizationApplicationService) UpdateMember(ctx context.Context, appID, userID int64, role string, permissions string) error {
 	// 检查应用是否存在
-	_, err := s.appRepo.GetByID(ctx, appID)
+	app, err := s.appRepo.GetByID(ctx, appID)
 	if err != nil {
 		return err
 	}
@@ -278,29 +534,106 @@ func (s *organizationApplicationService) UpdateMember(ctx context.Context, appID
 	}
 
 	// 更新成员
+	before := *member
 	member.Role = role
 	member.Permissions = permissions
-	return s.appMemberRepo.Update(ctx, member)
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.appMemberRepo.Update(ctx, member); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: app.OrganizationId,
+			Action:         "application_member.update",
+			ResourceType:   AuditResourceApplicationMember,
+			ResourceId:     member.ID,
+			Before:         &before,
+			After:          member,
+		})
+	})
 }
 
 // SetLimit 设置应用限制
 func (s *organizationApplicationService) SetLimit(ctx context.Context, limit *model.OrganizationApplicationLimit) error {
 	// 检查应用是否存在
-	_, err := s.appRepo.GetByID(ctx, limit.OrganizationApplicationId)
+	app, err := s.appRepo.GetByID(ctx, limit.OrganizationApplicationId)
 	if err != nil {
 		return err
 	}
 
-	// 检查是否已存在限制
-	existingLimit, err := s.appLimitRepo.GetByApplicationID(ctx, limit.OrganizationApplicationId)
-	if err == nil && existingLimit != nil {
-		// 更新现有限制
-		limit.ID = existingLimit.ID
-		return s.appLimitRepo.Update(ctx, limit)
+	// 验证限制数值
+	if limit.MaxUsers < 0 || limit.MaxStorage < 0 || limit.MaxRequests < 0 {
+		return errors.New("应用限制不能为负数")
+	}
+
+	// 验证配额执行模式
+	if limit.EnforcementMode == "" {
+		limit.EnforcementMode = model.EnforcementModeHard
+	}
+	if err := validateEnforcementMode(limit.EnforcementMode, limit.OveragePercent); err != nil {
+		return err
 	}
 
-	// 创建新限制
-	return s.appLimitRepo.Create(ctx, limit)
+	// 验证限流设置
+	if err := validateRateLimit(limit.RateLimitBurst, limit.RateLimitPerMin); err != nil {
+		return err
+	}
+
+	// 验证功能权益
+	if limit.Features == "" {
+		limit.Features = "{}"
+	}
+	if _, err := parseEntitlements(limit.Features); err != nil {
+		return err
+	}
+
+	// 最大用户数不能低于当前成员数
+	memberCount, err := countApplicationMembers(ctx, s.appMemberRepo, limit.OrganizationApplicationId)
+	if err != nil {
+		return err
+	}
+	if int64(limit.MaxUsers) < memberCount {
+		return errors.New("最大用户数不能低于当前成员数")
+	}
+
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 检查是否已存在限制
+		existingLimit, err := s.appLimitRepo.GetByApplicationID(ctx, limit.OrganizationApplicationId)
+		if err == nil && existingLimit != nil {
+			// 套餐关联只能通过变更套餐修改
+			limit.PlanId = existingLimit.PlanId
+			limit.PlanVersion = existingLimit.PlanVersion
+			if limit.PlanName == "" {
+				limit.PlanName = existingLimit.PlanName
+			}
+
+			// 更新现有限制
+			limit.ID = existingLimit.ID
+			err = s.appLimitRepo.Update(ctx, limit)
+		} else {
+			// 创建新限制
+			limit.PlanId = 0
+			limit.PlanVersion = 0
+			if limit.PlanName == "" {
+				limit.PlanName = "custom"
+			}
+			err = s.appLimitRepo.Create(ctx, limit)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := recordLimitAudit(ctx, s.auditService, app.OrganizationId, "application_limit.update", existingLimit, limit); err != nil {
+			return err
+		}
+		return s.publisher.Publish(ctx, newLimitUpdatedEvent(app.OrganizationId, limit))
+	})
+	if err != nil {
+		return err
+	}
+
+	appEntitlementCache.invalidate(limit.OrganizationApplicationId)
+	appRateLimitCache.invalidate(limit.OrganizationApplicationId)
+	return nil
 }
 
 // GetLimit 获取应用限制
@@ -313,3 +646,61 @@ func (s *organizationApplicationService) GetLimit(ctx context.Context, appID int
 
 	return s.appLimitRepo.GetByApplicationID(ctx, appID)
 }
+
+// ChangePlan 变更应用套餐（升级或降级），将套餐权益复制到应用限制
+func (s *organizationApplicationService) ChangePlan(ctx context.Context, appID, planID int64) (*model.OrganizationApplicationLimit, error) {
+	// 检查套餐是否存在
+	plan, err := s.planRepo.GetByID(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 成员数检查和套餐变更在同一事务中，并锁定应用，避免与并发添加成员交错后超出目标套餐的最大用户数
+	var limit *model.OrganizationApplicationLimit
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 检查应用是否存在
+		app, err := s.appRepo.GetByIDForUpdate(ctx, appID)
+		if err != nil {
+			return err
+		}
+
+		// 获取当前应用限制，不存在时创建
+		limit, err = s.appLimitRepo.GetByApplicationID(ctx, appID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			limit = &model.OrganizationApplicationLimit{OrganizationApplicationId: appID}
+		} else if err != nil {
+			return err
+		}
+
+		// 校验套餐变更
+		if err := validatePlanChange(ctx, s.appMemberRepo, s.gaugeRepo, limit, plan); err != nil {
+			return err
+		}
+
+		fromPlanID := limit.PlanId
+		before := *limit
+		applyPlan(limit, plan)
+
+		if limit.ID == 0 {
+			err = s.appLimitRepo.Create(ctx, limit)
+		} else {
+			err = s.appLimitRepo.Update(ctx, limit)
+		}
+		if err != nil {
+			return err
+		}
+		recordPlanChange(ctx, s.planChangeRepo, appID, fromPlanID, limit.PlanId)
+
+		if err := recordLimitAudit(ctx, s.auditService, app.OrganizationId, "application_limit.change_plan", &before, limit); err != nil {
+			return err
+		}
+		return s.publisher.Publish(ctx, newLimitUpdatedEvent(app.OrganizationId, limit))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	appEntitlementCache.invalidate(appID)
+	appRateLimitCache.invalidate(appID)
+	return limit, nil
+}
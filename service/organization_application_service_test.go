@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"saas-account/model"
+	"testing"
+)
+
+func TestValidatePlanChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    model.Plan
+		storage int64
+		wantErr bool
+	}{
+		{name: "成员数和存储都在上限内", plan: model.Plan{Status: "active", MaxUsers: 1, MaxStorage: 100}, storage: 100},
+		{name: "成员数超过上限", plan: model.Plan{Status: "active", MaxUsers: 0, MaxStorage: 100}, wantErr: true},
+		{name: "存储占用超过上限", plan: model.Plan{Status: "active", MaxUsers: 1, MaxStorage: 100}, storage: 101, wantErr: true},
+		{name: "已停售的套餐", plan: model.Plan{Base: model.Base{ID: 2}, Status: "deprecated", MaxUsers: 1, MaxStorage: 100}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := &model.OrganizationApplicationLimit{OrganizationApplicationId: 1, PlanId: 1}
+			gaugeRepo := &fakeGaugeRepo{gauge: model.StorageGauge{ApplicationId: 1, CurrentBytes: tt.storage}}
+
+			err := validatePlanChange(context.Background(), &fakeMemberCountRepo{}, gaugeRepo, limit, &tt.plan)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("错误 = %v, 期望出错 %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"saas-account/repository"
 )
 
+// ErrTimeZoneLocked 组织已有使用量汇总，不能修改时区
+// 汇总按组织时区的小时和天分桶，修改时区后已有的汇总区间与新时区不对齐，且超过保留期的原始记录无法重新汇总
+var ErrTimeZoneLocked = errors.New("组织已有使用量统计，不能修改时区")
+
 // OrganizationService 组织服务接口
 type OrganizationService interface {
 	Create(ctx context.Context, org *model.Organization, creatorID int64) error
@@ -26,6 +30,11 @@ type organizationService struct {
 	orgRepo       repository.OrganizationRepository
 	orgMemberRepo repository.OrganizationMemberRepository
 	userRepo      repository.UserRepository
+	appRepo       repository.OrganizationApplicationRepository
+	publisher     EventPublisher
+	txManager     repository.TransactionManager
+	auditService  AuditService
+	rollupRepo    repository.UsageRollupRepository
 }
 
 // NewOrganizationService 创建组织服务
@@ -33,11 +42,21 @@ func NewOrganizationService(
 	orgRepo repository.OrganizationRepository,
 	orgMemberRepo repository.OrganizationMemberRepository,
 	userRepo repository.UserRepository,
+	appRepo repository.OrganizationApplicationRepository,
+	publisher EventPublisher,
+	txManager repository.TransactionManager,
+	auditService AuditService,
+	rollupRepo repository.UsageRollupRepository,
 ) OrganizationService {
 	return &organizationService{
 		orgRepo:       orgRepo,
 		orgMemberRepo: orgMemberRepo,
 		userRepo:      userRepo,
+		appRepo:       appRepo,
+		publisher:     publisher,
+		txManager:     txManager,
+		auditService:  auditService,
+		rollupRepo:    rollupRepo,
 	}
 }
 
@@ -57,20 +76,54 @@ func (s *organizationService) Create(ctx context.Context, org *model.Organizatio
 		org.Status = "active"
 	}
 
-	// 创建组织
-	if err := s.orgRepo.Create(ctx, org); err != nil {
-		return err
+	// 设置默认时区
+	if org.TimeZone == "" {
+		org.TimeZone = "UTC"
 	}
-
-	// 添加创建者为组织成员（拥有者角色）
-	member := &model.OrganizationMember{
-		OrganizationId: org.ID,
-		UserId:         creator.ID,
-		Role:           "owner",
-		Status:         "active",
+	if err := validateTimeZone(org.TimeZone); err != nil {
+		return err
 	}
 
-	return s.orgMemberRepo.Create(ctx, member)
+	// 组织、拥有者成员和事件在同一事务中写入
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 创建组织
+		if err := s.orgRepo.Create(ctx, org); err != nil {
+			return err
+		}
+
+		// 添加创建者为组织成员（拥有者角色）
+		member := &model.OrganizationMember{
+			OrganizationId: org.ID,
+			UserId:         creator.ID,
+			Role:           "owner",
+			Status:         "active",
+		}
+		if err := s.orgMemberRepo.Create(ctx, member); err != nil {
+			return err
+		}
+
+		if err := s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: org.ID,
+			Action:         "organization.create",
+			ResourceType:   AuditResourceOrganization,
+			ResourceId:     org.ID,
+			After:          org,
+		}); err != nil {
+			return err
+		}
+
+		return s.publisher.Publish(ctx, Event{
+			Type:           EventOrganizationCreated,
+			AggregateType:  AggregateOrganization,
+			AggregateId:    org.ID,
+			OrganizationId: org.ID,
+			Data: map[string]interface{}{
+				"organization_id": org.ID,
+				"name":            org.Name,
+				"owner_id":        org.OwnerId,
+			},
+		})
+	})
 }
 
 // GetByID 根据ID获取组织
@@ -99,13 +152,68 @@ func (s *organizationService) Update(ctx context.Context, org *model.Organizatio
 	// 保留拥有者ID
 	org.OwnerId = existingOrg.OwnerId
 
+	// 未指定时区时保留原时区
+	if org.TimeZone == "" {
+		org.TimeZone = existingOrg.TimeZone
+	}
+	if err := validateTimeZone(org.TimeZone); err != nil {
+		return err
+	}
+	if org.TimeZone != existingOrg.TimeZone {
+		hasUsage, err := s.rollupRepo.ExistsByOrganization(ctx, org.ID)
+		if err != nil {
+			return err
+		}
+		if hasUsage {
+			return ErrTimeZoneLocked
+		}
+	}
+
 	// 更新组织
-	return s.orgRepo.Update(ctx, org)
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.orgRepo.Update(ctx, org); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: org.ID,
+			Action:         "organization.update",
+			ResourceType:   AuditResourceOrganization,
+			ResourceId:     org.ID,
+			Before:         existingOrg,
+			After:          org,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	orgLocationCache.invalidate(org.ID)
+	return nil
 }
 
-// Delete 删除组织
+// Delete 删除组织，组织下的应用在同一事务中一并删除，恢复组织后可在回收站中单独恢复应用
 func (s *organizationService) Delete(ctx context.Context, id int64) error {
-	return s.orgRepo.Delete(ctx, id)
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 检查组织是否存在
+		org, err := s.orgRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.appRepo.DeleteByOrganization(ctx, id); err != nil {
+			return err
+		}
+		if err := s.orgRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: id,
+			Action:         "organization.delete",
+			ResourceType:   AuditResourceOrganization,
+			ResourceId:     id,
+			Before:         org,
+		})
+	})
 }
 
 // AddMember 添加组织成员
@@ -141,7 +249,33 @@ func (s *organizationService) AddMember(ctx context.Context, orgID, userID int64
 		Status:         "active",
 	}
 
-	return s.orgMemberRepo.Create(ctx, member)
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.orgMemberRepo.Create(ctx, member); err != nil {
+			return err
+		}
+
+		if err := s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: orgID,
+			Action:         "organization_member.add",
+			ResourceType:   AuditResourceOrganizationMember,
+			ResourceId:     member.ID,
+			After:          member,
+		}); err != nil {
+			return err
+		}
+
+		return s.publisher.Publish(ctx, Event{
+			Type:           EventOrganizationMemberAdded,
+			AggregateType:  AggregateOrganization,
+			AggregateId:    orgID,
+			OrganizationId: orgID,
+			Data: map[string]interface{}{
+				"organization_id": orgID,
+				"user_id":         userID,
+				"role":            role,
+			},
+		})
+	})
 }
 
 // RemoveMember 移除组织成员
@@ -157,8 +291,25 @@ func (s *organizationService) RemoveMember(ctx context.Context, orgID, userID in
 		return errors.New("不能移除组织拥有者")
 	}
 
+	// 检查成员是否存在
+	member, err := s.orgMemberRepo.GetByOrganizationAndUser(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+
 	// 移除组织成员
-	return s.orgMemberRepo.DeleteByOrganizationAndUser(ctx, orgID, userID)
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.orgMemberRepo.DeleteByOrganizationAndUser(ctx, orgID, userID); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: orgID,
+			Action:         "organization_member.remove",
+			ResourceType:   AuditResourceOrganizationMember,
+			ResourceId:     member.ID,
+			Before:         member,
+		})
+	})
 }
 
 // GetMembers 获取组织成员列表
@@ -197,6 +348,19 @@ func (s *organizationService) UpdateMember(ctx context.Context, orgID, userID in
 	}
 
 	// 更新成员角色
+	before := *member
 	member.Role = role
-	return s.orgMemberRepo.Update(ctx, member)
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.orgMemberRepo.Update(ctx, member); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			OrganizationId: orgID,
+			Action:         "organization_member.update",
+			ResourceType:   AuditResourceOrganizationMember,
+			ResourceId:     member.ID,
+			Before:         &before,
+			After:          member,
+		})
+	})
 }
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"saas-account/repository"
+	"testing"
+)
+
+// fakeOrgUpdateRepo 支持更新的内存组织仓库
+type fakeOrgUpdateRepo struct {
+	fakeOrgRepo
+}
+
+func (r *fakeOrgUpdateRepo) Update(ctx context.Context, org *model.Organization) error {
+	updated := *org
+	r.orgs[org.ID] = &updated
+	return nil
+}
+
+// fakeOrgRollupRepo 记录组织是否已有使用量汇总
+type fakeOrgRollupRepo struct {
+	repository.UsageRollupRepository
+	orgs map[int64]bool
+}
+
+func (r *fakeOrgRollupRepo) ExistsByOrganization(ctx context.Context, orgID int64) (bool, error) {
+	return r.orgs[orgID], nil
+}
+
+// fakeAuditService 不记录审计日志的审计服务
+type fakeAuditService struct {
+	AuditService
+}
+
+func (s *fakeAuditService) Record(ctx context.Context, entry AuditEntry) error {
+	return nil
+}
+
+func TestOrganizationUpdateTimeZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeZone string
+		hasUsage bool
+		wantErr  error
+		wantZone string
+	}{
+		{name: "没有使用量时可以修改", timeZone: "Asia/Kolkata", wantZone: "Asia/Kolkata"},
+		{name: "已有使用量时不能修改", timeZone: "Asia/Kolkata", hasUsage: true, wantErr: ErrTimeZoneLocked, wantZone: "Asia/Shanghai"},
+		{name: "未指定时保留原时区", hasUsage: true, wantZone: "Asia/Shanghai"},
+		{name: "时区未变化", timeZone: "Asia/Shanghai", hasUsage: true, wantZone: "Asia/Shanghai"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := nextFixtureID()
+			orgRepo := &fakeOrgUpdateRepo{fakeOrgRepo{orgs: map[int64]*model.Organization{
+				id: {Base: model.Base{ID: id}, Name: "组织", TimeZone: "Asia/Shanghai"},
+			}}}
+			svc := NewOrganizationService(orgRepo, nil, nil, nil, nil,
+				newFakeTxManager(), &fakeAuditService{},
+				&fakeOrgRollupRepo{orgs: map[int64]bool{id: tt.hasUsage}})
+
+			err := svc.Update(context.Background(), &model.Organization{Base: model.Base{ID: id}, Name: "组织", TimeZone: tt.timeZone})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("错误 = %v, 期望 %v", err, tt.wantErr)
+			}
+			if zone := orgRepo.orgs[id].TimeZone; zone != tt.wantZone {
+				t.Errorf("时区 = %s, 期望 %s", zone, tt.wantZone)
+			}
+		})
+	}
+}
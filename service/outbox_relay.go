@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"saas-account/logger"
+	"saas-account/model"
+	"saas-account/outbox"
+	"saas-account/repository"
+	"saas-account/utils"
+	"strings"
+	"time"
+)
+
+const (
+	// outboxMaxBackoff 发件箱重试间隔上限
+	outboxMaxBackoff = 10 * time.Minute
+	// outboxLease 领取事件后的租约时长，实例异常退出时租约结束后事件会被重新发布
+	outboxLease = time.Minute
+	// outboxBatchSize 单次中继最多发布的事件数
+	outboxBatchSize = 500
+)
+
+// OutboxRelay 发件箱中继接口，将发件箱中的事件发布到投递目标
+type OutboxRelay interface {
+	Relay(ctx context.Context) (int, error)
+}
+
+// outboxRelay 发件箱中继实现
+type outboxRelay struct {
+	outboxRepo  repository.OutboxRepository
+	sinkNames   []string
+	sinks       []outbox.Sink
+	retryBase   time.Duration
+	maxAttempts int
+}
+
+// NewOutboxRelay 创建发件箱中继，事件依次发布到 sinkNames 对应的投递目标，全部成功后才标记为已发布
+// 发布失败时按 retryBase 指数退避重试，保证至少一次投递，同一聚合的后续事件会等待前一条发布成功
+// 尝试 maxAttempts 次仍失败的事件进入死信，不再阻塞同一聚合的后续事件
+func NewOutboxRelay(outboxRepo repository.OutboxRepository, sinkNames []string, retryBase time.Duration, maxAttempts int) OutboxRelay {
+	relay := &outboxRelay{
+		outboxRepo:  outboxRepo,
+		retryBase:   retryBase,
+		maxAttempts: maxAttempts,
+	}
+	for _, name := range sinkNames {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		sink, ok := outbox.GetSink(name)
+		if !ok {
+			logger.GetLogger().Error("未知的事件投递目标: %s", name)
+			continue
+		}
+		relay.sinkNames = append(relay.sinkNames, name)
+		relay.sinks = append(relay.sinks, sink)
+	}
+	return relay
+}
+
+// newOutboxMessage 将发件箱事件转换为投递消息
+func newOutboxMessage(event *model.OutboxEvent) *outbox.Message {
+	return &outbox.Message{
+		ID:             fmt.Sprintf("evt_%d", event.ID),
+		Type:           event.EventType,
+		AggregateType:  event.AggregateType,
+		AggregateId:    event.AggregateId,
+		OrganizationId: event.OrganizationId,
+		OccurredAt:     event.CreatedAt,
+		Data:           json.RawMessage(event.Payload),
+	}
+}
+
+// Relay 发布已到发布时间的事件，返回发布成功的数量
+// 每轮只取各聚合序号最小的未发布事件，发布成功或进入死信后再取下一轮，直到没有可发布的事件
+func (r *outboxRelay) Relay(ctx context.Context) (int, error) {
+	published := 0
+	for published < outboxBatchSize {
+		now := time.Now()
+		events, err := r.outboxRepo.GetPendingHeads(ctx, now.Unix(), outboxBatchSize-published)
+		if err != nil {
+			return published, err
+		}
+
+		progressed := false
+		for i := range events {
+			event := &events[i]
+
+			claimed, err := r.outboxRepo.Claim(ctx, event.ID, event.NextAttemptAt, now.Add(outboxLease).Unix())
+			if err != nil {
+				return published, err
+			}
+			if !claimed {
+				continue
+			}
+
+			r.publish(ctx, event)
+			if err := r.outboxRepo.Update(ctx, event); err != nil {
+				return published, err
+			}
+			switch event.Status {
+			case model.OutboxEventPublished:
+				published++
+				progressed = true
+			case model.OutboxEventDead:
+				progressed = true
+			}
+		}
+
+		if !progressed {
+			break
+		}
+	}
+
+	return published, nil
+}
+
+// publish 将事件发布到所有投递目标并记录结果，任一目标失败时安排重试，已成功的目标会再次收到该事件
+// 达到最大尝试次数时进入死信
+func (r *outboxRelay) publish(ctx context.Context, event *model.OutboxEvent) {
+	now := time.Now()
+	event.Attempts++
+
+	msg := newOutboxMessage(event)
+	for i, sink := range r.sinks {
+		name := r.sinkNames[i]
+		if err := sink.Publish(ctx, msg); err != nil {
+			event.LastError = utils.TruncateString(fmt.Sprintf("%s: %v", name, err), 500)
+			if event.Attempts >= r.maxAttempts {
+				event.Status = model.OutboxEventDead
+				logger.GetLogger().ErrorWithContext(ctx, "事件发布失败次数达到上限，进入死信: 事件=%d, 类型=%s, 聚合=%s:%d, 错误: %v",
+					event.ID, event.EventType, event.AggregateType, event.AggregateId, err)
+				return
+			}
+			event.NextAttemptAt = now.Add(retryBackoff(r.retryBase, event.Attempts, outboxMaxBackoff)).Unix()
+			logger.GetLogger().ErrorWithContext(ctx, "发布事件失败: 事件=%d, 投递目标=%s, 错误: %v", event.ID, name, err)
+			return
+		}
+	}
+
+	event.Status = model.OutboxEventPublished
+	event.PublishedAt = now.Unix()
+	event.LastError = ""
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"saas-account/model"
+	"saas-account/outbox"
+	"saas-account/repository"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeOutboxRepo 内存中的发件箱仓库，按聚合内序号选出每个聚合的待发布事件
+type fakeOutboxRepo struct {
+	repository.OutboxRepository
+	events    []*model.OutboxEvent
+	sequences map[string]int64
+}
+
+func newFakeOutboxRepo() *fakeOutboxRepo {
+	return &fakeOutboxRepo{sequences: map[string]int64{}}
+}
+
+func (r *fakeOutboxRepo) Create(ctx context.Context, event *model.OutboxEvent) error {
+	key := fmt.Sprintf("%s:%d", event.AggregateType, event.AggregateId)
+	r.sequences[key]++
+	event.Sequence = r.sequences[key]
+	if event.ID == 0 {
+		event.ID = nextFixtureID()
+	}
+	saved := *event
+	r.events = append(r.events, &saved)
+	return nil
+}
+
+func (r *fakeOutboxRepo) GetPendingHeads(ctx context.Context, now int64, limit int) ([]model.OutboxEvent, error) {
+	heads := map[string]*model.OutboxEvent{}
+	for _, event := range r.events {
+		if event.Status != model.OutboxEventPending {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d", event.AggregateType, event.AggregateId)
+		if head, ok := heads[key]; !ok || event.Sequence < head.Sequence {
+			heads[key] = event
+		}
+	}
+
+	var events []model.OutboxEvent
+	for _, head := range heads {
+		if head.NextAttemptAt <= now {
+			events = append(events, *head)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r *fakeOutboxRepo) Claim(ctx context.Context, id, nextAttemptAt, leaseUntil int64) (bool, error) {
+	for _, event := range r.events {
+		if event.ID == id && event.Status == model.OutboxEventPending && event.NextAttemptAt == nextAttemptAt {
+			event.NextAttemptAt = leaseUntil
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeOutboxRepo) Update(ctx context.Context, event *model.OutboxEvent) error {
+	for i := range r.events {
+		if r.events[i].ID == event.ID {
+			saved := *event
+			r.events[i] = &saved
+		}
+	}
+	return nil
+}
+
+// get 根据ID获取事件
+func (r *fakeOutboxRepo) get(id int64) *model.OutboxEvent {
+	for _, event := range r.events {
+		if event.ID == id {
+			return event
+		}
+	}
+	return nil
+}
+
+// recordingSink 记录发布的事件，fail 中的事件类型发布失败
+type recordingSink struct {
+	published []string
+	fail      map[string]bool
+}
+
+func (s *recordingSink) Publish(ctx context.Context, msg *outbox.Message) error {
+	if s.fail[msg.Type] {
+		return errors.New("投递失败")
+	}
+	s.published = append(s.published, msg.Type)
+	return nil
+}
+
+// newRelayFixture 创建发布到记录投递目标的中继
+func newRelayFixture(t *testing.T, maxAttempts int) (OutboxRelay, *fakeOutboxRepo, *recordingSink) {
+	t.Helper()
+	name := fmt.Sprintf("test-%d", nextFixtureID())
+	sink := &recordingSink{fail: map[string]bool{}}
+	outbox.RegisterSink(name, sink)
+
+	repo := newFakeOutboxRepo()
+	return NewOutboxRelay(repo, []string{name}, time.Second, maxAttempts), repo, sink
+}
+
+// addEvent 写入待发布事件
+func addEvent(t *testing.T, repo *fakeOutboxRepo, id, aggregateID int64, eventType string) {
+	t.Helper()
+	err := repo.Create(context.Background(), &model.OutboxEvent{
+		Base:          model.Base{ID: id},
+		AggregateType: AggregateApplication,
+		AggregateId:   aggregateID,
+		EventType:     eventType,
+		Payload:       "{}",
+		Status:        model.OutboxEventPending,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutboxRelayOrder(t *testing.T) {
+	relay, repo, sink := newRelayFixture(t, 3)
+
+	// 事件ID不代表提交顺序，同一聚合按序号发布
+	addEvent(t, repo, 30, 1, "a.first")
+	addEvent(t, repo, 10, 1, "a.second")
+	addEvent(t, repo, 20, 2, "b.first")
+
+	published, err := relay.Relay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 3 {
+		t.Errorf("发布数 = %d, 期望 3", published)
+	}
+	want := []string{"b.first", "a.first", "a.second"}
+	if fmt.Sprint(sink.published) != fmt.Sprint(want) {
+		t.Errorf("发布顺序 = %v, 期望 %v", sink.published, want)
+	}
+}
+
+func TestOutboxRelayRetry(t *testing.T) {
+	relay, repo, sink := newRelayFixture(t, 3)
+	addEvent(t, repo, 1, 1, "a.first")
+	addEvent(t, repo, 2, 1, "a.second")
+	addEvent(t, repo, 3, 2, "b.first")
+
+	// 发布失败的事件安排重试，同一聚合的后续事件等待，其他聚合不受影响
+	sink.fail["a.first"] = true
+	published, err := relay.Relay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 1 || fmt.Sprint(sink.published) != "[b.first]" {
+		t.Errorf("发布数 = %d, 已发布 %v", published, sink.published)
+	}
+	failed := repo.get(1)
+	if failed.Status != model.OutboxEventPending || failed.Attempts != 1 || failed.LastError == "" || failed.NextAttemptAt <= time.Now().Unix() {
+		t.Errorf("失败的事件 = %+v, 期望等待重试", failed)
+	}
+
+	// 重试时间到后按顺序发布
+	sink.fail["a.first"] = false
+	failed.NextAttemptAt = 0
+	if _, err := relay.Relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sink.published) != "[b.first a.first a.second]" {
+		t.Errorf("已发布 %v", sink.published)
+	}
+}
+
+func TestOutboxRelayDeadLetter(t *testing.T) {
+	relay, repo, sink := newRelayFixture(t, 2)
+	addEvent(t, repo, 1, 1, "a.first")
+	addEvent(t, repo, 2, 1, "a.second")
+	sink.fail["a.first"] = true
+
+	// 第一次失败后等待重试
+	if _, err := relay.Relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if event := repo.get(1); event.Status != model.OutboxEventPending {
+		t.Fatalf("第一次失败后状态 = %s, 期望 pending", event.Status)
+	}
+
+	// 达到最大尝试次数后进入死信，不再阻塞同一聚合的后续事件
+	repo.get(1).NextAttemptAt = 0
+	published, err := relay.Relay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event := repo.get(1); event.Status != model.OutboxEventDead || event.Attempts != 2 {
+		t.Errorf("死信事件 = %+v", event)
+	}
+	if published != 1 || fmt.Sprint(sink.published) != "[a.second]" {
+		t.Errorf("发布数 = %d, 已发布 %v", published, sink.published)
+	}
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"saas-account/repository"
+
+	"gorm.io/gorm"
+)
+
+// PlanService 套餐服务接口
+type PlanService interface {
+	Create(ctx context.Context, plan *model.Plan) error
+	GetByID(ctx context.Context, id int64) (*model.Plan, error)
+	GetVersions(ctx context.Context, code string) ([]model.Plan, error)
+	List(ctx context.Context, status string, page, pageSize int) ([]model.Plan, int64, error)
+	Deprecate(ctx context.Context, id int64) error
+	SetDefault(ctx context.Context, id int64) error
+}
+
+// planService 套餐服务实现
+type planService struct {
+	planRepo  repository.PlanRepository
+	txManager repository.TransactionManager
+}
+
+// NewPlanService 创建套餐服务
+func NewPlanService(planRepo repository.PlanRepository, txManager repository.TransactionManager) PlanService {
+	return &planService{
+		planRepo:  planRepo,
+		txManager: txManager,
+	}
+}
+
+// validatePlan 校验套餐配额与价格
+func validatePlan(plan *model.Plan) error {
+	if plan.Code == "" || plan.Name == "" {
+		return errors.New("套餐编码和名称不能为空")
+	}
+	if plan.MaxUsers < 0 || plan.MaxStorage < 0 || plan.MaxRequests < 0 {
+		return errors.New("套餐配额不能为负数")
+	}
+	if plan.Price < 0 {
+		return errors.New("套餐价格不能为负数")
+	}
+	if plan.TrialDays < 0 {
+		return errors.New("试用天数不能为负数")
+	}
+	if plan.BillingCycle != "monthly" && plan.BillingCycle != "yearly" {
+		return errors.New("无效的计费周期")
+	}
+	if err := validateEnforcementMode(plan.EnforcementMode, plan.OveragePercent); err != nil {
+		return err
+	}
+	if err := validateRateLimit(plan.RateLimitBurst, plan.RateLimitPerMin); err != nil {
+		return err
+	}
+	if _, err := parseEntitlements(plan.Features); err != nil {
+		return err
+	}
+	if _, err := parsePriceComponents(plan.PriceComponents); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Create 创建套餐，编码已存在时创建新版本，旧版本停售但已订阅的应用保留原权益
+func (s *planService) Create(ctx context.Context, plan *model.Plan) error {
+	// 设置默认值
+	if plan.Features == "" {
+		plan.Features = "{}"
+	}
+	if plan.PriceComponents == "" {
+		plan.PriceComponents = "[]"
+	}
+	if plan.Currency == "" {
+		plan.Currency = "CNY"
+	}
+	if plan.BillingCycle == "" {
+		plan.BillingCycle = "monthly"
+	}
+	if plan.EnforcementMode == "" {
+		plan.EnforcementMode = model.EnforcementModeHard
+	}
+
+	if err := validatePlan(plan); err != nil {
+		return err
+	}
+
+	// 停售旧版本、取消其他默认套餐和创建新版本在同一事务中完成，创建失败时旧版本保持在售
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 同一编码同时创建新版本时串行执行，避免版本号冲突
+		if err := s.planRepo.LockCode(ctx, plan.Code); err != nil {
+			return err
+		}
+
+		// 计算版本号
+		plan.Version = 1
+		latest, err := s.planRepo.GetLatestByCode(ctx, plan.Code)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if latest != nil {
+			plan.Version = latest.Version + 1
+
+			// 停售旧版本
+			versions, err := s.planRepo.GetVersions(ctx, plan.Code)
+			if err != nil {
+				return err
+			}
+			for i := range versions {
+				if versions[i].Status != "active" {
+					continue
+				}
+				// 新版本继承默认标记
+				if versions[i].IsDefault {
+					plan.IsDefault = true
+				}
+				versions[i].Status = "deprecated"
+				versions[i].IsDefault = false
+				if err := s.planRepo.Update(ctx, &versions[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 只能有一个默认套餐
+		if plan.IsDefault {
+			if err := s.planRepo.ClearDefault(ctx); err != nil {
+				return err
+			}
+		}
+
+		plan.Status = "active"
+
+		return s.planRepo.Create(ctx, plan)
+	})
+}
+
+// GetByID 根据ID获取套餐
+func (s *planService) GetByID(ctx context.Context, id int64) (*model.Plan, error) {
+	return s.planRepo.GetByID(ctx, id)
+}
+
+// GetVersions 获取套餐的所有版本
+func (s *planService) GetVersions(ctx context.Context, code string) ([]model.Plan, error) {
+	return s.planRepo.GetVersions(ctx, code)
+}
+
+// List 获取套餐列表
+func (s *planService) List(ctx context.Context, status string, page, pageSize int) ([]model.Plan, int64, error) {
+	return s.planRepo.List(ctx, status, page, pageSize)
+}
+
+// Deprecate 停售套餐，已订阅的应用保留原权益
+func (s *planService) Deprecate(ctx context.Context, id int64) error {
+	plan, err := s.planRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// 默认套餐不能停售
+	if plan.IsDefault {
+		return errors.New("默认套餐不能停售，请先设置其他默认套餐")
+	}
+
+	plan.Status = "deprecated"
+	return s.planRepo.Update(ctx, plan)
+}
+
+// SetDefault 设置新应用的默认套餐
+func (s *planService) SetDefault(ctx context.Context, id int64) error {
+	plan, err := s.planRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if plan.Status != "active" {
+		return errors.New("已停售的套餐不能设为默认套餐")
+	}
+
+	// 清除原默认套餐和设置新默认套餐在同一事务中，并发设置时由唯一索引保证只有一个默认套餐
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.planRepo.ClearDefault(ctx); err != nil {
+			return err
+		}
+
+		plan.IsDefault = true
+		return s.planRepo.Update(ctx, plan)
+	})
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"saas-account/repository"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fakeVersionedPlanRepo 内存中的套餐仓库，支持按编码管理版本，failCreate、failLatest 和 failUpdate 为注入的错误
+type fakeVersionedPlanRepo struct {
+	repository.PlanRepository
+	plans      []model.Plan
+	locked     []string
+	failCreate error
+	failLatest error
+	failUpdate error
+}
+
+func (r *fakeVersionedPlanRepo) GetByID(ctx context.Context, id int64) (*model.Plan, error) {
+	for _, plan := range r.plans {
+		if plan.ID == id {
+			return &plan, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeVersionedPlanRepo) LockCode(ctx context.Context, code string) error {
+	if ctx.Value(fakeTxKey{}) == nil {
+		return errors.New("未在事务中锁定套餐编码")
+	}
+	r.locked = append(r.locked, code)
+	return nil
+}
+
+func (r *fakeVersionedPlanRepo) GetLatestByCode(ctx context.Context, code string) (*model.Plan, error) {
+	if r.failLatest != nil {
+		return nil, r.failLatest
+	}
+	var latest *model.Plan
+	for i := range r.plans {
+		if r.plans[i].Code == code && (latest == nil || r.plans[i].Version > latest.Version) {
+			plan := r.plans[i]
+			latest = &plan
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return latest, nil
+}
+
+func (r *fakeVersionedPlanRepo) GetVersions(ctx context.Context, code string) ([]model.Plan, error) {
+	var versions []model.Plan
+	for _, plan := range r.plans {
+		if plan.Code == code {
+			versions = append(versions, plan)
+		}
+	}
+	return versions, nil
+}
+
+func (r *fakeVersionedPlanRepo) Update(ctx context.Context, plan *model.Plan) error {
+	if r.failUpdate != nil {
+		return r.failUpdate
+	}
+	for i := range r.plans {
+		if r.plans[i].ID == plan.ID {
+			r.plans[i] = *plan
+		}
+	}
+	return nil
+}
+
+func (r *fakeVersionedPlanRepo) ClearDefault(ctx context.Context) error {
+	for i := range r.plans {
+		r.plans[i].IsDefault = false
+	}
+	return nil
+}
+
+func (r *fakeVersionedPlanRepo) Create(ctx context.Context, plan *model.Plan) error {
+	if r.failCreate != nil {
+		return r.failCreate
+	}
+	plan.ID = nextFixtureID()
+	r.plans = append(r.plans, *plan)
+	return nil
+}
+
+// snapshot 保存当前套餐，返回恢复函数，用于假事务回滚
+func (r *fakeVersionedPlanRepo) snapshot() func() {
+	saved := append([]model.Plan(nil), r.plans...)
+	return func() { r.plans = saved }
+}
+
+// newPlanFixture 创建已有默认套餐 basic v1 的套餐服务
+func newPlanFixture() (PlanService, *fakeVersionedPlanRepo) {
+	repo := &fakeVersionedPlanRepo{plans: []model.Plan{
+		{Base: model.Base{ID: 1}, Code: "basic", Name: "基础版", Version: 1, Status: "active", IsDefault: true},
+	}}
+	return NewPlanService(repo, newFakeTxManager(repo)), repo
+}
+
+func TestPlanCreateVersion(t *testing.T) {
+	svc, repo := newPlanFixture()
+
+	plan := &model.Plan{Code: "basic", Name: "基础版"}
+	if err := svc.Create(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Version != 2 || !plan.IsDefault || plan.Status != "active" {
+		t.Errorf("新版本 = %+v, 期望 v2 在售并继承默认标记", plan)
+	}
+	if old := repo.plans[0]; old.Status != "deprecated" || old.IsDefault {
+		t.Errorf("旧版本 = %+v, 期望停售且不是默认套餐", old)
+	}
+	if len(repo.locked) != 1 || repo.locked[0] != "basic" {
+		t.Errorf("锁定的编码 = %v", repo.locked)
+	}
+
+	// 新编码从版本1开始
+	other := &model.Plan{Code: "pro", Name: "专业版"}
+	if err := svc.Create(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+	if other.Version != 1 || other.IsDefault {
+		t.Errorf("新编码 = %+v, 期望 v1 且不是默认套餐", other)
+	}
+}
+
+func TestPlanCreateFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		failCreate error
+		failLatest error
+	}{
+		// 例如并发创建同一版本时违反唯一索引
+		{name: "创建失败时回滚停售", failCreate: errInjected},
+		{name: "查询最新版本出错", failLatest: errInjected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newPlanFixture()
+			repo.failCreate, repo.failLatest = tt.failCreate, tt.failLatest
+
+			if err := svc.Create(context.Background(), &model.Plan{Code: "basic", Name: "基础版"}); !errors.Is(err, errInjected) {
+				t.Fatalf("错误 = %v, 期望 %v", err, errInjected)
+			}
+			// 旧版本仍在售且是默认套餐，不会变成版本1
+			if len(repo.plans) != 1 || repo.plans[0].Status != "active" || !repo.plans[0].IsDefault {
+				t.Errorf("套餐 = %+v, 期望旧版本保持不变", repo.plans)
+			}
+		})
+	}
+}
+
+func TestPlanSetDefault(t *testing.T) {
+	svc, repo := newPlanFixture()
+	pro := &model.Plan{Code: "pro", Name: "专业版"}
+	if err := svc.Create(context.Background(), pro); err != nil {
+		t.Fatal(err)
+	}
+
+	// 设置失败时回滚，原默认套餐保持不变
+	repo.failUpdate = errInjected
+	if err := svc.SetDefault(context.Background(), pro.ID); !errors.Is(err, errInjected) {
+		t.Fatalf("错误 = %v, 期望 %v", err, errInjected)
+	}
+	if !repo.plans[0].IsDefault || repo.plans[1].IsDefault {
+		t.Errorf("套餐 = %+v, 期望 basic 仍是默认套餐", repo.plans)
+	}
+
+	repo.failUpdate = nil
+	if err := svc.SetDefault(context.Background(), pro.ID); err != nil {
+		t.Fatal(err)
+	}
+	if repo.plans[0].IsDefault || !repo.plans[1].IsDefault {
+		t.Errorf("套餐 = %+v, 期望只有 pro 是默认套餐", repo.plans)
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"saas-account/model"
+)
+
+// parsePriceComponents 解析并校验套餐的价格组成
+func parsePriceComponents(raw string) ([]model.PriceComponent, error) {
+	var components []model.PriceComponent
+	if raw == "" {
+		return components, nil
+	}
+
+	if err := json.Unmarshal([]byte(raw), &components); err != nil {
+		return nil, errors.New("价格组成必须是有效的JSON数组")
+	}
+
+	for i, c := range components {
+		if c.Name == "" {
+			return nil, fmt.Errorf("第%d项价格组成缺少名称", i+1)
+		}
+		if c.UnitAmount < 0 || c.UnitSize < 0 || c.Included < 0 {
+			return nil, fmt.Errorf("价格组成 %s 的金额和数量不能为负数", c.Name)
+		}
+		switch c.Type {
+		case model.PriceTypeFlat, model.PriceTypePerSeat:
+			if len(c.Tiers) > 0 {
+				return nil, fmt.Errorf("价格组成 %s 不支持阶梯价格", c.Name)
+			}
+		case model.PriceTypePerUnit:
+			if c.Metric == "" {
+				return nil, fmt.Errorf("价格组成 %s 缺少计量项", c.Name)
+			}
+			if err := validatePriceTiers(c.Tiers); err != nil {
+				return nil, fmt.Errorf("价格组成 %s 的%v", c.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("价格组成 %s 的类型无效", c.Name)
+		}
+	}
+
+	return components, nil
+}
+
+// validatePriceTiers 校验阶梯价格，上限必须递增，最后一个阶梯必须不设上限
+func validatePriceTiers(tiers []model.PriceTier) error {
+	if len(tiers) > 0 && tiers[len(tiers)-1].UpTo != 0 {
+		return errors.New("最后一个阶梯必须不设上限")
+	}
+
+	var previous int64
+	for i, tier := range tiers {
+		if tier.UnitAmount < 0 || tier.FlatAmount < 0 {
+			return errors.New("阶梯价格不能为负数")
+		}
+		if tier.UpTo == 0 {
+			if i != len(tiers)-1 {
+				return errors.New("只有最后一个阶梯可以不设上限")
+			}
+			continue
+		}
+		if tier.UpTo <= previous {
+			return errors.New("阶梯上限必须递增")
+		}
+		previous = tier.UpTo
+	}
+	return nil
+}
+
+// billableUnits 将用量换算为计费单位数，不足一个计费单位按一个计，并扣除免费单位数
+func billableUnits(quantity int64, c model.PriceComponent, included int64) int64 {
+	unitSize := c.UnitSize
+	if unitSize <= 0 {
+		unitSize = 1
+	}
+	units := (quantity + unitSize - 1) / unitSize
+	if units <= included {
+		return 0
+	}
+	return units - included
+}
+
+// unitPrice 计算计费单位数对应的金额，配置了阶梯价格时按累进方式计算
+func unitPrice(units int64, c model.PriceComponent) int64 {
+	if len(c.Tiers) == 0 {
+		return units * c.UnitAmount
+	}
+
+	var amount, lower int64
+	for _, tier := range c.Tiers {
+		if units <= lower {
+			break
+		}
+		upper := tier.UpTo
+		if upper == 0 || upper > units {
+			upper = units
+		}
+		amount += tier.FlatAmount + (upper-lower)*tier.UnitAmount
+		lower = upper
+	}
+	return amount
+}
+
+// prorate 按使用时长占计费周期的比例折算金额或数量，向下取整
+func prorate(value, used, period int64) int64 {
+	if period <= 0 || used >= period {
+		return value
+	}
+	return value * used / period
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"saas-account/model"
+	"strings"
+	"testing"
+)
+
+func TestProrate(t *testing.T) {
+	const day = int64(86400)
+
+	tests := []struct {
+		name   string
+		value  int64
+		used   int64
+		period int64
+		want   int64
+	}{
+		{name: "整个周期", value: 3000, used: 30 * day, period: 30 * day, want: 3000},
+		{name: "超过周期", value: 3000, used: 31 * day, period: 30 * day, want: 3000},
+		{name: "半个周期", value: 3000, used: 15 * day, period: 30 * day, want: 1500},
+		{name: "向下取整", value: 1000, used: 20 * day, period: 30 * day, want: 666},
+		{name: "未使用", value: 3000, used: 0, period: 30 * day, want: 0},
+		{name: "周期无效", value: 3000, used: day, period: 0, want: 3000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prorate(tt.value, tt.used, tt.period); got != tt.want {
+				t.Errorf("prorate(%d, %d, %d) = %d, 期望 %d", tt.value, tt.used, tt.period, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBillableUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity int64
+		unitSize int64
+		included int64
+		want     int64
+	}{
+		{name: "默认计费单位", quantity: 250, included: 100, want: 150},
+		{name: "不足一个计费单位按一个计", quantity: 1001, unitSize: 1000, want: 2},
+		{name: "免费额度内", quantity: 999, unitSize: 1000, included: 1, want: 0},
+		{name: "扣除免费单位", quantity: 5000, unitSize: 1000, included: 2, want: 3},
+		{name: "没有用量", quantity: 0, unitSize: 1000, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := model.PriceComponent{UnitSize: tt.unitSize}
+			if got := billableUnits(tt.quantity, c, tt.included); got != tt.want {
+				t.Errorf("billableUnits = %d, 期望 %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnitPrice(t *testing.T) {
+	tiered := model.PriceComponent{Tiers: []model.PriceTier{
+		{UpTo: 10, UnitAmount: 100},
+		{UpTo: 100, UnitAmount: 50, FlatAmount: 200},
+		{UnitAmount: 10},
+	}}
+
+	tests := []struct {
+		name  string
+		c     model.PriceComponent
+		units int64
+		want  int64
+	}{
+		{name: "单价", c: model.PriceComponent{UnitAmount: 10}, units: 150, want: 1500},
+		{name: "第一阶梯内", c: tiered, units: 5, want: 500},
+		{name: "第一阶梯上限", c: tiered, units: 10, want: 1000},
+		{name: "进入第二阶梯收取固定费用", c: tiered, units: 11, want: 1000 + 200 + 50},
+		{name: "最后一个阶梯不设上限", c: tiered, units: 150, want: 1000 + 200 + 90*50 + 50*10},
+		{name: "没有用量", c: tiered, units: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unitPrice(tt.units, tt.c); got != tt.want {
+				t.Errorf("unitPrice(%d) = %d, 期望 %d", tt.units, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePriceComponents(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{name: "未配置", raw: ""},
+		{name: "有效", raw: `[{"type":"flat","name":"基础费","unit_amount":3000},{"type":"per_unit","name":"导出","metric":"export","unit_amount":10}]`},
+		{name: "不是数组", raw: `{"type":"flat"}`, wantErr: "JSON数组"},
+		{name: "缺少名称", raw: `[{"type":"flat"}]`, wantErr: "缺少名称"},
+		{name: "负数金额", raw: `[{"type":"flat","name":"基础费","unit_amount":-1}]`, wantErr: "不能为负数"},
+		{name: "类型无效", raw: `[{"type":"monthly","name":"基础费"}]`, wantErr: "类型无效"},
+		{name: "按用量缺少计量项", raw: `[{"type":"per_unit","name":"调用"}]`, wantErr: "缺少计量项"},
+		{name: "固定费用不支持阶梯", raw: `[{"type":"flat","name":"基础费","tiers":[{"unit_amount":1}]}]`, wantErr: "不支持阶梯"},
+		{name: "最后阶梯设了上限", raw: `[{"type":"per_unit","name":"调用","metric":"api_call","tiers":[{"up_to":10,"unit_amount":1}]}]`, wantErr: "不设上限"},
+		{name: "阶梯上限不递增", raw: `[{"type":"per_unit","name":"调用","metric":"api_call","tiers":[{"up_to":10,"unit_amount":1},{"up_to":5,"unit_amount":1},{"unit_amount":1}]}]`, wantErr: "递增"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePriceComponents(tt.raw)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("错误 = %v, 期望通过", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("错误 = %v, 期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"saas-account/logger"
+	"saas-account/model"
+	"saas-account/notify"
+	"time"
+)
+
+// QuotaStatus 配额状态
+type QuotaStatus struct {
+	UsageType string `json:"usage_type"` // 使用类型
+	Mode      string `json:"mode"`       // 配额执行模式
+	Limit     int64  `json:"limit"`      // 配额上限，-1表示不限
+	Used      int64  `json:"used"`       // 当前窗口已使用量
+	Remaining int64  `json:"remaining"`  // 剩余配额，-1表示不限
+	ResetAt   int64  `json:"reset_at"`   // 配额重置时间，0表示不重置
+}
+
+// QuotaExceededError 超出配额错误
+type QuotaExceededError struct {
+	Status *QuotaStatus
+}
+
+// Error 错误信息
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("已超过%s使用限制", e.Status.UsageType)
+}
+
+// quotaWindow 获取使用类型在组织时区下的配额窗口，api_call 按自然日重置，其他类型不重置
+func quotaWindow(usageType string, at time.Time, loc *time.Location) (windowStart, resetAt int64) {
+	if usageType == "api_call" {
+		day := periodStart(UsageGranularityDay, at, loc)
+		return day.Unix(), nextPeriod(UsageGranularityDay, day).Unix()
+	}
+	return 0, 0
+}
+
+// quotaLimit 获取使用类型的配额上限，-1表示不限
+func quotaLimit(limit *model.OrganizationApplicationLimit, usageType string) int64 {
+	switch usageType {
+	case "api_call":
+		return int64(limit.MaxRequests)
+	case "storage":
+		return limit.MaxStorage
+	}
+	return -1
+}
+
+// newQuotaStatus 根据已使用量计算配额状态
+func newQuotaStatus(usageType, mode string, quota, used, resetAt int64) *QuotaStatus {
+	status := &QuotaStatus{
+		UsageType: usageType,
+		Mode:      mode,
+		Limit:     quota,
+		Used:      used,
+		Remaining: -1,
+		ResetAt:   resetAt,
+	}
+	if quota >= 0 {
+		status.Remaining = quota - used
+		if status.Remaining < 0 {
+			status.Remaining = 0
+		}
+	}
+	return status
+}
+
+// notifyQuotaExceeded 发送超出配额通知，发送失败只记录日志
+func notifyQuotaExceeded(ctx context.Context, notifier notify.Notifier, app *model.OrganizationApplication, status *QuotaStatus) {
+	err := notifier.Notify(ctx, &notify.Notification{
+		Type:           "quota.exceeded",
+		OrganizationId: app.OrganizationId,
+		ApplicationId:  app.ID,
+		Subject:        "已超出使用配额",
+		Content:        fmt.Sprintf("应用 %s 的 %s 使用量 %d 已超出配额 %d", app.Name, status.UsageType, status.Used, status.Limit),
+		Data: map[string]interface{}{
+			"usage_type": status.UsageType,
+			"used":       status.Used,
+			"limit":      status.Limit,
+			"mode":       status.Mode,
+		},
+	})
+	if err != nil {
+		logger.GetLogger().ErrorWithContext(ctx, "发送超出配额通知失败: 应用=%d, 错误: %v", app.ID, err)
+	}
+}
+
+// validateEnforcementMode 校验配额执行模式
+func validateEnforcementMode(mode string, overagePercent int) error {
+	switch mode {
+	case model.EnforcementModeHard, model.EnforcementModeSoft, model.EnforcementModeNotify:
+	default:
+		return fmt.Errorf("无效的配额执行模式: %s", mode)
+	}
+	if overagePercent < 0 {
+		return errors.New("超额百分比不能为负数")
+	}
+	return nil
+}
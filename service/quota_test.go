@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"saas-account/model"
+	"testing"
+	"time"
+)
+
+func TestRecordAPIUsageEnforcement(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       *model.OrganizationApplicationLimit
+		amounts     []int64
+		wantErr     []bool  // 每次记录是否超出配额
+		wantOverage []int64 // 每条使用记录中的超额部分
+		wantUsed    int64
+		wantNotify  int
+	}{
+		{
+			name:        "未配置限制",
+			amounts:     []int64{8, 5},
+			wantErr:     []bool{false, false},
+			wantOverage: []int64{0, 0},
+			wantUsed:    13,
+		},
+		{
+			name:        "硬限制",
+			limit:       &model.OrganizationApplicationLimit{MaxRequests: 10, EnforcementMode: model.EnforcementModeHard},
+			amounts:     []int64{8, 3, 2},
+			wantErr:     []bool{false, true, false},
+			wantOverage: []int64{0, 0},
+			wantUsed:    10,
+		},
+		{
+			name:        "软限制不限超额",
+			limit:       &model.OrganizationApplicationLimit{MaxRequests: 10, EnforcementMode: model.EnforcementModeSoft},
+			amounts:     []int64{8, 5, 4},
+			wantErr:     []bool{false, false, false},
+			wantOverage: []int64{0, 3, 4},
+			wantUsed:    17,
+			wantNotify:  1,
+		},
+		{
+			name:        "软限制允许超额20%",
+			limit:       &model.OrganizationApplicationLimit{MaxRequests: 10, EnforcementMode: model.EnforcementModeSoft, OveragePercent: 20},
+			amounts:     []int64{8, 3, 2, 1},
+			wantErr:     []bool{false, false, true, false},
+			wantOverage: []int64{0, 1, 1},
+			wantUsed:    12,
+			wantNotify:  1,
+		},
+		{
+			name:        "仅通知",
+			limit:       &model.OrganizationApplicationLimit{MaxRequests: 10, EnforcementMode: model.EnforcementModeNotify},
+			amounts:     []int64{10, 1, 100},
+			wantErr:     []bool{false, false, false},
+			wantOverage: []int64{0, 1, 100},
+			wantUsed:    111,
+			wantNotify:  1,
+		},
+		{
+			name:        "配额不限",
+			limit:       &model.OrganizationApplicationLimit{MaxRequests: -1, EnforcementMode: model.EnforcementModeHard},
+			amounts:     []int64{1000},
+			wantErr:     []bool{false},
+			wantOverage: []int64{0},
+			wantUsed:    1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newUsageFixture(t, "", tt.limit)
+			ctx := context.Background()
+
+			for i, amount := range tt.amounts {
+				err := f.service.RecordAPIUsage(ctx, f.app.ID, nil, amount)
+				var quotaErr *QuotaExceededError
+				if got := errors.As(err, &quotaErr); got != tt.wantErr[i] {
+					t.Fatalf("第 %d 次记录错误 = %v, 期望超出配额 = %v", i, err, tt.wantErr[i])
+				}
+				if err != nil && quotaErr == nil {
+					t.Fatalf("第 %d 次记录错误 = %v", i, err)
+				}
+			}
+
+			windowStart, _ := quotaWindow("api_call", time.Now(), time.UTC)
+			if got := f.store.counter(f.app.ID, "api_call", windowStart); got != tt.wantUsed {
+				t.Errorf("计数器 = %d, 期望 %d", got, tt.wantUsed)
+			}
+
+			f.store.mu.Lock()
+			usages := f.store.state.usages
+			f.store.mu.Unlock()
+			if len(usages) != len(tt.wantOverage) {
+				t.Fatalf("使用记录数 = %d, 期望 %d", len(usages), len(tt.wantOverage))
+			}
+			for i, usage := range usages {
+				var details struct {
+					Overage int64 `json:"overage"`
+				}
+				if err := json.Unmarshal([]byte(usage.Details), &details); err != nil {
+					t.Fatalf("详细信息 %q 不是有效的JSON: %v", usage.Details, err)
+				}
+				if details.Overage != tt.wantOverage[i] {
+					t.Errorf("使用记录 %d 超额 = %d, 期望 %d", i, details.Overage, tt.wantOverage[i])
+				}
+			}
+
+			if got := f.notifier.count(); got != tt.wantNotify {
+				t.Errorf("超出配额通知数 = %d, 期望 %d", got, tt.wantNotify)
+			}
+		})
+	}
+}
+
+func TestRecordAPIUsageRollback(t *testing.T) {
+	f := newUsageFixture(t, "", &model.OrganizationApplicationLimit{MaxRequests: 10, EnforcementMode: model.EnforcementModeHard})
+	ctx := context.Background()
+
+	// 写入使用记录失败时配额计数一起回滚，不占用配额
+	f.store.failCreate = errInjected
+	if err := f.service.RecordAPIUsage(ctx, f.app.ID, nil, 10); !errors.Is(err, errInjected) {
+		t.Fatalf("错误 = %v, 期望 %v", err, errInjected)
+	}
+
+	windowStart, _ := quotaWindow("api_call", time.Now(), time.UTC)
+	if got := f.store.counter(f.app.ID, "api_call", windowStart); got != 0 {
+		t.Errorf("失败后计数器 = %d, 期望回滚为 0", got)
+	}
+
+	f.store.failCreate = nil
+	if err := f.service.RecordAPIUsage(ctx, f.app.ID, nil, 10); err != nil {
+		t.Fatalf("重试错误 = %v", err)
+	}
+	if len(f.store.outsideTx) != 0 {
+		t.Errorf("事务外的写操作: %v", f.store.outsideTx)
+	}
+}
+
+func TestRecordFeatureUsageDetails(t *testing.T) {
+	name := `export "csv"\beta`
+	features, err := json.Marshal(model.Entitlements{
+		name: {Type: model.EntitlementTypeBoolean, Enabled: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newUsageFixture(t, "", &model.OrganizationApplicationLimit{MaxRequests: -1, Features: string(features)})
+
+	if err := f.service.RecordFeatureUsage(context.Background(), f.app.ID, nil, name, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.RecordFeatureUsage(context.Background(), f.app.ID, nil, "sso", 1); !errors.Is(err, ErrFeatureNotIncluded) {
+		t.Errorf("未包含的功能错误 = %v, 期望 %v", err, ErrFeatureNotIncluded)
+	}
+
+	// 功能名称中的特殊字符经过转义，详细信息仍是有效的JSON
+	var details struct {
+		FeatureName string `json:"feature_name"`
+	}
+	if err := json.Unmarshal([]byte(f.store.state.usages[0].Details), &details); err != nil {
+		t.Fatalf("详细信息 %q 不是有效的JSON: %v", f.store.state.usages[0].Details, err)
+	}
+	if details.FeatureName != name {
+		t.Errorf("功能名称 = %q, 期望 %q", details.FeatureName, name)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"saas-account/ratelimit"
+	"saas-account/repository"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// validateRateLimit 校验限流令牌桶设置
+func validateRateLimit(burst, perMin int) error {
+	if burst < 0 || perMin < 0 {
+		return errors.New("限流设置不能为负数")
+	}
+	if burst > 0 && perMin == 0 {
+		return errors.New("启用限流时每分钟补充的令牌数必须大于0")
+	}
+	return nil
+}
+
+// RateLimitService 应用限流策略服务接口
+type RateLimitService interface {
+	GetPolicy(ctx context.Context, appID int64) (*ratelimit.Policy, error)
+}
+
+// rateLimitService 应用限流策略服务实现
+type rateLimitService struct {
+	limitRepo repository.OrganizationApplicationLimitRepository
+}
+
+// NewRateLimitService 创建应用限流策略服务
+func NewRateLimitService(limitRepo repository.OrganizationApplicationLimitRepository) RateLimitService {
+	return &rateLimitService{
+		limitRepo: limitRepo,
+	}
+}
+
+// rateLimitCacheEntry 限流策略缓存条目
+type rateLimitCacheEntry struct {
+	policy    *ratelimit.Policy
+	expiresAt time.Time
+}
+
+// rateLimitCache 应用限流策略缓存，避免每个请求都查询应用限制
+type rateLimitCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[int64]rateLimitCacheEntry
+}
+
+// appRateLimitCache 全局应用限流策略缓存，应用限制变更时失效
+var appRateLimitCache = &rateLimitCache{
+	ttl:     30 * time.Second,
+	entries: make(map[int64]rateLimitCacheEntry),
+}
+
+// get 获取缓存的限流策略
+func (c *rateLimitCache) get(appID int64) (*ratelimit.Policy, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[appID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.policy, true
+}
+
+// set 缓存限流策略
+func (c *rateLimitCache) set(appID int64, policy *ratelimit.Policy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[appID] = rateLimitCacheEntry{
+		policy:    policy,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
+
+// invalidate 使限流策略缓存失效
+func (c *rateLimitCache) invalidate(appID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, appID)
+}
+
+// GetPolicy 获取应用的限流策略，未配置应用限制或未启用限流时返回nil
+func (s *rateLimitService) GetPolicy(ctx context.Context, appID int64) (*ratelimit.Policy, error) {
+	if policy, ok := appRateLimitCache.get(appID); ok {
+		return policy, nil
+	}
+
+	var policy *ratelimit.Policy
+	limit, err := s.limitRepo.GetByApplicationID(ctx, appID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if limit != nil && limit.RateLimitBurst > 0 {
+		policy = &ratelimit.Policy{
+			Burst:     int64(limit.RateLimitBurst),
+			PerMinute: int64(limit.RateLimitPerMin),
+		}
+	}
+
+	appRateLimitCache.set(appID, policy)
+	return policy, nil
+}
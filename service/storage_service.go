@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"saas-account/metrics"
+	"saas-account/model"
+	"saas-account/notify"
+	"saas-account/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// hoursPerMonth 计算GB月时每月的小时数
+const hoursPerMonth = 730
+
+// StorageStatus 应用存储用量状态
+type StorageStatus struct {
+	CurrentBytes int64        `json:"current_bytes"` // 当前存储占用（字节）
+	PeakBytes    int64        `json:"peak_bytes"`    // 历史最高存储占用（字节）
+	Quota        *QuotaStatus `json:"quota"`         // 按当前占用计算的配额状态
+}
+
+// StorageBilling 应用在统计区间内的存储计费用量
+type StorageBilling struct {
+	Start        int64   `json:"start"`         // 统计开始时间（含）
+	End          int64   `json:"end"`           // 统计结束时间（不含）
+	Hours        int64   `json:"hours"`         // 统计的小时数
+	PeakBytes    int64   `json:"peak_bytes"`    // 区间内最高存储占用（字节）
+	AverageBytes int64   `json:"average_bytes"` // 区间内按小时平均的存储占用（字节）
+	GBMonths     float64 `json:"gb_months"`     // 区间内的存储用量（GB月）
+}
+
+// StorageService 存储用量服务接口，按当前占用而非累计量管理存储
+type StorageService interface {
+	GetStatus(ctx context.Context, appID int64) (*StorageStatus, error)
+	SetUsage(ctx context.Context, appID int64, bytes int64) (*StorageStatus, error)
+	AddUsage(ctx context.Context, appID int64, delta int64) (*StorageStatus, error)
+	GetBilling(ctx context.Context, appID int64, startDate, endDate time.Time) (*StorageBilling, error)
+}
+
+// storageService 存储用量服务实现
+type storageService struct {
+	gaugeRepo    repository.StorageGaugeRepository
+	appRepo      repository.OrganizationApplicationRepository
+	orgRepo      repository.OrganizationRepository
+	limitRepo    repository.OrganizationApplicationLimitRepository
+	notifier     notify.Notifier
+	alertService AlertService
+	txManager    repository.TransactionManager
+}
+
+// NewStorageService 创建存储用量服务
+func NewStorageService(
+	gaugeRepo repository.StorageGaugeRepository,
+	appRepo repository.OrganizationApplicationRepository,
+	orgRepo repository.OrganizationRepository,
+	limitRepo repository.OrganizationApplicationLimitRepository,
+	notifier notify.Notifier,
+	alertService AlertService,
+	txManager repository.TransactionManager,
+) StorageService {
+	return &storageService{
+		gaugeRepo:    gaugeRepo,
+		appRepo:      appRepo,
+		orgRepo:      orgRepo,
+		limitRepo:    limitRepo,
+		notifier:     notifier,
+		alertService: alertService,
+		txManager:    txManager,
+	}
+}
+
+// GetStatus 获取应用当前的存储用量状态
+func (s *storageService) GetStatus(ctx context.Context, appID int64) (*StorageStatus, error) {
+	// 检查应用是否存在
+	if _, err := s.appRepo.GetByID(ctx, appID); err != nil {
+		return nil, err
+	}
+
+	gauge, err := s.gaugeRepo.Get(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, quota, _, err := s.storageLimit(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStorageStatus(gauge, mode, quota), nil
+}
+
+// SetUsage 设置应用当前的存储占用，用于上报实际测量值，超出配额时只发送通知
+func (s *storageService) SetUsage(ctx context.Context, appID int64, bytes int64) (*StorageStatus, error) {
+	if bytes < 0 {
+		return nil, errors.New("存储占用不能为负数")
+	}
+
+	app, err := s.appRepo.GetByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, quota, _, err := s.storageLimit(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketStart, err := s.bucketStart(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+
+	// 存储用量和小时快照在同一个事务中更新
+	var previous, gauge *model.StorageGauge
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if previous, err = s.gaugeRepo.Get(ctx, appID); err != nil {
+			return err
+		}
+		gauge, err = s.gaugeRepo.Set(ctx, appID, app.OrganizationId, bytes, bucketStart)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := newStorageStatus(gauge, mode, quota)
+	if quota >= 0 && gauge.CurrentBytes > quota && previous.CurrentBytes <= quota {
+		notifyQuotaExceeded(ctx, s.notifier, app, status.Quota)
+	}
+	s.alertService.Evaluate(ctx, app, status.Quota, time.Now())
+	return status, nil
+}
+
+// AddUsage 增减应用的存储占用，增加时按配额执行模式检查当前占用
+func (s *storageService) AddUsage(ctx context.Context, appID int64, delta int64) (*StorageStatus, error) {
+	app, err := s.appRepo.GetByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, quota, ceiling, err := s.storageLimit(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketStart, err := s.bucketStart(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+
+	// 存储用量和小时快照在同一个事务中更新
+	var gauge *model.StorageGauge
+	var ok bool
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		gauge, ok, err = s.gaugeRepo.Add(ctx, appID, app.OrganizationId, delta, ceiling, bucketStart)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := newStorageStatus(gauge, mode, quota)
+	if !ok {
+		metrics.QuotaRejections.WithLabelValues(status.Quota.UsageType).Inc()
+		return nil, &QuotaExceededError{Status: status.Quota}
+	}
+
+	// 本次增加首次超出配额时发送通知
+	if quota >= 0 && delta > 0 && gauge.CurrentBytes > quota && gauge.CurrentBytes-delta <= quota {
+		notifyQuotaExceeded(ctx, s.notifier, app, status.Quota)
+	}
+	s.alertService.Evaluate(ctx, app, status.Quota, time.Now())
+	return status, nil
+}
+
+// GetBilling 获取应用在日期区间内的存储计费用量，日期部分按组织时区解释，包含结束日期当天
+// 每小时按该小时内的最高占用计，没有变更的小时沿用上一次变更后的占用
+func (s *storageService) GetBilling(ctx context.Context, appID int64, startDate, endDate time.Time) (*StorageBilling, error) {
+	app, err := s.appRepo.GetByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := loadOrgLocation(ctx, s.orgRepo, app.OrganizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := localDateRange(startDate, endDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	// 不统计未来的时间
+	if now := periodStart(UsageGranularityHour, time.Now(), loc).Add(time.Hour); end.After(now) {
+		end = now
+	}
+
+	billing := &StorageBilling{Start: start.Unix(), End: end.Unix()}
+	if !start.Before(end) {
+		return billing, nil
+	}
+
+	snapshots, err := s.gaugeRepo.GetSnapshots(ctx, appID, start.Unix(), end.Unix())
+	if err != nil {
+		return nil, err
+	}
+	byHour := make(map[int64]model.StorageSnapshot, len(snapshots))
+	for _, snapshot := range snapshots {
+		byHour[snapshot.BucketStart] = snapshot
+	}
+
+	// 区间开始前的占用
+	var current int64
+	last, err := s.gaugeRepo.GetLastSnapshotBefore(ctx, appID, start.Unix())
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		current = last.LastBytes
+	}
+
+	var byteHours int64
+	for t := start; t.Before(end); t = t.Add(time.Hour) {
+		value := current
+		if snapshot, ok := byHour[t.Unix()]; ok {
+			value = snapshot.MaxBytes
+			current = snapshot.LastBytes
+		}
+		if value > billing.PeakBytes {
+			billing.PeakBytes = value
+		}
+		byteHours += value
+		billing.Hours++
+	}
+
+	billing.AverageBytes = byteHours / billing.Hours
+	billing.GBMonths = float64(byteHours) / float64(1<<30) / hoursPerMonth
+	return billing, nil
+}
+
+// storageLimit 获取应用的存储配额执行模式、配额和允许的上限，-1表示不限
+func (s *storageService) storageLimit(ctx context.Context, appID int64) (string, int64, int64, error) {
+	limit, err := s.limitRepo.GetByApplicationID(ctx, appID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 未配置应用限制，不做限制
+		return "", -1, -1, nil
+	}
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	quota := quotaLimit(limit, "storage")
+	ceiling := int64(-1)
+	switch {
+	case quota < 0 || limit.EnforcementMode == model.EnforcementModeNotify:
+	case limit.EnforcementMode == model.EnforcementModeSoft && limit.OveragePercent == 0:
+	case limit.EnforcementMode == model.EnforcementModeSoft:
+		ceiling = quota + quota*int64(limit.OveragePercent)/100
+	default:
+		ceiling = quota
+	}
+	return limit.EnforcementMode, quota, ceiling, nil
+}
+
+// bucketStart 获取当前时间在组织时区下所在小时的开始时间
+func (s *storageService) bucketStart(ctx context.Context, app *model.OrganizationApplication) (int64, error) {
+	loc, err := loadOrgLocation(ctx, s.orgRepo, app.OrganizationId)
+	if err != nil {
+		return 0, err
+	}
+	return periodStart(UsageGranularityHour, time.Now(), loc).Unix(), nil
+}
+
+// newStorageStatus 根据存储用量计算存储状态
+func newStorageStatus(gauge *model.StorageGauge, mode string, quota int64) *StorageStatus {
+	return &StorageStatus{
+		CurrentBytes: gauge.CurrentBytes,
+		PeakBytes:    gauge.PeakBytes,
+		Quota:        newQuotaStatus("storage", mode, quota, gauge.CurrentBytes, 0),
+	}
+}
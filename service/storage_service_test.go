@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"saas-account/model"
+	"saas-account/repository"
+	"testing"
+	"time"
+)
+
+// fakeGaugeRepo 内存中的存储用量仓库
+type fakeGaugeRepo struct {
+	repository.StorageGaugeRepository
+	gauge     model.StorageGauge
+	snapshots []model.StorageSnapshot
+	outsideTx []string // 在事务外执行的写操作
+}
+
+func (r *fakeGaugeRepo) Get(ctx context.Context, appID int64) (*model.StorageGauge, error) {
+	gauge := r.gauge
+	return &gauge, nil
+}
+
+func (r *fakeGaugeRepo) Add(ctx context.Context, appID, orgID, delta, max, bucketStart int64) (*model.StorageGauge, bool, error) {
+	if ctx.Value(fakeTxKey{}) == nil {
+		r.outsideTx = append(r.outsideTx, "add")
+	}
+	if max >= 0 && delta > 0 && r.gauge.CurrentBytes+delta > max {
+		gauge := r.gauge
+		return &gauge, false, nil
+	}
+	r.gauge.CurrentBytes += delta
+	if r.gauge.CurrentBytes < 0 {
+		r.gauge.CurrentBytes = 0
+	}
+	if r.gauge.CurrentBytes > r.gauge.PeakBytes {
+		r.gauge.PeakBytes = r.gauge.CurrentBytes
+	}
+	gauge := r.gauge
+	return &gauge, true, nil
+}
+
+func (r *fakeGaugeRepo) GetSnapshots(ctx context.Context, appID int64, start, end int64) ([]model.StorageSnapshot, error) {
+	var snapshots []model.StorageSnapshot
+	for _, snapshot := range r.snapshots {
+		if snapshot.BucketStart >= start && snapshot.BucketStart < end {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+func (r *fakeGaugeRepo) GetLastSnapshotBefore(ctx context.Context, appID int64, before int64) (*model.StorageSnapshot, error) {
+	var last *model.StorageSnapshot
+	for i := range r.snapshots {
+		if r.snapshots[i].BucketStart < before && (last == nil || r.snapshots[i].BucketStart > last.BucketStart) {
+			last = &r.snapshots[i]
+		}
+	}
+	return last, nil
+}
+
+// newStorageFixture 创建存储用量服务的测试环境
+func newStorageFixture(timeZone string, limit *model.OrganizationApplicationLimit) (*storageService, *fakeGaugeRepo, *model.OrganizationApplication) {
+	fa := newFixtureApp(timeZone, limit)
+	gaugeRepo := &fakeGaugeRepo{}
+	svc := NewStorageService(
+		gaugeRepo,
+		fa.apps,
+		fa.orgs,
+		fa.limits,
+		&recordingNotifier{},
+		&fakeAlertService{},
+		newFakeTxManager(),
+	).(*storageService)
+	return svc, gaugeRepo, fa.app
+}
+
+func TestStorageBillingGBMonths(t *testing.T) {
+	const gib = int64(1 << 30)
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("缺少时区数据:", err)
+	}
+	day := time.Date(2026, 1, 10, 0, 0, 0, 0, loc)
+	hour := func(h int) int64 { return day.Add(time.Duration(h) * time.Hour).Unix() }
+
+	tests := []struct {
+		name          string
+		snapshots     []model.StorageSnapshot
+		wantByteHours int64
+		wantPeak      int64
+	}{
+		{
+			name: "没有快照",
+		},
+		{
+			name:          "沿用区间开始前的占用",
+			snapshots:     []model.StorageSnapshot{{BucketStart: hour(-30), MaxBytes: 3 * gib, LastBytes: gib}},
+			wantByteHours: 24 * gib,
+			wantPeak:      gib,
+		},
+		{
+			name: "每小时按最高占用计",
+			snapshots: []model.StorageSnapshot{
+				{BucketStart: hour(-1), MaxBytes: gib, LastBytes: gib},
+				{BucketStart: hour(6), MaxBytes: 4 * gib, LastBytes: 2 * gib},
+			},
+			wantByteHours: 6*gib + 4*gib + 17*2*gib,
+			wantPeak:      4 * gib,
+		},
+		{
+			// 新小时第一次变更是降低占用时，快照最高值从上一小时最后的占用开始
+			name: "占用降低的小时按降低前计",
+			snapshots: []model.StorageSnapshot{
+				{BucketStart: hour(-1), MaxBytes: 2 * gib, LastBytes: 2 * gib},
+				{BucketStart: hour(3), MaxBytes: 2 * gib, LastBytes: 0},
+			},
+			wantByteHours: 3*2*gib + 2*gib,
+			wantPeak:      2 * gib,
+		},
+		{
+			name:      "区间之后的快照不计入",
+			snapshots: []model.StorageSnapshot{{BucketStart: hour(24), MaxBytes: 8 * gib, LastBytes: 8 * gib}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, gaugeRepo, app := newStorageFixture("Asia/Shanghai", nil)
+			gaugeRepo.snapshots = tt.snapshots
+
+			billing, err := svc.GetBilling(context.Background(), app.ID, day, day)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if billing.Start != day.Unix() || billing.End != day.AddDate(0, 0, 1).Unix() || billing.Hours != 24 {
+				t.Fatalf("统计区间 = [%d, %d) %d小时, 期望组织时区下的一整天", billing.Start, billing.End, billing.Hours)
+			}
+			if billing.PeakBytes != tt.wantPeak {
+				t.Errorf("最高占用 = %d, 期望 %d", billing.PeakBytes, tt.wantPeak)
+			}
+			if billing.AverageBytes != tt.wantByteHours/24 {
+				t.Errorf("平均占用 = %d, 期望 %d", billing.AverageBytes, tt.wantByteHours/24)
+			}
+			wantGBMonths := float64(tt.wantByteHours) / float64(gib) / hoursPerMonth
+			if math.Abs(billing.GBMonths-wantGBMonths) > 1e-9 {
+				t.Errorf("GB月 = %v, 期望 %v", billing.GBMonths, wantGBMonths)
+			}
+		})
+	}
+}
+
+func TestStorageBillingFutureHours(t *testing.T) {
+	svc, gaugeRepo, app := newStorageFixture("", nil)
+	gaugeRepo.snapshots = []model.StorageSnapshot{{BucketStart: 0, MaxBytes: 1 << 30, LastBytes: 1 << 30}}
+
+	// 不统计当前小时之后的时间
+	now := time.Now().UTC()
+	billing, err := svc.GetBilling(context.Background(), app.ID, now, now.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(time.Now().UTC().Hour() + 1); billing.Hours != want && billing.Hours != int64(now.Hour()+1) {
+		t.Errorf("统计小时数 = %d, 期望 %d", billing.Hours, want)
+	}
+}
+
+func TestStorageAddUsage(t *testing.T) {
+	svc, gaugeRepo, app := newStorageFixture("", &model.OrganizationApplicationLimit{
+		MaxStorage:      100,
+		EnforcementMode: model.EnforcementModeHard,
+	})
+	ctx := context.Background()
+
+	status, err := svc.AddUsage(ctx, app.ID, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.CurrentBytes != 80 || status.Quota.Remaining != 20 {
+		t.Errorf("存储状态 = %+v, 配额 = %+v", status, status.Quota)
+	}
+
+	var quotaErr *QuotaExceededError
+	if _, err := svc.AddUsage(ctx, app.ID, 30); !errors.As(err, &quotaErr) {
+		t.Fatalf("错误 = %v, 期望超出配额", err)
+	}
+	if quotaErr.Status.Used != 80 {
+		t.Errorf("超出配额时已使用 = %d, 期望 80", quotaErr.Status.Used)
+	}
+
+	// 释放存储不受配额限制
+	if status, err = svc.AddUsage(ctx, app.ID, -50); err != nil || status.CurrentBytes != 30 {
+		t.Fatalf("释放存储后 = %+v, 错误 = %v", status, err)
+	}
+
+	// 用量和小时快照在事务中更新
+	if len(gaugeRepo.outsideTx) != 0 {
+		t.Errorf("事务外的写操作: %v", gaugeRepo.outsideTx)
+	}
+}
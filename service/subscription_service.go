@@ -0,0 +1,566 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"saas-account/logger"
+	"saas-account/model"
+	"saas-account/notify"
+	"saas-account/payment"
+	"saas-account/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SubscriptionService 订阅服务接口，处理试用、续费、宽限期和到期降级
+type SubscriptionService interface {
+	Get(ctx context.Context, appID int64) (*model.OrganizationApplicationLimit, error)
+	Subscribe(ctx context.Context, appID, planID int64, autoRenew bool, idempotencyKey string) (*model.OrganizationApplicationLimit, error)
+	StartTrial(ctx context.Context, appID, planID int64, autoRenew bool) (*model.OrganizationApplicationLimit, error)
+	Renew(ctx context.Context, appID int64) (*model.OrganizationApplicationLimit, error)
+	SetAutoRenew(ctx context.Context, appID int64, autoRenew bool) error
+	ProcessExpirations(ctx context.Context) error
+}
+
+// subscriptionService 订阅服务实现
+type subscriptionService struct {
+	appRepo        repository.OrganizationApplicationRepository
+	appMemberRepo  repository.OrganizationApplicationMemberRepository
+	appLimitRepo   repository.OrganizationApplicationLimitRepository
+	planRepo       repository.PlanRepository
+	planChangeRepo repository.PlanChangeRepository
+	provider       payment.Provider
+	notifier       notify.Notifier
+	gracePeriod    time.Duration
+	notifyBefore   time.Duration
+	publisher      EventPublisher
+	txManager      repository.TransactionManager
+	auditService   AuditService
+	paymentRepo    repository.SubscriptionPaymentRepository
+	gaugeRepo      repository.StorageGaugeRepository
+}
+
+// ErrIdempotencyKeyReused 幂等键已用于其他套餐的订阅
+var ErrIdempotencyKeyReused = errors.New("幂等键已用于其他套餐的订阅")
+
+// NewSubscriptionService 创建订阅服务
+func NewSubscriptionService(
+	appRepo repository.OrganizationApplicationRepository,
+	appMemberRepo repository.OrganizationApplicationMemberRepository,
+	appLimitRepo repository.OrganizationApplicationLimitRepository,
+	planRepo repository.PlanRepository,
+	planChangeRepo repository.PlanChangeRepository,
+	provider payment.Provider,
+	notifier notify.Notifier,
+	gracePeriod time.Duration,
+	notifyBefore time.Duration,
+	publisher EventPublisher,
+	txManager repository.TransactionManager,
+	auditService AuditService,
+	paymentRepo repository.SubscriptionPaymentRepository,
+	gaugeRepo repository.StorageGaugeRepository,
+) SubscriptionService {
+	return &subscriptionService{
+		appRepo:        appRepo,
+		appMemberRepo:  appMemberRepo,
+		appLimitRepo:   appLimitRepo,
+		planRepo:       planRepo,
+		planChangeRepo: planChangeRepo,
+		provider:       provider,
+		notifier:       notifier,
+		gracePeriod:    gracePeriod,
+		notifyBefore:   notifyBefore,
+		publisher:      publisher,
+		txManager:      txManager,
+		auditService:   auditService,
+		paymentRepo:    paymentRepo,
+		gaugeRepo:      gaugeRepo,
+	}
+}
+
+// nextPeriodEnd 计算计费周期结束时间
+func nextPeriodEnd(start time.Time, billingCycle string) time.Time {
+	if billingCycle == "yearly" {
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// Get 获取应用订阅信息
+func (s *subscriptionService) Get(ctx context.Context, appID int64) (*model.OrganizationApplicationLimit, error) {
+	return s.appLimitRepo.GetByApplicationID(ctx, appID)
+}
+
+// subscribeIdempotencyKey 订阅扣款的幂等键，优先使用客户端提供的幂等键，否则按应用、套餐、
+// 最后一次离开该套餐的变更记录和当天日期生成，切换到其他套餐后再订阅同一套餐会使用新的幂等键
+func subscribeIdempotencyKey(appID, planID int64, clientKey string, leftChangeID int64, now time.Time) string {
+	if clientKey != "" {
+		return fmt.Sprintf("subscribe:%d:%s", appID, clientKey)
+	}
+	return fmt.Sprintf("subscribe:%d:%d:%d:%s", appID, planID, leftChangeID, now.UTC().Format("2006-01-02"))
+}
+
+// subscribeKey 生成订阅扣款的幂等键，未提供客户端幂等键时查询最后一次离开该套餐的变更记录
+func (s *subscriptionService) subscribeKey(ctx context.Context, appID, planID int64, clientKey string, now time.Time) (string, error) {
+	if clientKey != "" {
+		return subscribeIdempotencyKey(appID, planID, clientKey, 0, now), nil
+	}
+	left, err := s.planChangeRepo.GetLastFrom(ctx, appID, planID)
+	if err != nil {
+		return "", err
+	}
+	var leftChangeID int64
+	if left != nil {
+		leftChangeID = left.ID
+	}
+	return subscribeIdempotencyKey(appID, planID, "", leftChangeID, now), nil
+}
+
+// Subscribe 订阅套餐，付费套餐会先扣款，同一幂等键重复请求只扣款并生效一次
+func (s *subscriptionService) Subscribe(ctx context.Context, appID, planID int64, autoRenew bool, idempotencyKey string) (*model.OrganizationApplicationLimit, error) {
+	limit, plan, err := s.prepare(ctx, appID, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var paid *model.SubscriptionPayment
+	if plan.Price > 0 {
+		key, err := s.subscribeKey(ctx, appID, plan.ID, idempotencyKey, now)
+		if err != nil {
+			return nil, err
+		}
+		paid, err = s.charge(ctx, appID, plan, key)
+		if err != nil {
+			return nil, err
+		}
+		if paid.Status == model.PaymentStatusCompleted {
+			// 重试已完成的订阅时直接返回当前订阅，之后已切换到其他套餐时重新使已付款的套餐生效
+			if limit.PlanId == paid.PlanId {
+				return limit, nil
+			}
+			paid = nil
+		}
+		limit.ExpiresAt = nextPeriodEnd(now, plan.BillingCycle).Unix()
+	} else {
+		// 免费套餐永不过期
+		limit.ExpiresAt = 0
+		autoRenew = false
+	}
+
+	fromPlanID := limit.PlanId
+	applyPlan(limit, plan)
+	limit.SubscriptionStatus = "active"
+	limit.AutoRenew = autoRenew
+	limit.GraceEndsAt = 0
+	limit.ExpiryNotifiedAt = 0
+
+	if err := s.savePaid(ctx, limit, plan, paid); err != nil {
+		return nil, err
+	}
+	recordPlanChange(ctx, s.planChangeRepo, appID, fromPlanID, limit.PlanId)
+	return limit, nil
+}
+
+// StartTrial 开始套餐试用，每个应用只能试用一次
+func (s *subscriptionService) StartTrial(ctx context.Context, appID, planID int64, autoRenew bool) (*model.OrganizationApplicationLimit, error) {
+	limit, plan, err := s.prepare(ctx, appID, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.TrialDays <= 0 {
+		return nil, errors.New("该套餐不支持试用")
+	}
+	if limit.TrialEndsAt != 0 {
+		return nil, errors.New("该应用已使用过试用")
+	}
+
+	trialEndsAt := time.Now().AddDate(0, 0, plan.TrialDays).Unix()
+
+	fromPlanID := limit.PlanId
+	applyPlan(limit, plan)
+	limit.SubscriptionStatus = "trialing"
+	limit.TrialEndsAt = trialEndsAt
+	limit.ExpiresAt = trialEndsAt
+	limit.AutoRenew = autoRenew
+	limit.GraceEndsAt = 0
+	limit.ExpiryNotifiedAt = 0
+
+	if err := s.saveWith(ctx, limit, plan, nil); err != nil {
+		return nil, err
+	}
+	recordPlanChange(ctx, s.planChangeRepo, appID, fromPlanID, limit.PlanId)
+	return limit, nil
+}
+
+// Renew 手动续费当前套餐
+func (s *subscriptionService) Renew(ctx context.Context, appID int64) (*model.OrganizationApplicationLimit, error) {
+	limit, err := s.appLimitRepo.GetByApplicationID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit.PlanId == 0 {
+		return nil, errors.New("当前应用未订阅套餐")
+	}
+
+	// 已停售的版本也允许续费，老用户保留原权益
+	plan, err := s.planRepo.GetByID(ctx, limit.PlanId)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Price <= 0 {
+		return nil, errors.New("免费套餐无需续费")
+	}
+
+	if err := s.renew(ctx, limit, plan, time.Now()); err != nil {
+		return nil, err
+	}
+	return limit, nil
+}
+
+// SetAutoRenew 设置是否自动续费
+func (s *subscriptionService) SetAutoRenew(ctx context.Context, appID int64, autoRenew bool) error {
+	limit, err := s.appLimitRepo.GetByApplicationID(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	limit.AutoRenew = autoRenew
+	return s.save(ctx, limit)
+}
+
+// ProcessExpirations 发送到期提醒，并处理已到期的订阅（自动续费、宽限期、降级）
+func (s *subscriptionService) ProcessExpirations(ctx context.Context) error {
+	now := time.Now()
+
+	// 发送到期提醒
+	expiring, err := s.appLimitRepo.GetExpiringUnnotified(ctx, now.Unix(), now.Add(s.notifyBefore).Unix())
+	if err != nil {
+		return err
+	}
+	for i := range expiring {
+		limit := &expiring[i]
+		s.notify(ctx, limit, "subscription.expiring", "订阅即将到期",
+			fmt.Sprintf("套餐 %s 将于 %s 到期", limit.PlanName, time.Unix(limit.ExpiresAt, 0).Format("2006-01-02 15:04:05")))
+		limit.ExpiryNotifiedAt = now.Unix()
+		if err := s.save(ctx, limit); err != nil {
+			logger.GetLogger().ErrorWithContext(ctx, "更新到期提醒状态失败: 应用=%d, 错误: %v", limit.OrganizationApplicationId, err)
+		}
+	}
+
+	// 处理已到期的订阅
+	expired, err := s.appLimitRepo.GetExpired(ctx, now.Unix())
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		if err := s.handleExpired(ctx, &expired[i], now); err != nil {
+			logger.GetLogger().ErrorWithContext(ctx, "处理到期订阅失败: 应用=%d, 错误: %v", expired[i].OrganizationApplicationId, err)
+		}
+	}
+
+	return nil
+}
+
+// handleExpired 处理单个已到期的订阅
+func (s *subscriptionService) handleExpired(ctx context.Context, limit *model.OrganizationApplicationLimit, now time.Time) error {
+	// 自动续费
+	if limit.AutoRenew && limit.PlanId != 0 {
+		plan, err := s.planRepo.GetByID(ctx, limit.PlanId)
+		if err == nil {
+			err = s.renew(ctx, limit, plan, now)
+		}
+		if err == nil {
+			s.notify(ctx, limit, "subscription.renewed", "订阅已自动续费",
+				fmt.Sprintf("套餐 %s 已续费至 %s", limit.PlanName, time.Unix(limit.ExpiresAt, 0).Format("2006-01-02 15:04:05")))
+			return nil
+		}
+		logger.GetLogger().WarnWithContext(ctx, "自动续费失败: 应用=%d, 错误: %v", limit.OrganizationApplicationId, err)
+	}
+
+	// 未续费，进入宽限期
+	if limit.SubscriptionStatus != "grace" {
+		limit.SubscriptionStatus = "grace"
+		limit.GraceEndsAt = now.Add(s.gracePeriod).Unix()
+		if err := s.save(ctx, limit); err != nil {
+			return err
+		}
+		s.notify(ctx, limit, "subscription.grace_started", "订阅已到期",
+			fmt.Sprintf("套餐 %s 已到期，请在 %s 前续费，否则将降级为默认套餐", limit.PlanName, time.Unix(limit.GraceEndsAt, 0).Format("2006-01-02 15:04:05")))
+		return nil
+	}
+
+	// 宽限期未结束
+	if now.Unix() < limit.GraceEndsAt {
+		return nil
+	}
+
+	// 宽限期结束，降级为默认套餐
+	previousPlan, previousPlanID := limit.PlanName, limit.PlanId
+	applyPlan(limit, getDefaultPlan(ctx, s.planRepo))
+	limit.SubscriptionStatus = "active"
+	limit.ExpiresAt = 0
+	limit.AutoRenew = false
+	limit.GraceEndsAt = 0
+	limit.ExpiryNotifiedAt = 0
+	if err := s.save(ctx, limit); err != nil {
+		return err
+	}
+	recordPlanChange(ctx, s.planChangeRepo, limit.OrganizationApplicationId, previousPlanID, limit.PlanId)
+	s.notify(ctx, limit, "subscription.downgraded", "订阅已降级",
+		fmt.Sprintf("套餐 %s 未续费，已降级为 %s", previousPlan, limit.PlanName))
+	return nil
+}
+
+// prepare 获取并校验订阅目标套餐和应用当前限制
+func (s *subscriptionService) prepare(ctx context.Context, appID, planID int64) (*model.OrganizationApplicationLimit, *model.Plan, error) {
+	// 检查应用是否存在
+	_, err := s.appRepo.GetByID(ctx, appID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 检查套餐是否存在
+	plan, err := s.planRepo.GetByID(ctx, planID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 获取当前应用限制，不存在时创建
+	limit, err := s.appLimitRepo.GetByApplicationID(ctx, appID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		limit = &model.OrganizationApplicationLimit{OrganizationApplicationId: appID}
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if err := validatePlanChange(ctx, s.appMemberRepo, s.gaugeRepo, limit, plan); err != nil {
+		return nil, nil, err
+	}
+
+	return limit, plan, nil
+}
+
+// renew 续费一个计费周期
+func (s *subscriptionService) renew(ctx context.Context, limit *model.OrganizationApplicationLimit, plan *model.Plan, now time.Time) error {
+	// 未过期时从原到期时间顺延，否则从当前时间开始
+	periodStart := now
+	if limit.ExpiresAt > now.Unix() {
+		periodStart = time.Unix(limit.ExpiresAt, 0)
+	}
+
+	var paid *model.SubscriptionPayment
+	if plan.Price > 0 {
+		var err error
+		key := fmt.Sprintf("renew:%d:%d", limit.OrganizationApplicationId, limit.ExpiresAt)
+		paid, err = s.charge(ctx, limit.OrganizationApplicationId, plan, key)
+		if err != nil {
+			return err
+		}
+		// 该周期已续费，重新读取续费后的订阅
+		if paid.Status == model.PaymentStatusCompleted {
+			current, err := s.appLimitRepo.GetByApplicationID(ctx, limit.OrganizationApplicationId)
+			if err != nil {
+				return err
+			}
+			*limit = *current
+			return nil
+		}
+	}
+
+	fromPlanID := limit.PlanId
+	applyPlan(limit, plan)
+	limit.SubscriptionStatus = "active"
+	limit.ExpiresAt = nextPeriodEnd(periodStart, plan.BillingCycle).Unix()
+	limit.GraceEndsAt = 0
+	limit.ExpiryNotifiedAt = 0
+
+	if err := s.savePaid(ctx, limit, nil, paid); err != nil {
+		return err
+	}
+	recordPlanChange(ctx, s.planChangeRepo, limit.OrganizationApplicationId, fromPlanID, limit.PlanId)
+	return nil
+}
+
+// charge 按幂等键记录扣款并调用支付渠道扣款，已扣款或已完成的幂等键不再扣款
+func (s *subscriptionService) charge(ctx context.Context, appID int64, plan *model.Plan, idempotencyKey string) (*model.SubscriptionPayment, error) {
+	paid, err := s.paymentRepo.Begin(ctx, &model.SubscriptionPayment{
+		ApplicationId:  appID,
+		PlanId:         plan.ID,
+		IdempotencyKey: idempotencyKey,
+		Amount:         plan.Price,
+		Currency:       plan.Currency,
+		Status:         model.PaymentStatusPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if paid.ApplicationId != appID || paid.PlanId != plan.ID {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	switch paid.Status {
+	case model.PaymentStatusCharged, model.PaymentStatusCompleted:
+		return paid, nil
+	case model.PaymentStatusFailed, model.PaymentStatusRefunded:
+		// 上次扣款失败或已退款，重新扣款需要使用新的支付渠道幂等键
+		paid.Attempts++
+		paid.Status = model.PaymentStatusPending
+		if err := s.paymentRepo.Update(ctx, paid); err != nil {
+			return nil, err
+		}
+	}
+
+	// 待扣款的记录可能是上次请求在扣款后中断留下的，使用相同的支付渠道幂等键不会重复扣款
+	result, err := s.provider.Charge(ctx, &payment.ChargeRequest{
+		ApplicationId:  appID,
+		PlanId:         plan.ID,
+		Amount:         plan.Price,
+		Currency:       plan.Currency,
+		Description:    fmt.Sprintf("%s v%d", plan.Name, plan.Version),
+		IdempotencyKey: providerIdempotencyKey(paid),
+	})
+	if err != nil {
+		paid.Status = model.PaymentStatusFailed
+		if updateErr := s.paymentRepo.Update(ctx, paid); updateErr != nil {
+			logger.GetLogger().ErrorWithContext(ctx, "更新扣款记录失败: 幂等键=%s, 错误: %v", paid.IdempotencyKey, updateErr)
+		}
+		return nil, fmt.Errorf("扣款失败: %w", err)
+	}
+
+	paid.Status = model.PaymentStatusCharged
+	paid.Amount = result.Amount
+	paid.TransactionId = result.TransactionId
+	if err := s.paymentRepo.Update(ctx, paid); err != nil {
+		return nil, err
+	}
+	return paid, nil
+}
+
+// providerIdempotencyKey 支付渠道的幂等键，每次重新扣款使用不同的键
+func providerIdempotencyKey(paid *model.SubscriptionPayment) string {
+	if paid.Attempts == 0 {
+		return paid.IdempotencyKey
+	}
+	return fmt.Sprintf("%s:%d", paid.IdempotencyKey, paid.Attempts)
+}
+
+// savePaid 保存扣款后的应用限制，并在同一事务中将扣款记录标记为已完成，保存失败时退款，
+// plan 不为空时在锁定应用后重新校验套餐变更
+func (s *subscriptionService) savePaid(ctx context.Context, limit *model.OrganizationApplicationLimit, plan *model.Plan, paid *model.SubscriptionPayment) error {
+	if paid == nil {
+		return s.saveWith(ctx, limit, plan, nil)
+	}
+
+	err := s.saveWith(ctx, limit, plan, func(ctx context.Context) error {
+		paid.Status = model.PaymentStatusCompleted
+		return s.paymentRepo.Update(ctx, paid)
+	})
+	if err == nil {
+		return nil
+	}
+
+	paid.Status = model.PaymentStatusCharged
+	if refundErr := s.refund(ctx, paid); refundErr != nil {
+		// 退款失败时保留已扣款状态，重试时不再扣款，直接使套餐生效
+		logger.GetLogger().ErrorWithContext(ctx, "订阅生效失败后退款失败: 幂等键=%s, 交易号=%s, 错误: %v", paid.IdempotencyKey, paid.TransactionId, refundErr)
+	}
+	return err
+}
+
+// refund 退还扣款并将扣款记录标记为已退款
+func (s *subscriptionService) refund(ctx context.Context, paid *model.SubscriptionPayment) error {
+	err := s.provider.Refund(ctx, &payment.RefundRequest{
+		TransactionId:  paid.TransactionId,
+		Amount:         paid.Amount,
+		IdempotencyKey: "refund:" + providerIdempotencyKey(paid),
+	})
+	if err != nil {
+		return err
+	}
+
+	paid.Status = model.PaymentStatusRefunded
+	return s.paymentRepo.Update(ctx, paid)
+}
+
+// save 保存应用限制并写入限制变更事件，使权益缓存失效
+func (s *subscriptionService) save(ctx context.Context, limit *model.OrganizationApplicationLimit) error {
+	return s.saveWith(ctx, limit, nil, nil)
+}
+
+// saveWith 保存应用限制，inTx 不为空时在同一事务中执行。保存前锁定应用，plan 不为空时
+// 在锁内重新校验套餐变更，避免与并发添加成员交错后成员数超过目标套餐的最大用户数
+func (s *subscriptionService) saveWith(ctx context.Context, limit *model.OrganizationApplicationLimit, plan *model.Plan, inTx func(ctx context.Context) error) error {
+	var before *model.OrganizationApplicationLimit
+	if limit.ID != 0 {
+		before, _ = s.appLimitRepo.GetByApplicationID(ctx, limit.OrganizationApplicationId)
+	}
+
+	var app *model.OrganizationApplication
+	err := s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		app, err = s.appRepo.GetByIDForUpdate(ctx, limit.OrganizationApplicationId)
+		if err != nil {
+			return err
+		}
+		if plan != nil {
+			if err := validatePlanChange(ctx, s.appMemberRepo, s.gaugeRepo, limit, plan); err != nil {
+				return err
+			}
+		}
+
+		if limit.ID == 0 {
+			err = s.appLimitRepo.Create(ctx, limit)
+		} else {
+			err = s.appLimitRepo.Update(ctx, limit)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := recordLimitAudit(ctx, s.auditService, app.OrganizationId, "application_limit.update", before, limit); err != nil {
+			return err
+		}
+		if err := s.publisher.Publish(ctx, newLimitUpdatedEvent(app.OrganizationId, limit)); err != nil {
+			return err
+		}
+		if inTx != nil {
+			return inTx(ctx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	appEntitlementCache.invalidate(limit.OrganizationApplicationId)
+	appRateLimitCache.invalidate(limit.OrganizationApplicationId)
+	return nil
+}
+
+// notify 发送订阅相关通知，发送失败只记录日志
+func (s *subscriptionService) notify(ctx context.Context, limit *model.OrganizationApplicationLimit, notificationType, subject, content string) {
+	var orgID int64
+	if app, err := s.appRepo.GetByID(ctx, limit.OrganizationApplicationId); err == nil {
+		orgID = app.OrganizationId
+	}
+
+	err := s.notifier.Notify(ctx, &notify.Notification{
+		Type:           notificationType,
+		OrganizationId: orgID,
+		ApplicationId:  limit.OrganizationApplicationId,
+		Subject:        subject,
+		Content:        content,
+		Data: map[string]interface{}{
+			"plan_name":  limit.PlanName,
+			"expires_at": limit.ExpiresAt,
+		},
+	})
+	if err != nil {
+		logger.GetLogger().ErrorWithContext(ctx, "发送订阅通知失败: 应用=%d, 类型=%s, 错误: %v", limit.OrganizationApplicationId, notificationType, err)
+	}
+}
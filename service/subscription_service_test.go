@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"saas-account/payment"
+	"saas-account/repository"
+	"testing"
+	"time"
+)
+
+// fakeSubscriptionLimitRepo 支持保存的内存应用限制仓库，failSave 为保存时返回的错误，failGet 为读取时返回的错误
+type fakeSubscriptionLimitRepo struct {
+	fakeLimitRepo
+	failSave error
+	failGet  error
+}
+
+func (r *fakeSubscriptionLimitRepo) GetByApplicationID(ctx context.Context, appID int64) (*model.OrganizationApplicationLimit, error) {
+	if r.failGet != nil {
+		return nil, r.failGet
+	}
+	limit, err := r.fakeLimitRepo.GetByApplicationID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	copied := *limit
+	return &copied, nil
+}
+
+func (r *fakeSubscriptionLimitRepo) Create(ctx context.Context, limit *model.OrganizationApplicationLimit) error {
+	if r.failSave != nil {
+		return r.failSave
+	}
+	limit.ID = nextFixtureID()
+	return r.Update(ctx, limit)
+}
+
+func (r *fakeSubscriptionLimitRepo) Update(ctx context.Context, limit *model.OrganizationApplicationLimit) error {
+	if r.failSave != nil {
+		return r.failSave
+	}
+	saved := *limit
+	r.limits[limit.OrganizationApplicationId] = &saved
+	return nil
+}
+
+// fakeMemberCountRepo 成员数固定的应用成员仓库
+type fakeMemberCountRepo struct {
+	repository.OrganizationApplicationMemberRepository
+}
+
+func (r *fakeMemberCountRepo) GetByApplication(ctx context.Context, appID int64, page, pageSize int) ([]model.OrganizationApplicationMember, int64, error) {
+	return nil, 1, nil
+}
+
+// fakeRecordingPlanChangeRepo 记录套餐变更的仓库
+type fakeRecordingPlanChangeRepo struct {
+	fakePlanChangeRepo
+}
+
+func (r *fakeRecordingPlanChangeRepo) Create(ctx context.Context, change *model.PlanChange) error {
+	change.ID = nextFixtureID()
+	r.changes = append(r.changes, *change)
+	return nil
+}
+
+// fakeEventPublisher 丢弃事件的发布器
+type fakeEventPublisher struct{}
+
+func (p *fakeEventPublisher) Publish(ctx context.Context, event Event) error {
+	return nil
+}
+
+// fakePaymentRepo 内存中的订阅扣款记录仓库
+type fakePaymentRepo struct {
+	payments map[string]*model.SubscriptionPayment
+}
+
+func (r *fakePaymentRepo) Begin(ctx context.Context, p *model.SubscriptionPayment) (*model.SubscriptionPayment, error) {
+	if _, ok := r.payments[p.IdempotencyKey]; !ok {
+		p.ID = nextFixtureID()
+		saved := *p
+		r.payments[p.IdempotencyKey] = &saved
+	}
+	existing := *r.payments[p.IdempotencyKey]
+	return &existing, nil
+}
+
+func (r *fakePaymentRepo) Update(ctx context.Context, p *model.SubscriptionPayment) error {
+	saved := *p
+	r.payments[p.IdempotencyKey] = &saved
+	return nil
+}
+
+// countingProvider 记录实际发往支付渠道的扣款次数
+type countingProvider struct {
+	*payment.FakeProvider
+	charges int
+}
+
+func (p *countingProvider) Charge(ctx context.Context, req *payment.ChargeRequest) (*payment.ChargeResult, error) {
+	p.charges++
+	return p.FakeProvider.Charge(ctx, req)
+}
+
+// subscriptionFixture 订阅服务的测试环境，应用已有限制，套餐2为付费套餐
+type subscriptionFixture struct {
+	service  SubscriptionService
+	appID    int64
+	limits   *fakeSubscriptionLimitRepo
+	payments *fakePaymentRepo
+	provider *countingProvider
+}
+
+func newSubscriptionFixture(t *testing.T) *subscriptionFixture {
+	t.Helper()
+
+	fa := newFixtureApp("UTC", &model.OrganizationApplicationLimit{PlanId: 1, SubscriptionStatus: "active"})
+	appID := fa.app.ID
+	fa.limits.limits[appID].ID = appID
+	limits := &fakeSubscriptionLimitRepo{fakeLimitRepo: *fa.limits}
+	plans := &fakePlanRepo{plans: map[int64]*model.Plan{
+		1: {Base: model.Base{ID: 1}, Name: "免费版", Status: "active", MaxUsers: 5},
+		2: {Base: model.Base{ID: 2}, Name: "专业版", Status: "active", MaxUsers: 50, Price: 9900, Currency: "CNY", BillingCycle: "monthly"},
+	}}
+	payments := &fakePaymentRepo{payments: map[string]*model.SubscriptionPayment{}}
+	provider := &countingProvider{FakeProvider: payment.NewFakeProvider()}
+
+	svc := NewSubscriptionService(
+		fa.apps,
+		&fakeMemberCountRepo{},
+		limits,
+		plans,
+		&fakeRecordingPlanChangeRepo{},
+		provider,
+		&recordingNotifier{},
+		time.Hour,
+		time.Hour,
+		&fakeEventPublisher{},
+		newFakeTxManager(),
+		&fakeAuditService{},
+		payments,
+		&fakeGaugeRepo{},
+	)
+	return &subscriptionFixture{service: svc, appID: appID, limits: limits, payments: payments, provider: provider}
+}
+
+// payment 获取应用唯一的扣款记录
+func (f *subscriptionFixture) payment(t *testing.T) *model.SubscriptionPayment {
+	t.Helper()
+	if len(f.payments.payments) != 1 {
+		t.Fatalf("扣款记录数 = %d, 期望 1", len(f.payments.payments))
+	}
+	for _, p := range f.payments.payments {
+		return p
+	}
+	return nil
+}
+
+func TestSubscribeRetryChargesOnce(t *testing.T) {
+	for _, clientKey := range []string{"", "client-key"} {
+		t.Run("幂等键="+clientKey, func(t *testing.T) {
+			f := newSubscriptionFixture(t)
+			ctx := context.Background()
+
+			first, err := f.service.Subscribe(ctx, f.appID, 2, true, clientKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 重试时不再扣款，返回已生效的订阅
+			second, err := f.service.Subscribe(ctx, f.appID, 2, true, clientKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if f.provider.charges != 1 {
+				t.Errorf("扣款次数 = %d, 期望 1", f.provider.charges)
+			}
+			if first.PlanId != 2 || second.PlanId != 2 || second.ExpiresAt != first.ExpiresAt {
+				t.Errorf("第一次 = %+v, 第二次 = %+v", first, second)
+			}
+			if p := f.payment(t); p.Status != model.PaymentStatusCompleted || p.TransactionId == "" {
+				t.Errorf("扣款记录 = %+v, 期望已完成", p)
+			}
+		})
+	}
+}
+
+func TestSubscribeSaveFailureRefunds(t *testing.T) {
+	f := newSubscriptionFixture(t)
+	ctx := context.Background()
+
+	// 扣款后保存失败时退款
+	f.limits.failSave = errInjected
+	if _, err := f.service.Subscribe(ctx, f.appID, 2, false, "key"); !errors.Is(err, errInjected) {
+		t.Fatalf("错误 = %v, 期望 %v", err, errInjected)
+	}
+	p := f.payment(t)
+	if p.Status != model.PaymentStatusRefunded || f.provider.Refunded(p.TransactionId) != 9900 {
+		t.Fatalf("扣款记录 = %+v, 退款金额 = %d", p, f.provider.Refunded(p.TransactionId))
+	}
+	if f.limits.limits[f.appID].PlanId != 1 {
+		t.Errorf("保存失败后套餐 = %d, 期望仍为 1", f.limits.limits[f.appID].PlanId)
+	}
+
+	// 已退款的幂等键重试时使用新的支付渠道幂等键重新扣款
+	f.limits.failSave = nil
+	if _, err := f.service.Subscribe(ctx, f.appID, 2, false, "key"); err != nil {
+		t.Fatal(err)
+	}
+	retried := f.payment(t)
+	if f.provider.charges != 2 || retried.Attempts != 1 || retried.TransactionId == p.TransactionId || retried.Status != model.PaymentStatusCompleted {
+		t.Errorf("扣款次数 = %d, 扣款记录 = %+v", f.provider.charges, retried)
+	}
+	if f.limits.limits[f.appID].PlanId != 2 {
+		t.Errorf("重试后套餐 = %d, 期望 2", f.limits.limits[f.appID].PlanId)
+	}
+}
+
+func TestSubscribeChargeFailure(t *testing.T) {
+	f := newSubscriptionFixture(t)
+	ctx := context.Background()
+
+	f.provider.SetFailure(f.appID, true)
+	if _, err := f.service.Subscribe(ctx, f.appID, 2, false, "key"); err == nil {
+		t.Fatal("扣款失败时没有返回错误")
+	}
+	if p := f.payment(t); p.Status != model.PaymentStatusFailed {
+		t.Errorf("扣款记录状态 = %s, 期望 failed", p.Status)
+	}
+
+	// 同一幂等键用于其他套餐时拒绝
+	f.provider.SetFailure(f.appID, false)
+	key := subscribeIdempotencyKey(f.appID, 2, "other", 0, time.Now())
+	f.payments.payments[key] = &model.SubscriptionPayment{ApplicationId: f.appID, PlanId: 3, IdempotencyKey: key}
+	if _, err := f.service.Subscribe(ctx, f.appID, 2, false, "other"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("错误 = %v, 期望 %v", err, ErrIdempotencyKeyReused)
+	}
+}
+
+func TestSubscribeLimitLookupError(t *testing.T) {
+	f := newSubscriptionFixture(t)
+
+	// 读取限制出错时返回错误，不当作没有限制再创建一条
+	f.limits.failGet = errInjected
+	if _, err := f.service.Subscribe(context.Background(), f.appID, 2, false, ""); !errors.Is(err, errInjected) {
+		t.Fatalf("错误 = %v, 期望 %v", err, errInjected)
+	}
+	if f.provider.charges != 0 {
+		t.Errorf("扣款次数 = %d, 期望 0", f.provider.charges)
+	}
+}
+
+func TestSubscribeAgainAfterPlanSwitch(t *testing.T) {
+	for _, clientKey := range []string{"", "client-key"} {
+		t.Run("幂等键="+clientKey, func(t *testing.T) {
+			f := newSubscriptionFixture(t)
+			ctx := context.Background()
+
+			if _, err := f.service.Subscribe(ctx, f.appID, 2, false, clientKey); err != nil {
+				t.Fatal(err)
+			}
+			// 当天切换回免费版后再订阅专业版
+			if _, err := f.service.Subscribe(ctx, f.appID, 1, false, ""); err != nil {
+				t.Fatal(err)
+			}
+			limit, err := f.service.Subscribe(ctx, f.appID, 2, false, clientKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 未提供幂等键时重新扣款，重试同一幂等键时使用已完成的扣款，两种情况专业版都重新生效
+			wantCharges := 2
+			if clientKey != "" {
+				wantCharges = 1
+			}
+			if f.provider.charges != wantCharges {
+				t.Errorf("扣款次数 = %d, 期望 %d", f.provider.charges, wantCharges)
+			}
+			if limit.PlanId != 2 || f.limits.limits[f.appID].PlanId != 2 {
+				t.Errorf("再次订阅后套餐 = %d, 保存的套餐 = %d, 期望 2", limit.PlanId, f.limits.limits[f.appID].PlanId)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"saas-account/model"
+	"saas-account/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 回收站恢复和彻底删除时的冲突错误
+var (
+	ErrRestoreEmailTaken   = errors.New("邮箱已被其他用户注册，无法恢复")
+	ErrRestorePhoneTaken   = errors.New("手机号已被其他用户注册，无法恢复")
+	ErrRestoreOwnerMissing = errors.New("组织拥有者不存在，无法恢复")
+	ErrRestoreOrgMissing   = errors.New("应用所属组织不存在，无法恢复")
+	ErrRestoreAppKeyTaken  = errors.New("AppKey已被其他应用使用，无法恢复")
+	ErrPurgeOwnerOfLiveOrg = errors.New("用户仍是组织的拥有者，请先转移或删除组织后再彻底删除")
+)
+
+// TrashService 回收站服务接口，管理已软删除的用户、组织和应用
+type TrashService interface {
+	ListDeletedUsers(ctx context.Context, page, pageSize int) ([]model.User, int64, error)
+	RestoreUser(ctx context.Context, id int64) error
+	PurgeUser(ctx context.Context, id int64) error
+	ListDeletedOrganizations(ctx context.Context, page, pageSize int) ([]model.Organization, int64, error)
+	RestoreOrganization(ctx context.Context, id int64) error
+	PurgeOrganization(ctx context.Context, id int64) error
+	ListDeletedApplications(ctx context.Context, page, pageSize int) ([]model.OrganizationApplication, int64, error)
+	RestoreApplication(ctx context.Context, id int64) error
+	PurgeApplication(ctx context.Context, id int64) error
+	PurgeExpired(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+// trashService 回收站服务实现
+type trashService struct {
+	userRepo      repository.UserRepository
+	orgRepo       repository.OrganizationRepository
+	appRepo       repository.OrganizationApplicationRepository
+	orgMemberRepo repository.OrganizationMemberRepository
+	txManager     repository.TransactionManager
+	appMemberRepo repository.OrganizationApplicationMemberRepository
+}
+
+// NewTrashService 创建回收站服务
+func NewTrashService(
+	userRepo repository.UserRepository,
+	orgRepo repository.OrganizationRepository,
+	appRepo repository.OrganizationApplicationRepository,
+	orgMemberRepo repository.OrganizationMemberRepository,
+	txManager repository.TransactionManager,
+	appMemberRepo repository.OrganizationApplicationMemberRepository,
+) TrashService {
+	return &trashService{
+		userRepo:      userRepo,
+		orgRepo:       orgRepo,
+		appRepo:       appRepo,
+		orgMemberRepo: orgMemberRepo,
+		txManager:     txManager,
+		appMemberRepo: appMemberRepo,
+	}
+}
+
+// ListDeletedUsers 获取已删除的用户列表
+func (s *trashService) ListDeletedUsers(ctx context.Context, page, pageSize int) ([]model.User, int64, error) {
+	return s.userRepo.ListDeleted(ctx, page, pageSize)
+}
+
+// RestoreUser 恢复已删除的用户，检查和恢复在同一事务中执行
+func (s *trashService) RestoreUser(ctx context.Context, id int64) error {
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 获取已删除的用户
+		user, err := s.userRepo.GetDeletedByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// 检查邮箱是否已被其他用户使用
+		_, err = s.userRepo.GetByEmail(ctx, user.Email)
+		if err := checkUnique(err, ErrRestoreEmailTaken); err != nil {
+			return err
+		}
+
+		// 检查手机号是否已被其他用户使用
+		if user.Phone != "" {
+			_, err = s.userRepo.GetByPhone(ctx, user.Phone)
+			if err := checkUnique(err, ErrRestorePhoneTaken); err != nil {
+				return err
+			}
+		}
+
+		return s.userRepo.Restore(ctx, id)
+	})
+}
+
+// PurgeUser 彻底删除用户，连同用户的组织成员和应用成员身份，以及用户拥有的已删除组织及其数据在同一事务中删除
+// 用户仍是未删除组织的拥有者时不能彻底删除
+func (s *trashService) PurgeUser(ctx context.Context, id int64) error {
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		orgs, err := s.orgRepo.GetByOwnerID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(orgs) > 0 {
+			return ErrPurgeOwnerOfLiveOrg
+		}
+
+		if err := s.userRepo.Purge(ctx, id); err != nil {
+			return err
+		}
+		_, err = s.purgeUserData(ctx, []int64{id})
+		return err
+	})
+}
+
+// purgeUserData 彻底删除用户的组织成员和应用成员身份，以及用户拥有的已删除组织及其数据，返回删除的组织数和应用数
+func (s *trashService) purgeUserData(ctx context.Context, userIDs []int64) (int64, error) {
+	if err := s.orgMemberRepo.PurgeByUsers(ctx, userIDs); err != nil {
+		return 0, err
+	}
+	if err := s.appMemberRepo.PurgeByUsers(ctx, userIDs); err != nil {
+		return 0, err
+	}
+
+	orgIDs, err := s.orgRepo.PurgeByOwners(ctx, userIDs)
+	if err != nil {
+		return 0, err
+	}
+	apps, err := s.purgeOrganizationData(ctx, orgIDs)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(orgIDs)) + apps, nil
+}
+
+// ListDeletedOrganizations 获取已删除的组织列表
+func (s *trashService) ListDeletedOrganizations(ctx context.Context, page, pageSize int) ([]model.Organization, int64, error) {
+	return s.orgRepo.ListDeleted(ctx, page, pageSize)
+}
+
+// RestoreOrganization 恢复已删除的组织，检查和恢复在同一事务中执行
+func (s *trashService) RestoreOrganization(ctx context.Context, id int64) error {
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 获取已删除的组织
+		org, err := s.orgRepo.GetDeletedByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// 检查组织拥有者是否存在
+		if _, err := s.userRepo.GetByID(ctx, org.OwnerId); err != nil {
+			return notFoundAs(err, ErrRestoreOwnerMissing)
+		}
+
+		return s.orgRepo.Restore(ctx, id)
+	})
+}
+
+// PurgeOrganization 彻底删除组织及其应用、成员和应用数据
+func (s *trashService) PurgeOrganization(ctx context.Context, id int64) error {
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.orgRepo.Purge(ctx, id); err != nil {
+			return err
+		}
+		_, err := s.purgeOrganizationData(ctx, []int64{id})
+		return err
+	})
+}
+
+// purgeOrganizationData 彻底删除组织的全部应用、应用数据和成员，返回删除的应用数
+func (s *trashService) purgeOrganizationData(ctx context.Context, orgIDs []int64) (int64, error) {
+	appIDs, err := s.appRepo.PurgeByOrganizations(ctx, orgIDs)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.appRepo.PurgeData(ctx, appIDs); err != nil {
+		return 0, err
+	}
+	if err := s.orgMemberRepo.PurgeByOrganizations(ctx, orgIDs); err != nil {
+		return 0, err
+	}
+	return int64(len(appIDs)), nil
+}
+
+// ListDeletedApplications 获取已删除的应用列表
+func (s *trashService) ListDeletedApplications(ctx context.Context, page, pageSize int) ([]model.OrganizationApplication, int64, error) {
+	return s.appRepo.ListDeleted(ctx, page, pageSize)
+}
+
+// RestoreApplication 恢复已删除的应用，检查和恢复在同一事务中执行
+func (s *trashService) RestoreApplication(ctx context.Context, id int64) error {
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 获取已删除的应用
+		app, err := s.appRepo.GetDeletedByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// 检查所属组织是否存在
+		if _, err := s.orgRepo.GetByID(ctx, app.OrganizationId); err != nil {
+			return notFoundAs(err, ErrRestoreOrgMissing)
+		}
+
+		// 检查AppKey是否已被其他应用使用
+		_, err = s.appRepo.GetByAppKey(ctx, app.AppKey)
+		if err := checkUnique(err, ErrRestoreAppKeyTaken); err != nil {
+			return err
+		}
+
+		return s.appRepo.Restore(ctx, id)
+	})
+}
+
+// PurgeApplication 彻底删除应用及其限制、成员、使用量和计数器等数据
+func (s *trashService) PurgeApplication(ctx context.Context, id int64) error {
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.appRepo.Purge(ctx, id); err != nil {
+			return err
+		}
+		return s.appRepo.PurgeData(ctx, []int64{id})
+	})
+}
+
+// PurgeExpired 彻底删除超过保留期限的已删除数据，返回删除总数
+func (s *trashService) PurgeExpired(ctx context.Context, retention time.Duration) (int64, error) {
+	before := time.Now().Add(-retention)
+
+	// 应用和组织连同其数据在同一事务中删除，不会留下没有所属应用或组织的数据
+	var apps, orgs int64
+	err := s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		appIDs, err := s.appRepo.PurgeDeletedBefore(ctx, before)
+		if err != nil {
+			return err
+		}
+		if err := s.appRepo.PurgeData(ctx, appIDs); err != nil {
+			return err
+		}
+
+		orgIDs, err := s.orgRepo.PurgeDeletedBefore(ctx, before)
+		if err != nil {
+			return err
+		}
+		orgApps, err := s.purgeOrganizationData(ctx, orgIDs)
+		if err != nil {
+			return err
+		}
+
+		apps = int64(len(appIDs)) + orgApps
+		orgs = int64(len(orgIDs))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// 用户连同其成员身份和拥有的已删除组织在同一事务中删除
+	var users, userData int64
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		userIDs, err := s.userRepo.PurgeDeletedBefore(ctx, before)
+		if err != nil {
+			return err
+		}
+		userData, err = s.purgeUserData(ctx, userIDs)
+		users = int64(len(userIDs))
+		return err
+	})
+	if err != nil {
+		return apps + orgs, err
+	}
+
+	return apps + orgs + users + userData, nil
+}
+
+// checkUnique 检查唯一字段的查询结果，记录不存在时返回nil，已被其他记录占用时返回 conflict，查询出错时返回原错误
+func checkUnique(err, conflict error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return conflict
+}
+
+// notFoundAs 记录不存在时返回 notFound，否则返回原错误
+func notFoundAs(err, notFound error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound
+	}
+	return err
+}
@@ -0,0 +1,452 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"saas-account/model"
+	"saas-account/repository"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// trashState 回收站测试数据：应用所属组织、应用数据行数、组织成员数、组织拥有者、用户的成员身份数和已删除标记
+type trashState struct {
+	appOrg       map[int64]int64
+	appData      map[int64]int
+	members      map[int64]int
+	deletedApps  map[int64]bool
+	deletedOrgs  map[int64]bool
+	orgs         map[int64]bool
+	orgOwner     map[int64]int64
+	users        map[int64]bool
+	deletedUsers map[int64]bool
+	userOrgs     map[int64]int
+	userApps     map[int64]int
+}
+
+// clone 复制测试数据，用于事务回滚
+func (s *trashState) clone() *trashState {
+	c := &trashState{
+		appOrg: map[int64]int64{}, appData: map[int64]int{}, members: map[int64]int{},
+		deletedApps: map[int64]bool{}, deletedOrgs: map[int64]bool{}, orgs: map[int64]bool{},
+		orgOwner: map[int64]int64{}, users: map[int64]bool{}, deletedUsers: map[int64]bool{},
+		userOrgs: map[int64]int{}, userApps: map[int64]int{},
+	}
+	for k, v := range s.appOrg {
+		c.appOrg[k] = v
+	}
+	for k, v := range s.appData {
+		c.appData[k] = v
+	}
+	for k, v := range s.members {
+		c.members[k] = v
+	}
+	for k, v := range s.deletedApps {
+		c.deletedApps[k] = v
+	}
+	for k, v := range s.deletedOrgs {
+		c.deletedOrgs[k] = v
+	}
+	for k, v := range s.orgs {
+		c.orgs[k] = v
+	}
+	for k, v := range s.orgOwner {
+		c.orgOwner[k] = v
+	}
+	for k, v := range s.users {
+		c.users[k] = v
+	}
+	for k, v := range s.deletedUsers {
+		c.deletedUsers[k] = v
+	}
+	for k, v := range s.userOrgs {
+		c.userOrgs[k] = v
+	}
+	for k, v := range s.userApps {
+		c.userApps[k] = v
+	}
+	return c
+}
+
+// trashStore 回收站测试仓库共享的数据，failData 为true时删除应用数据失败，lookupErr 不为nil时按唯一字段查询失败，
+// takenEmails 是已被其他用户使用的邮箱
+type trashStore struct {
+	state       *trashState
+	failData    bool
+	lookupErr   error
+	takenEmails map[string]bool
+}
+
+// snapshot 保存当前数据，返回恢复函数，用于假事务回滚
+func (s *trashStore) snapshot() func() {
+	saved := s.state.clone()
+	return func() { s.state = saved }
+}
+
+// fakeTrashAppRepo 回收站测试的应用仓库
+type fakeTrashAppRepo struct {
+	repository.OrganizationApplicationRepository
+	store *trashStore
+}
+
+func (r *fakeTrashAppRepo) Purge(ctx context.Context, id int64) error {
+	if !r.store.state.deletedApps[id] {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.state.appOrg, id)
+	return nil
+}
+
+func (r *fakeTrashAppRepo) GetDeletedByID(ctx context.Context, id int64) (*model.OrganizationApplication, error) {
+	orgID, ok := r.store.state.appOrg[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &model.OrganizationApplication{Base: model.Base{ID: id}, OrganizationId: orgID}, nil
+}
+
+func (r *fakeTrashAppRepo) PurgeDeletedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	var ids []int64
+	for id := range r.store.state.deletedApps {
+		if _, ok := r.store.state.appOrg[id]; ok {
+			delete(r.store.state.appOrg, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeTrashAppRepo) PurgeByOrganizations(ctx context.Context, orgIDs []int64) ([]int64, error) {
+	var ids []int64
+	for _, orgID := range orgIDs {
+		for id, appOrg := range r.store.state.appOrg {
+			if appOrg == orgID {
+				delete(r.store.state.appOrg, id)
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeTrashAppRepo) PurgeData(ctx context.Context, ids []int64) error {
+	if r.store.failData {
+		return errInjected
+	}
+	for _, id := range ids {
+		delete(r.store.state.appData, id)
+	}
+	return nil
+}
+
+// fakeTrashOrgRepo 回收站测试的组织仓库
+type fakeTrashOrgRepo struct {
+	repository.OrganizationRepository
+	store *trashStore
+}
+
+func (r *fakeTrashOrgRepo) Purge(ctx context.Context, id int64) error {
+	if !r.store.state.deletedOrgs[id] || !r.store.state.orgs[id] {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.state.orgs, id)
+	return nil
+}
+
+func (r *fakeTrashOrgRepo) PurgeDeletedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	var ids []int64
+	for id := range r.store.state.deletedOrgs {
+		if r.store.state.orgs[id] {
+			delete(r.store.state.orgs, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeTrashOrgRepo) GetByOwnerID(ctx context.Context, ownerID int64) ([]model.Organization, error) {
+	var orgs []model.Organization
+	for id, owner := range r.store.state.orgOwner {
+		if owner == ownerID && r.store.state.orgs[id] && !r.store.state.deletedOrgs[id] {
+			orgs = append(orgs, model.Organization{Base: model.Base{ID: id}, OwnerId: owner})
+		}
+	}
+	return orgs, nil
+}
+
+func (r *fakeTrashOrgRepo) PurgeByOwners(ctx context.Context, ownerIDs []int64) ([]int64, error) {
+	var ids []int64
+	for _, ownerID := range ownerIDs {
+		for id, owner := range r.store.state.orgOwner {
+			if owner == ownerID && r.store.state.orgs[id] && r.store.state.deletedOrgs[id] {
+				delete(r.store.state.orgs, id)
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeTrashOrgRepo) GetByID(ctx context.Context, id int64) (*model.Organization, error) {
+	if r.store.lookupErr != nil {
+		return nil, r.store.lookupErr
+	}
+	if !r.store.state.orgs[id] || r.store.state.deletedOrgs[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &model.Organization{Base: model.Base{ID: id}, OwnerId: r.store.state.orgOwner[id]}, nil
+}
+
+// fakeTrashMemberRepo 回收站测试的组织成员仓库
+type fakeTrashMemberRepo struct {
+	repository.OrganizationMemberRepository
+	store *trashStore
+}
+
+func (r *fakeTrashMemberRepo) PurgeByOrganizations(ctx context.Context, orgIDs []int64) error {
+	for _, id := range orgIDs {
+		delete(r.store.state.members, id)
+	}
+	return nil
+}
+
+func (r *fakeTrashMemberRepo) PurgeByUsers(ctx context.Context, userIDs []int64) error {
+	for _, id := range userIDs {
+		delete(r.store.state.userOrgs, id)
+	}
+	return nil
+}
+
+// fakeTrashAppMemberRepo 回收站测试的应用成员仓库
+type fakeTrashAppMemberRepo struct {
+	repository.OrganizationApplicationMemberRepository
+	store *trashStore
+}
+
+func (r *fakeTrashAppMemberRepo) PurgeByUsers(ctx context.Context, userIDs []int64) error {
+	for _, id := range userIDs {
+		delete(r.store.state.userApps, id)
+	}
+	return nil
+}
+
+// fakeTrashUserRepo 回收站测试的用户仓库，用户 id 的邮箱为 user<id>@example.com
+type fakeTrashUserRepo struct {
+	repository.UserRepository
+	store *trashStore
+}
+
+func (r *fakeTrashUserRepo) GetDeletedByID(ctx context.Context, id int64) (*model.User, error) {
+	if !r.store.state.users[id] || !r.store.state.deletedUsers[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &model.User{Base: model.Base{ID: id}, Email: fmt.Sprintf("user%d@example.com", id)}, nil
+}
+
+func (r *fakeTrashUserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	if r.store.lookupErr != nil {
+		return nil, r.store.lookupErr
+	}
+	if !r.store.takenEmails[email] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &model.User{Email: email}, nil
+}
+
+func (r *fakeTrashUserRepo) Restore(ctx context.Context, id int64) error {
+	delete(r.store.state.deletedUsers, id)
+	return nil
+}
+
+func (r *fakeTrashUserRepo) Purge(ctx context.Context, id int64) error {
+	if !r.store.state.users[id] || !r.store.state.deletedUsers[id] {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.state.users, id)
+	return nil
+}
+
+func (r *fakeTrashUserRepo) PurgeDeletedBefore(ctx context.Context, before time.Time) ([]int64, error) {
+	var ids []int64
+	for id := range r.store.state.deletedUsers {
+		if !r.store.state.users[id] {
+			continue
+		}
+		ownsLiveOrg := false
+		for orgID, owner := range r.store.state.orgOwner {
+			if owner == id && r.store.state.orgs[orgID] && !r.store.state.deletedOrgs[orgID] {
+				ownsLiveOrg = true
+			}
+		}
+		if !ownsLiveOrg {
+			delete(r.store.state.users, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// newTrashFixture 创建回收站服务：组织1已删除，有应用10、11；组织2有已删除的应用20和正常的应用21；
+// 已删除的用户101拥有组织1，已删除的用户102不拥有组织，已删除的用户104拥有组织2
+func newTrashFixture() (TrashService, *trashStore) {
+	store := &trashStore{state: &trashState{
+		appOrg:       map[int64]int64{10: 1, 11: 1, 20: 2, 21: 2},
+		appData:      map[int64]int{10: 3, 11: 2, 20: 4, 21: 1},
+		members:      map[int64]int{1: 2, 2: 3},
+		deletedApps:  map[int64]bool{20: true},
+		deletedOrgs:  map[int64]bool{1: true},
+		orgs:         map[int64]bool{1: true, 2: true},
+		orgOwner:     map[int64]int64{1: 101, 2: 104},
+		users:        map[int64]bool{101: true, 102: true, 103: true, 104: true},
+		deletedUsers: map[int64]bool{101: true, 102: true, 104: true},
+		userOrgs:     map[int64]int{101: 1, 102: 2, 103: 1, 104: 1},
+		userApps:     map[int64]int{101: 1, 102: 1, 103: 2},
+	}}
+	svc := NewTrashService(&fakeTrashUserRepo{store: store}, &fakeTrashOrgRepo{store: store}, &fakeTrashAppRepo{store: store},
+		&fakeTrashMemberRepo{store: store}, newFakeTxManager(store), &fakeTrashAppMemberRepo{store: store})
+	return svc, store
+}
+
+func TestPurgeOrganizationCascades(t *testing.T) {
+	svc, store := newTrashFixture()
+	if err := svc.PurgeOrganization(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 组织的应用、应用数据和成员一起删除，其他组织不受影响
+	state := store.state
+	if state.orgs[1] || len(state.appOrg) != 2 || state.appData[10] != 0 || state.appData[11] != 0 || state.members[1] != 0 {
+		t.Errorf("删除组织后的数据 = %+v", state)
+	}
+	if state.appData[20] != 4 || state.appData[21] != 1 || state.members[2] != 3 {
+		t.Errorf("其他组织的数据被删除: %+v", state)
+	}
+
+	// 未删除的组织不能彻底删除
+	if err := svc.PurgeOrganization(context.Background(), 2); err != gorm.ErrRecordNotFound {
+		t.Errorf("错误 = %v, 期望 %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestPurgeApplicationCascades(t *testing.T) {
+	svc, store := newTrashFixture()
+	if err := svc.PurgeApplication(context.Background(), 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.state.appOrg[20]; ok || store.state.appData[20] != 0 || store.state.appData[21] != 1 {
+		t.Errorf("删除应用后的数据 = %+v", store.state)
+	}
+
+	// 未删除的应用不能彻底删除
+	if err := svc.PurgeApplication(context.Background(), 21); err != gorm.ErrRecordNotFound {
+		t.Errorf("错误 = %v, 期望 %v", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func TestPurgeRollsBack(t *testing.T) {
+	svc, store := newTrashFixture()
+	store.failData = true
+
+	// 删除应用数据失败时，组织、应用和成员都不删除
+	if err := svc.PurgeOrganization(context.Background(), 1); err != errInjected {
+		t.Fatalf("错误 = %v, 期望 %v", err, errInjected)
+	}
+	if err := svc.PurgeApplication(context.Background(), 20); err != errInjected {
+		t.Fatalf("错误 = %v, 期望 %v", err, errInjected)
+	}
+	if _, err := svc.PurgeExpired(context.Background(), time.Hour); err != errInjected {
+		t.Fatalf("错误 = %v, 期望 %v", err, errInjected)
+	}
+
+	// 删除用户拥有的组织的应用数据失败时，用户和成员身份也不删除
+	if err := svc.PurgeUser(context.Background(), 101); err != errInjected {
+		t.Fatalf("错误 = %v, 期望 %v", err, errInjected)
+	}
+	if !store.state.users[101] || store.state.userOrgs[101] != 1 || store.state.userApps[101] != 1 {
+		t.Errorf("回滚后的用户数据 = %+v", store.state)
+	}
+	if !store.state.orgs[1] || len(store.state.appOrg) != 4 || store.state.members[1] != 2 {
+		t.Errorf("回滚后的数据 = %+v", store.state)
+	}
+}
+
+func TestPurgeExpiredCascades(t *testing.T) {
+	svc, store := newTrashFixture()
+	purged, err := svc.PurgeExpired(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 已删除的应用20，已删除组织1和它的应用10、11，以及已删除的用户101、102；用户104仍拥有组织2，保留
+	if purged != 6 {
+		t.Errorf("删除数 = %d, 期望 6", purged)
+	}
+	state := store.state
+	if len(state.appOrg) != 1 || state.appData[21] != 1 || len(state.appData) != 1 || state.members[1] != 0 || state.members[2] != 3 {
+		t.Errorf("清理后的数据 = %+v", state)
+	}
+	if state.users[101] || state.users[102] || !state.users[104] || state.userOrgs[101] != 0 || state.userApps[102] != 0 || state.userOrgs[104] != 1 {
+		t.Errorf("清理后的用户数据 = %+v", state)
+	}
+}
+
+func TestPurgeUserCascades(t *testing.T) {
+	svc, store := newTrashFixture()
+	if err := svc.PurgeUser(context.Background(), 101); err != nil {
+		t.Fatal(err)
+	}
+
+	// 用户的成员身份，以及用户拥有的已删除组织1和它的应用、成员一起删除
+	state := store.state
+	if state.users[101] || state.userOrgs[101] != 0 || state.userApps[101] != 0 {
+		t.Errorf("删除用户后的用户数据 = %+v", state)
+	}
+	if state.orgs[1] || state.appData[10] != 0 || state.appData[11] != 0 || state.members[1] != 0 {
+		t.Errorf("删除用户后的组织数据 = %+v", state)
+	}
+	if !state.orgs[2] || state.appData[21] != 1 || state.userOrgs[102] != 2 || state.userApps[103] != 2 {
+		t.Errorf("其他数据被删除: %+v", state)
+	}
+
+	// 仍拥有未删除组织的用户不能彻底删除
+	if err := svc.PurgeUser(context.Background(), 104); err != ErrPurgeOwnerOfLiveOrg {
+		t.Errorf("错误 = %v, 期望 %v", err, ErrPurgeOwnerOfLiveOrg)
+	}
+	if !state.users[104] || state.userOrgs[104] != 1 {
+		t.Errorf("拥有组织的用户被删除: %+v", state)
+	}
+}
+
+func TestRestoreLookupErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		lookupErr error
+		taken     bool
+		restore   func(svc TrashService) error
+		want      error
+	}{
+		{name: "邮箱未被使用时恢复用户", restore: func(svc TrashService) error { return svc.RestoreUser(context.Background(), 102) }},
+		{name: "邮箱已被使用时不恢复用户", taken: true, want: ErrRestoreEmailTaken,
+			restore: func(svc TrashService) error { return svc.RestoreUser(context.Background(), 102) }},
+		{name: "查询邮箱出错时返回原错误", lookupErr: errInjected, want: errInjected,
+			restore: func(svc TrashService) error { return svc.RestoreUser(context.Background(), 102) }},
+		{name: "所属组织已删除时不恢复应用", want: ErrRestoreOrgMissing,
+			restore: func(svc TrashService) error { return svc.RestoreApplication(context.Background(), 10) }},
+		{name: "查询所属组织出错时返回原错误", lookupErr: errInjected, want: errInjected,
+			restore: func(svc TrashService) error { return svc.RestoreApplication(context.Background(), 10) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store := newTrashFixture()
+			store.lookupErr = tt.lookupErr
+			store.takenEmails = map[string]bool{"user102@example.com": tt.taken}
+
+			if err := tt.restore(svc); err != tt.want {
+				t.Errorf("错误 = %v, 期望 %v", err, tt.want)
+			}
+		})
+	}
+}
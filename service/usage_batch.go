@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"saas-account/model"
+	"time"
+)
+
+// 批量上报使用事件的处理结果
+const (
+	UsageEventAccepted  = "accepted"
+	UsageEventDuplicate = "duplicate"
+	UsageEventRejected  = "rejected"
+)
+
+// maxUsageEventSkew 使用事件时间允许超前服务器时间的最大值
+const maxUsageEventSkew = 5 * time.Minute
+
+// UsageEvent 客户端上报的使用事件
+type UsageEvent struct {
+	IdempotencyKey string          `json:"idempotency_key"` // 客户端提供的幂等键
+	UsageType      string          `json:"usage_type"`      // 使用类型：api_call, storage, feature
+	Amount         int64           `json:"amount"`          // 使用量
+	Timestamp      int64           `json:"timestamp"`       // 事件发生时间，0表示当前时间
+	UserId         *int64          `json:"user_id"`         // 用户ID
+	Details        json.RawMessage `json:"details"`         // 详细信息，feature类型需包含feature_name
+}
+
+// UsageEventResult 单个使用事件的处理结果
+type UsageEventResult struct {
+	Index          int          `json:"index"`           // 事件在请求中的序号
+	IdempotencyKey string       `json:"idempotency_key"` // 幂等键
+	Status         string       `json:"status"`          // 处理结果：accepted, duplicate, rejected
+	Error          string       `json:"error,omitempty"` // 拒绝原因
+	Quota          *QuotaStatus `json:"quota,omitempty"` // 处理后的配额状态
+}
+
+// RecordBatch 批量记录使用事件，幂等键在有效期内重复的事件不会重复计量
+func (s *applicationUsageService) RecordBatch(ctx context.Context, appID int64, events []UsageEvent) ([]UsageEventResult, error) {
+	// 检查应用是否存在
+	app, err := s.appRepo.GetByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]UsageEventResult, 0, len(events))
+	for i := range events {
+		result := UsageEventResult{Index: i, IdempotencyKey: events[i].IdempotencyKey}
+		status, err := s.recordEvent(ctx, app, &events[i])
+		switch {
+		case errors.Is(err, errDuplicateUsageEvent):
+			result.Status = UsageEventDuplicate
+		case err != nil:
+			result.Status = UsageEventRejected
+			result.Error = err.Error()
+			var quotaErr *QuotaExceededError
+			if errors.As(err, &quotaErr) {
+				result.Quota = quotaErr.Status
+			}
+		default:
+			result.Status = UsageEventAccepted
+			result.Quota = status
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// errDuplicateUsageEvent 幂等键重复的使用事件
+var errDuplicateUsageEvent = errors.New("重复的使用事件")
+
+// recordEvent 记录单个使用事件，占用幂等键、累加配额和写入使用记录在同一个事务中执行，处理失败时全部回滚
+func (s *applicationUsageService) recordEvent(ctx context.Context, app *model.OrganizationApplication, event *UsageEvent) (*QuotaStatus, error) {
+	details, err := validateUsageEvent(event, s.idempotencyWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	var status *QuotaStatus
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		// 占用幂等键
+		reserved, err := s.idempotencyRepo.Reserve(ctx, &model.UsageIdempotencyKey{
+			ApplicationId:  app.ID,
+			IdempotencyKey: event.IdempotencyKey,
+			UsageType:      event.UsageType,
+			UsageAmount:    event.Amount,
+			ExpiresAt:      time.Now().Add(s.idempotencyWindow).Unix(),
+		})
+		if err != nil {
+			return err
+		}
+		if !reserved {
+			return errDuplicateUsageEvent
+		}
+
+		status, err = s.meterEvent(ctx, app, event, details)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if event.UsageType == "api_call" {
+		s.quotaConsumed(ctx, app, status, event.Amount, time.Unix(event.Timestamp, 0))
+	}
+	return status, nil
+}
+
+// meterEvent 检查配额或功能权益后写入使用记录，使用记录直接写入数据库而不经过异步写入，
+// 以便和幂等键在同一个事务中提交
+func (s *applicationUsageService) meterEvent(ctx context.Context, app *model.OrganizationApplication, event *UsageEvent, details map[string]interface{}) (*QuotaStatus, error) {
+	var status *QuotaStatus
+
+	switch event.UsageType {
+	case "storage":
+		// 存储按当前占用计算，不写入使用记录
+		storage, err := s.storageService.AddUsage(ctx, app.ID, event.Amount)
+		if err != nil {
+			return nil, err
+		}
+		return storage.Quota, nil
+	case "api_call":
+		var err error
+		status, err = s.consumeQuota(ctx, app, event.UsageType, event.Amount, time.Unix(event.Timestamp, 0))
+		if err != nil {
+			return nil, err
+		}
+		// 超额部分单独记录，便于计费
+		if status.Limit >= 0 && status.Used > status.Limit {
+			overage := status.Used - status.Limit
+			if overage > event.Amount {
+				overage = event.Amount
+			}
+			details["overage"] = overage
+		}
+	case "feature":
+		featureName, _ := details["feature_name"].(string)
+		entitlements, err := loadEntitlements(ctx, s.limitRepo, app.ID)
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := entitlements[featureName]; !ok || !entitlementIncluded(e) {
+			return nil, ErrFeatureNotIncluded
+		}
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &model.ApplicationUsage{
+		ApplicationId:  app.ID,
+		UserId:         event.UserId,
+		UsageType:      event.UsageType,
+		UsageAmount:    event.Amount,
+		UsageDate:      event.Timestamp,
+		Details:        string(detailsJSON),
+		OrganizationId: app.OrganizationId,
+	}
+
+	if err := s.createUsage(ctx, usage); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// validateUsageEvent 校验使用事件并解析详细信息，事件时间早于幂等键有效期时拒绝，
+// 否则幂等键过期后重放的旧事件会被重复计量
+func validateUsageEvent(event *UsageEvent, idempotencyWindow time.Duration) (map[string]interface{}, error) {
+	if event.IdempotencyKey == "" || len(event.IdempotencyKey) > 128 {
+		return nil, errors.New("幂等键不能为空且长度不能超过128")
+	}
+	switch event.UsageType {
+	case "api_call", "storage", "feature":
+	default:
+		return nil, errors.New("无效的使用类型")
+	}
+	if event.Amount <= 0 {
+		return nil, errors.New("使用量必须大于0")
+	}
+
+	now := time.Now()
+	if event.Timestamp == 0 {
+		event.Timestamp = now.Unix()
+	}
+	if event.Timestamp > now.Add(maxUsageEventSkew).Unix() {
+		return nil, errors.New("事件时间不能晚于当前时间")
+	}
+	if idempotencyWindow > 0 && event.Timestamp < now.Add(-idempotencyWindow).Unix() {
+		return nil, errors.New("事件时间不能早于幂等键有效期")
+	}
+
+	details := make(map[string]interface{})
+	if len(event.Details) > 0 && string(event.Details) != "null" {
+		if err := json.Unmarshal(event.Details, &details); err != nil {
+			return nil, errors.New("详细信息必须是JSON对象")
+		}
+	}
+	if event.UsageType == "feature" {
+		if name, _ := details["feature_name"].(string); name == "" {
+			return nil, errors.New("功能使用事件必须包含feature_name")
+		}
+	}
+
+	return details, nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"saas-account/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateUsageEvent(t *testing.T) {
+	now := time.Now()
+	window := 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		event   UsageEvent
+		wantErr string
+	}{
+		{name: "有效事件", event: UsageEvent{IdempotencyKey: "k1", UsageType: "api_call", Amount: 1}},
+		{name: "缺少幂等键", event: UsageEvent{UsageType: "api_call", Amount: 1}, wantErr: "幂等键"},
+		{name: "幂等键过长", event: UsageEvent{IdempotencyKey: strings.Repeat("k", 129), UsageType: "api_call", Amount: 1}, wantErr: "幂等键"},
+		{name: "无效类型", event: UsageEvent{IdempotencyKey: "k1", UsageType: "cpu", Amount: 1}, wantErr: "使用类型"},
+		{name: "使用量为0", event: UsageEvent{IdempotencyKey: "k1", UsageType: "api_call"}, wantErr: "使用量"},
+		{name: "事件时间超前", event: UsageEvent{IdempotencyKey: "k1", UsageType: "api_call", Amount: 1, Timestamp: now.Add(time.Hour).Unix()}, wantErr: "晚于"},
+		{name: "允许的时钟偏差", event: UsageEvent{IdempotencyKey: "k1", UsageType: "api_call", Amount: 1, Timestamp: now.Add(time.Minute).Unix()}},
+		{name: "有效期内的旧事件", event: UsageEvent{IdempotencyKey: "k1", UsageType: "api_call", Amount: 1, Timestamp: now.Add(-23 * time.Hour).Unix()}},
+		{name: "早于幂等键有效期", event: UsageEvent{IdempotencyKey: "k1", UsageType: "api_call", Amount: 1, Timestamp: now.Add(-25 * time.Hour).Unix()}, wantErr: "早于"},
+		{name: "详细信息不是对象", event: UsageEvent{IdempotencyKey: "k1", UsageType: "api_call", Amount: 1, Details: []byte(`[1]`)}, wantErr: "JSON对象"},
+		{name: "功能事件缺少名称", event: UsageEvent{IdempotencyKey: "k1", UsageType: "feature", Amount: 1}, wantErr: "feature_name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateUsageEvent(&tt.event, window)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("错误 = %v, 期望通过", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("错误 = %v, 期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecordBatchIdempotency(t *testing.T) {
+	f := newUsageFixture(t, "", nil)
+	ctx := context.Background()
+	now := time.Now()
+
+	events := []UsageEvent{
+		{IdempotencyKey: "a", UsageType: "api_call", Amount: 2, Timestamp: now.Unix()},
+		{IdempotencyKey: "b", UsageType: "api_call", Amount: 3, Timestamp: now.Unix()},
+		{IdempotencyKey: "a", UsageType: "api_call", Amount: 2, Timestamp: now.Unix()},
+	}
+	results, err := f.service.RecordBatch(ctx, f.app.ID, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{UsageEventAccepted, UsageEventAccepted, UsageEventDuplicate}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Errorf("事件 %d 状态 = %s, 期望 %s", i, result.Status, want[i])
+		}
+	}
+
+	// 重放整个批次时全部视为重复
+	results, err = f.service.RecordBatch(ctx, f.app.ID, events[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if result.Status != UsageEventDuplicate {
+			t.Errorf("重放事件 %d 状态 = %s, 期望 %s", i, result.Status, UsageEventDuplicate)
+		}
+	}
+
+	windowStart, _ := quotaWindow("api_call", now, time.UTC)
+	if got := f.store.counter(f.app.ID, "api_call", windowStart); got != 5 {
+		t.Errorf("计数器 = %d, 期望 5", got)
+	}
+	if got := f.store.usageCount(); got != 2 {
+		t.Errorf("使用记录数 = %d, 期望 2", got)
+	}
+}
+
+func TestRecordBatchSingleTransaction(t *testing.T) {
+	f := newUsageFixture(t, "", nil)
+	ctx := context.Background()
+	now := time.Now()
+
+	// 写入使用记录失败时幂等键和计数器一起回滚，客户端可以用同一个幂等键重试
+	f.store.failCreate = errInjected
+	results, err := f.service.RecordBatch(ctx, f.app.ID, []UsageEvent{
+		{IdempotencyKey: "a", UsageType: "api_call", Amount: 4, Timestamp: now.Unix()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != UsageEventRejected {
+		t.Fatalf("状态 = %s, 期望 %s", results[0].Status, UsageEventRejected)
+	}
+
+	windowStart, _ := quotaWindow("api_call", now, time.UTC)
+	if got := f.store.counter(f.app.ID, "api_call", windowStart); got != 0 {
+		t.Errorf("失败后计数器 = %d, 期望回滚为 0", got)
+	}
+
+	f.store.failCreate = nil
+	results, err = f.service.RecordBatch(ctx, f.app.ID, []UsageEvent{
+		{IdempotencyKey: "a", UsageType: "api_call", Amount: 4, Timestamp: now.Unix()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != UsageEventAccepted {
+		t.Fatalf("重试状态 = %s, 期望 %s", results[0].Status, UsageEventAccepted)
+	}
+	if got := f.store.counter(f.app.ID, "api_call", windowStart); got != 4 {
+		t.Errorf("重试后计数器 = %d, 期望 4", got)
+	}
+
+	// 幂等键、计数器、使用记录和汇总都在事务中写入，不经过异步写入
+	if len(f.store.outsideTx) != 0 {
+		t.Errorf("事务外的写操作: %v", f.store.outsideTx)
+	}
+	if f.store.usageCount() != 1 {
+		t.Errorf("使用记录数 = %d, 期望 1", f.store.usageCount())
+	}
+}
+
+func TestRecordBatchQuota(t *testing.T) {
+	f := newUsageFixture(t, "", &model.OrganizationApplicationLimit{
+		MaxRequests:     5,
+		EnforcementMode: model.EnforcementModeHard,
+	})
+	ctx := context.Background()
+	now := time.Now()
+
+	results, err := f.service.RecordBatch(ctx, f.app.ID, []UsageEvent{
+		{IdempotencyKey: "a", UsageType: "api_call", Amount: 4, Timestamp: now.Unix()},
+		{IdempotencyKey: "b", UsageType: "api_call", Amount: 2, Timestamp: now.Unix()},
+		{IdempotencyKey: "c", UsageType: "api_call", Amount: 1, Timestamp: now.Unix()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{UsageEventAccepted, UsageEventRejected, UsageEventAccepted}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Errorf("事件 %d 状态 = %s, 期望 %s", i, result.Status, want[i])
+		}
+	}
+	if results[1].Quota == nil || results[1].Quota.Used != 4 {
+		t.Errorf("超出配额时的配额状态 = %+v", results[1].Quota)
+	}
+
+	// 超出配额被拒绝的事件释放幂等键，配额恢复后可以重试
+	f.store.mu.Lock()
+	reserved := f.store.state.keys[keyOf(f.app.ID, "b")]
+	f.store.mu.Unlock()
+	if reserved {
+		t.Error("被拒绝事件的幂等键应随事务回滚")
+	}
+}
+
+// keyOf 幂等键在内存存储中的键
+func keyOf(appID int64, key string) string {
+	return fmt.Sprintf("%d/%s", appID, key)
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"saas-account/filestore"
+	"saas-account/logger"
+	"saas-account/model"
+	"saas-account/repository"
+	"strconv"
+	"time"
+)
+
+// 使用记录导出格式
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// exportJobLease 领取导出任务后的租约时长，实例异常退出时租约结束后任务会被重新领取
+const exportJobLease = 30 * time.Minute
+
+// ErrExportNotReady 导出文件尚未生成
+var ErrExportNotReady = errors.New("导出文件尚未生成")
+
+// ErrInvalidExportToken 无效的下载令牌
+var ErrInvalidExportToken = errors.New("无效的下载令牌")
+
+// usageExportColumns CSV导出的列
+var usageExportColumns = []string{
+	"id", "application_id", "organization_id", "user_id", "usage_type",
+	"usage_amount", "usage_date", "ingested_at", "details",
+}
+
+// UsageExportRequest 使用记录导出请求，日期部分按组织时区解释，包含结束日期当天
+type UsageExportRequest struct {
+	ApplicationId  int64     // 组织应用ID，为0表示导出整个组织
+	OrganizationId int64     // 组织ID，导出整个组织时必填
+	Format         string    // 导出格式：csv, ndjson
+	UsageType      string    // 使用类型，为空表示不限
+	UserId         *int64    // 用户ID，为nil表示不限
+	StartDate      time.Time // 开始日期
+	EndDate        time.Time // 结束日期
+}
+
+// UsageExportService 使用记录导出服务接口
+type UsageExportService interface {
+	Export(ctx context.Context, req *UsageExportRequest, w io.Writer) error
+	CreateJob(ctx context.Context, req *UsageExportRequest) (*model.UsageExportJob, error)
+	GetJob(ctx context.Context, id int64) (*model.UsageExportJob, error)
+	OpenDownload(ctx context.Context, id int64, token string) (*model.UsageExportJob, io.ReadCloser, int64, error)
+	ProcessPending(ctx context.Context) (int, error)
+	PurgeExpired(ctx context.Context) (int, error)
+}
+
+// usageExportService 使用记录导出服务实现
+type usageExportService struct {
+	usageRepo repository.ApplicationUsageRepository
+	jobRepo   repository.UsageExportJobRepository
+	appRepo   repository.OrganizationApplicationRepository
+	orgRepo   repository.OrganizationRepository
+	store     filestore.Store
+	retention time.Duration
+}
+
+// NewUsageExportService 创建使用记录导出服务
+func NewUsageExportService(
+	usageRepo repository.ApplicationUsageRepository,
+	jobRepo repository.UsageExportJobRepository,
+	appRepo repository.OrganizationApplicationRepository,
+	orgRepo repository.OrganizationRepository,
+	store filestore.Store,
+	retention time.Duration,
+) UsageExportService {
+	return &usageExportService{
+		usageRepo: usageRepo,
+		jobRepo:   jobRepo,
+		appRepo:   appRepo,
+		orgRepo:   orgRepo,
+		store:     store,
+		retention: retention,
+	}
+}
+
+// Export 将符合条件的使用记录流式写入w
+func (s *usageExportService) Export(ctx context.Context, req *UsageExportRequest, w io.Writer) error {
+	filter, err := s.resolveFilter(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.write(ctx, req.Format, filter, w)
+	return err
+}
+
+// CreateJob 创建异步导出任务，由后台任务写入文件存储
+func (s *usageExportService) CreateJob(ctx context.Context, req *UsageExportRequest) (*model.UsageExportJob, error) {
+	filter, err := s.resolveFilter(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := newExportToken()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &model.UsageExportJob{
+		OrganizationId: filter.OrganizationId,
+		ApplicationId:  filter.ApplicationId,
+		Format:         req.Format,
+		UsageType:      filter.UsageType,
+		UserId:         filter.UserId,
+		StartDate:      filter.StartDate,
+		EndDate:        filter.EndDate,
+		Status:         model.ExportStatusPending,
+		Token:          token,
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetJob 获取导出任务
+func (s *usageExportService) GetJob(ctx context.Context, id int64) (*model.UsageExportJob, error) {
+	return s.jobRepo.GetByID(ctx, id)
+}
+
+// OpenDownload 校验下载令牌并打开导出文件
+func (s *usageExportService) OpenDownload(ctx context.Context, id int64, token string) (*model.UsageExportJob, io.ReadCloser, int64, error) {
+	job, err := s.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(job.Token), []byte(token)) != 1 {
+		return nil, nil, 0, ErrInvalidExportToken
+	}
+	if job.Status != model.ExportStatusCompleted {
+		return nil, nil, 0, ErrExportNotReady
+	}
+
+	file, size, err := s.store.Open(job.FileName)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return job, file, size, nil
+}
+
+// ProcessPending 执行等待中的导出任务，返回完成的任务数
+func (s *usageExportService) ProcessPending(ctx context.Context) (int, error) {
+	staleBefore := time.Now().Add(-exportJobLease).Unix()
+	jobs, err := s.jobRepo.GetPending(ctx, staleBefore, 10)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for i := range jobs {
+		claimedAt := time.Now().Unix()
+		claimed, err := s.jobRepo.Claim(ctx, jobs[i].ID, claimedAt, staleBefore)
+		if err != nil {
+			return processed, err
+		}
+		if !claimed {
+			continue
+		}
+
+		jobs[i].Status = model.ExportStatusRunning
+		jobs[i].ClaimedAt = claimedAt
+		if err := s.runJob(ctx, &jobs[i]); err != nil {
+			logger.GetLogger().Error("导出任务执行失败: 任务=%d, 错误: %v", jobs[i].ID, err)
+			jobs[i].Status = model.ExportStatusFailed
+			jobs[i].Error = err.Error()
+		} else {
+			jobs[i].Status = model.ExportStatusCompleted
+			processed++
+		}
+
+		now := time.Now()
+		jobs[i].CompletedAt = now.Unix()
+		jobs[i].ExpiresAt = now.Add(s.retention).Unix()
+		finished, err := s.jobRepo.Finish(ctx, &jobs[i])
+		if err != nil {
+			return processed, err
+		}
+		if !finished {
+			// 执行超过租约时长，任务已由其他实例重新领取，结果以该实例为准
+			logger.GetLogger().Warn("导出任务执行超过租约时长，结果已丢弃: 任务=%d", jobs[i].ID)
+		}
+	}
+
+	return processed, nil
+}
+
+// PurgeExpired 删除已过期的导出任务和导出文件，返回删除的任务数
+func (s *usageExportService) PurgeExpired(ctx context.Context) (int, error) {
+	jobs, err := s.jobRepo.GetExpired(ctx, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, job := range jobs {
+		if job.FileName != "" {
+			if err := s.store.Remove(job.FileName); err != nil {
+				return purged, err
+			}
+		}
+		if err := s.jobRepo.Delete(ctx, job.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// runJob 将导出任务的记录写入文件存储
+func (s *usageExportService) runJob(ctx context.Context, job *model.UsageExportJob) error {
+	job.FileName = fmt.Sprintf("usage-export-%d.%s", job.ID, job.Format)
+
+	file, err := s.store.Create(job.FileName)
+	if err != nil {
+		return err
+	}
+
+	counter := &countingWriter{w: file}
+	rows, err := s.write(ctx, job.Format, repository.UsageFilter{
+		ApplicationId:  job.ApplicationId,
+		OrganizationId: job.OrganizationId,
+		UsageType:      job.UsageType,
+		UserId:         job.UserId,
+		StartDate:      job.StartDate,
+		EndDate:        job.EndDate,
+	}, counter)
+	if err != nil {
+		file.Abort()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	job.RowCount = rows
+	job.FileSize = counter.n
+	return nil
+}
+
+// resolveFilter 校验导出请求并转换为过滤条件
+func (s *usageExportService) resolveFilter(ctx context.Context, req *UsageExportRequest) (repository.UsageFilter, error) {
+	if req.Format != ExportFormatCSV && req.Format != ExportFormatNDJSON {
+		return repository.UsageFilter{}, errors.New("无效的导出格式，可选值为csv, ndjson")
+	}
+
+	filter := repository.UsageFilter{
+		ApplicationId:  req.ApplicationId,
+		OrganizationId: req.OrganizationId,
+		UsageType:      req.UsageType,
+		UserId:         req.UserId,
+	}
+
+	// 导出单个应用时以应用所属组织为准
+	if req.ApplicationId != 0 {
+		app, err := s.appRepo.GetByID(ctx, req.ApplicationId)
+		if err != nil {
+			return repository.UsageFilter{}, err
+		}
+		filter.OrganizationId = app.OrganizationId
+	}
+
+	loc, err := loadOrgLocation(ctx, s.orgRepo, filter.OrganizationId)
+	if err != nil {
+		return repository.UsageFilter{}, err
+	}
+
+	start, end, err := localDateRange(req.StartDate, req.EndDate, loc)
+	if err != nil {
+		return repository.UsageFilter{}, err
+	}
+	filter.StartDate = start.Unix()
+	filter.EndDate = end.Unix()
+
+	return filter, nil
+}
+
+// write 按格式逐条写入使用记录，返回写入的记录数
+func (s *usageExportService) write(ctx context.Context, format string, filter repository.UsageFilter, w io.Writer) (int64, error) {
+	var rows int64
+
+	if format == ExportFormatNDJSON {
+		encoder := json.NewEncoder(w)
+		err := s.usageRepo.Stream(ctx, filter, func(usage *model.ApplicationUsage) error {
+			rows++
+			return encoder.Encode(usage)
+		})
+		return rows, err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(usageExportColumns); err != nil {
+		return 0, err
+	}
+	err := s.usageRepo.Stream(ctx, filter, func(usage *model.ApplicationUsage) error {
+		userID := ""
+		if usage.UserId != nil {
+			userID = strconv.FormatInt(*usage.UserId, 10)
+		}
+		rows++
+		return writer.Write([]string{
+			strconv.FormatInt(usage.ID, 10),
+			strconv.FormatInt(usage.ApplicationId, 10),
+			strconv.FormatInt(usage.OrganizationId, 10),
+			userID,
+			usage.UsageType,
+			strconv.FormatInt(usage.UsageAmount, 10),
+			time.Unix(usage.UsageDate, 0).UTC().Format(time.RFC3339),
+			time.Unix(usage.IngestedAt, 0).UTC().Format(time.RFC3339),
+			usage.Details,
+		})
+	})
+	if err != nil {
+		return rows, err
+	}
+
+	writer.Flush()
+	return rows, writer.Error()
+}
+
+// countingWriter 统计写入字节数的写入器
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write 写入数据并累计字节数
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// newExportToken 生成随机下载令牌
+func newExportToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"saas-account/model"
+	"saas-account/notify"
+	"saas-account/repository"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// usageState 内存中的使用数据
+type usageState struct {
+	keys     map[string]bool  // 已占用的幂等键，键为 应用ID/幂等键
+	counters map[string]int64 // 配额计数器，键为 应用ID/使用类型/窗口开始时间
+	usages   []*model.ApplicationUsage
+	rollups  map[string]int64 // 汇总，键为 应用ID/使用类型/粒度/区间开始时间
+}
+
+// clone 复制数据，用于事务回滚
+func (s usageState) clone() usageState {
+	c := usageState{
+		keys:     make(map[string]bool, len(s.keys)),
+		counters: make(map[string]int64, len(s.counters)),
+		usages:   append([]*model.ApplicationUsage(nil), s.usages...),
+		rollups:  make(map[string]int64, len(s.rollups)),
+	}
+	for k, v := range s.keys {
+		c.keys[k] = v
+	}
+	for k, v := range s.counters {
+		c.counters[k] = v
+	}
+	for k, v := range s.rollups {
+		c.rollups[k] = v
+	}
+	return c
+}
+
+// memUsageStore 内存中的使用数据存储，事务失败时恢复到事务开始前的快照
+type memUsageStore struct {
+	mu    sync.Mutex
+	state usageState
+
+	nextID     int64
+	failCreate error    // 写入使用记录时返回的错误
+	outsideTx  []string // 在事务外执行的写操作
+}
+
+func newMemUsageStore() *memUsageStore {
+	return &memUsageStore{state: usageState{
+		keys:     map[string]bool{},
+		counters: map[string]int64{},
+		rollups:  map[string]int64{},
+	}}
+}
+
+// write 记录写操作，不在事务中时记录到 outsideTx
+func (m *memUsageStore) write(ctx context.Context, op string) {
+	if ctx.Value(fakeTxKey{}) == nil {
+		m.outsideTx = append(m.outsideTx, op)
+	}
+}
+
+// snapshot 保存当前数据，返回恢复函数，用于假事务回滚
+func (m *memUsageStore) snapshot() func() {
+	m.mu.Lock()
+	saved := m.state.clone()
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		m.state = saved
+		m.mu.Unlock()
+	}
+}
+
+// counter 获取计数器的值
+func (m *memUsageStore) counter(appID int64, usageType string, windowStart int64) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.counters[fmt.Sprintf("%d/%s/%d", appID, usageType, windowStart)]
+}
+
+// usageCount 使用记录数
+func (m *memUsageStore) usageCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.state.usages)
+}
+
+// fakeIdempotencyRepo 内存中的幂等键仓库
+type fakeIdempotencyRepo struct {
+	repository.UsageIdempotencyRepository
+	store *memUsageStore
+}
+
+func (r *fakeIdempotencyRepo) Reserve(ctx context.Context, key *model.UsageIdempotencyKey) (bool, error) {
+	r.store.write(ctx, "reserve")
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	k := fmt.Sprintf("%d/%s", key.ApplicationId, key.IdempotencyKey)
+	if r.store.state.keys[k] {
+		return false, nil
+	}
+	r.store.state.keys[k] = true
+	return true, nil
+}
+
+// fakeCounterRepo 内存中的配额计数器仓库
+type fakeCounterRepo struct {
+	repository.UsageCounterRepository
+	store *memUsageStore
+}
+
+func (r *fakeCounterRepo) Get(ctx context.Context, appID int64, usageType string, windowStart int64) (int64, error) {
+	return r.store.counter(appID, usageType, windowStart), nil
+}
+
+func (r *fakeCounterRepo) Increment(ctx context.Context, appID int64, usageType string, windowStart, amount int64) (int64, error) {
+	r.store.write(ctx, "increment")
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	k := fmt.Sprintf("%d/%s/%d", appID, usageType, windowStart)
+	r.store.state.counters[k] += amount
+	return r.store.state.counters[k], nil
+}
+
+func (r *fakeCounterRepo) IncrementWithinLimit(ctx context.Context, appID int64, usageType string, windowStart, amount, max int64) (int64, bool, error) {
+	r.store.write(ctx, "increment")
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	k := fmt.Sprintf("%d/%s/%d", appID, usageType, windowStart)
+	if r.store.state.counters[k]+amount > max {
+		return r.store.state.counters[k], false, nil
+	}
+	r.store.state.counters[k] += amount
+	return r.store.state.counters[k], true, nil
+}
+
+// fakeUsageRepo 内存中的使用记录仓库
+type fakeUsageRepo struct {
+	repository.ApplicationUsageRepository
+	store *memUsageStore
+}
+
+func (r *fakeUsageRepo) Create(ctx context.Context, usage *model.ApplicationUsage) error {
+	r.store.write(ctx, "create")
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.failCreate != nil {
+		return r.store.failCreate
+	}
+	r.store.nextID++
+	usage.ID = r.store.nextID
+	r.store.state.usages = append(r.store.state.usages, usage)
+	return nil
+}
+
+func (r *fakeUsageRepo) GetByID(ctx context.Context, id int64) (*model.ApplicationUsage, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, usage := range r.store.state.usages {
+		if usage.ID == id {
+			return usage, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUsageRepo) Delete(ctx context.Context, id int64) error {
+	r.store.write(ctx, "delete")
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	usages := r.store.state.usages[:0:0]
+	for _, usage := range r.store.state.usages {
+		if usage.ID != id {
+			usages = append(usages, usage)
+		}
+	}
+	r.store.state.usages = usages
+	return nil
+}
+
+// fakeRollupRepo 内存中的汇总仓库，只按小时汇总
+type fakeRollupRepo struct {
+	repository.UsageRollupRepository
+	store *memUsageStore
+}
+
+func (r *fakeRollupRepo) Add(ctx context.Context, usage *model.ApplicationUsage, loc *time.Location) error {
+	r.store.write(ctx, "rollup")
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.state.rollups[rollupKey(usage, loc)] += usage.UsageAmount
+	return nil
+}
+
+func (r *fakeRollupRepo) Subtract(ctx context.Context, usage *model.ApplicationUsage, loc *time.Location) error {
+	r.store.write(ctx, "rollup")
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.state.rollups[rollupKey(usage, loc)] -= usage.UsageAmount
+	return nil
+}
+
+// rollupKey 使用记录所在小时汇总的键
+func rollupKey(usage *model.ApplicationUsage, loc *time.Location) string {
+	bucket := periodStart(UsageGranularityHour, time.Unix(usage.UsageDate, 0), loc).Unix()
+	return fmt.Sprintf("%d/%s/hour/%d", usage.ApplicationId, usage.UsageType, bucket)
+}
+
+// fakeAlertService 不检查告警规则的告警服务
+type fakeAlertService struct {
+	AlertService
+}
+
+func (s *fakeAlertService) Evaluate(ctx context.Context, app *model.OrganizationApplication, status *QuotaStatus, at time.Time) {
+}
+
+// recordingNotifier 记录发送的通知，insideTx 为在事务中发送的通知数
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []*notify.Notification
+	insideTx      int
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification *notify.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if ctx.Value(fakeTxKey{}) != nil {
+		n.insideTx++
+	}
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+// count 已发送的通知数
+func (n *recordingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.notifications)
+}
+
+// usageFixture 使用记录服务的测试环境
+type usageFixture struct {
+	store    *memUsageStore
+	service  *applicationUsageService
+	app      *model.OrganizationApplication
+	notifier *recordingNotifier
+}
+
+// newUsageFixture 创建测试环境，limit 为nil表示应用未配置限制
+func newUsageFixture(t *testing.T, timeZone string, limit *model.OrganizationApplicationLimit) *usageFixture {
+	t.Helper()
+
+	fa := newFixtureApp(timeZone, limit)
+	store := newMemUsageStore()
+	notifier := &recordingNotifier{}
+	svc := NewApplicationUsageService(
+		&fakeUsageRepo{store: store},
+		&fakeRollupRepo{store: store},
+		fa.apps,
+		fa.orgs,
+		fa.limits,
+		&fakeCounterRepo{store: store},
+		notifier,
+		nil,
+		&fakeIdempotencyRepo{store: store},
+		24*time.Hour,
+		nil,
+		&fakeAlertService{},
+		newFakeTxManager(store),
+	).(*applicationUsageService)
+
+	return &usageFixture{store: store, service: svc, app: fa.app, notifier: notifier}
+}
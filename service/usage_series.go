@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"saas-account/model"
+	"time"
+)
+
+// UsagePoint 使用量时间序列中的一个统计区间
+type UsagePoint struct {
+	Start  int64            `json:"start"`  // 区间开始时间（Unix秒）
+	Time   string           `json:"time"`   // 区间开始时间（组织时区，RFC3339）
+	Values map[string]int64 `json:"values"` // 各使用类型的使用量
+}
+
+// UsageSeries 使用量时间序列
+type UsageSeries struct {
+	Granularity string           `json:"granularity"` // 统计粒度：hour, day, month
+	TimeZone    string           `json:"time_zone"`   // 统计所用的组织时区
+	Start       int64            `json:"start"`       // 统计开始时间（含）
+	End         int64            `json:"end"`         // 统计结束时间（不含）
+	Summary     map[string]int64 `json:"summary"`     // 区间内各使用类型的合计
+	Points      []UsagePoint     `json:"points"`      // 按区间排列的使用量，无使用的区间值为空
+}
+
+// GetSeriesByApplication 获取应用的使用量时间序列，日期部分按组织时区解释，包含结束日期当天
+func (s *applicationUsageService) GetSeriesByApplication(ctx context.Context, appID int64, granularity string, startDate, endDate time.Time) (*UsageSeries, error) {
+	// 检查应用是否存在
+	app, err := s.appRepo.GetByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := loadOrgLocation(ctx, s.orgRepo, app.OrganizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildSeries(granularity, startDate, endDate, loc, func(rollupGranularity string, start, end int64) ([]model.UsageRollup, error) {
+		return s.rollupRepo.SeriesByApplication(ctx, appID, rollupGranularity, start, end)
+	})
+}
+
+// GetSeriesByOrganization 获取组织下所有应用的使用量时间序列
+func (s *applicationUsageService) GetSeriesByOrganization(ctx context.Context, orgID int64, granularity string, startDate, endDate time.Time) (*UsageSeries, error) {
+	// 检查组织是否存在
+	loc, err := loadOrgLocation(ctx, s.orgRepo, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildSeries(granularity, startDate, endDate, loc, func(rollupGranularity string, start, end int64) ([]model.UsageRollup, error) {
+		return s.rollupRepo.SeriesByOrganization(ctx, orgID, rollupGranularity, start, end)
+	})
+}
+
+// buildSeries 从汇总中构建时间序列，按小时统计读取小时汇总，按天和按月统计读取天汇总
+func buildSeries(
+	granularity string,
+	startDate, endDate time.Time,
+	loc *time.Location,
+	query func(rollupGranularity string, start, end int64) ([]model.UsageRollup, error),
+) (*UsageSeries, error) {
+	if err := validateGranularity(granularity); err != nil {
+		return nil, err
+	}
+
+	start, end, err := localDateRange(startDate, endDate, loc)
+	if err != nil {
+		return nil, err
+	}
+	if granularity == UsageGranularityHour && end.Sub(start) > maxHourlySeriesDays*24*time.Hour {
+		return nil, fmt.Errorf("按小时统计时日期范围不能超过%d天", maxHourlySeriesDays)
+	}
+
+	rollupGranularity := model.RollupGranularityDay
+	if granularity == UsageGranularityHour {
+		rollupGranularity = model.RollupGranularityHour
+	}
+
+	rollups, err := query(rollupGranularity, start.Unix(), end.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	// 按区间生成连续的时间序列
+	series := &UsageSeries{
+		Granularity: granularity,
+		TimeZone:    loc.String(),
+		Start:       start.Unix(),
+		End:         end.Unix(),
+		Summary:     make(map[string]int64),
+		Points:      make([]UsagePoint, 0),
+	}
+	index := make(map[int64]int)
+	for t := periodStart(granularity, start, loc); t.Before(end); t = nextPeriod(granularity, t) {
+		index[t.Unix()] = len(series.Points)
+		series.Points = append(series.Points, UsagePoint{
+			Start:  t.Unix(),
+			Time:   t.Format(time.RFC3339),
+			Values: make(map[string]int64),
+		})
+	}
+
+	for _, rollup := range rollups {
+		bucket := periodStart(granularity, time.Unix(rollup.BucketStart, 0), loc).Unix()
+		i, ok := index[bucket]
+		if !ok {
+			continue
+		}
+		series.Points[i].Values[rollup.UsageType] += rollup.Amount
+		series.Summary[rollup.UsageType] += rollup.Amount
+	}
+
+	return series, nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"saas-account/repository"
+	"sync"
+	"time"
+)
+
+// 使用量统计粒度
+const (
+	UsageGranularityHour  = "hour"
+	UsageGranularityDay   = "day"
+	UsageGranularityMonth = "month"
+)
+
+// maxHourlySeriesDays 按小时统计时允许的最大天数
+const maxHourlySeriesDays = 31
+
+// validateTimeZone 校验IANA时区名称
+func validateTimeZone(timeZone string) error {
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return fmt.Errorf("无效的时区: %s", timeZone)
+	}
+	return nil
+}
+
+// validateGranularity 校验统计粒度
+func validateGranularity(granularity string) error {
+	switch granularity {
+	case UsageGranularityHour, UsageGranularityDay, UsageGranularityMonth:
+		return nil
+	}
+	return errors.New("无效的统计粒度，可选值为hour, day, month")
+}
+
+// periodStart 获取时间在指定时区下所在统计区间的开始时间
+func periodStart(granularity string, t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch granularity {
+	case UsageGranularityHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case UsageGranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// nextPeriod 获取下一个统计区间的开始时间
+func nextPeriod(granularity string, start time.Time) time.Time {
+	switch granularity {
+	case UsageGranularityHour:
+		return start.Add(time.Hour)
+	case UsageGranularityMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// localDateRange 将日期部分按时区解释为 [开始日期零点, 结束日期次日零点)
+func localDateRange(startDate, endDate time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	start := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, errors.New("开始日期不能晚于结束日期")
+	}
+	return start, end, nil
+}
+
+// locationCacheEntry 时区缓存条目
+type locationCacheEntry struct {
+	loc       *time.Location
+	expiresAt time.Time
+}
+
+// locationCache 组织时区缓存，减少记录使用量时的数据库查询
+type locationCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[int64]locationCacheEntry
+}
+
+// orgLocationCache 全局组织时区缓存，组织时区变更时失效
+var orgLocationCache = &locationCache{
+	ttl:     5 * time.Minute,
+	entries: make(map[int64]locationCacheEntry),
+}
+
+// get 获取缓存的组织时区
+func (c *locationCache) get(orgID int64) (*time.Location, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[orgID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.loc, true
+}
+
+// set 缓存组织时区
+func (c *locationCache) set(orgID int64, loc *time.Location) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[orgID] = locationCacheEntry{
+		loc:       loc,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
+
+// invalidate 使组织时区缓存失效
+func (c *locationCache) invalidate(orgID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, orgID)
+}
+
+// loadOrgLocation 获取组织的时区，未设置或无效时使用UTC
+func loadOrgLocation(ctx context.Context, orgRepo repository.OrganizationRepository, orgID int64) (*time.Location, error) {
+	if loc, ok := orgLocationCache.get(orgID); ok {
+		return loc, nil
+	}
+
+	org, err := orgRepo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := time.UTC
+	if org.TimeZone != "" {
+		if l, err := time.LoadLocation(org.TimeZone); err == nil {
+			loc = l
+		}
+	}
+
+	orgLocationCache.set(orgID, loc)
+	return loc, nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"saas-account/metrics"
 	"saas-account/model"
 	"saas-account/repository"
 	"saas-account/utils"
@@ -14,24 +15,37 @@ import (
 // UserService 用户服务接口
 type UserService interface {
 	Create(ctx context.Context, user *model.User) error
-	GetByID(ctx context.Context, id uint) (*model.User, error)
+	GetByID(ctx context.Context, id int64) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
 	GetByPhone(ctx context.Context, phone string) (*model.User, error)
 	List(ctx context.Context, page, pageSize int) ([]model.User, int64, error)
 	Update(ctx context.Context, user *model.User) error
-	Delete(ctx context.Context, id uint) error
-	ChangePassword(ctx context.Context, id uint, oldPassword, newPassword string) error
+	Delete(ctx context.Context, id int64) error
+	ChangePassword(ctx context.Context, id int64, oldPassword, newPassword string) error
+	SetRole(ctx context.Context, id int64, role string) (*model.User, error)
+	IssueToken(ctx context.Context, id int64) (string, error)
 }
 
 // userService 用户服务实现
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo     repository.UserRepository
+	publisher    EventPublisher
+	txManager    repository.TransactionManager
+	auditService AuditService
 }
 
 // NewUserService 创建用户服务
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(
+	userRepo repository.UserRepository,
+	publisher EventPublisher,
+	txManager repository.TransactionManager,
+	auditService AuditService,
+) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:     userRepo,
+		publisher:    publisher,
+		txManager:    txManager,
+		auditService: auditService,
 	}
 }
 
@@ -63,14 +77,47 @@ func (s *userService) Create(ctx context.Context, user *model.User) error {
 		user.Status = "active"
 	}
 
+	// 平台角色只能通过 SetRole 修改
+	user.Role = model.UserRoleUser
+
 	user.ID = utils.GenerateID()
 
-	// 创建用户
-	return s.userRepo.Create(ctx, user)
+	// 创建用户，事件与用户一起提交
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+		if err := s.auditService.Record(ctx, AuditEntry{
+			Action:       "user.create",
+			ResourceType: AuditResourceUser,
+			ResourceId:   user.ID,
+			After:        user,
+		}); err != nil {
+			return err
+		}
+
+		// 用户不属于任何组织，事件只投递给平台级订阅
+		return s.publisher.Publish(ctx, Event{
+			Type:          EventUserCreated,
+			AggregateType: AggregateUser,
+			AggregateId:   user.ID,
+			Data: map[string]interface{}{
+				"user_id": user.ID,
+				"name":    user.Name,
+				"email":   user.Email,
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.UsersCreated.Inc()
+	return nil
 }
 
 // GetByID 根据ID获取用户
-func (s *userService) GetByID(ctx context.Context, id uint) (*model.User, error) {
+func (s *userService) GetByID(ctx context.Context, id int64) (*model.User, error) {
 	return s.userRepo.GetByID(ctx, id)
 }
 
@@ -113,20 +160,47 @@ func (s *userService) Update(ctx context.Context, user *model.User) error {
 		}
 	}
 
-	// 保留原密码
+	// 保留原密码和平台角色
 	user.Password = existingUser.Password
+	user.Role = existingUser.Role
 
 	// 更新用户
-	return s.userRepo.Update(ctx, user)
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			Action:       "user.update",
+			ResourceType: AuditResourceUser,
+			ResourceId:   user.ID,
+			Before:       existingUser,
+			After:        user,
+		})
+	})
 }
 
 // Delete 删除用户
-func (s *userService) Delete(ctx context.Context, id uint) error {
-	return s.userRepo.Delete(ctx, id)
+func (s *userService) Delete(ctx context.Context, id int64) error {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			Action:       "user.delete",
+			ResourceType: AuditResourceUser,
+			ResourceId:   user.ID,
+			Before:       user,
+		})
+	})
 }
 
 // ChangePassword 修改密码
-func (s *userService) ChangePassword(ctx context.Context, id uint, oldPassword, newPassword string) error {
+func (s *userService) ChangePassword(ctx context.Context, id int64, oldPassword, newPassword string) error {
 	// 获取用户
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -149,5 +223,76 @@ func (s *userService) ChangePassword(ctx context.Context, id uint, oldPassword,
 	user.Password = string(hashedPassword)
 	user.UpdatedAt = time.Now().Unix()
 
-	return s.userRepo.Update(ctx, user)
+	// 密码不记录在审计日志中，只记录修改动作
+	return s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			Action:       "user.change_password",
+			ResourceType: AuditResourceUser,
+			ResourceId:   user.ID,
+		})
+	})
+}
+
+// SetRole 设置用户的平台角色
+func (s *userService) SetRole(ctx context.Context, id int64, role string) (*model.User, error) {
+	if role != model.UserRoleUser && role != model.UserRoleAdmin {
+		return nil, errors.New("无效的平台角色，可选值为user, admin")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	before := *user
+	user.Role = role
+	user.UpdatedAt = time.Now().Unix()
+
+	err = s.txManager.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditEntry{
+			Action:       "user.set_role",
+			ResourceType: AuditResourceUser,
+			ResourceId:   user.ID,
+			Before:       &before,
+			After:        user,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// IssueToken 为用户签发访问令牌，令牌中的角色为用户当前的平台角色
+func (s *userService) IssueToken(ctx context.Context, id int64) (token string, err error) {
+	defer func() {
+		if err != nil {
+			metrics.Logins.WithLabelValues("failure").Inc()
+		} else {
+			metrics.Logins.WithLabelValues("success").Inc()
+		}
+	}()
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if user.Status != "active" {
+		return "", errors.New("用户状态不可用")
+	}
+
+	role := user.Role
+	if role == "" {
+		role = model.UserRoleUser
+	}
+	return utils.GenerateToken(user.ID, user.Name, user.Email, role)
 }
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// errWebhookAddressBlocked Webhook 地址指向内网、回环、链路本地或元数据地址
+var errWebhookAddressBlocked = errors.New("webhook地址不能指向内网、回环、链路本地或元数据地址")
+
+// blockedWebhookPrefixes 标准库分类之外不允许投递的地址段
+var blockedWebhookPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),        // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),    // 运营商级NAT，包含阿里云元数据地址 100.100.100.200
+	netip.MustParsePrefix("192.0.0.0/24"),     // IETF协议分配
+	netip.MustParsePrefix("198.18.0.0/15"),    // 基准测试
+	netip.MustParsePrefix("240.0.0.0/4"),      // 保留地址
+	netip.MustParsePrefix("168.63.129.16/32"), // Azure 平台服务地址
+	netip.MustParsePrefix("64:ff9b::/96"),     // NAT64，可映射到任意IPv4地址
+	netip.MustParsePrefix("64:ff9b:1::/48"),   // 本地NAT64
+	netip.MustParsePrefix("2002::/16"),        // 6to4，可映射到任意IPv4地址
+	netip.MustParsePrefix("fec0::/10"),        // 已废弃的站点本地地址
+	netip.MustParsePrefix("100::/64"),         // 丢弃地址
+	netip.MustParsePrefix("2001:db8::/32"),    // 文档地址
+}
+
+// lookupWebhookHost 解析 Webhook 主机名，测试时替换
+var lookupWebhookHost = net.DefaultResolver.LookupNetIP
+
+// webhookAddressBlocked 判断地址是否不允许作为 Webhook 投递目标，链路本地地址包含云平台元数据地址 169.254.169.254
+func webhookAddressBlocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedWebhookPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWebhookHost 解析主机名，任意一个解析结果不允许投递时返回错误
+func checkWebhookHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if webhookAddressBlocked(addr) {
+			return errWebhookAddressBlocked
+		}
+		return nil
+	}
+
+	addrs, err := lookupWebhookHost(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("无法解析webhook地址: %s", host)
+	}
+	for _, addr := range addrs {
+		if webhookAddressBlocked(addr) {
+			return errWebhookAddressBlocked
+		}
+	}
+	return nil
+}
+
+// webhookDialControl 在建立连接前检查实际连接的地址，防止订阅后通过DNS重新绑定或重定向访问内网
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("无效的webhook连接地址: %s", address)
+	}
+	if webhookAddressBlocked(addrPort.Addr()) {
+		return errWebhookAddressBlocked
+	}
+	return nil
+}
+
+// newWebhookClient 创建投递 Webhook 的HTTP客户端，不使用环境变量中的代理，只连接允许投递的地址
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   webhookDialControl,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
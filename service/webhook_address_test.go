@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"saas-account/model"
+	"testing"
+	"time"
+)
+
+func TestWebhookAddressBlocked(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"fe80::1", true},
+		{"fd00:ec2::254", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"224.0.0.1", true},
+		{"168.63.129.16", true},
+		{"93.184.216.34", false},
+		{"8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := webhookAddressBlocked(netip.MustParseAddr(tt.addr)); got != tt.blocked {
+				t.Errorf("webhookAddressBlocked(%s) = %v, 期望 %v", tt.addr, got, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestValidateWebhookSubscription(t *testing.T) {
+	orig := lookupWebhookHost
+	lookupWebhookHost = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		switch host {
+		case "hooks.example.com":
+			return []netip.Addr{netip.MustParseAddr("93.184.216.34")}, nil
+		case "internal.example.com":
+			return []netip.Addr{netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.0.0.5")}, nil
+		case "metadata.example.com":
+			return []netip.Addr{netip.MustParseAddr("169.254.169.254")}, nil
+		default:
+			return nil, errors.New("no such host")
+		}
+	}
+	t.Cleanup(func() { lookupWebhookHost = orig })
+
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://hooks.example.com/webhook", false},
+		{"http://93.184.216.34:8080/webhook", false},
+		{"https://internal.example.com/webhook", true},
+		{"https://metadata.example.com/latest/meta-data", true},
+		{"http://127.0.0.1:8080/webhook", true},
+		{"http://[::1]/webhook", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"https://unknown.example.com/webhook", true},
+		{"ftp://hooks.example.com/webhook", true},
+		{"https:///webhook", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			sub := &model.WebhookSubscription{URL: tt.url, EventTypes: `["*"]`}
+			err := validateWebhookSubscription(context.Background(), sub)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateWebhookSubscription(%s) 错误 = %v, 期望出错 %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookClientRefusesBlockedAddress(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// 订阅时地址合法，投递时解析或重定向到回环地址也不能连接
+	resp, err := newWebhookClient(5*time.Second).Post(server.URL, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("期望拒绝连接回环地址")
+	}
+	if !errors.Is(err, errWebhookAddressBlocked) {
+		t.Errorf("错误 = %v, 期望 %v", err, errWebhookAddressBlocked)
+	}
+	if called {
+		t.Error("请求不应到达服务端")
+	}
+}
@@ -0,0 +1,455 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"saas-account/logger"
+	"saas-account/model"
+	"saas-account/outbox"
+	"saas-account/repository"
+	"saas-account/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// webhookMaxBackoff 重试间隔上限
+const webhookMaxBackoff = 6 * time.Hour
+
+// WebhookService Webhook 服务接口，同时作为发件箱中继的投递目标
+type WebhookService interface {
+	outbox.Sink
+	CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) (string, error)
+	GetSubscription(ctx context.Context, orgID, id int64) (*model.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, orgID int64) ([]model.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, sub *model.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, orgID, id int64) error
+	RotateSecret(ctx context.Context, orgID, id int64) (string, error)
+	ListDeliveries(ctx context.Context, orgID, subID int64, status string, page, pageSize int) ([]model.WebhookDelivery, int64, error)
+	GetDelivery(ctx context.Context, orgID, id int64) (*model.WebhookDelivery, error)
+	Redeliver(ctx context.Context, orgID, id int64) (*model.WebhookDelivery, error)
+	ProcessDue(ctx context.Context) (int, error)
+}
+
+// webhookService Webhook 服务实现
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	orgRepo     repository.OrganizationRepository
+	client      *http.Client
+	maxAttempts int
+	retryBase   time.Duration
+}
+
+// NewWebhookService 创建 Webhook 服务，投递失败时按 retryBase 指数退避重试，最多尝试 maxAttempts 次
+func NewWebhookService(
+	webhookRepo repository.WebhookRepository,
+	orgRepo repository.OrganizationRepository,
+	timeout time.Duration,
+	maxAttempts int,
+	retryBase time.Duration,
+) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		orgRepo:     orgRepo,
+		client:      newWebhookClient(timeout),
+		maxAttempts: maxAttempts,
+		retryBase:   retryBase,
+	}
+}
+
+// webhookEvent 投递给订阅方的事件内容
+type webhookEvent struct {
+	ID             string          `json:"id"`              // 事件ID，订阅方可据此去重
+	Type           string          `json:"type"`            // 事件类型
+	OrganizationId int64           `json:"organization_id"` // 事件所属组织ID，0表示不属于任何组织
+	CreatedAt      int64           `json:"created_at"`      // 事件时间
+	Data           json.RawMessage `json:"data"`            // 事件数据
+}
+
+// generateWebhookSecret 生成签名密钥
+func generateWebhookSecret() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(bytes), nil
+}
+
+// signWebhookPayload 计算签名：HMAC-SHA256(secret, "{timestamp}.{body}")
+func signWebhookPayload(secret string, timestamp int64, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", timestamp, body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseEventTypes 解析并校验订阅的事件类型
+func parseEventTypes(raw string) ([]string, error) {
+	var types []string
+	if err := json.Unmarshal([]byte(raw), &types); err != nil {
+		return nil, errors.New("事件类型必须是有效的JSON数组")
+	}
+	if len(types) == 0 {
+		return nil, errors.New("至少需要订阅一种事件类型")
+	}
+
+	for _, t := range types {
+		if t == "*" {
+			continue
+		}
+		known := false
+		for _, eventType := range eventTypes {
+			if t == eventType {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("不支持的事件类型: %s", t)
+		}
+	}
+	return types, nil
+}
+
+// validateWebhookSubscription 校验订阅，地址解析到内网或元数据地址时拒绝
+func validateWebhookSubscription(ctx context.Context, sub *model.WebhookSubscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("无效的webhook地址")
+	}
+	if err := checkWebhookHost(ctx, u.Hostname()); err != nil {
+		return err
+	}
+
+	_, err = parseEventTypes(sub.EventTypes)
+	return err
+}
+
+// subscribed 判断订阅是否包含事件类型
+func subscribed(sub *model.WebhookSubscription, eventType string) bool {
+	types, err := parseEventTypes(sub.EventTypes)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if t == "*" || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// retryBackoff 计算第 attempts 次失败后的重试间隔，每次翻倍，不超过 max
+func retryBackoff(base time.Duration, attempts int, max time.Duration) time.Duration {
+	backoff := base
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= max {
+			return max
+		}
+	}
+	return backoff
+}
+
+// CreateSubscription 创建订阅，返回签名密钥，密钥只在创建和轮换时返回
+func (s *webhookService) CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) (string, error) {
+	// 检查组织是否存在
+	if sub.OrganizationId != 0 {
+		if _, err := s.orgRepo.GetByID(ctx, sub.OrganizationId); err != nil {
+			return "", err
+		}
+	}
+
+	if err := validateWebhookSubscription(ctx, sub); err != nil {
+		return "", err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return "", err
+	}
+	sub.Secret = secret
+
+	if err := s.webhookRepo.CreateSubscription(ctx, sub); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// GetSubscription 获取组织的订阅
+func (s *webhookService) GetSubscription(ctx context.Context, orgID, id int64) (*model.WebhookSubscription, error) {
+	sub, err := s.webhookRepo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.OrganizationId != orgID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return sub, nil
+}
+
+// ListSubscriptions 获取组织的所有订阅
+func (s *webhookService) ListSubscriptions(ctx context.Context, orgID int64) ([]model.WebhookSubscription, error) {
+	return s.webhookRepo.GetSubscriptionsByOrganization(ctx, orgID)
+}
+
+// UpdateSubscription 更新订阅的地址、事件类型、描述和启用状态，签名密钥只能通过轮换修改
+func (s *webhookService) UpdateSubscription(ctx context.Context, sub *model.WebhookSubscription) error {
+	existing, err := s.GetSubscription(ctx, sub.OrganizationId, sub.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := validateWebhookSubscription(ctx, sub); err != nil {
+		return err
+	}
+
+	existing.URL = sub.URL
+	existing.EventTypes = sub.EventTypes
+	existing.Description = sub.Description
+	existing.Enabled = sub.Enabled
+	if err := s.webhookRepo.UpdateSubscription(ctx, existing); err != nil {
+		return err
+	}
+
+	*sub = *existing
+	return nil
+}
+
+// DeleteSubscription 删除组织的订阅，未完成的投递将不再重试
+func (s *webhookService) DeleteSubscription(ctx context.Context, orgID, id int64) error {
+	if _, err := s.GetSubscription(ctx, orgID, id); err != nil {
+		return err
+	}
+	return s.webhookRepo.DeleteSubscription(ctx, id)
+}
+
+// RotateSecret 轮换订阅的签名密钥，返回新密钥
+func (s *webhookService) RotateSecret(ctx context.Context, orgID, id int64) (string, error) {
+	sub, err := s.GetSubscription(ctx, orgID, id)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return "", err
+	}
+	sub.Secret = secret
+
+	if err := s.webhookRepo.UpdateSubscription(ctx, sub); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ListDeliveries 获取组织的投递记录
+func (s *webhookService) ListDeliveries(ctx context.Context, orgID, subID int64, status string, page, pageSize int) ([]model.WebhookDelivery, int64, error) {
+	return s.webhookRepo.GetDeliveries(ctx, orgID, subID, status, page, pageSize)
+}
+
+// GetDelivery 获取组织的投递记录
+func (s *webhookService) GetDelivery(ctx context.Context, orgID, id int64) (*model.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.OrganizationId != orgID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return delivery, nil
+}
+
+// Redeliver 使用原事件内容创建一条新的投递记录，由投递任务尽快发送
+func (s *webhookService) Redeliver(ctx context.Context, orgID, id int64) (*model.WebhookDelivery, error) {
+	original, err := s.GetDelivery(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 订阅已删除时不能重新投递
+	if _, err := s.GetSubscription(ctx, orgID, original.SubscriptionId); err != nil {
+		return nil, err
+	}
+
+	delivery := &model.WebhookDelivery{
+		SubscriptionId: original.SubscriptionId,
+		OrganizationId: original.OrganizationId,
+		EventId:        original.EventId,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         model.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now().Unix(),
+		RedeliveryOf:   original.ID,
+	}
+	if err := s.webhookRepo.CreateDeliveries(ctx, []*model.WebhookDelivery{delivery}); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Publish 为订阅了该事件的组织订阅和平台级订阅创建投递记录，由投递任务异步发送
+// 中继重试已投递过的事件时不再重复创建投递记录
+func (s *webhookService) Publish(ctx context.Context, msg *outbox.Message) error {
+	exists, err := s.webhookRepo.HasDeliveries(ctx, msg.ID)
+	if err != nil || exists {
+		return err
+	}
+
+	subs, err := s.webhookRepo.GetEnabledSubscriptions(ctx, msg.OrganizationId)
+	if err != nil {
+		return err
+	}
+
+	var matched []model.WebhookSubscription
+	for i := range subs {
+		if subscribed(&subs[i], msg.Type) {
+			matched = append(matched, subs[i])
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(webhookEvent{
+		ID:             msg.ID,
+		Type:           msg.Type,
+		OrganizationId: msg.OrganizationId,
+		CreatedAt:      msg.OccurredAt,
+		Data:           msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	deliveries := make([]*model.WebhookDelivery, 0, len(matched))
+	for _, sub := range matched {
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			SubscriptionId: sub.ID,
+			OrganizationId: sub.OrganizationId,
+			EventId:        msg.ID,
+			EventType:      msg.Type,
+			Payload:        string(payload),
+			Status:         model.WebhookDeliveryPending,
+			NextAttemptAt:  now,
+		})
+	}
+	return s.webhookRepo.CreateDeliveries(ctx, deliveries)
+}
+
+// ProcessDue 发送已到投递时间的投递记录，返回投递成功的数量
+func (s *webhookService) ProcessDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	deliveries, err := s.webhookRepo.GetDueDeliveries(ctx, now.Unix(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	// 租约需要覆盖一次请求的超时时间
+	lease := now.Add(s.client.Timeout + 30*time.Second).Unix()
+
+	succeeded := 0
+	subs := make(map[int64]*model.WebhookSubscription)
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		claimed, err := s.webhookRepo.ClaimDelivery(ctx, delivery.ID, delivery.NextAttemptAt, lease)
+		if err != nil {
+			return succeeded, err
+		}
+		if !claimed {
+			continue
+		}
+
+		sub, ok := subs[delivery.SubscriptionId]
+		if !ok {
+			sub, err = s.webhookRepo.GetSubscription(ctx, delivery.SubscriptionId)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return succeeded, err
+			}
+			subs[delivery.SubscriptionId] = sub
+		}
+
+		s.attempt(ctx, delivery, sub)
+		if err := s.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+			logger.GetLogger().ErrorWithContext(ctx, "更新投递记录失败: 投递=%d, 错误: %v", delivery.ID, err)
+			continue
+		}
+		if delivery.Status == model.WebhookDeliverySucceeded {
+			succeeded++
+		}
+	}
+
+	return succeeded, nil
+}
+
+// attempt 发送一次投递并记录结果，失败时安排重试或标记为失败
+func (s *webhookService) attempt(ctx context.Context, delivery *model.WebhookDelivery, sub *model.WebhookSubscription) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = now.Unix()
+	delivery.ResponseStatus = 0
+	delivery.LastError = ""
+	delivery.DurationMs = 0
+
+	// 订阅已删除或停用时不再投递
+	if sub == nil || !sub.Enabled {
+		delivery.Status = model.WebhookDeliveryFailed
+		delivery.LastError = "订阅已删除或已停用"
+		return
+	}
+
+	err := s.send(ctx, delivery, sub)
+	delivery.DurationMs = time.Since(now).Milliseconds()
+	if err == nil {
+		delivery.Status = model.WebhookDeliverySucceeded
+		return
+	}
+
+	delivery.LastError = utils.TruncateString(err.Error(), 500)
+	if delivery.Attempts >= s.maxAttempts {
+		delivery.Status = model.WebhookDeliveryFailed
+		return
+	}
+	delivery.Status = model.WebhookDeliveryPending
+	delivery.NextAttemptAt = now.Add(retryBackoff(s.retryBase, delivery.Attempts, webhookMaxBackoff)).Unix()
+}
+
+// send 发送签名后的事件，响应状态码不是2xx时返回错误
+func (s *webhookService) send(ctx context.Context, delivery *model.WebhookDelivery, sub *model.WebhookSubscription) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", delivery.EventId)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", fmt.Sprintf("%d", delivery.ID))
+	req.Header.Set("X-Webhook-Signature", fmt.Sprintf("t=%d,v1=%s", timestamp, signWebhookPayload(sub.Secret, timestamp, delivery.Payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 只记录状态码，不保存响应内容；读取少量响应内容以便复用连接
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	delivery.ResponseStatus = resp.StatusCode
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"saas-account/logger"
+	"sync"
+	"time"
+)
+
+// Hook 子系统的停止函数，ctx 到期时应尽快返回
+type Hook struct {
+	Name string
+	Stop func(ctx context.Context) error
+}
+
+// Result 停止函数的执行结果
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+var (
+	hooks   []Hook
+	hooksMu sync.Mutex
+
+	// shuttingDown 是否已开始关闭，开始后不再接收新的停止函数
+	shuttingDown bool
+)
+
+// Register 注册子系统的停止函数，关闭时按注册的相反顺序执行，先启动的子系统最后停止
+// 开始关闭后注册的停止函数不会再执行，记录警告后忽略
+func Register(name string, stop func(ctx context.Context) error) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	if shuttingDown {
+		logger.GetLogger().Warn("服务正在关闭，忽略停止函数 %s 的注册", name)
+		return
+	}
+	hooks = append(hooks, Hook{Name: name, Stop: stop})
+}
+
+// ShuttingDown 是否已开始关闭
+func ShuttingDown() bool {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	return shuttingDown
+}
+
+// Run 按注册的相反顺序依次执行全部停止函数并返回每个函数的执行结果，只有第一次调用会执行
+// ctx 到期后剩余的停止函数仍会执行，由停止函数根据已取消的 ctx 决定是否放弃等待
+func Run(ctx context.Context) []Result {
+	hooksMu.Lock()
+	if shuttingDown {
+		hooksMu.Unlock()
+		return nil
+	}
+	shuttingDown = true
+	pending := append([]Hook(nil), hooks...)
+	hooksMu.Unlock()
+
+	results := make([]Result, 0, len(pending))
+	for i := len(pending) - 1; i >= 0; i-- {
+		results = append(results, run(ctx, pending[i]))
+	}
+	return results
+}
+
+// run 执行单个停止函数，停止函数 panic 时记为失败，不影响其他停止函数
+func run(ctx context.Context, hook Hook) (result Result) {
+	start := time.Now()
+	result.Name = hook.Name
+	defer func() {
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("panic: %v", r)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	result.Err = hook.Stop(ctx)
+	return result
+}
@@ -2,7 +2,8 @@ package utils
 
 import (
 	"log"
-	"os"
+	config2 "saas-account/config"
+	"saas-account/metrics"
 	"strconv"
 	"sync"
 	"time"
@@ -16,27 +17,11 @@ var (
 // GetIDGenerator 获取全局ID生成器实例
 func GetIDGenerator() *Snowflake {
 	idGeneratorOnce.Do(func() {
-		// 从环境变量获取机器ID和数据中心ID，如果不存在则使用默认值
-		workerIDStr := os.Getenv("SNOWFLAKE_WORKER_ID")
-		datacenterIDStr := os.Getenv("SNOWFLAKE_DATACENTER_ID")
-
-		workerID := int64(1)
-		datacenterID := int64(1)
-
-		if workerIDStr != "" {
-			if id, err := strconv.ParseInt(workerIDStr, 10, 64); err == nil {
-				workerID = id
-			}
-		}
-
-		if datacenterIDStr != "" {
-			if id, err := strconv.ParseInt(datacenterIDStr, 10, 64); err == nil {
-				datacenterID = id
-			}
-		}
+		// 机器ID和数据中心ID来自配置
+		appConfig := config2.GetConfig()
 
 		var err error
-		idGenerator, err = NewSnowflake(workerID, datacenterID)
+		idGenerator, err = NewSnowflake(appConfig.SnowflakeWorkerID, appConfig.SnowflakeDatacenterID)
 		if err != nil {
 			log.Fatalf("初始化ID生成器失败: %v", err)
 		}
@@ -49,10 +34,14 @@ func GetIDGenerator() *Snowflake {
 func GenerateID() int64 {
 	id, err := GetIDGenerator().NextID()
 	if err != nil {
+		// NextID 只在时钟回退时返回错误
+		metrics.SnowflakeClockRollbacks.Inc()
 		log.Printf("生成ID失败: %v", err)
 		// 如果生成失败，返回当前时间戳作为备用ID
 		return time.Now().UnixNano()
 	}
+
+	metrics.SnowflakeIDsGenerated.Inc()
 	return id
 }
 
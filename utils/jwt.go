@@ -10,7 +10,7 @@ import (
 
 // JWTClaims 自定义JWT声明
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
+	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Role     string `json:"role"`
@@ -18,7 +18,7 @@ type JWTClaims struct {
 }
 
 // GenerateToken 生成JWT令牌
-func GenerateToken(userID uint, username, email, role string) (string, error) {
+func GenerateToken(userID int64, username, email, role string) (string, error) {
 	config := config2.GetConfig()
 
 	// 设置过期时间
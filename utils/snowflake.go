@@ -89,3 +89,25 @@ func (s *Snowflake) NextID() (int64, error) {
 
 	return id, nil
 }
+
+// SnowflakeParts Snowflake ID 的组成部分
+type SnowflakeParts struct {
+	Time         time.Time `json:"time"`          // 生成时间，精确到毫秒
+	DatacenterID int64     `json:"datacenter_id"` // 数据中心ID
+	WorkerID     int64     `json:"worker_id"`     // 机器ID
+	Sequence     int64     `json:"sequence"`      // 同一毫秒内的序列号
+}
+
+// DecodeSnowflake 将 Snowflake ID 拆分为生成时间、数据中心ID、机器ID和序列号
+func DecodeSnowflake(id int64) (*SnowflakeParts, error) {
+	if id <= 0 {
+		return nil, errors.New("无效的Snowflake ID")
+	}
+
+	return &SnowflakeParts{
+		Time:         time.UnixMilli((id >> timestampLeftShift) + twepoch),
+		DatacenterID: (id >> datacenterIDShift) & maxDatacenterID,
+		WorkerID:     (id >> workerIDShift) & maxWorkerID,
+		Sequence:     id & maxSequence,
+	}, nil
+}